@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+
+	jwtmanager "jwt_manager"
 )
 
 // Config - структура для хранения конфигурации приложения
@@ -17,10 +22,76 @@ type Config struct {
 	DBSSL                  string // Параметры SSL для подключения к базе данных
 	DBTimeout              int    // Таймаут для операций с базой данных в секундах
 	JWTSecretKey           string // Секретный ключ для JWT токенов
+	JWTAlgorithm           string // Алгоритм подписи JWT: HS256, RS256 или EdDSA
+	JWTPrivateKey          []byte // Закрытый ключ в формате PEM для RS256 и EdDSA
+	JWTKeysFile            string // Файл связки ключей для ротации; если задан, заменяет JWTSecretKey и JWTPrivateKey
+	JWTIssuer              string // Издатель токенов (iss)
+	JWTAudience            string // Аудитория токенов (aud)
+	JWTClockSkew           int    // Допустимое расхождение часов при проверке токенов в секундах
 	AccessTokenExpiration  int    // Срок действия access токена в часах
 	RefreshTokenExpiration int    // Срок действия refresh токена в часах
+	RedisHost              string // Хост Redis сервера (общее хранилище отозванных токенов); для sqlite и memory необязателен
+	RedisPort              string // Порт Redis сервера
+	RedisPassword          string // Пароль для подключения к Redis
+	LoginMaxUserAttempts   int    // Число неудачных попыток входа для имени пользователя до блокировки
+	LoginMaxIPAttempts     int    // Число неудачных попыток входа с одного IP до блокировки
+	LoginLockoutBase       int    // Длительность первой блокировки входа в секундах
+	LoginLockoutMax        int    // Максимальная длительность блокировки входа в секундах
+	LoginAttemptWindow     int    // Время хранения счетчика неудачных попыток входа в секундах
+	PasswordMinLength      int    // Минимальная длина пароля
+	PasswordRequireUpper   bool   // Пароль должен содержать заглавную букву
+	PasswordRequireLower   bool   // Пароль должен содержать строчную букву
+	PasswordRequireDigit   bool   // Пароль должен содержать цифру
+	PasswordRequireSymbol  bool   // Пароль должен содержать специальный символ
+	PasswordForbidUsername bool   // Пароль не может содержать имя пользователя
+	PasswordBreachedFile   string // Файл со списком утекших паролей, по одному на строку
+	TOTPEnabled            bool   // Пользователи могут включать двухфакторную аутентификацию
+	TOTPIssuer             string // Название сервиса в приложении-аутентификаторе
+	TOTPEncryptionKey      string // Ключ шифрования секретов TOTP в базе данных (32 байта в base64)
+	MFATokenTTL            int    // Срок действия промежуточного токена второго фактора в секундах
+	MailDriver             string // Способ отправки писем: smtp или log
+	SMTPHost               string // Хост SMTP сервера
+	SMTPPort               string // Порт SMTP сервера
+	SMTPUsername           string // Имя пользователя SMTP сервера
+	SMTPPassword           string // Пароль SMTP сервера
+	MailFrom               string // Адрес отправителя писем
+	MailLogFile            string // Файл для писем при MailDriver=log; если пустой, письма выводятся в stdout
+	AppBaseURL             string // Адрес клиентского приложения для ссылок в письмах
+	EmailVerificationTTL   int    // Срок действия ссылки подтверждения адреса в секундах
+	PasswordResetTTL       int    // Срок действия ссылки сброса пароля в секундах
+	EmailMaxAddressSends   int    // Число писем подтверждения или сброса пароля на один адрес за EmailSendWindow
+	EmailMaxIPSends        int    // Число запросов писем с одного IP за EmailSendWindow
+	EmailSendWindow        int    // Окно ограничения отправки писем в секундах
+	OAuthIssuerURL         string // Внешний адрес сервиса auth для документа обнаружения OAuth2
+	OAuthCodeTTL           int    // Срок действия кода авторизации OAuth2 в секундах
+	IntrospectionCacheTTL  int    // Время хранения разобранного токена в кеше интроспекции в секундах
+	IntrospectionCacheSize int    // Максимальное число токенов в кеше интроспекции
+	EventsStream           string // Redis Stream, в который публикуются доменные события
+	EventsStreamMaxLen     int    // Приблизительная максимальная длина потока событий
+	OutboxPollInterval     int    // Период опроса таблицы outbox в секундах
+	OutboxBatchSize        int    // Число событий, публикуемых за одну транзакцию
+	OutboxRetention        int    // Сколько часов хранить опубликованные события
+	AccountDeletionGrace   int    // Срок в часах, в течение которого удаленную учетную запись можно восстановить
+	AccountPurgeInterval   int    // Период окончательного удаления учетных записей с истекшим сроком в секундах
+	DBAutoMigrate          bool   // Применять миграции базы данных при запуске сервера
+	StoreBackend           string // Хранилище пользователей: postgres, sqlite или memory
+	SQLitePath             string // Путь к файлу базы SQLite для хранилища sqlite
+	UsernameMinLength      int    // Минимальная длина имени пользователя
+	UsernameMaxLength      int    // Максимальная длина имени пользователя
+	UsernameReserved       string // Дополнительные зарезервированные имена через запятую
+	UsernameChangeCooldown int    // Сколько часов после смены имени нельзя сменить его снова
+	GRPCPort               string // Порт gRPC API; пустое значение отключает gRPC сервер
+	AdminUsername          string // Пользователь, получающий роль admin при запуске сервера
+	AdminPassword          string // Пароль для создания AdminUsername, если такого пользователя еще нет
 }
 
+// Хранилища пользователей
+const (
+	STORE_POSTGRES = "postgres" // PostgreSQL, основное хранилище
+	STORE_SQLITE   = "sqlite"   // Файл SQLite для локального запуска без контейнера базы данных
+	STORE_MEMORY   = "memory"   // Память процесса, данные теряются при перезапуске
+)
+
 // NewConfig - конструктор для создания новой конфигурации
 // Возвращает указатель на Config с параметрами по умолчанию
 func NewConfig() *Config {
@@ -88,6 +159,46 @@ func NewConfig() *Config {
 		fmt.Println("Не удалось получить JWT_SECRET_KEY из переменной окружения, используется значение по умолчанию")
 	}
 
+	// Алгоритм подписи, по умолчанию HS256 с общим секретом
+	jwtAlgorithm := "HS256"
+	if envValue, err := getEnv("JWT_ALGORITHM"); err == nil {
+		jwtAlgorithm = envValue
+	}
+
+	// Закрытый ключ для асимметричных алгоритмов читается из файла,
+	// а при первом запуске создается, чтобы ключ подписи не хранился в репозитории
+	var jwtPrivateKey []byte
+	if keyFile, err := getEnv("JWT_PRIVATE_KEY_FILE"); err == nil {
+		jwtPrivateKey, err = readOrCreatePrivateKey(keyFile, jwtAlgorithm)
+		if err != nil {
+			fmt.Printf("Не удалось прочитать закрытый ключ JWT из файла %s: %v\n", keyFile, err)
+		}
+	} else if jwtAlgorithm != "HS256" {
+		fmt.Println("Не удалось получить JWT_PRIVATE_KEY_FILE из переменной окружения")
+	}
+
+	// Связка ключей для ротации без выхода пользователей из системы
+	jwtKeysFile, err := getEnv("JWT_KEYS_FILE")
+	if err != nil {
+		fmt.Println("Не удалось получить JWT_KEYS_FILE из переменной окружения, используется один ключ подписи")
+	}
+
+	// Издатель и аудитория токенов, проверяются всеми сервисами
+	jwtIssuer, err := getEnv("JWT_ISSUER")
+	if err != nil {
+		fmt.Println("Не удалось получить JWT_ISSUER из переменной окружения, издатель не проверяется")
+	}
+	jwtAudience, err := getEnv("JWT_AUDIENCE")
+	if err != nil {
+		fmt.Println("Не удалось получить JWT_AUDIENCE из переменной окружения, аудитория не проверяется")
+	}
+	jwtClockSkew := 30 // по умолчанию 30 секунд
+	if envValue, err := getEnv("JWT_CLOCK_SKEW"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			jwtClockSkew = parsed
+		}
+	}
+
 	accessTokenExpiration := 24 // по умолчанию 24 часа
 	if envValue, err := getEnv("JWT_ACCESS_TOKEN_EXPIRATION"); err == nil {
 		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
@@ -102,17 +213,384 @@ func NewConfig() *Config {
 		}
 	}
 
+	// Redis используется как общее с сервисом notes хранилище отозванных токенов
+	redisHost, err := getEnv("REDIS_HOST")
+	if err != nil {
+		fmt.Println("Не удалось получить REDIS_HOST из переменной окружения")
+	}
+	redisPort, err := getEnv("REDIS_PORT")
+	if err != nil {
+		fmt.Println("Не удалось получить REDIS_PORT из переменной окружения")
+	}
+	redisPassword, err := getEnv("REDIS_PASSWORD")
+	if err != nil {
+		fmt.Println("Не удалось получить REDIS_PASSWORD из переменной окружения")
+	}
+
+	// Защита входа от подбора пароля
+	loginMaxUserAttempts := 5 // по умолчанию 5 попыток
+	if envValue, err := getEnv("LOGIN_MAX_USER_ATTEMPTS"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			loginMaxUserAttempts = parsed
+		}
+	}
+	loginMaxIPAttempts := 20 // по умолчанию 20 попыток
+	if envValue, err := getEnv("LOGIN_MAX_IP_ATTEMPTS"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			loginMaxIPAttempts = parsed
+		}
+	}
+	loginLockoutBase := 30 // по умолчанию 30 секунд
+	if envValue, err := getEnv("LOGIN_LOCKOUT_BASE"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			loginLockoutBase = parsed
+		}
+	}
+	loginLockoutMax := 900 // по умолчанию 15 минут
+	if envValue, err := getEnv("LOGIN_LOCKOUT_MAX"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			loginLockoutMax = parsed
+		}
+	}
+	loginAttemptWindow := 900 // по умолчанию 15 минут
+	if envValue, err := getEnv("LOGIN_ATTEMPT_WINDOW"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			loginAttemptWindow = parsed
+		}
+	}
+
+	// Политика паролей
+	passwordMinLength := 8 // по умолчанию 8 символов
+	if envValue, err := getEnv("PASSWORD_MIN_LENGTH"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			passwordMinLength = parsed
+		}
+	}
+	passwordRequireUpper := true
+	if envValue, err := getEnv("PASSWORD_REQUIRE_UPPER"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			passwordRequireUpper = parsed
+		}
+	}
+	passwordRequireLower := true
+	if envValue, err := getEnv("PASSWORD_REQUIRE_LOWER"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			passwordRequireLower = parsed
+		}
+	}
+	passwordRequireDigit := true
+	if envValue, err := getEnv("PASSWORD_REQUIRE_DIGIT"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			passwordRequireDigit = parsed
+		}
+	}
+	passwordRequireSymbol := false
+	if envValue, err := getEnv("PASSWORD_REQUIRE_SYMBOL"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			passwordRequireSymbol = parsed
+		}
+	}
+	passwordForbidUsername := true
+	if envValue, err := getEnv("PASSWORD_FORBID_USERNAME"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			passwordForbidUsername = parsed
+		}
+	}
+	passwordBreachedFile, err := getEnv("PASSWORD_BREACHED_FILE")
+	if err != nil {
+		fmt.Println("Не удалось получить PASSWORD_BREACHED_FILE из переменной окружения, проверка по списку утекших паролей отключена")
+	}
+
+	// Двухфакторная аутентификация
+	totpEnabled := true
+	if envValue, err := getEnv("TOTP_ENABLED"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			totpEnabled = parsed
+		}
+	}
+	totpIssuer := "Notes" // по умолчанию Notes
+	if envValue, err := getEnv("TOTP_ISSUER"); err == nil {
+		totpIssuer = envValue
+	}
+	totpEncryptionKey, err := getEnv("TOTP_ENCRYPTION_KEY")
+	if err != nil {
+		fmt.Println("Не удалось получить TOTP_ENCRYPTION_KEY из переменной окружения")
+	}
+	mfaTokenTTL := 300 // по умолчанию 5 минут
+	if envValue, err := getEnv("MFA_TOKEN_TTL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			mfaTokenTTL = parsed
+		}
+	}
+
+	// Отправка писем
+	// Значения по умолчанию нет: драйвер log записывает в лог действующие ссылки сброса пароля,
+	// поэтому его нужно выбрать явно, а без MAIL_DRIVER сервер не запускается
+	mailDriver, err := getEnv("MAIL_DRIVER")
+	if err != nil {
+		fmt.Println("Не удалось получить MAIL_DRIVER из переменной окружения")
+	}
+	smtpHost, _ := getEnv("SMTP_HOST")
+	smtpPort := "587" // по умолчанию порт submission
+	if envValue, err := getEnv("SMTP_PORT"); err == nil {
+		smtpPort = envValue
+	}
+	smtpUsername, _ := getEnv("SMTP_USERNAME")
+	smtpPassword, _ := getEnv("SMTP_PASSWORD")
+	mailFrom := "noreply@localhost"
+	if envValue, err := getEnv("MAIL_FROM"); err == nil {
+		mailFrom = envValue
+	}
+	mailLogFile, _ := getEnv("MAIL_LOG_FILE")
+	appBaseURL := "http://localhost:3000"
+	if envValue, err := getEnv("APP_BASE_URL"); err == nil {
+		appBaseURL = envValue
+	} else {
+		fmt.Println("Не удалось получить APP_BASE_URL из переменной окружения, используется http://localhost:3000")
+	}
+	emailVerificationTTL := 86400 // по умолчанию 24 часа
+	if envValue, err := getEnv("EMAIL_VERIFICATION_TTL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			emailVerificationTTL = parsed
+		}
+	}
+	passwordResetTTL := 3600 // по умолчанию 1 час
+	if envValue, err := getEnv("PASSWORD_RESET_TTL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			passwordResetTTL = parsed
+		}
+	}
+	// Ограничение запросов писем, чтобы через сервис нельзя было засыпать чужой ящик письмами
+	emailMaxAddressSends := 3 // по умолчанию 3 письма
+	if envValue, err := getEnv("EMAIL_MAX_ADDRESS_SENDS"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			emailMaxAddressSends = parsed
+		}
+	}
+	emailMaxIPSends := 20 // по умолчанию 20 запросов
+	if envValue, err := getEnv("EMAIL_MAX_IP_SENDS"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			emailMaxIPSends = parsed
+		}
+	}
+	emailSendWindow := 3600 // по умолчанию 1 час
+	if envValue, err := getEnv("EMAIL_SEND_WINDOW"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed > 0 {
+			emailSendWindow = parsed
+		}
+	}
+	oauthIssuerURL := "http://localhost:8101"
+	if envValue, err := getEnv("OAUTH_ISSUER_URL"); err == nil {
+		oauthIssuerURL = strings.TrimRight(envValue, "/")
+	} else {
+		fmt.Println("Не удалось получить OAUTH_ISSUER_URL из переменной окружения, используется http://localhost:8101")
+	}
+	oauthCodeTTL := 60 // по умолчанию 1 минута
+	if envValue, err := getEnv("OAUTH_CODE_TTL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			oauthCodeTTL = parsed
+		}
+	}
+	introspectionCacheTTL := 30 // по умолчанию 30 секунд
+	if envValue, err := getEnv("INTROSPECTION_CACHE_TTL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			introspectionCacheTTL = parsed
+		}
+	}
+	introspectionCacheSize := 10000 // по умолчанию 10000 токенов
+	if envValue, err := getEnv("INTROSPECTION_CACHE_SIZE"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			introspectionCacheSize = parsed
+		}
+	}
+	eventsStream := "auth:events"
+	if envValue, err := getEnv("EVENTS_STREAM"); err == nil {
+		eventsStream = envValue
+	}
+	eventsStreamMaxLen := 100000 // по умолчанию 100000 событий
+	if envValue, err := getEnv("EVENTS_STREAM_MAXLEN"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			eventsStreamMaxLen = parsed
+		}
+	}
+	outboxPollInterval := 1 // по умолчанию 1 секунда
+	if envValue, err := getEnv("OUTBOX_POLL_INTERVAL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed > 0 {
+			outboxPollInterval = parsed
+		}
+	}
+	outboxBatchSize := 100 // по умолчанию 100 событий
+	if envValue, err := getEnv("OUTBOX_BATCH_SIZE"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed > 0 {
+			outboxBatchSize = parsed
+		}
+	}
+	outboxRetention := 168 // по умолчанию 7 дней
+	if envValue, err := getEnv("OUTBOX_RETENTION"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil {
+			outboxRetention = parsed
+		}
+	}
+	accountDeletionGrace := 720 // по умолчанию 30 дней
+	if envValue, err := getEnv("ACCOUNT_DELETION_GRACE"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed >= 0 {
+			accountDeletionGrace = parsed
+		}
+	}
+	accountPurgeInterval := 3600 // по умолчанию 1 час
+	if envValue, err := getEnv("ACCOUNT_PURGE_INTERVAL"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed > 0 {
+			accountPurgeInterval = parsed
+		}
+	}
+	// Миграции можно применять отдельно командой "migrate up"
+	dbAutoMigrate := true
+	if envValue, err := getEnv("DB_AUTO_MIGRATE"); err == nil {
+		if parsed, parseErr := strconv.ParseBool(envValue); parseErr == nil {
+			dbAutoMigrate = parsed
+		}
+	}
+	storeBackend := STORE_POSTGRES
+	if envValue, err := getEnv("STORE_BACKEND"); err == nil {
+		storeBackend = envValue
+	}
+	sqlitePath := "auth.db"
+	if envValue, err := getEnv("SQLITE_PATH"); err == nil {
+		sqlitePath = envValue
+	}
+	usernameMinLength := 3
+	if envValue, err := getEnv("USERNAME_MIN_LENGTH"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed > 0 {
+			usernameMinLength = parsed
+		}
+	}
+	usernameMaxLength := 32
+	if envValue, err := getEnv("USERNAME_MAX_LENGTH"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed >= usernameMinLength {
+			usernameMaxLength = parsed
+		}
+	}
+	// Встроенный список зарезервированных имен дополняется, а не заменяется
+	usernameReserved, _ := getEnv("USERNAME_RESERVED")
+	usernameChangeCooldown := 720 // по умолчанию 30 дней
+	if envValue, err := getEnv("USERNAME_CHANGE_COOLDOWN"); err == nil {
+		if parsed, parseErr := strconv.Atoi(envValue); parseErr == nil && parsed >= 0 {
+			usernameChangeCooldown = parsed
+		}
+	}
+	// gRPC API работает рядом с HTTP на отдельном порту, "off" отключает его
+	grpcPort := "8105"
+	if envValue, err := getEnv("GRPC_PORT"); err == nil {
+		grpcPort = envValue
+	}
+	if grpcPort == "off" {
+		grpcPort = ""
+	}
+	// Первый администратор назначается при запуске, без ручного изменения базы данных
+	adminUsername, _ := getEnv("ADMIN_USERNAME")
+	adminPassword, _ := getEnv("ADMIN_PASSWORD")
+
 	return &Config{
 		Port:                   port,
 		Host:                   host,
 		DBDSN:                  dbDSN, // Строка подключения к базе данных
 		DBSSL:                  dbSSL,
 		JWTSecretKey:           jwtSecretKey,
+		JWTAlgorithm:           jwtAlgorithm,
+		JWTPrivateKey:          jwtPrivateKey,
+		JWTKeysFile:            jwtKeysFile,
+		JWTIssuer:              jwtIssuer,
+		JWTAudience:            jwtAudience,
+		JWTClockSkew:           jwtClockSkew,
 		AccessTokenExpiration:  accessTokenExpiration,
 		RefreshTokenExpiration: refreshTokenExpiration,
 		Timeout:                timeout,   // Таймаут для операций с сервером
 		DBTimeout:              dbTimeout, // Таймаут для операций с базой данных
+		RedisHost:              redisHost,
+		RedisPort:              redisPort,
+		RedisPassword:          redisPassword,
+		LoginMaxUserAttempts:   loginMaxUserAttempts,
+		LoginMaxIPAttempts:     loginMaxIPAttempts,
+		LoginLockoutBase:       loginLockoutBase,
+		LoginLockoutMax:        loginLockoutMax,
+		LoginAttemptWindow:     loginAttemptWindow,
+		PasswordMinLength:      passwordMinLength,
+		PasswordRequireUpper:   passwordRequireUpper,
+		PasswordRequireLower:   passwordRequireLower,
+		PasswordRequireDigit:   passwordRequireDigit,
+		PasswordRequireSymbol:  passwordRequireSymbol,
+		PasswordForbidUsername: passwordForbidUsername,
+		PasswordBreachedFile:   passwordBreachedFile,
+		TOTPEnabled:            totpEnabled,
+		TOTPIssuer:             totpIssuer,
+		TOTPEncryptionKey:      totpEncryptionKey,
+		MFATokenTTL:            mfaTokenTTL,
+		MailDriver:             mailDriver,
+		SMTPHost:               smtpHost,
+		SMTPPort:               smtpPort,
+		SMTPUsername:           smtpUsername,
+		SMTPPassword:           smtpPassword,
+		MailFrom:               mailFrom,
+		MailLogFile:            mailLogFile,
+		AppBaseURL:             appBaseURL,
+		EmailVerificationTTL:   emailVerificationTTL,
+		PasswordResetTTL:       passwordResetTTL,
+		EmailMaxAddressSends:   emailMaxAddressSends,
+		EmailMaxIPSends:        emailMaxIPSends,
+		EmailSendWindow:        emailSendWindow,
+		OAuthIssuerURL:         oauthIssuerURL,
+		OAuthCodeTTL:           oauthCodeTTL,
+		IntrospectionCacheTTL:  introspectionCacheTTL,
+		IntrospectionCacheSize: introspectionCacheSize,
+		EventsStream:           eventsStream,
+		EventsStreamMaxLen:     eventsStreamMaxLen,
+		OutboxPollInterval:     outboxPollInterval,
+		OutboxBatchSize:        outboxBatchSize,
+		OutboxRetention:        outboxRetention,
+		AccountDeletionGrace:   accountDeletionGrace,
+		AccountPurgeInterval:   accountPurgeInterval,
+		DBAutoMigrate:          dbAutoMigrate,
+		StoreBackend:           storeBackend,
+		SQLitePath:             sqlitePath,
+		UsernameMinLength:      usernameMinLength,
+		UsernameMaxLength:      usernameMaxLength,
+		UsernameReserved:       usernameReserved,
+		UsernameChangeCooldown: usernameChangeCooldown,
+		GRPCPort:               grpcPort,
+		AdminUsername:          adminUsername,
+		AdminPassword:          adminPassword,
+	}
+}
+
+// readOrCreatePrivateKey читает закрытый ключ JWT из файла path
+// Если файла нет, создает новый ключ для алгоритма algorithm и сохраняет его с правами 0600
+func readOrCreatePrivateKey(path, algorithm string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if !errors.Is(err, os.ErrNotExist) || algorithm == jwtmanager.ALG_HS256 {
+		return data, err
+	}
+
+	data, err = jwtmanager.GeneratePrivateKeyPEM(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	// O_EXCL не дает перезаписать ключ, созданный одновременно другой репликой
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return nil, err
 	}
+	fmt.Printf("Создан новый закрытый ключ JWT %s в файле %s\n", algorithm, path)
+	return data, nil
 }
 
 // getEnv получает значение переменной окружения
@@ -10,44 +10,72 @@ import (
 	"gorm.io/gorm"
 )
 
+// Пауза между попытками подключения к базе данных удваивается от минимальной до максимальной
+const (
+	connectRetryMinDelay = 250 * time.Millisecond
+	connectRetryMaxDelay = 5 * time.Second
+)
+
 // NewDatabase - функция для создания нового подключения к базе данных
-// Принимает контекст, DSN (строку подключения)
+// Подключается к базе из конфигурации и, если включено DBAutoMigrate, применяет встроенные миграции
 // Возвращает указатель на gorm.DB или ошибку, если она произошла
-func NewDatabase(cfg *config.Config, models ...any) (*gorm.DB, error) {
+func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 
 	// Создаем контекст с таймаутом для инициализации БД
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
 	// Отмена контекста при завершении работы функции
 	defer cancel()
 
-	// Добавляем задержку в 1 секунду, // чтобы дать время на инициализацию других компонентов, если это необходимо
-	// Это может быть полезно, если база данных запускается в контейнере или сервисе, который требует времени на инициализацию
-	// Например, если база данных запускается в Docker-контейнере, то может потребоваться время на его запуск и готовность к соединению
-	time.Sleep(1 * time.Second)
-
-	// Применяем контекст к подключению к БД
-	db, err := gorm.Open(postgres.Open(cfg.DBDSN), &gorm.Config{})
+	db, err := Connect(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %v", "ошибка подключения к базе данных", err)
+		return nil, err
 	}
 
-	errMigration := runMigrations(db, models...) // Выполняем миграции
-	if errMigration != nil {
-		return nil, fmt.Errorf("%s: %v", "ошибка миграции базы данных", errMigration)
+	if cfg.DBAutoMigrate {
+		if err := runMigrations(ctx, db); err != nil {
+			return nil, fmt.Errorf("%s: %v", "ошибка миграции базы данных", err)
+		}
 	}
 
 	return db.WithContext(ctx), nil
 }
 
-// Автоматические миграции для моделей
-func runMigrations(db *gorm.DB, models ...any) error {
-	// Выполняем миграции для всех переданных моделей
-	for _, model := range models {
-		if err := db.AutoMigrate(model); err != nil {
-			return fmt.Errorf("ошибка миграции модели %T: %w", model, err)
+// Connect подключается к базе данных, повторяя попытки до отмены ctx
+// База данных, запущенная в соседнем контейнере, может быть еще не готова принимать соединения
+func Connect(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	delay := connectRetryMinDelay
+	for {
+		db, err := gorm.Open(postgres.Open(cfg.DBDSN), &gorm.Config{
+			// Ошибки драйвера приводятся к ошибкам gorm, например, gorm.ErrDuplicatedKey
+			TranslateError: true,
+		})
+		if err == nil {
+			return db, nil
 		}
+		fmt.Printf("База данных недоступна, повтор через %s: %v\n", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %v", "ошибка подключения к базе данных", err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, connectRetryMaxDelay)
+	}
+}
+
+// runMigrations применяет встроенные миграции, еще не примененные к базе данных
+func runMigrations(ctx context.Context, db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		fmt.Printf("Применена миграция %04d_%s\n", migration.Version, migration.Name)
 	}
-	// Если все миграции прошли успешно, возвращаем nil
 	fmt.Println("Все миграции успешно выполнены")
 	return nil
 }
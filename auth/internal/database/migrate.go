@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles - SQL миграции, встроенные в исполняемый файл
+// Каждая миграция состоит из пары файлов NNNN_название.up.sql и NNNN_название.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID - ключ рекомендательной блокировки Postgres на время миграций
+// Несколько одновременно запущенных экземпляров сервиса применяют миграции по очереди
+const migrationLockID = 7_270_518_901
+
+// migrationFileName - формат имени файла миграции
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// goSteps - шаги миграций на Go, которые нельзя выразить на SQL
+// Шаг выполняется в транзакции миграции с той же версией после ее SQL файла up
+var goSteps = map[int]func(tx *gorm.DB) error{
+	7: backfillNormalizedUsernames,
+}
+
+// Migration - одна версионированная миграция схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL применения миграции
+	Down    string // SQL отката миграции
+}
+
+// MigrationStatus - состояние миграции в базе данных
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil, если миграция не применена
+	Missing   bool       // Миграция применена, но отсутствует в исполняемом файле
+}
+
+// schemaMigration - запись о примененной миграции в таблице schema_migrations
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// createSchemaMigrations создает таблицу истории миграций
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    applied_at timestamptz NOT NULL
+)`
+
+// TableName задает имя таблицы истории миграций
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator применяет и откатывает встроенные миграции
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration // Миграции в порядке возрастания версии
+}
+
+// NewMigrator создает мигратор для базы данных db
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up применяет все еще не примененные миграции по возрастанию версии
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations
+// Возвращает примененные миграции
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				if step, ok := goSteps[migration.Version]; ok {
+					if err := step(tx); err != nil {
+						return err
+					}
+				}
+				return tx.Create(&schemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("миграция %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних примененных миграций
+// Возвращает откаченные миграции
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("число откатываемых миграций должно быть положительным: %d", steps)
+	}
+
+	byVersion := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		var records []schemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			migration, ok := byVersion[record.Version]
+			if !ok {
+				return fmt.Errorf("миграция %04d_%s отсутствует в исполняемом файле", record.Version, record.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("откат миграции %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает состояние всех известных и примененных миграций по возрастанию версии
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if record, ok := done[migration.Version]; ok {
+				status.AppliedAt = &record.AppliedAt
+				delete(done, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		// Миграции, примененные более новой версией сервиса
+		for _, record := range done {
+			statuses = append(statuses, MigrationStatus{
+				Version:   record.Version,
+				Name:      record.Name,
+				AppliedAt: &record.AppliedAt,
+				Missing:   true,
+			})
+		}
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].Version < statuses[j].Version
+		})
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock выполняет fn на одном соединении под рекомендательной блокировкой
+// Блокировка сессионная, поэтому захват и освобождение выполняются на том же соединении
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+		// Контекст может быть уже отменен, блокировка освобождается в любом случае
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+
+		if err := conn.Exec(createSchemaMigrations).Error; err != nil {
+			return fmt.Errorf("не удалось создать таблицу schema_migrations: %w", err)
+		}
+		return fn(conn)
+	})
+}
+
+// appliedVersions возвращает примененные миграции по версиям
+func appliedVersions(conn *gorm.DB) (map[int]schemaMigration, error) {
+	var records []schemaMigration
+	if err := conn.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]schemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
+
+// loadMigrations читает миграции из files и проверяет, что у каждой есть up и down
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("неверное имя файла миграции: %s", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("неверная версия миграции %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(files, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("у миграции %04d разные названия: %s и %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет файла up или down", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
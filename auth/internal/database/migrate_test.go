@@ -0,0 +1,221 @@
+package database
+
+import (
+	"auth/internal/config"
+	"auth/internal/models"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("миграция %04d_%s: ожидалась версия %d", migration.Version, migration.Name, i+1)
+		}
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"неверное имя", fstest.MapFS{"migrations/first.up.sql": file("SELECT 1")}},
+		{"нет down", fstest.MapFS{"migrations/0001_first.up.sql": file("SELECT 1")}},
+		{"пустой up", fstest.MapFS{
+			"migrations/0001_first.up.sql":   file(""),
+			"migrations/0001_first.down.sql": file("SELECT 1"),
+		}},
+		{"разные названия", fstest.MapFS{
+			"migrations/0001_first.up.sql":    file("SELECT 1"),
+			"migrations/0001_second.down.sql": file("SELECT 1"),
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := loadMigrations(tt.files); err == nil {
+			t.Errorf("%s: ожидалась ошибка", tt.name)
+		}
+	}
+
+	migrations, err := loadMigrations(fstest.MapFS{
+		"migrations/0010_second.up.sql":   file("SELECT 2"),
+		"migrations/0010_second.down.sql": file("SELECT -2"),
+		"migrations/0002_first.up.sql":    file("SELECT 1"),
+		"migrations/0002_first.down.sql":  file("SELECT -1"),
+	})
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Version != 10 || migrations[1].Down != "SELECT -2" {
+		t.Errorf("loadMigrations = %+v", migrations)
+	}
+}
+
+// TestMigratorPostgres проверяет миграции на PostgreSQL, если задан AUTH_TEST_POSTGRES_DSN
+// Миграции применяются в отдельной схеме, которая удаляется после теста
+func TestMigratorPostgres(t *testing.T) {
+	dsn := os.Getenv("AUTH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("AUTH_TEST_POSTGRES_DSN не задан")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	db := testSchema(ctx, t, dsn)
+
+	// База, созданная AutoMigrate до появления миграций
+	if err := db.Exec(`CREATE TABLE users (
+		id bigserial PRIMARY KEY,
+		username text NOT NULL CONSTRAINT uni_users_username UNIQUE,
+		password text NOT NULL
+	)`).Error; err != nil {
+		t.Fatalf("CREATE TABLE users: %v", err)
+	}
+	if err := db.Exec("INSERT INTO users (username, password) VALUES ('Alice', 'hash'), ('ÄRGER', 'hash')").Error; err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("Up: применено %d миграций из %d", len(applied), len(migrator.migrations))
+	}
+
+	// Существующий пользователь получает новые столбцы со значениями по умолчанию
+	var user struct {
+		Role               string
+		Disabled           bool
+		UsernameNormalized string
+	}
+	if err := db.Raw("SELECT role, disabled, username_normalized FROM users WHERE username = 'Alice'").Scan(&user).Error; err != nil {
+		t.Fatalf("SELECT: %v", err)
+	}
+	if user.Role != "user" || user.Disabled || user.UsernameNormalized != "alice" {
+		t.Errorf("новые столбцы существующего пользователя: %+v", user)
+	}
+	// Нормализованное имя совпадает с models.NormalizeUsername и для букв вне ASCII, которые
+	// lower() в базе с локалью C не сворачивает
+	var normalized string
+	if err := db.Raw("SELECT username_normalized FROM users WHERE username = 'ÄRGER'").Scan(&normalized).Error; err != nil {
+		t.Fatalf("SELECT: %v", err)
+	}
+	if normalized != models.NormalizeUsername("ÄRGER") {
+		t.Errorf("username_normalized = %q, ожидалось %q", normalized, models.NormalizeUsername("ÄRGER"))
+	}
+
+	if again, err := migrator.Up(ctx); err != nil || len(again) != 0 {
+		t.Errorf("повторный Up = %d миграций, %v", len(again), err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil || status.Missing {
+			t.Errorf("Status: миграция %04d_%s не отмечена примененной", status.Version, status.Name)
+		}
+	}
+
+	// Все миграции откатываются и применяются заново
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	if err != nil || len(reverted) != len(migrator.migrations) {
+		t.Fatalf("Down = %d миграций, %v", len(reverted), err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != len(migrator.migrations) {
+		t.Errorf("Up после Down = %d миграций, %v", len(applied), err)
+	}
+}
+
+// TestNormalizedUsernameConflicts проверяет, что миграция останавливается на совпадающих именах
+// и не переименовывает пользователей, если задан AUTH_TEST_POSTGRES_DSN
+func TestNormalizedUsernameConflicts(t *testing.T) {
+	dsn := os.Getenv("AUTH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("AUTH_TEST_POSTGRES_DSN не задан")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	db := testSchema(ctx, t, dsn)
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	// Применяем миграции до нормализации имен и создаем пользователей с совпадающими именами
+	for _, migration := range migrator.migrations {
+		if goSteps[migration.Version] != nil {
+			break
+		}
+		if err := db.Exec(migration.Up).Error; err != nil {
+			t.Fatalf("миграция %04d_%s: %v", migration.Version, migration.Name, err)
+		}
+	}
+	if err := db.Exec("INSERT INTO users (username, password) VALUES ('Alice', 'hash'), ('bob', 'hash'), ('ALICE', 'hash')").Error; err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+
+	_, err = migrator.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), `"Alice"`) || !strings.Contains(err.Error(), `"ALICE"`) || strings.Contains(err.Error(), `"bob"`) {
+		t.Fatalf("Up: ожидалась ошибка со списком совпадающих имен, получено %v", err)
+	}
+	var usernames []string
+	if err := db.Raw("SELECT username FROM users ORDER BY id").Scan(&usernames).Error; err != nil {
+		t.Fatalf("SELECT: %v", err)
+	}
+	if strings.Join(usernames, ",") != "Alice,bob,ALICE" {
+		t.Errorf("имена пользователей изменены: %v", usernames)
+	}
+}
+
+// testSchema создает отдельную схему, которая удаляется после теста,
+// и возвращает подключение, использующее ее по умолчанию
+func testSchema(ctx context.Context, t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	admin, err := Connect(ctx, &config.Config{DBDSN: dsn})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("CREATE SCHEMA: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := Connect(ctx, &config.Config{DBDSN: withSearchPath(dsn, schema)})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return db
+}
+
+// withSearchPath добавляет к строке подключения схему по умолчанию
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
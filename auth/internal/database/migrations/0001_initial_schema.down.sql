@@ -0,0 +1,3 @@
+-- Удаляет таблицу пользователей вместе с данными
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,9 @@
+-- Исходная схема базы данных сервиса auth: таблица users в том виде,
+-- в котором ее создавал AutoMigrate до появления версионированных миграций.
+-- В существующих базах таблица уже есть, и миграция только отмечается примененной
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    username text NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    password text NOT NULL
+);
@@ -0,0 +1,4 @@
+-- Удаляет сессии и refresh токены вместе с данными
+
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,27 @@
+-- Refresh токены с семействами для обнаружения повторного использования и сессии входа
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id varchar(64) PRIMARY KEY,
+    user_id bigint NOT NULL,
+    family_id varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    replaced_by varchar(64),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id varchar(64) PRIMARY KEY,
+    user_id bigint NOT NULL,
+    user_agent text,
+    ip varchar(64),
+    client_id varchar(64),
+    created_at timestamptz,
+    last_refreshed_at timestamptz,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_client_id ON sessions (client_id);
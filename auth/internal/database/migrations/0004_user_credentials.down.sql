@@ -0,0 +1,5 @@
+-- Удаляет коды восстановления, токены из писем и персональные токены вместе с данными
+
+DROP TABLE IF EXISTS personal_access_tokens;
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS recovery_codes;
@@ -0,0 +1,4 @@
+-- Удаляет клиентов и коды авторизации OAuth2 вместе с данными
+
+DROP TABLE IF EXISTS o_auth_authorization_codes;
+DROP TABLE IF EXISTS o_auth_clients;
@@ -0,0 +1,30 @@
+-- Клиенты и коды авторизации сервера OAuth2
+
+CREATE TABLE IF NOT EXISTS o_auth_clients (
+    id varchar(64) PRIMARY KEY,
+    owner_id bigint NOT NULL,
+    name text NOT NULL,
+    secret_hash text,
+    public boolean NOT NULL DEFAULT false,
+    redirect_uris text NOT NULL DEFAULT '',
+    grant_types text NOT NULL DEFAULT '',
+    scope text NOT NULL DEFAULT '',
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_o_auth_clients_owner_id ON o_auth_clients (owner_id);
+
+CREATE TABLE IF NOT EXISTS o_auth_authorization_codes (
+    code_hash varchar(64) PRIMARY KEY,
+    client_id varchar(64) NOT NULL,
+    user_id bigint NOT NULL,
+    redirect_uri text NOT NULL,
+    scope text NOT NULL DEFAULT '',
+    code_challenge text,
+    code_challenge_method text,
+    session_id varchar(64),
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_o_auth_authorization_codes_client_id ON o_auth_authorization_codes (client_id);
+CREATE INDEX IF NOT EXISTS idx_o_auth_authorization_codes_user_id ON o_auth_authorization_codes (user_id);
@@ -0,0 +1,3 @@
+-- Удаляет исходящие события вместе с данными
+
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,13 @@
+-- Исходящие события для ретранслятора в Redis Streams
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id bigserial PRIMARY KEY,
+    event_id varchar(64) NOT NULL,
+    type text NOT NULL,
+    aggregate_id bigint NOT NULL,
+    payload text NOT NULL,
+    created_at timestamptz NOT NULL,
+    published_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
@@ -0,0 +1,4 @@
+-- Удаляет нормализованные имена и время смены имени
+
+ALTER TABLE users DROP COLUMN IF EXISTS username_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS username_normalized;
@@ -0,0 +1,7 @@
+-- Имена пользователей уникальны без учета регистра: уникальность и поиск используют
+-- нормализованную форму имени, а в username сохраняется написание, выбранное пользователем.
+-- Нормализованные имена заполняет шаг миграции на Go (backfillNormalizedUsernames),
+-- чтобы они совпадали с models.NormalizeUsername
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_normalized text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at timestamptz;
@@ -0,0 +1,4 @@
+-- Возвращает уникальность имен с учетом регистра
+
+DROP INDEX IF EXISTS idx_users_username_normalized;
+ALTER TABLE users ALTER COLUMN username_normalized DROP NOT NULL;
@@ -0,0 +1,4 @@
+-- Нормализованные имена заполнены предыдущей миграцией и уникальны
+
+ALTER TABLE users ALTER COLUMN username_normalized SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_normalized ON users (username_normalized);
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSQLiteDatabase открывает базу SQLite по пути path и создает таблицы для моделей
+// Версионированные миграции написаны для PostgreSQL, поэтому схема SQLite создается AutoMigrate.
+// SQLite не поддерживает параллельную запись, поэтому используется одно соединение
+func NewSQLiteDatabase(path string, models ...any) (*gorm.DB, error) {
+	// busy_timeout - ожидание блокировки файла другим процессом вместо немедленной ошибки
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		// Ошибки драйвера приводятся к ошибкам gorm, например, gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", "ошибка подключения к базе данных", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	for _, model := range models {
+		if err := db.AutoMigrate(model); err != nil {
+			return nil, fmt.Errorf("%s: ошибка миграции модели %T: %v", "ошибка миграции базы данных", model, err)
+		}
+	}
+
+	return db, nil
+}
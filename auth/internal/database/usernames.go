@@ -0,0 +1,50 @@
+package database
+
+import (
+	"auth/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// backfillNormalizedUsernames заполняет нормализованные имена существующих пользователей
+// Имена нормализуются той же функцией, что и при поиске, поэтому SQL не повторяет ее правила.
+// Если имена нескольких пользователей совпадают без учета регистра, миграция останавливается
+// и перечисляет их: учетные записи нужно переименовать вручную и повторить миграцию
+func backfillNormalizedUsernames(tx *gorm.DB) error {
+	var users []struct {
+		ID       int
+		Username string
+	}
+	if err := tx.Raw("SELECT id, username FROM users ORDER BY id").Scan(&users).Error; err != nil {
+		return err
+	}
+
+	byName := make(map[string][]string, len(users))
+	for _, user := range users {
+		normalized := models.NormalizeUsername(user.Username)
+		byName[normalized] = append(byName[normalized], fmt.Sprintf("id=%d %q", user.ID, user.Username))
+	}
+	var conflicts []string
+	for _, rows := range byName {
+		if len(rows) > 1 {
+			conflicts = append(conflicts, strings.Join(rows, ", "))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("имена пользователей совпадают без учета регистра, переименуйте учетные записи и повторите миграцию: %s",
+			strings.Join(conflicts, "; "))
+	}
+
+	for _, user := range users {
+		err := tx.Exec("UPDATE users SET username_normalized = ? WHERE id = ?",
+			models.NormalizeUsername(user.Username), user.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,11 +5,15 @@ import "errors"
 // Общие ошибки сервиса аутентификации
 var (
 	// Ошибки пользователей
-	ErrUserNotFound       = errors.New("пользователь не найден")
-	ErrInvalidCredentials = errors.New("неверные учетные данные")
-	ErrUserAlreadyExists  = errors.New("пользователь уже существует")
-	ErrInvalidUserData    = errors.New("неверные данные пользователя")
-	ErrUserIdNotFound     = errors.New("ID пользователя не найден в контексте")
+	ErrUserNotFound        = errors.New("пользователь не найден")
+	ErrInvalidCredentials  = errors.New("неверные учетные данные")
+	ErrUserAlreadyExists   = errors.New("пользователь уже существует")
+	ErrInvalidUserData     = errors.New("неверные данные пользователя")
+	ErrWeakPassword        = errors.New("пароль не соответствует политике")
+	ErrUserIdNotFound      = errors.New("ID пользователя не найден в контексте")
+	ErrUserDisabled        = errors.New("учетная запись отключена")
+	ErrUserPendingDeletion = errors.New("учетная запись ожидает удаления")
+	ErrUsernameCooldown    = errors.New("имя пользователя менялось недавно")
 
 	// Ошибки авторизации
 	ErrMissingAuthHeader = errors.New("отсутствует заголовок Authorization")
@@ -18,20 +22,33 @@ var (
 	ErrInvalidToken      = errors.New("неверный или истекший токен")
 	ErrRefreshToken      = errors.New("неверный или истекший refresh токен")
 	ErrAuthRequired      = errors.New("необходима авторизация")
+	ErrRefreshTokenReuse = errors.New("повторное использование refresh токена")
+	ErrSessionNotFound   = errors.New("сессия не найдена")
+	ErrLoginLocked       = errors.New("слишком много неудачных попыток входа")
+	ErrInvalidTOTPCode   = errors.New("неверный код подтверждения")
+	ErrTOTPNotSetup      = errors.New("двухфакторная аутентификация не настроена")
+	ErrInvalidUserToken  = errors.New("недействительный или истекший токен")
+	ErrInvalidAuthCode   = errors.New("недействительный или истекший код авторизации")
+	ErrAuthCodeReuse     = errors.New("повторное использование кода авторизации")
+	ErrRedirectMismatch  = errors.New("redirect_uri не совпадает с указанным при авторизации")
+	ErrInvalidVerifier   = errors.New("неверный code_verifier")
 
 	// Ошибки токенов
 	ErrTokenGeneration = errors.New("ошибка генерации токенов")
 
 	// Ошибки базы данных
-	ErrDatabaseConnection = errors.New("ошибка подключения к базе данных")
-	ErrDatabaseMigration  = errors.New("ошибка выполнения миграций")
-	ErrDatabaseClose      = errors.New("ошибка закрытия соединения с базой данных")
-	ErrDatabaseOperation  = errors.New("ошибка операции с базой данных")
+	ErrDatabaseConnection  = errors.New("ошибка подключения к базе данных")
+	ErrDatabaseMigration   = errors.New("ошибка выполнения миграций")
+	ErrDatabaseClose       = errors.New("ошибка закрытия соединения с базой данных")
+	ErrDatabaseOperation   = errors.New("ошибка операции с базой данных")
+	ErrUnknownStoreBackend = errors.New("неизвестное хранилище пользователей")
+	ErrCacheConnection     = errors.New("ошибка подключения к Redis")
 
 	// Ошибки конфигурации
 	ErrMissingEnvVar   = errors.New("переменная окружения не установлена")
 	ErrEmptyDSN        = errors.New("строка подключения к базе данных не указана")
 	ErrDatabaseNotInit = errors.New("база данных не инициализирована")
+	ErrTOTPKeyRequired = errors.New("при включенной двухфакторной аутентификации нужен TOTP_ENCRYPTION_KEY")
 
 	// Ошибки сервиса
 	ErrServiceCreation = errors.New("ошибка создания сервиса")
@@ -42,11 +59,23 @@ var (
 // Сообщения для ошибок
 const (
 	// Сообщения для пользователей
-	MsgUserNotFound       = "Пользователь не найден"
-	MsgInvalidCredentials = "Неверные учетные данные"
-	MsgUserAlreadyExists  = "Пользователь уже существует"
-	MsgInvalidUserData    = "Неверные данные пользователя"
-	MsgUserIdNotFound     = "ID пользователя не найден в контексте"
+	MsgUserNotFound        = "Пользователь не найден"
+	MsgInvalidCredentials  = "Неверные учетные данные"
+	MsgUserAlreadyExists   = "Пользователь уже существует"
+	MsgInvalidUserData     = "Неверные данные пользователя"
+	MsgWeakPassword        = "Пароль не соответствует требованиям"
+	MsgUserIdNotFound      = "ID пользователя не найден в контексте"
+	MsgUserDisabled        = "Учетная запись отключена"
+	MsgUserPendingDeletion = "Учетная запись ожидает удаления, ее можно восстановить через /auth/restore"
+	MsgInvalidUserID       = "Неверный ID пользователя"
+	MsgUserNotDeleted      = "Учетная запись не ожидает удаления"
+	MsgUserRestoreExpired  = "Срок восстановления учетной записи истек"
+
+	// Сообщения для имен пользователей
+	MsgInvalidUsername        = "Имя пользователя не соответствует требованиям"
+	MsgUsernameTaken          = "Имя пользователя уже занято"
+	MsgUsernameCooldown       = "Имя пользователя менялось недавно, повторите позже"
+	MsgUsernameChangeEndpoint = "Имя пользователя меняется через PUT /auth/user/username"
 
 	// Сообщения для авторизации
 	MsgMissingAuthHeader = "Отсутствует заголовок Authorization"
@@ -55,6 +84,40 @@ const (
 	MsgInvalidToken      = "Неверный или истекший токен"
 	MsgRefreshToken      = "Неверный или истекший refresh токен"
 	MsgAuthRequired      = "Необходима авторизация"
+	MsgRefreshTokenReuse = "Обнаружено повторное использование refresh токена, все связанные сессии отозваны"
+	MsgSessionNotFound   = "Сессия не найдена"
+
+	// Сообщения для защиты входа
+	MsgTooManyLoginAttempts = "Слишком много неудачных попыток входа, повторите позже"
+	MsgLockoutCheck         = "Не удалось проверить блокировку входа"
+	MsgTooManyMailRequests  = "Слишком много запросов писем, повторите позже"
+	MsgMailLimitCheck       = "Не удалось проверить ограничение отправки писем"
+
+	// Сообщения для двухфакторной аутентификации
+	MsgMFARequired        = "Требуется код двухфакторной аутентификации"
+	MsgMFAToken           = "Неверный или истекший токен двухфакторной аутентификации"
+	MsgMFAUnavailable     = "Вход с двухфакторной аутентификацией недоступен: не настроено хранилище отозванных токенов"
+	MsgInvalidTOTPCode    = "Неверный код подтверждения"
+	MsgTOTPNotSetup       = "Двухфакторная аутентификация не настроена"
+	MsgTOTPNotEnabled     = "Двухфакторная аутентификация не включена"
+	MsgTOTPAlreadyEnabled = "Двухфакторная аутентификация уже включена"
+	MsgTOTPSetup          = "Отсканируйте QR-код в приложении-аутентификаторе и подтвердите кодом"
+	MsgTOTPEnabled        = "Двухфакторная аутентификация включена"
+	MsgTOTPDisabled       = "Двухфакторная аутентификация отключена"
+	MsgTOTPGeneration     = "Ошибка генерации секрета двухфакторной аутентификации"
+	MsgTOTPSecret         = "Ошибка шифрования секрета двухфакторной аутентификации"
+	MsgTOTPUnavailable    = "Двухфакторная аутентификация отключена на сервере"
+
+	// Сообщения для электронной почты и сброса пароля
+	MsgInvalidEmail         = "Неверный адрес электронной почты"
+	MsgEmailTaken           = "Адрес электронной почты уже используется"
+	MsgEmailNotSet          = "Адрес электронной почты не указан"
+	MsgEmailAlreadyVerified = "Адрес электронной почты уже подтвержден"
+	MsgVerificationSent     = "Письмо для подтверждения адреса отправлено"
+	MsgEmailVerified        = "Адрес электронной почты подтвержден"
+	MsgInvalidUserToken     = "Недействительная или устаревшая ссылка"
+	MsgPasswordResetSent    = "Если адрес зарегистрирован и подтвержден, на него отправлено письмо для сброса пароля"
+	MsgPasswordReset        = "Пароль изменен, все сессии завершены"
 
 	// Сообщения для токенов
 	MsgTokenGeneration = "Ошибка генерации токенов"
@@ -64,6 +127,8 @@ const (
 	MsgDatabaseMigration  = "Ошибка выполнения миграций"
 	MsgDatabaseClose      = "Ошибка закрытия соединения с базой данных"
 	MsgDatabaseOperation  = "Ошибка операции с базой данных"
+	MsgCacheConnection    = "Ошибка подключения к Redis"
+	MsgTokenRevocation    = "Ошибка отзыва токена"
 
 	// Сообщения для конфигурации
 	MsgMissingEnvVar   = "Переменная окружения не установлена"
@@ -80,5 +145,53 @@ const (
 	MsgLoginSuccess    = "Успешная авторизация"
 	MsgTokensRefreshed = "Токены успешно обновлены"
 	MsgUserUpdated     = "Данные пользователя успешно обновлены"
-	MsgUserDeleted     = "Пользователь успешно удален"
+	MsgUsernameChanged = "Имя пользователя изменено"
+	MsgUserDeleted     = "Учетная запись удалена и может быть восстановлена до окончания срока ожидания"
+	MsgUserRestored    = "Учетная запись восстановлена"
+	MsgLoggedOut       = "Выход из системы выполнен"
+	MsgLoggedOutAll    = "Выполнен выход на всех устройствах, персональные токены доступа отозваны"
+	MsgSessionsFound   = "Активные сессии получены"
+	MsgSessionRevoked  = "Сессия завершена"
+
+	// Сообщения для администрирования
+	MsgUsersFound          = "Пользователи получены"
+	MsgUserDisabledByAdmin = "Учетная запись отключена, все сессии пользователя завершены"
+	MsgUserEnabled         = "Учетная запись включена"
+	MsgPasswordResetForced = "Установлен временный пароль, пользователь должен сменить его после входа"
+	MsgUserSessionsRevoked = "Все сессии пользователя завершены"
+	MsgCannotDisableSelf   = "Нельзя отключить собственную учетную запись"
+	MsgPasswordGeneration  = "Ошибка генерации временного пароля"
+	MsgAdminRequired       = "Чужие учетные записи доступны только администратору"
+
+	// Сообщения для персональных токенов доступа
+	MsgPATCreated         = "Персональный токен создан, сохраните его: повторно он показан не будет"
+	MsgPATRevoked         = "Персональный токен отозван"
+	MsgPATNotFound        = "Персональный токен не найден"
+	MsgPATScopeNotAllowed = "Область доступа недоступна пользователю"
+	MsgPATStore           = "Ошибка хранилища персональных токенов"
+
+	// Сообщения для OAuth2
+	MsgOAuthClientCreated           = "OAuth клиент зарегистрирован, сохраните секрет: повторно он показан не будет"
+	MsgOAuthClientDeleted           = "OAuth клиент удален, выданные ему сессии завершены"
+	MsgOAuthClientNotFound          = "OAuth клиент не найден"
+	MsgOAuthScopeNotAllowed         = "Область доступа OAuth клиента недоступна его владельцу"
+	MsgOAuthInvalidRedirectURI      = "Неверный адрес возврата: нужен абсолютный https адрес без фрагмента или http для localhost"
+	MsgOAuthRedirectURIRequired     = "Для authorization_code нужен хотя бы один адрес возврата"
+	MsgOAuthUnsupportedGrant        = "Неподдерживаемый тип гранта"
+	MsgOAuthPublicClientCredentials = "Публичный клиент не может использовать client_credentials"
+	MsgOAuthClientAuth              = "Неверные учетные данные клиента"
+	MsgOAuthGrantNotAllowed         = "Тип гранта не разрешен клиенту"
+	MsgOAuthUnsupportedResponseType = "Поддерживается только response_type=code"
+	MsgOAuthPKCERequired            = "Публичный клиент должен использовать PKCE (code_challenge с методом S256)"
+	MsgOAuthInvalidPKCE             = "Неверный code_challenge или метод, поддерживается только S256"
+	MsgOAuthInvalidVerifier         = "Неверный code_verifier"
+	MsgOAuthInvalidCode             = "Недействительный или истекший код авторизации"
+	MsgOAuthCodeReuse               = "Код авторизации уже использован, выданные по нему токены отозваны"
+	MsgOAuthRedirectMismatch        = "redirect_uri не совпадает с указанным при авторизации"
+	MsgOAuthInvalidScope            = "Запрошенные области доступа недоступны"
+	MsgOAuthConsentRequired         = "Пользователь не подтвердил выдачу доступа клиенту"
+	MsgOAuthMissingParameter        = "Отсутствует обязательный параметр"
+	MsgOAuthClientTokenNotAllowed   = "Токен OAuth клиента не дает доступа к управлению учетной записью"
+	MsgOAuthPublicIntrospection     = "Публичный клиент не может проверять токены"
+	MsgRevocationCheck              = "Не удалось проверить статус отзыва токена"
 )
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"auth/internal/models"
+	"auth/internal/service"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Publisher публикует события outbox во внешний брокер
+// Публикация должна быть идемпотентной со стороны получателя: при сбое после
+// отправки событие будет отправлено повторно (доставка "хотя бы один раз")
+type Publisher interface {
+	Publish(ctx context.Context, events []models.OutboxEvent) error
+}
+
+// RedisStreamPublisher публикует события в Redis Stream
+// Каждое событие - отдельная запись с полями event_id, type, aggregate_id, payload и occurred_at
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+	maxLen int64 // Приблизительная максимальная длина потока, 0 - без ограничения
+}
+
+// NewRedisStreamPublisher создает публикатор событий в поток stream
+func NewRedisStreamPublisher(client *redis.Client, stream string, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+// Publish добавляет события в поток одним конвейером команд
+func (p *RedisStreamPublisher) Publish(ctx context.Context, events []models.OutboxEvent) error {
+	pipe := p.client.WithContext(ctx).Pipeline()
+	defer pipe.Close()
+
+	for _, event := range events {
+		pipe.XAdd(&redis.XAddArgs{
+			Stream:       p.stream,
+			MaxLenApprox: p.maxLen,
+			ID:           "*",
+			Values: map[string]interface{}{
+				"event_id":     event.EventID,
+				"type":         event.Type,
+				"aggregate_id": strconv.Itoa(event.AggregateID),
+				"payload":      event.Payload,
+				"occurred_at":  event.CreatedAt.UTC().Format(time.RFC3339Nano),
+			},
+		})
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// LogPublisher выводит события в стандартный вывод
+// Используется без Redis, когда читать события некому, чтобы outbox не рос
+type LogPublisher struct{}
+
+// Publish выводит события по одному на строку
+func (LogPublisher) Publish(ctx context.Context, events []models.OutboxEvent) error {
+	for _, event := range events {
+		fmt.Printf("Событие %s %s (пользователь %d): %s\n", event.EventID, event.Type, event.AggregateID, event.Payload)
+	}
+	return nil
+}
+
+// Relay периодически переносит события из таблицы outbox в брокер
+type Relay struct {
+	service   service.Service
+	publisher Publisher
+	interval  time.Duration // Период опроса таблицы outbox
+	batchSize int           // Число событий, публикуемых за одну транзакцию
+	retention time.Duration // Сколько хранить опубликованные события перед удалением
+}
+
+// NewRelay создает ретранслятор событий outbox
+func NewRelay(service service.Service, publisher Publisher, interval time.Duration, batchSize int, retention time.Duration) *Relay {
+	return &Relay{
+		service:   service,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+	}
+}
+
+// Run публикует события до отмены ctx
+// Ошибки публикации выводятся в лог, неопубликованные события повторяются на следующем шаге
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+	for {
+		r.publishPending(ctx)
+
+		// Опубликованные события удаляются не чаще раза в час
+		if r.retention > 0 && time.Since(lastPurge) >= time.Hour {
+			if _, err := r.service.PurgeOutbox(ctx, time.Now().Add(-r.retention)); err != nil {
+				fmt.Printf("Ошибка очистки опубликованных событий: %v\n", err)
+			}
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishPending публикует порции событий, пока в outbox есть неопубликованные события
+func (r *Relay) publishPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.service.PublishOutbox(ctx, r.batchSize, r.publisher.Publish)
+		if err != nil {
+			fmt.Printf("Ошибка публикации событий: %v\n", err)
+			return
+		}
+		if published < r.batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"auth/internal/models"
+	"auth/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// recordingPublisher запоминает опубликованные события и может завершаться ошибкой
+type recordingPublisher struct {
+	mu       sync.Mutex
+	events   []models.OutboxEvent
+	failures int // Сколько следующих публикаций завершатся ошибкой
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, events []models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("redis недоступен")
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func (p *recordingPublisher) published() []models.OutboxEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.OutboxEvent(nil), p.events...)
+}
+
+// newOutboxService создает сервис с событиями user.created для count пользователей
+func newOutboxService(t *testing.T, count int) service.Service {
+	t.Helper()
+	s := service.NewMemoryService()
+	for i := 0; i < count; i++ {
+		_, err := s.Create(context.Background(), &models.User{Username: fmt.Sprintf("user%d", i), Password: "Secret-password-1"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	return s
+}
+
+// TestRelayPublishPending проверяет, что ретранслятор публикует все события порциями,
+// отмечает их опубликованными и повторяет публикацию после ошибки
+func TestRelayPublishPending(t *testing.T) {
+	ctx := context.Background()
+	s := newOutboxService(t, 5)
+	publisher := &recordingPublisher{failures: 1}
+	relay := NewRelay(s, publisher, time.Minute, 2, 0)
+
+	// Ошибка публикации оставляет события в outbox
+	relay.publishPending(ctx)
+	if events := publisher.published(); len(events) != 0 {
+		t.Fatalf("опубликовано при ошибке: %d событий", len(events))
+	}
+
+	// Следующий запуск публикует все события, несмотря на размер порции
+	relay.publishPending(ctx)
+	events := publisher.published()
+	if len(events) != 5 {
+		t.Fatalf("опубликовано %d событий, ожидалось 5", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].ID <= events[i-1].ID {
+			t.Fatalf("события опубликованы не в порядке записи: %d после %d", events[i].ID, events[i-1].ID)
+		}
+	}
+
+	// Опубликованные события не публикуются повторно
+	relay.publishPending(ctx)
+	if events := publisher.published(); len(events) != 5 {
+		t.Errorf("после повторного запуска опубликовано %d событий, ожидалось 5", len(events))
+	}
+	published, err := s.PublishOutbox(ctx, 100, func(ctx context.Context, events []models.OutboxEvent) error { return nil })
+	if err != nil || published != 0 {
+		t.Errorf("PublishOutbox после ретранслятора = %d, %v, ожидалось отсутствие неопубликованных событий", published, err)
+	}
+}
+
+// TestRelayRun проверяет, что Run публикует события, удаляет опубликованные
+// по истечении срока хранения и завершается при отмене контекста
+func TestRelayRun(t *testing.T) {
+	s := newOutboxService(t, 2)
+	publisher := &recordingPublisher{}
+	relay := NewRelay(s, publisher, time.Millisecond, 10, time.Nanosecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.published()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Run не опубликовал события")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run не завершился после отмены контекста")
+	}
+
+	// Удаленные опубликованные события не возвращаются в outbox
+	if purged, err := s.PurgeOutbox(context.Background(), time.Now()); err != nil || purged != 0 {
+		t.Errorf("PurgeOutbox после Run = %d, %v, ожидалось, что события уже удалены", purged, err)
+	}
+}
+
+// TestRedisStreamPublisher проверяет формат записей, добавляемых в Redis Stream
+func TestRedisStreamPublisher(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	publisher := NewRedisStreamPublisher(client, "auth.events", 0)
+
+	event, err := models.NewUserEvent(models.EVENT_USER_DELETED, 42, "")
+	if err != nil {
+		t.Fatalf("NewUserEvent: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), []models.OutboxEvent{*event}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	messages, err := client.XRange("auth.events", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("в потоке %d записей, ожидалась 1", len(messages))
+	}
+	values := messages[0].Values
+	want := map[string]string{
+		"event_id":     event.EventID,
+		"type":         models.EVENT_USER_DELETED,
+		"aggregate_id": strconv.Itoa(42),
+		"payload":      event.Payload,
+		"occurred_at":  event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	for field, expected := range want {
+		if values[field] != expected {
+			t.Errorf("%s = %v, ожидалось %q", field, values[field], expected)
+		}
+	}
+}
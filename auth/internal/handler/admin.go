@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/service"
+	"auth/internal/username"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Параметры постраничного вывода пользователей
+const (
+	defaultUsersLimit = 20  // Размер страницы по умолчанию
+	maxUsersLimit     = 100 // Максимальный размер страницы
+)
+
+// ListUsers обрабатывает запрос администратора на получение списка пользователей
+// GET /auth/admin/users?username=&limit=&offset=
+func (h *Handler) ListUsers(c *gin.Context) {
+	filter := service.UserFilter{
+		Username: c.Query("username"),
+		Limit:    defaultUsersLimit,
+	}
+
+	// Разбираем параметры постраничного вывода
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			c.JSON(400, gin.H{
+				"error": errors.MsgInvalidData,
+			})
+			return
+		}
+		filter.Limit = min(limit, maxUsersLimit)
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(400, gin.H{
+				"error": errors.MsgInvalidData,
+			})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	users, total, err := h.service.ListUsers(ctx, filter)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Убираем пароли из ответа
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgUsersFound,
+		"users":   users,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// GetUser обрабатывает запрос администратора на получение пользователя по ID
+// GET /auth/admin/users/:id
+func (h *Handler) GetUser(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	// Убираем пароль из ответа
+	user.Password = ""
+
+	c.JSON(200, gin.H{
+		"user": user,
+	})
+}
+
+// DisableUser обрабатывает запрос администратора на отключение учетной записи
+// Отключенный пользователь не может войти и обновить токены, все его сессии завершаются
+// POST /auth/admin/users/:id/disable
+func (h *Handler) DisableUser(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Администратор не может отключить сам себя
+	if currentUserID, err := h.GetCurrentUserID(c); err == nil && currentUserID == userID {
+		c.JSON(400, gin.H{
+			"error": errors.MsgCannotDisableSelf,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if err := h.service.SetUserDisabled(ctx, userID, true); err != nil {
+		h.adminServiceError(c, err)
+		return
+	}
+
+	if msg, err := h.revokeUserAccess(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Персональные токены тоже перестают действовать
+	if msg, err := h.revokeUserPATs(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgUserDisabledByAdmin,
+	})
+}
+
+// EnableUser обрабатывает запрос администратора на включение учетной записи
+// POST /auth/admin/users/:id/enable
+func (h *Handler) EnableUser(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if err := h.service.SetUserDisabled(ctx, userID, false); err != nil {
+		h.adminServiceError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgUserEnabled,
+	})
+}
+
+// ResetUserPassword обрабатывает запрос администратора на принудительный сброс пароля
+// Устанавливает временный пароль, завершает все сессии пользователя
+// и возвращает временный пароль для передачи пользователю
+// POST /auth/admin/users/:id/reset-password
+func (h *Handler) ResetUserPassword(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	tempPassword, err := generateTempPassword()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgPasswordGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if err := h.service.ForcePasswordReset(ctx, userID, tempPassword); err != nil {
+		h.adminServiceError(c, err)
+		return
+	}
+
+	if msg, err := h.revokeUserAccess(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Персональные токены тоже перестают действовать
+	if msg, err := h.revokeUserPATs(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       errors.MsgPasswordResetForced,
+		"temp_password": tempPassword,
+	})
+}
+
+// RevokeUserSessions обрабатывает запрос администратора на завершение всех сессий пользователя
+// DELETE /auth/admin/users/:id/sessions
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if _, err := h.service.Read(ctx, userID); err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	if msg, err := h.revokeUserAccess(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgUserSessionsRevoked,
+	})
+}
+
+// BootstrapAdmin назначает роль admin пользователю AdminUsername из конфигурации
+// Если пользователя еще нет и задан AdminPassword, он создается, поэтому администратор
+// появляется и в хранилище в памяти, которое пустое при каждом запуске
+// Вызывается при запуске сервиса; без AdminUsername ничего не делает
+func (h *Handler) BootstrapAdmin(ctx context.Context) error {
+	if h.cfg.AdminUsername == "" {
+		return nil
+	}
+
+	user, err := h.service.ReadByUsername(ctx, h.cfg.AdminUsername)
+	if stderrors.Is(err, gorm.ErrRecordNotFound) && h.cfg.AdminPassword != "" {
+		user, err = h.createAdmin(ctx)
+	}
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: пользователь %s не найден, задайте ADMIN_PASSWORD для его создания", errors.ErrUserNotFound, h.cfg.AdminUsername)
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.Role == jwtmanager.ROLE_ADMIN {
+		return nil
+	}
+	return h.service.SetUserRole(ctx, user.ID, jwtmanager.ROLE_ADMIN)
+}
+
+// createAdmin создает пользователя AdminUsername с паролем AdminPassword
+// Имя и пароль проверяются теми же правилами, что и при регистрации,
+// но зарезервированное имя допускается: его выбрал оператор, а не посторонний пользователь
+func (h *Handler) createAdmin(ctx context.Context) (*models.User, error) {
+	name, violations := h.usernames.Validate(h.cfg.AdminUsername)
+	for _, violation := range violations {
+		if violation.Code != username.CODE_RESERVED {
+			return nil, fmt.Errorf("%w: ADMIN_USERNAME: %s", errors.ErrInvalidUserData, violation.Message)
+		}
+	}
+	if violations := h.passwords.Validate(name, h.cfg.AdminPassword); len(violations) > 0 {
+		return nil, fmt.Errorf("%w: ADMIN_PASSWORD: %s", errors.ErrWeakPassword, violations[0].Message)
+	}
+	return h.service.Create(ctx, &models.User{Username: name, Password: h.cfg.AdminPassword})
+}
+
+// userIDParam извлекает ID пользователя из пути запроса
+// При неверном ID отправляет ответ 400 и возвращает false
+func (h *Handler) userIDParam(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidUserID,
+		})
+		return 0, false
+	}
+	return userID, true
+}
+
+// adminServiceError отправляет ответ на ошибку сервиса при изменении пользователя
+func (h *Handler) adminServiceError(c *gin.Context, err error) {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+	c.JSON(500, gin.H{
+		"error":   errors.MsgDatabaseOperation,
+		"details": err.Error(),
+	})
+}
+
+// revokeUserAccess завершает все сессии пользователя и отзывает выпущенные ему access токены
+// Возвращает сообщение для ответа клиенту вместе с ошибкой
+func (h *Handler) revokeUserAccess(ctx context.Context, userID int) (string, error) {
+	if err := h.service.RevokeUserTokens(ctx, userID); err != nil {
+		return errors.MsgDatabaseOperation, err
+	}
+
+	// Отметка отзыва хранится столько же, сколько живет access токен
+	if store := h.jwtManager.RevocationStore(); store != nil {
+		if err := store.RevokeAllUserTokens(ctx, userID, h.jwtManager.AccessTokenTTL()); err != nil {
+			return errors.MsgTokenRevocation, err
+		}
+	}
+	return "", nil
+}
+
+// generateTempPassword генерирует случайный временный пароль
+func generateTempPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RequireAdmin возвращает middleware, пропускающий только администраторов
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return jwtmanager.RequireRole(jwtmanager.ROLE_ADMIN)
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAdminRouter подключает маршруты администрирования так же, как routes.SetupRouter
+func newAdminRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/auth/admin")
+	admin.Use(h.RequireAuth(), h.RequireFirstParty(), h.RequireAdmin())
+	{
+		admin.GET("/users", h.ListUsers)
+		admin.GET("/users/:id", h.GetUser)
+		admin.POST("/users/:id/disable", h.DisableUser)
+		admin.POST("/users/:id/enable", h.EnableUser)
+		admin.POST("/users/:id/reset-password", h.ResetUserPassword)
+		admin.DELETE("/users/:id/sessions", h.RevokeUserSessions)
+	}
+	return router
+}
+
+// adminRequest выполняет запрос к маршрутам администрирования с access токеном
+// и возвращает статус и разобранный ответ
+func adminRequest(t *testing.T, router *gin.Engine, method, path, accessToken string) (int, map[string]any) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	router.ServeHTTP(recorder, request)
+	var response map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", recorder.Body, err)
+	}
+	return recorder.Code, response
+}
+
+// loginAs выпускает пару токенов пользователю так же, как при входе
+func loginAs(t *testing.T, h *Handler, user *models.User) *jwtmanager.TokenPair {
+	t.Helper()
+	user, err := h.service.Read(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	pair, err := h.issueTokens(context.Background(), clientInfo{userAgent: "test", ip: "127.0.0.1"}, user)
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	return pair
+}
+
+// newAdminFixture создает администратора и обычного пользователя
+func newAdminFixture(t *testing.T) (*Handler, *models.User, *models.User) {
+	t.Helper()
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.pats = newMemoryPATs()
+	admin, err := h.service.Create(ctx, &models.User{Username: "root-admin", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := h.service.SetUserRole(ctx, admin.ID, jwtmanager.ROLE_ADMIN); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return h, admin, user
+}
+
+// TestAdminRequiresRole проверяет, что маршруты администрирования недоступны обычному пользователю
+func TestAdminRequiresRole(t *testing.T) {
+	h, _, user := newAdminFixture(t)
+	router := newAdminRouter(h)
+
+	status, _ := adminRequest(t, router, http.MethodGet, "/auth/admin/users", loginAs(t, h, user).AccessToken)
+	if status != http.StatusForbidden {
+		t.Errorf("ListUsers от обычного пользователя: статус %d, ожидался 403", status)
+	}
+	status, _ = adminRequest(t, router, http.MethodGet, "/auth/admin/users", "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("ListUsers без токена: статус %d, ожидался 401", status)
+	}
+}
+
+// TestAdminListUsers проверяет фильтр, постраничный вывод и отсутствие паролей в ответе
+func TestAdminListUsers(t *testing.T) {
+	h, admin, _ := newAdminFixture(t)
+	router := newAdminRouter(h)
+	token := loginAs(t, h, admin).AccessToken
+
+	status, body := adminRequest(t, router, http.MethodGet, "/auth/admin/users?limit=1&offset=1", token)
+	if status != http.StatusOK {
+		t.Fatalf("ListUsers: статус %d, ответ %v", status, body)
+	}
+	users, _ := body["users"].([]any)
+	if len(users) != 1 || body["total"] != float64(2) {
+		t.Fatalf("ListUsers: users=%v total=%v, ожидалась страница из 1 пользователя из 2", users, body["total"])
+	}
+	if password := users[0].(map[string]any)["password"]; password != "" && password != nil {
+		t.Errorf("ListUsers вернул пароль %v", password)
+	}
+
+	status, body = adminRequest(t, router, http.MethodGet, "/auth/admin/users?username=ALI", token)
+	if users, _ := body["users"].([]any); status != http.StatusOK || len(users) != 1 {
+		t.Errorf("ListUsers по имени: статус %d, ответ %v", status, body)
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "offset=-1"} {
+		if status, _ := adminRequest(t, router, http.MethodGet, "/auth/admin/users?"+query, token); status != http.StatusBadRequest {
+			t.Errorf("ListUsers?%s: статус %d, ожидался 400", query, status)
+		}
+	}
+}
+
+// TestAdminGetUser проверяет получение пользователя и ответы на неверный и несуществующий ID
+func TestAdminGetUser(t *testing.T) {
+	h, admin, user := newAdminFixture(t)
+	router := newAdminRouter(h)
+	token := loginAs(t, h, admin).AccessToken
+
+	status, body := adminRequest(t, router, http.MethodGet, "/auth/admin/users/"+strconv.Itoa(user.ID), token)
+	if status != http.StatusOK || body["user"].(map[string]any)["username"] != "alice" {
+		t.Errorf("GetUser: статус %d, ответ %v", status, body)
+	}
+	if status, _ := adminRequest(t, router, http.MethodGet, "/auth/admin/users/abc", token); status != http.StatusBadRequest {
+		t.Errorf("GetUser с неверным ID: статус %d, ожидался 400", status)
+	}
+	if status, _ := adminRequest(t, router, http.MethodGet, "/auth/admin/users/1000", token); status != http.StatusNotFound {
+		t.Errorf("GetUser несуществующего: статус %d, ожидался 404", status)
+	}
+}
+
+// TestAdminDisableUser проверяет, что отключение завершает сессии и отзывает персональные токены,
+// а включение снова разрешает вход
+func TestAdminDisableUser(t *testing.T) {
+	ctx := context.Background()
+	h, admin, user := newAdminFixture(t)
+	router := newAdminRouter(h)
+	token := loginAs(t, h, admin).AccessToken
+	userPair := loginAs(t, h, user)
+
+	patToken, hash, err := jwtmanager.GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+	pat := &models.PersonalAccessToken{ID: "pat", UserID: user.ID, Name: "ci", Scope: "notes:read", TokenHash: hash}
+	if err := h.service.CreatePAT(ctx, pat); err != nil {
+		t.Fatalf("CreatePAT: %v", err)
+	}
+	if err := h.pats.SavePAT(ctx, hash, patRecord(pat)); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+
+	// Администратор не может отключить сам себя
+	if status, _ := adminRequest(t, router, http.MethodPost, "/auth/admin/users/"+strconv.Itoa(admin.ID)+"/disable", token); status != http.StatusBadRequest {
+		t.Errorf("DisableUser себя: статус %d, ожидался 400", status)
+	}
+
+	path := "/auth/admin/users/" + strconv.Itoa(user.ID)
+	if status, body := adminRequest(t, router, http.MethodPost, path+"/disable", token); status != http.StatusOK {
+		t.Fatalf("DisableUser: статус %d, ответ %v", status, body)
+	}
+	claims, err := h.jwtManager.ValidateAccessTokenClaims(userPair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, claims); !stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+		t.Errorf("access токен отключенного пользователя не отозван: %v", err)
+	}
+	if record, err := h.activePAT(ctx, patToken); err != nil || record != nil {
+		t.Errorf("персональный токен отключенного пользователя действует: %v, %v", record, err)
+	}
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-1"); !stderrors.Is(err, errors.ErrUserDisabled) {
+		t.Errorf("Authenticate отключенного: %v, ожидалась ErrUserDisabled", err)
+	}
+
+	if status, body := adminRequest(t, router, http.MethodPost, path+"/enable", token); status != http.StatusOK {
+		t.Fatalf("EnableUser: статус %d, ответ %v", status, body)
+	}
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-1"); err != nil {
+		t.Errorf("Authenticate после включения: %v", err)
+	}
+	if status, _ := adminRequest(t, router, http.MethodPost, "/auth/admin/users/1000/disable", token); status != http.StatusNotFound {
+		t.Errorf("DisableUser несуществующего: статус %d, ожидался 404", status)
+	}
+}
+
+// TestAdminResetUserPassword проверяет, что временный пароль заменяет старый,
+// требует смены при входе и завершает сессии пользователя
+func TestAdminResetUserPassword(t *testing.T) {
+	ctx := context.Background()
+	h, admin, user := newAdminFixture(t)
+	router := newAdminRouter(h)
+	token := loginAs(t, h, admin).AccessToken
+	userPair := loginAs(t, h, user)
+
+	status, body := adminRequest(t, router, http.MethodPost, "/auth/admin/users/"+strconv.Itoa(user.ID)+"/reset-password", token)
+	if status != http.StatusOK {
+		t.Fatalf("ResetUserPassword: статус %d, ответ %v", status, body)
+	}
+	tempPassword, _ := body["temp_password"].(string)
+	if tempPassword == "" {
+		t.Fatalf("ResetUserPassword не вернул временный пароль: %v", body)
+	}
+
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-1"); err == nil {
+		t.Error("старый пароль действует после сброса")
+	}
+	authenticated, err := h.service.Authenticate(ctx, "alice", tempPassword)
+	if err != nil {
+		t.Fatalf("Authenticate с временным паролем: %v", err)
+	}
+	if !authenticated.PasswordResetRequired {
+		t.Error("после сброса не требуется смена пароля")
+	}
+	claims, err := h.jwtManager.ValidateAccessTokenClaims(userPair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, claims); !stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+		t.Errorf("access токен не отозван после сброса пароля: %v", err)
+	}
+}
+
+// TestAdminRevokeUserSessions проверяет завершение всех сессий пользователя
+func TestAdminRevokeUserSessions(t *testing.T) {
+	ctx := context.Background()
+	h, admin, user := newAdminFixture(t)
+	router := newAdminRouter(h)
+	token := loginAs(t, h, admin).AccessToken
+	userPair := loginAs(t, h, user)
+
+	status, body := adminRequest(t, router, http.MethodDelete, "/auth/admin/users/"+strconv.Itoa(user.ID)+"/sessions", token)
+	if status != http.StatusOK {
+		t.Fatalf("RevokeUserSessions: статус %d, ответ %v", status, body)
+	}
+	if sessions, err := h.service.ListSessions(ctx, user.ID); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions после завершения = %v, %v", sessions, err)
+	}
+	claims, err := h.jwtManager.ValidateAccessTokenClaims(userPair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, claims); !stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+		t.Errorf("access токен не отозван: %v", err)
+	}
+	if status, _ := adminRequest(t, router, http.MethodDelete, "/auth/admin/users/1000/sessions", token); status != http.StatusNotFound {
+		t.Errorf("RevokeUserSessions несуществующего: статус %d, ожидался 404", status)
+	}
+}
+
+// TestBootstrapAdmin проверяет назначение администратора из конфигурации при запуске
+func TestBootstrapAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("создает пользователя", func(t *testing.T) {
+		h := newTestHandler(t, newMemoryRevocations())
+		// Зарезервированное имя допускается, его выбрал оператор
+		h.cfg.AdminUsername = "admin"
+		h.cfg.AdminPassword = "Bootstrap-password-1"
+		if err := h.BootstrapAdmin(ctx); err != nil {
+			t.Fatalf("BootstrapAdmin: %v", err)
+		}
+		user, err := h.service.Authenticate(ctx, "admin", "Bootstrap-password-1")
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+		if user.Role != jwtmanager.ROLE_ADMIN {
+			t.Errorf("роль %q, ожидалась %q", user.Role, jwtmanager.ROLE_ADMIN)
+		}
+		// Повторный запуск не создает пользователя заново
+		if err := h.BootstrapAdmin(ctx); err != nil {
+			t.Errorf("повторный BootstrapAdmin: %v", err)
+		}
+	})
+
+	t.Run("повышает существующего", func(t *testing.T) {
+		h := newTestHandler(t, newMemoryRevocations())
+		created, err := h.service.Create(ctx, &models.User{Username: "Alice", Password: "Secret-password-1"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		h.cfg.AdminUsername = "alice"
+		h.cfg.AdminPassword = "Other-password-1"
+		if err := h.BootstrapAdmin(ctx); err != nil {
+			t.Fatalf("BootstrapAdmin: %v", err)
+		}
+		user, err := h.service.Authenticate(ctx, "alice", "Secret-password-1")
+		if err != nil {
+			t.Fatalf("пароль существующего пользователя изменился: %v", err)
+		}
+		if user.ID != created.ID || user.Role != jwtmanager.ROLE_ADMIN {
+			t.Errorf("пользователь %d с ролью %q, ожидался %d с ролью %q", user.ID, user.Role, created.ID, jwtmanager.ROLE_ADMIN)
+		}
+	})
+
+	t.Run("без пароля и пользователя", func(t *testing.T) {
+		h := newTestHandler(t, newMemoryRevocations())
+		h.cfg.AdminUsername = "admin"
+		if err := h.BootstrapAdmin(ctx); !stderrors.Is(err, errors.ErrUserNotFound) {
+			t.Errorf("BootstrapAdmin = %v, ожидалась ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("слабый пароль", func(t *testing.T) {
+		h := newTestHandler(t, newMemoryRevocations())
+		h.cfg.AdminUsername = "admin"
+		h.cfg.AdminPassword = "short"
+		if err := h.BootstrapAdmin(ctx); !stderrors.Is(err, errors.ErrWeakPassword) {
+			t.Errorf("BootstrapAdmin = %v, ожидалась ErrWeakPassword", err)
+		}
+	})
+
+	t.Run("не задан", func(t *testing.T) {
+		h := newTestHandler(t, newMemoryRevocations())
+		if err := h.BootstrapAdmin(ctx); err != nil {
+			t.Errorf("BootstrapAdmin без ADMIN_USERNAME: %v", err)
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/mailer"
+	"auth/internal/models"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// VerifyEmail обрабатывает переход по ссылке подтверждения адреса электронной почты
+// POST /auth/email/verify
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var verifyRequest struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if _, err := h.service.ConfirmEmail(ctx, models.HashUserToken(verifyRequest.Token)); err != nil {
+		h.userTokenError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgEmailVerified,
+	})
+}
+
+// ResendVerification обрабатывает запрос на повторную отправку письма подтверждения
+// POST /auth/email/resend
+func (h *Handler) ResendVerification(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+	if user.Email == nil {
+		c.JSON(400, gin.H{
+			"error": errors.MsgEmailNotSet,
+		})
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(409, gin.H{
+			"error": errors.MsgEmailAlreadyVerified,
+		})
+		return
+	}
+	if !h.allowMailRequest(ctx, c, *user.Email) {
+		return
+	}
+
+	if err := h.sendVerificationEmail(ctx, user); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgVerificationSent,
+	})
+}
+
+// ForgotPassword обрабатывает запрос на сброс забытого пароля
+// Ответ не зависит от того, зарегистрирован ли адрес, чтобы по нему нельзя было
+// определить существование учетной записи
+// POST /auth/password/forgot
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var forgotRequest struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&forgotRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	email, ok := normalizeEmail(forgotRequest.Email)
+	if !ok {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidEmail,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Запрос учитывается до поиска пользователя, чтобы ограничение не зависело от существования адреса
+	if !h.allowMailRequest(ctx, c, email) {
+		return
+	}
+
+	user, err := h.service.ReadByEmail(ctx, email)
+	if err != nil && !stderrors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Письмо отправляется только на подтвержденный адрес активной учетной записи,
+	// иначе указавший чужой адрес получил бы доступ к учетной записи владельца адреса
+	if err == nil && user.EmailVerified && !user.Disabled {
+		token, hash, err := models.GenerateUserToken()
+		if err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgTokenGeneration,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		err = h.service.CreateUserToken(ctx, &models.UserToken{
+			UserID:    user.ID,
+			Purpose:   models.TOKEN_PASSWORD_RESET,
+			TokenHash: hash,
+			Email:     email,
+			ExpiresAt: time.Now().Add(time.Duration(h.cfg.PasswordResetTTL) * time.Second),
+		})
+		if err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgDatabaseOperation,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		link := h.appLink("/reset-password", token)
+		h.sendMail(mailer.Message{
+			To:      email,
+			Subject: "Сброс пароля",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+				"Для сброса пароля перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действительна %d мин. Если вы не запрашивали сброс пароля, проигнорируйте это письмо.",
+				user.Username, link, h.cfg.PasswordResetTTL/60),
+		})
+	}
+
+	c.JSON(202, gin.H{
+		"message": errors.MsgPasswordResetSent,
+	})
+}
+
+// ResetPassword обрабатывает установку нового пароля по токену из письма
+// После смены пароля все сессии пользователя завершаются
+// POST /auth/password/reset
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var resetRequest struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&resetRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Токен проверяется до проверки пароля, чтобы знать имя пользователя для политики паролей
+	tokenHash := models.HashUserToken(resetRequest.Token)
+	token, err := h.service.ReadUserToken(ctx, models.TOKEN_PASSWORD_RESET, tokenHash)
+	if err != nil {
+		h.userTokenError(c, err)
+		return
+	}
+	user, err := h.service.Read(ctx, token.UserID)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidUserToken,
+		})
+		return
+	}
+	// Отключенная после отправки письма учетная запись не должна восстанавливаться сбросом пароля
+	if user.Disabled {
+		c.JSON(403, gin.H{
+			"error": errors.MsgUserDisabled,
+		})
+		return
+	}
+
+	if violations := h.passwords.Validate(user.Username, resetRequest.Password); len(violations) > 0 {
+		h.weakPassword(c, violations)
+		return
+	}
+
+	// Токен используется в одной транзакции со сменой пароля
+	if _, err := h.service.ResetPassword(ctx, tokenHash, resetRequest.Password); err != nil {
+		h.userTokenError(c, err)
+		return
+	}
+
+	if msg, err := h.revokeUserAccess(ctx, user.ID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Персональные токены тоже перестают действовать
+	if msg, err := h.revokeUserPATs(ctx, user.ID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgPasswordReset,
+	})
+}
+
+// allowMailRequest учитывает запрос письма на адрес address с IP клиента
+// Если лимит для адреса или IP исчерпан, отправляет ответ 429 и возвращает false
+func (h *Handler) allowMailRequest(ctx context.Context, c *gin.Context, address string) bool {
+	if h.mailLimits == nil {
+		return true
+	}
+
+	clientIP := c.ClientIP()
+	retryAfter, err := h.mailLimits.Check(ctx, address, clientIP)
+	if err == nil && retryAfter == 0 {
+		// Каждый запрос учитывается как попытка, блокировка наступает после последнего разрешенного
+		_, err = h.mailLimits.RegisterFailure(ctx, address, clientIP)
+	}
+	if err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgMailLimitCheck,
+			"details": err.Error(),
+		})
+		return false
+	}
+	if retryAfter > 0 {
+		h.tooManyRequests(c, errors.MsgTooManyMailRequests, retryAfter)
+		return false
+	}
+	return true
+}
+
+// sendVerificationEmail выдает токен подтверждения адреса пользователя и отправляет письмо
+func (h *Handler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	if user.Email == nil {
+		return nil
+	}
+
+	token, hash, err := models.GenerateUserToken()
+	if err != nil {
+		return err
+	}
+
+	err = h.service.CreateUserToken(ctx, &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TOKEN_EMAIL_VERIFICATION,
+		TokenHash: hash,
+		Email:     *user.Email,
+		ExpiresAt: time.Now().Add(time.Duration(h.cfg.EmailVerificationTTL) * time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.appLink("/verify-email", token)
+	h.sendMail(mailer.Message{
+		To:      *user.Email,
+		Subject: "Подтверждение адреса электронной почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+			"Для подтверждения адреса электронной почты перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действительна %d ч.",
+			user.Username, link, h.cfg.EmailVerificationTTL/3600),
+	})
+	return nil
+}
+
+// sendMail отправляет письмо в фоне, чтобы время ответа не зависело от почтового сервера
+// и по нему нельзя было определить, было ли письмо отправлено
+func (h *Handler) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.cfg.Timeout)*time.Second)
+		defer cancel()
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			fmt.Printf("Ошибка отправки письма: %v\n", err)
+		}
+	}()
+}
+
+// appLink формирует ссылку на страницу клиентского приложения с токеном
+func (h *Handler) appLink(path, token string) string {
+	return strings.TrimRight(h.cfg.AppBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// userTokenError отправляет ответ на ошибку использования одноразового токена
+func (h *Handler) userTokenError(c *gin.Context, err error) {
+	if stderrors.Is(err, errors.ErrInvalidUserToken) {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidUserToken,
+		})
+		return
+	}
+	c.JSON(500, gin.H{
+		"error":   errors.MsgDatabaseOperation,
+		"details": err.Error(),
+	})
+}
+
+// normalizeEmail проверяет адрес электронной почты и приводит его к нижнему регистру
+// Принимается только адрес без имени, например user@example.com
+func normalizeEmail(raw string) (string, bool) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", false
+	}
+	return email, true
+}
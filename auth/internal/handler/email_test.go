@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"auth/internal/lockout"
+	"auth/internal/mailer"
+	"auth/internal/models"
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+)
+
+// discardMailer - отправка писем, которая ничего не отправляет, для тестов
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, msg mailer.Message) error { return nil }
+
+// recordingMailer передает отправленные письма в канал, для тестов
+type recordingMailer chan mailer.Message
+
+func (m recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m <- msg
+	return nil
+}
+
+// linkToken извлекает токен из ссылки в письме
+var linkToken = regexp.MustCompile(`token=(\S+)`)
+
+// receiveToken дожидается письма на адрес to и возвращает токен из ссылки
+func receiveToken(t *testing.T, mail recordingMailer, to string) string {
+	t.Helper()
+	select {
+	case msg := <-mail:
+		if msg.To != to {
+			t.Fatalf("письмо отправлено на %s, ожидалось %s", msg.To, to)
+		}
+		match := linkToken.FindStringSubmatch(msg.Body)
+		if match == nil {
+			t.Fatalf("в письме нет ссылки с токеном: %s", msg.Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("QueryUnescape: %v", err)
+		}
+		return token
+	case <-time.After(5 * time.Second):
+		t.Fatalf("письмо на %s не отправлено", to)
+		return ""
+	}
+}
+
+// createResetToken выдает пользователю токен сброса пароля со сроком действия до expiresAt
+func createResetToken(t *testing.T, h *Handler, user *models.User, expiresAt time.Time) string {
+	t.Helper()
+	token, hash, err := models.GenerateUserToken()
+	if err != nil {
+		t.Fatalf("GenerateUserToken: %v", err)
+	}
+	err = h.service.CreateUserToken(context.Background(), &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TOKEN_PASSWORD_RESET,
+		TokenHash: hash,
+		Email:     "alice@example.com",
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+	return token
+}
+
+// TestResetPassword проверяет, что токен сброса пароля действует один раз, не принимается
+// после истечения срока и не позволяет сменить пароль отключенной учетной записи
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.pats = newMemoryPATs()
+
+	email := "alice@example.com"
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1", Email: &email})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	reset := func(token, password string) int {
+		return callJSONRecorder(t, h.ResetPassword, gin.H{"token": token, "password": password}).Code
+	}
+
+	token := createResetToken(t, h, user, time.Now().Add(time.Hour))
+	if status := reset(token, "Secret-password-2"); status != http.StatusOK {
+		t.Fatalf("ResetPassword: статус %d, ожидался 200", status)
+	}
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-2"); err != nil {
+		t.Fatalf("вход с новым паролем: %v", err)
+	}
+	if status := reset(token, "Secret-password-3"); status != http.StatusBadRequest {
+		t.Errorf("повторное использование токена: статус %d, ожидался 400", status)
+	}
+
+	expired := createResetToken(t, h, user, time.Now().Add(-time.Minute))
+	if status := reset(expired, "Secret-password-3"); status != http.StatusBadRequest {
+		t.Errorf("истекший токен: статус %d, ожидался 400", status)
+	}
+
+	disabled := createResetToken(t, h, user, time.Now().Add(time.Hour))
+	if err := h.service.SetUserDisabled(ctx, user.ID, true); err != nil {
+		t.Fatalf("SetUserDisabled: %v", err)
+	}
+	if status := reset(disabled, "Secret-password-3"); status != http.StatusForbidden {
+		t.Errorf("отключенная учетная запись: статус %d, ожидался 403", status)
+	}
+
+	// Ни один из отклоненных запросов не сменил пароль
+	if err := h.service.SetUserDisabled(ctx, user.ID, false); err != nil {
+		t.Fatalf("SetUserDisabled: %v", err)
+	}
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-2"); err != nil {
+		t.Errorf("пароль изменен отклоненным запросом: %v", err)
+	}
+}
+
+// TestResetPasswordAfterEmailChange проверяет, что ссылка сброса пароля, отправленная
+// на прежний адрес, перестает действовать после смены адреса
+func TestResetPasswordAfterEmailChange(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.pats = newMemoryPATs()
+	mail := make(recordingMailer, 4)
+	h.mailer = mail
+	h.cfg.EmailVerificationTTL = 3600
+	h.cfg.PasswordResetTTL = 3600
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	email := "alice@example.com"
+	if err := h.service.Update(ctx, &models.User{ID: user.ID, Email: &email}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := h.sendVerificationEmail(ctx, &models.User{ID: user.ID, Username: "alice", Email: &email}); err != nil {
+		t.Fatalf("sendVerificationEmail: %v", err)
+	}
+	if _, err := h.service.ConfirmEmail(ctx, models.HashUserToken(receiveToken(t, mail, email))); err != nil {
+		t.Fatalf("ConfirmEmail: %v", err)
+	}
+
+	if status := callJSONRecorder(t, h.ForgotPassword, gin.H{"email": email}).Code; status != http.StatusAccepted {
+		t.Fatalf("ForgotPassword: статус %d, ожидался 202", status)
+	}
+	token := receiveToken(t, mail, email)
+
+	recorder := callJSONRecorder(t, h.UpdateUser, gin.H{"email": "alice@example.org"}, func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("UpdateUser: статус %d: %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = callJSONRecorder(t, h.ResetPassword, gin.H{"token": token, "password": "Secret-password-2"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("ResetPassword после смены адреса: статус %d, ожидался 400", recorder.Code)
+	}
+	if _, err := h.service.Authenticate(ctx, "alice", "Secret-password-1"); err != nil {
+		t.Errorf("пароль изменен ссылкой на прежний адрес: %v", err)
+	}
+}
+
+// TestForgotPasswordRateLimit проверяет, что запросы писем ограничиваются на адрес и на IP
+// независимо от того, зарегистрирован ли адрес
+func TestForgotPasswordRateLimit(t *testing.T) {
+	h := newTestHandler(t, newMemoryRevocations())
+	h.mailer = discardMailer{}
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	h.mailLimits = lockout.NewRedisLockout(client, lockout.Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   4,
+		BaseLockout:     time.Hour,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+		KeyPrefix:       "auth:email:",
+	})
+
+	forgot := func(email, ip string) *http.Response {
+		recorder := callJSONRecorder(t, h.ForgotPassword, gin.H{"email": email}, func(c *gin.Context) {
+			c.Request.RemoteAddr = ip + ":1234"
+		})
+		return recorder.Result()
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := forgot("alice@example.com", "10.0.0.1"); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("запрос #%d: статус %d, ожидался 202", i+1, resp.StatusCode)
+		}
+	}
+	resp := forgot("Alice@Example.com", "10.0.0.2")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("запрос сверх лимита адреса: статус %d, ожидался 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("ответ 429 без заголовка Retry-After")
+	}
+
+	// Другие адреса с того же IP ограничиваются лимитом IP
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if resp := forgot(email, "10.0.0.1"); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("запрос %s: статус %d, ожидался 202", email, resp.StatusCode)
+		}
+	}
+	if resp := forgot("dave@example.com", "10.0.0.1"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("запрос сверх лимита IP: статус %d, ожидался 429", resp.StatusCode)
+	}
+	if resp := forgot("dave@example.com", "10.0.0.3"); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("запрос с другого IP: статус %d, ожидался 202", resp.StatusCode)
+	}
+}
+
+// TestResendVerificationRateLimit проверяет, что повторная отправка письма подтверждения
+// ограничивается для адреса пользователя
+func TestResendVerificationRateLimit(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.mailer = discardMailer{}
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	h.mailLimits = lockout.NewRedisLockout(client, lockout.Options{
+		MaxUserAttempts: 1,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Hour,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+		KeyPrefix:       "auth:email:",
+	})
+
+	email := "alice@example.com"
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1", Email: &email})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	resend := func() int {
+		return callJSONRecorder(t, h.ResendVerification, nil, func(c *gin.Context) {
+			c.Set("user_id", user.ID)
+		}).Code
+	}
+
+	if status := resend(); status != http.StatusOK {
+		t.Fatalf("ResendVerification: статус %d, ожидался 200", status)
+	}
+	if status := resend(); status != http.StatusTooManyRequests {
+		t.Errorf("повторный ResendVerification: статус %d, ожидался 429", status)
+	}
+}
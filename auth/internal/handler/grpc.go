@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/password"
+	"auth/proto/authv1"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// grpcPublicMethods - методы gRPC API, которые вызываются без токена
+var grpcPublicMethods = []string{
+	authv1.AuthService_Register_FullMethodName,
+	authv1.AuthService_Login_FullMethodName,
+	authv1.AuthService_Refresh_FullMethodName,
+	authv1.AuthService_ValidateToken_FullMethodName,
+}
+
+// GRPCHandler реализует gRPC API сервиса авторизации
+// Использует сервис, JWT менеджер и политики обработчика HTTP, поэтому
+// правила регистрации, входа и выдачи токенов у обоих API одинаковые
+type GRPCHandler struct {
+	authv1.UnimplementedAuthServiceServer
+	h *Handler
+}
+
+// Проверка, что GRPCHandler реализует сгенерированный интерфейс сервера
+var _ authv1.AuthServiceServer = (*GRPCHandler)(nil)
+
+// NewGRPCHandler создает обработчик gRPC API поверх обработчика HTTP
+func NewGRPCHandler(h *Handler) *GRPCHandler {
+	return &GRPCHandler{h: h}
+}
+
+// UnaryAuthInterceptor возвращает gRPC interceptor для проверки токена в унарных вызовах
+func (h *Handler) UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return h.jwtManager.UnaryServerInterceptor(grpcPublicMethods...)
+}
+
+// StreamAuthInterceptor возвращает gRPC interceptor для проверки токена в потоковых вызовах
+func (h *Handler) StreamAuthInterceptor() grpc.StreamServerInterceptor {
+	return h.jwtManager.StreamServerInterceptor(grpcPublicMethods...)
+}
+
+// Register регистрирует нового пользователя, как и POST /auth/register
+func (g *GRPCHandler) Register(ctx context.Context, req *authv1.RegisterRequest) (*authv1.RegisterResponse, error) {
+	// Проверяем обязательные поля
+	if req.GetUsername() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidUserData)
+	}
+
+	// Проверяем имя пользователя и сохраняем его в канонической форме
+	name, violations := g.h.usernames.Validate(req.GetUsername())
+	if len(violations) > 0 {
+		return nil, violationsError(errors.MsgInvalidUsername, violations)
+	}
+
+	// Проверяем пароль на соответствие политике
+	if violations := g.h.passwords.Validate(name, req.GetPassword()); len(violations) > 0 {
+		return nil, violationsError(errors.MsgWeakPassword, violations)
+	}
+
+	user := models.User{
+		Username: name,
+		Password: req.GetPassword(),
+	}
+
+	// Адрес электронной почты необязателен, но должен быть корректным
+	if req.Email != nil {
+		email, ok := normalizeEmail(req.GetEmail())
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidEmail)
+		}
+		user.Email = &email
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	createdUser, err := g.h.service.Create(ctx, &user)
+	if stderrors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, status.Error(codes.AlreadyExists, errors.MsgUserAlreadyExists)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.MsgUserCreation)
+	}
+
+	// Ошибка отправки письма не отменяет регистрацию: письмо можно запросить повторно
+	if err := g.h.sendVerificationEmail(ctx, createdUser); err != nil {
+		fmt.Printf("Не удалось отправить письмо подтверждения: %v\n", err)
+	}
+
+	return &authv1.RegisterResponse{
+		User: userMessage(createdUser),
+	}, nil
+}
+
+// Login проверяет имя и пароль и выдает пару токенов, как и POST /auth/login
+// Попытки входа учитываются в тех же счетчиках блокировки, что и у HTTP API
+func (g *GRPCHandler) Login(ctx context.Context, req *authv1.LoginRequest) (*authv1.LoginResponse, error) {
+	if req.GetUsername() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidData)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Проверяем блокировку до проверки пароля, чтобы не тратить время на bcrypt
+	client := grpcClient(ctx)
+	retryAfter, err := g.h.lockouts.Check(ctx, req.GetUsername(), client.ip)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, errors.MsgLockoutCheck)
+	}
+	if retryAfter > 0 {
+		return nil, withDetails(codes.ResourceExhausted, errors.MsgTooManyLoginAttempts, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryAfter),
+		})
+	}
+
+	user, err := g.h.service.Authenticate(ctx, req.GetUsername(), req.GetPassword())
+	if stderrors.Is(err, errors.ErrUserPendingDeletion) {
+		return nil, withDetails(codes.FailedPrecondition, errors.MsgUserPendingDeletion, &errdetails.ErrorInfo{
+			Reason:   "PENDING_DELETION",
+			Domain:   "auth",
+			Metadata: map[string]string{"purge_at": user.PurgeAt(g.h.deletionGrace()).Format(time.RFC3339)},
+		})
+	}
+	if stderrors.Is(err, errors.ErrUserDisabled) {
+		return nil, status.Error(codes.PermissionDenied, errors.MsgUserDisabled)
+	}
+	if err != nil {
+		// Неверные учетные данные учитываются как неудачная попытка входа
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			if _, lockErr := g.h.lockouts.RegisterFailure(ctx, req.GetUsername(), client.ip); lockErr != nil {
+				return nil, status.Error(codes.Unavailable, errors.MsgLockoutCheck)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, errors.MsgInvalidCredentials)
+	}
+
+	// Второй фактор подтверждается через HTTP API, счетчик попыток сбрасывается после него
+	if user.TOTPEnabled {
+		mfaToken, err := g.h.jwtManager.GenerateMFAPendingToken(user.ID, time.Duration(g.h.cfg.MFATokenTTL)*time.Second)
+		if err != nil {
+			return nil, status.Error(codes.Internal, errors.MsgTokenGeneration)
+		}
+		return &authv1.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	// Успешный вход сбрасывает счетчик неудачных попыток пользователя
+	if err := g.h.lockouts.RegisterSuccess(ctx, req.GetUsername(), client.ip); err != nil {
+		return nil, status.Error(codes.Unavailable, errors.MsgLockoutCheck)
+	}
+
+	// Генерируем JWT токены и начинаем новое семейство refresh токенов
+	pair, err := g.h.issueTokens(ctx, client, user)
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.MsgTokenGeneration)
+	}
+
+	return &authv1.LoginResponse{
+		User:         userMessage(user),
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+	}, nil
+}
+
+// Refresh обменивает refresh токен на новую пару токенов, как и POST /auth/refresh
+func (g *GRPCHandler) Refresh(ctx context.Context, req *authv1.RefreshRequest) (*authv1.RefreshResponse, error) {
+	// Токены OAuth клиентов обновляются только через /oauth/token, чтобы не расширять их области доступа
+	claims, err := g.h.jwtManager.ValidateRefreshTokenClaims(req.GetRefreshToken())
+	if err != nil || claims.ClientID != "" {
+		return nil, status.Error(codes.Unauthenticated, errors.MsgRefreshToken)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := g.h.service.Read(ctx, claims.UserID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, errors.MsgUserNotFound)
+	}
+
+	// Отключенный пользователь не может обновить токены
+	if user.Disabled {
+		return nil, status.Error(codes.PermissionDenied, errors.MsgUserDisabled)
+	}
+
+	// Роль и области доступа берутся из базы, чтобы их изменение применялось при обновлении токенов
+	pair, msg, err := g.h.rotateTokens(ctx, grpcClient(ctx), claims, jwtmanager.Subject{
+		UserID: user.ID,
+		Scope:  user.TokenScope(),
+		Roles:  user.TokenRoles(),
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrRefreshTokenReuse) {
+			return nil, status.Error(codes.Unauthenticated, errors.MsgRefreshTokenReuse)
+		}
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.Unauthenticated, errors.MsgRefreshToken)
+		}
+		return nil, status.Error(codes.Internal, msg)
+	}
+
+	return &authv1.RefreshResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+	}, nil
+}
+
+// GetUser возвращает текущего пользователя, как и GET /auth/user
+// Пользователя с другим ID может получить только администратор
+func (g *GRPCHandler) GetUser(ctx context.Context, req *authv1.GetUserRequest) (*authv1.GetUserResponse, error) {
+	claims, err := jwtmanager.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, errors.MsgAuthRequired)
+	}
+
+	userID := claims.UserID
+	if req.GetId() != 0 && req.GetId() != int64(claims.UserID) {
+		if !claims.HasRole(jwtmanager.ROLE_ADMIN) {
+			return nil, status.Error(codes.PermissionDenied, errors.MsgAdminRequired)
+		}
+		userID = int(req.GetId())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := g.h.service.Read(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, errors.MsgUserNotFound)
+	}
+
+	return &authv1.GetUserResponse{
+		User: userMessage(user),
+	}, nil
+}
+
+// ValidateToken проверяет access, refresh или персональный токен, как и POST /auth/introspect
+// gRPC порт доступен только во внутренней сети, поэтому вызывающий сервис не аутентифицируется
+func (g *GRPCHandler) ValidateToken(ctx context.Context, req *authv1.ValidateTokenRequest) (*authv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, errors.MsgOAuthMissingParameter)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Персональные токены проверяются по общему хранилищу
+	if strings.HasPrefix(req.GetToken(), jwtmanager.PAT_PREFIX) {
+		record, err := g.h.activePAT(ctx, req.GetToken())
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, errors.MsgPATStore)
+		}
+		if record == nil {
+			return &authv1.ValidateTokenResponse{}, nil
+		}
+		response := &authv1.ValidateTokenResponse{
+			Active:    true,
+			TokenType: jwtmanager.PERSONAL_ACCESS_TOKEN,
+			UserId:    int64(record.UserID),
+			TokenId:   record.ID,
+			Scopes:    strings.Fields(record.Scope),
+		}
+		if !record.ExpiresAt.IsZero() {
+			response.ExpiresAt = timestamppb.New(record.ExpiresAt)
+		}
+		return response, nil
+	}
+
+	claims, err := g.h.activeTokenClaims(ctx, req.GetToken(), req.GetTokenTypeHint())
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, errors.MsgRevocationCheck)
+	}
+	if claims == nil {
+		return &authv1.ValidateTokenResponse{}, nil
+	}
+
+	return &authv1.ValidateTokenResponse{
+		Active:    true,
+		TokenType: claims.Type,
+		UserId:    int64(claims.UserID),
+		TokenId:   claims.ID,
+		SessionId: claims.SessionID,
+		ClientId:  claims.ClientID,
+		Scopes:    claims.Scopes(),
+		Roles:     claims.Roles,
+		IssuedAt:  timestamppb.New(claims.IssuedTime()),
+		ExpiresAt: timestamppb.New(claims.ExpiresTime()),
+	}, nil
+}
+
+// grpcClient возвращает данные клиента gRPC запроса
+// User-Agent берется из метаданных, IP - из адреса соединения
+func grpcClient(ctx context.Context) clientInfo {
+	var client clientInfo
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			client.userAgent = values[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.ip); err == nil {
+			client.ip = host
+		}
+	}
+	return client
+}
+
+// userMessage преобразует модель пользователя в сообщение gRPC API без пароля
+func userMessage(user *models.User) *authv1.User {
+	message := &authv1.User{
+		Id:                    int64(user.ID),
+		Username:              user.Username,
+		Email:                 user.Email,
+		EmailVerified:         user.EmailVerified,
+		Role:                  user.Role,
+		Scopes:                strings.Fields(user.TokenScope()),
+		Disabled:              user.Disabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		TotpEnabled:           user.TOTPEnabled,
+	}
+	if user.UsernameChangedAt != nil {
+		message.UsernameChangedAt = timestamppb.New(*user.UsernameChangedAt)
+	}
+	return message
+}
+
+// violationsError создает ошибку InvalidArgument с нарушениями правил по полям
+func violationsError(message string, violations []password.Violation) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Message,
+			Reason:      v.Code,
+		})
+	}
+	return withDetails(codes.InvalidArgument, message, badRequest)
+}
+
+// withDetails создает ошибку gRPC с дополнительными сведениями для клиента
+// Если сведения не удалось добавить, возвращается ошибка без них
+func withDetails(code codes.Code, message string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(details...); err == nil {
+		return detailed.Err()
+	}
+	return st.Err()
+}
@@ -3,14 +3,25 @@ package handler
 import (
 	"auth/internal/config"
 	"auth/internal/errors"
+	"auth/internal/introspection"
+	"auth/internal/lockout"
+	"auth/internal/mailer"
 	"auth/internal/models"
+	"auth/internal/password"
 	"auth/internal/service"
+	"auth/internal/totp"
+	"auth/internal/username"
 	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	jwtmanager "jwt_manager"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Handler содержит все обработчики для работы с пользователями
@@ -18,24 +29,99 @@ type Handler struct {
 	service    service.Service        // Сервис для работы с БД
 	jwtManager *jwtmanager.JWTManager // JWT менеджер для работы с токенами
 	cfg        *config.Config         // Конфигурация сервера
+	lockouts   lockout.Lockout        // Защита входа от подбора пароля
+	mailLimits lockout.Lockout        // Ограничение запросов писем на адрес и на IP; nil, если не задано
+	passwords  *password.Policy       // Политика паролей
+	usernames  *username.Policy       // Правила для имен пользователей
+	mailer     mailer.Mailer          // Отправка писем
+	pats       jwtmanager.PATStore    // Общее хранилище персональных токенов доступа
+	secrets    *totp.SecretCipher     // Шифрование секретов TOTP; nil, если ключ не задан
+	introspect *introspection.Cache   // Локальный кеш разобранных токенов для интроспекции
 }
 
+// breachedFalsePositiveRate - вероятность, с которой надежный пароль
+// будет ошибочно отклонен как утекший
+const breachedFalsePositiveRate = 0.001
+
 // NewHandler создает новый экземпляр обработчика пользователей
-func NewHandler(service service.Service, cfg *config.Config) *Handler {
+// revocations - общее с другими сервисами хранилище отозванных access токенов
+// lockouts - защита входа от подбора пароля
+// mailLimits - ограничение запросов писем подтверждения и сброса пароля
+// mail - отправка писем для подтверждения адреса и сброса пароля
+// pats - общее с другими сервисами хранилище персональных токенов доступа
+func NewHandler(service service.Service, cfg *config.Config, revocations jwtmanager.RevocationStore, lockouts lockout.Lockout, mailLimits lockout.Lockout, mail mailer.Mailer, pats jwtmanager.PATStore) (*Handler, error) {
 
 	// Создаем JWT менеджер
 	jwtConfig := jwtmanager.JWTConfig{
 		SecretKey:              cfg.JWTSecretKey,
+		Algorithm:              cfg.JWTAlgorithm,
+		PrivateKeyPEM:          cfg.JWTPrivateKey,
 		AccessTokenExpiration:  cfg.AccessTokenExpiration,
 		RefreshTokenExpiration: cfg.RefreshTokenExpiration,
+		Issuer:                 cfg.JWTIssuer,
+		Audience:               cfg.JWTAudience,
+		ClockSkew:              time.Duration(cfg.JWTClockSkew) * time.Second,
+		RevocationStore:        revocations,
+	}
+	// Связка ключей из файла позволяет ротировать ключи подписи
+	if cfg.JWTKeysFile != "" {
+		keys, err := jwtmanager.LoadKeysFile(cfg.JWTKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		jwtConfig.Keys = keys
+	}
+	jwtManager, err := jwtmanager.NewJWTManager(jwtConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Политика паролей с необязательной проверкой по списку утекших паролей
+	passwords := &password.Policy{
+		MinLength:      cfg.PasswordMinLength,
+		RequireUpper:   cfg.PasswordRequireUpper,
+		RequireLower:   cfg.PasswordRequireLower,
+		RequireDigit:   cfg.PasswordRequireDigit,
+		RequireSymbol:  cfg.PasswordRequireSymbol,
+		ForbidUsername: cfg.PasswordForbidUsername,
+	}
+	if cfg.PasswordBreachedFile != "" {
+		passwords.Breached, err = password.LoadBreachedList(cfg.PasswordBreachedFile, breachedFalsePositiveRate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Секреты TOTP хранятся в базе данных в зашифрованном виде,
+	// без ключа включенную двухфакторную аутентификацию использовать нельзя
+	if cfg.TOTPEnabled && cfg.TOTPEncryptionKey == "" {
+		return nil, errors.ErrTOTPKeyRequired
 	}
-	jwtManager := jwtmanager.NewJWTManager(jwtConfig)
+	var secrets *totp.SecretCipher
+	if cfg.TOTPEncryptionKey != "" {
+		secrets, err = totp.NewSecretCipher(cfg.TOTPEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Встроенный список зарезервированных имен дополняется именами из конфигурации
+	usernames := username.NewPolicy(cfg.UsernameMinLength, cfg.UsernameMaxLength, username.ParseReserved(cfg.UsernameReserved))
 
 	return &Handler{
 		service:    service,    // Сохраняем сервис в обработчике
 		jwtManager: jwtManager, // Сохраняем JWT менеджер в обработчике
 		cfg:        cfg,        // Сохраняем конфигурацию в обработчике
-	}
+		lockouts:   lockouts,   // Сохраняем защиту входа в обработчике
+		mailLimits: mailLimits, // Сохраняем ограничение запросов писем в обработчике
+		passwords:  passwords,  // Сохраняем политику паролей в обработчике
+		usernames:  usernames,  // Сохраняем правила для имен пользователей в обработчике
+		mailer:     mail,       // Сохраняем отправку писем в обработчике
+		pats:       pats,       // Сохраняем хранилище персональных токенов в обработчике
+		secrets:    secrets,    // Сохраняем шифрование секретов TOTP в обработчике
+		// Кеш интроспекции хранится в памяти каждого экземпляра сервиса
+		introspect: introspection.NewCache(time.Duration(cfg.IntrospectionCacheTTL)*time.Second, cfg.IntrospectionCacheSize),
+	}, nil
 }
 
 // RegisterUser обрабатывает запрос на регистрацию нового пользователя
@@ -59,11 +145,44 @@ func (h *Handler) RegisterUser(c *gin.Context) {
 		return
 	}
 
+	// Проверяем имя пользователя и сохраняем его в канонической форме
+	name, violations := h.usernames.Validate(user.Username)
+	if len(violations) > 0 {
+		h.invalidUsername(c, violations)
+		return
+	}
+	user.Username = name
+
+	// Проверяем пароль на соответствие политике
+	if violations := h.passwords.Validate(user.Username, user.Password); len(violations) > 0 {
+		h.weakPassword(c, violations)
+		return
+	}
+
+	// Адрес электронной почты необязателен, но должен быть корректным
+	if user.Email != nil {
+		email, ok := normalizeEmail(*user.Email)
+		if !ok {
+			c.JSON(400, gin.H{
+				"error": errors.MsgInvalidEmail,
+			})
+			return
+		}
+		user.Email = &email
+	}
+
 	// Создаем пользователя в базе данных с таймаутом
 	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
 	defer cancel()
 
 	createdUser, err := h.service.Create(ctx, &user)
+	if stderrors.Is(err, gorm.ErrDuplicatedKey) {
+		// Имя занято без учета регистра или адрес уже используется
+		c.JSON(409, gin.H{
+			"error": errors.MsgUserAlreadyExists,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{
 			"error":   errors.MsgUserCreation,
@@ -72,6 +191,12 @@ func (h *Handler) RegisterUser(c *gin.Context) {
 		return
 	}
 
+	// Отправляем письмо для подтверждения адреса
+	// Ошибка не отменяет регистрацию: письмо можно запросить повторно
+	if err := h.sendVerificationEmail(ctx, createdUser); err != nil {
+		fmt.Printf("Не удалось отправить письмо подтверждения: %v\n", err)
+	}
+
 	// Убираем пароль из ответа
 	createdUser.Password = ""
 
@@ -102,19 +227,91 @@ func (h *Handler) LoginUser(c *gin.Context) {
 	// Отменяем контекст после завершения работы функции
 	defer cancel()
 
+	// Проверяем блокировку до проверки пароля, чтобы не тратить время на bcrypt
+	clientIP := c.ClientIP()
+	retryAfter, err := h.lockouts.Check(ctx, loginRequest.Username, clientIP)
+	if err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+	if retryAfter > 0 {
+		h.tooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
 	user, err := h.service.Authenticate(ctx, loginRequest.Username, loginRequest.Password)
+	if stderrors.Is(err, errors.ErrUserPendingDeletion) {
+		h.userPendingDeletion(c, user)
+		return
+	}
+	if stderrors.Is(err, errors.ErrUserDisabled) {
+		c.JSON(403, gin.H{
+			"error": errors.MsgUserDisabled,
+		})
+		return
+	}
 	if err != nil {
+		// Неверные учетные данные учитываются как неудачная попытка входа
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			if _, lockErr := h.lockouts.RegisterFailure(ctx, loginRequest.Username, clientIP); lockErr != nil {
+				c.JSON(503, gin.H{
+					"error":   errors.MsgLockoutCheck,
+					"details": lockErr.Error(),
+				})
+				return
+			}
+		}
 		c.JSON(401, gin.H{
 			"error": errors.MsgInvalidCredentials,
 		})
 		return
 	}
 
+	// Если включен второй фактор, вместо пары токенов выдается промежуточный токен,
+	// который обменивается на пару в VerifyTwoFactor.
+	// Счетчик неудачных попыток сбрасывается только после проверки второго фактора,
+	// иначе повторный ввод пароля позволял бы бесконечно подбирать код.
+	// Промежуточный токен одноразовый, поэтому без хранилища отозванных токенов он не выдается
+	if user.TOTPEnabled {
+		if h.jwtManager.RevocationStore() == nil {
+			c.JSON(503, gin.H{
+				"error": errors.MsgMFAUnavailable,
+			})
+			return
+		}
+		mfaToken, err := h.jwtManager.GenerateMFAPendingToken(user.ID, time.Duration(h.cfg.MFATokenTTL)*time.Second)
+		if err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgTokenGeneration,
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(200, gin.H{
+			"message":      errors.MsgMFARequired,
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
+
+	// Успешный вход сбрасывает счетчик неудачных попыток пользователя
+	if err := h.lockouts.RegisterSuccess(ctx, loginRequest.Username, clientIP); err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Убираем пароль из ответа
 	user.Password = ""
 
-	// Генерируем JWT токены
-	accessToken, refreshToken, err := h.jwtManager.GenerateTokens(user.ID)
+	// Генерируем JWT токены и начинаем новое семейство refresh токенов
+	pair, err := h.issueTokens(ctx, requestClient(c), user)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"error":   errors.MsgTokenGeneration,
@@ -126,8 +323,8 @@ func (h *Handler) LoginUser(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"message":       errors.MsgLoginSuccess,
 		"user":          user,
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
 	})
 }
 
@@ -198,7 +395,56 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 	// Отменяем контекст после завершения работы функции
 	defer cancel()
 
+	currentUser, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	// Имя пользователя меняется отдельным запросом с ограничением частоты
+	if updateData.Username != "" && updateData.Username != currentUser.Username {
+		c.JSON(400, gin.H{
+			"error": errors.MsgUsernameChangeEndpoint,
+		})
+		return
+	}
+
+	// Новый пароль проверяется на соответствие политике
+	if updateData.Password != "" {
+		if violations := h.passwords.Validate(currentUser.Username, updateData.Password); len(violations) > 0 {
+			h.weakPassword(c, violations)
+			return
+		}
+	}
+
+	// Новый адрес электронной почты проверяется и требует подтверждения
+	emailChanged := false
+	if updateData.Email != nil {
+		email, ok := normalizeEmail(*updateData.Email)
+		if !ok {
+			c.JSON(400, gin.H{
+				"error": errors.MsgInvalidEmail,
+			})
+			return
+		}
+		if currentUser.Email != nil && *currentUser.Email == email {
+			// Адрес не изменился, подтверждение сохраняется
+			updateData.Email = nil
+		} else {
+			updateData.Email = &email
+			emailChanged = true
+		}
+	}
+
 	err = h.service.Update(ctx, &updateData)
+	if stderrors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(409, gin.H{
+			"error": errors.MsgEmailTaken,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{
 			"error":   errors.MsgDatabaseOperation,
@@ -219,6 +465,13 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// Отправляем письмо для подтверждения нового адреса
+	if emailChanged {
+		if err := h.sendVerificationEmail(readCtx, updatedUser); err != nil {
+			fmt.Printf("Не удалось отправить письмо подтверждения: %v\n", err)
+		}
+	}
+
 	// Убираем пароль из ответа
 	updatedUser.Password = ""
 
@@ -229,6 +482,7 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 }
 
 // DeleteUser обрабатывает запрос на удаление пользователя
+// Учетную запись можно восстановить через /auth/restore до окончания срока ожидания
 func (h *Handler) DeleteUser(c *gin.Context) {
 	// Получаем ID пользователя из токена
 	userID, err := h.GetCurrentUserID(c)
@@ -255,6 +509,18 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 	deleteCtx, deleteCancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
 	defer deleteCancel()
 
+	// Персональные токены убираются из общего хранилища, но не отзываются: при восстановлении
+	// учетной записи они снова начнут действовать. Отзываются они при окончательном удалении,
+	// как и OAuth клиенты пользователя, которые до этого недоступны
+	if msg, err := h.suspendUserPATs(deleteCtx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Учетная запись помечается удаленной и окончательно удаляется после срока ожидания
 	err = h.service.Delete(deleteCtx, userID)
 	if err != nil {
 		c.JSON(500, gin.H{
@@ -264,8 +530,20 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// Выпущенные access токены перестают действовать сразу, а не по истечении срока
+	if store := h.jwtManager.RevocationStore(); store != nil {
+		if err := store.RevokeAllUserTokens(deleteCtx, userID, h.jwtManager.AccessTokenTTL()); err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgTokenRevocation,
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
 	c.JSON(200, gin.H{
-		"message": errors.MsgUserDeleted,
+		"message":  errors.MsgUserDeleted,
+		"purge_at": time.Now().Add(h.deletionGrace()),
 	})
 }
 
@@ -285,16 +563,20 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 	}
 
 	// Валидируем refresh токен
-	userID, err := h.jwtManager.ValidateRefreshToken(refreshRequest.RefreshToken)
-	if err != nil {
+	// Токены OAuth клиентов обновляются только через /oauth/token, чтобы не расширять их области доступа
+	claims, err := h.jwtManager.ValidateRefreshTokenClaims(refreshRequest.RefreshToken)
+	if err != nil || claims.ClientID != "" {
 		c.JSON(401, gin.H{
 			"error": errors.MsgRefreshToken,
 		})
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
 	// Проверяем, что пользователь существует
-	user, err := h.service.Read(c.Request.Context(), userID)
+	user, err := h.service.Read(ctx, claims.UserID)
 	if err != nil {
 		c.JSON(404, gin.H{
 			"error": errors.MsgUserNotFound,
@@ -302,11 +584,35 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Генерируем новые токены
-	accessToken, refreshToken, err := h.jwtManager.GenerateTokens(user.ID)
+	// Отключенный пользователь не может обновить токены
+	if user.Disabled {
+		c.JSON(403, gin.H{
+			"error": errors.MsgUserDisabled,
+		})
+		return
+	}
+
+	// Роль и области доступа берутся из базы, чтобы их изменение применялось при обновлении токенов
+	pair, msg, err := h.rotateTokens(ctx, requestClient(c), claims, jwtmanager.Subject{
+		UserID: user.ID,
+		Scope:  user.TokenScope(),
+		Roles:  user.TokenRoles(),
+	})
 	if err != nil {
+		if stderrors.Is(err, errors.ErrRefreshTokenReuse) {
+			c.JSON(401, gin.H{
+				"error": errors.MsgRefreshTokenReuse,
+			})
+			return
+		}
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(401, gin.H{
+				"error": errors.MsgRefreshToken,
+			})
+			return
+		}
 		c.JSON(500, gin.H{
-			"error":   errors.MsgTokenGeneration,
+			"error":   msg,
 			"details": err.Error(),
 		})
 		return
@@ -314,9 +620,122 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 
 	c.JSON(200, gin.H{
 		"message":       errors.MsgTokensRefreshed,
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+	})
+}
+
+// clientInfo - данные клиента, которые сохраняются в сессии
+type clientInfo struct {
+	userAgent string // User-Agent клиента
+	ip        string // IP адрес клиента
+}
+
+// requestClient возвращает данные клиента HTTP запроса
+func requestClient(c *gin.Context) clientInfo {
+	return clientInfo{
+		userAgent: c.Request.UserAgent(),
+		ip:        c.ClientIP(),
+	}
+}
+
+// issueTokens начинает новую сессию пользователя:
+// генерирует пару токенов, сохраняет сессию с данными клиента
+// и refresh токен как начало нового семейства
+func (h *Handler) issueTokens(ctx context.Context, client clientInfo, user *models.User) (*jwtmanager.TokenPair, error) {
+	return h.startSession(ctx, client, jwtmanager.Subject{
+		UserID: user.ID,
+		Scope:  user.TokenScope(),
+		Roles:  user.TokenRoles(),
+	})
+}
+
+// startSession начинает новую сессию для субъекта и выпускает для нее пару токенов
+// ID сессии генерируется здесь, поле SessionID субъекта игнорируется
+func (h *Handler) startSession(ctx context.Context, client clientInfo, subject jwtmanager.Subject) (*jwtmanager.TokenPair, error) {
+	// ID семейства refresh токенов является и ID сессии
+	sessionID, err := jwtmanager.GenerateTokenID()
+	if err != nil {
+		return nil, err
+	}
+	subject.SessionID = sessionID
+
+	pair, err := h.jwtManager.GenerateTokenPair(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = h.service.CreateSession(ctx, &models.Session{
+		ID:              sessionID,
+		UserID:          subject.UserID,
+		UserAgent:       client.userAgent,
+		IP:              client.ip,
+		ClientID:        subject.ClientID,
+		CreatedAt:       now,
+		LastRefreshedAt: now,
+		ExpiresAt:       pair.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.service.CreateRefreshToken(ctx, &models.RefreshToken{
+		ID:        pair.RefreshTokenID,
+		UserID:    subject.UserID,
+		FamilyID:  sessionID,
+		ExpiresAt: pair.RefreshExpiresAt,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// rotateTokens продолжает семейство refresh токена claims: выпускает новую пару токенов
+// для субъекта, делает старый refresh токен недействительным и продлевает сессию
+// Возвращает сообщение для ответа клиенту вместе с ошибкой; ошибки ErrRefreshTokenReuse
+// и gorm.ErrRecordNotFound означают недействительный refresh токен.
+// При повторном использовании токена отзывается вся сессия вместе с ее access токенами
+func (h *Handler) rotateTokens(ctx context.Context, client clientInfo, claims *jwtmanager.Claims, subject jwtmanager.Subject) (*jwtmanager.TokenPair, string, error) {
+	// Находим refresh токен, чтобы продолжить его семейство (сессию)
+	storedToken, err := h.service.ReadRefreshToken(ctx, claims.ID)
+	if err != nil {
+		return nil, errors.MsgRefreshToken, gorm.ErrRecordNotFound
+	}
+	subject.SessionID = storedToken.FamilyID
+
+	// Генерируем новые токены
+	pair, err := h.jwtManager.GenerateTokenPair(subject)
+	if err != nil {
+		return nil, errors.MsgTokenGeneration, err
+	}
+
+	// Ротируем refresh токен: старый становится недействительным
+	err = h.service.RotateRefreshToken(ctx, claims.ID, &models.RefreshToken{
+		ID:        pair.RefreshTokenID,
+		ExpiresAt: pair.RefreshExpiresAt,
+	})
+	if stderrors.Is(err, errors.ErrRefreshTokenReuse) {
+		// Цепочка токенов украдена: сервис отозвал refresh токены семейства,
+		// а access токены, уже выпущенные в сессии, отзываем по утверждению sid
+		if msg, revokeErr := h.revokeSession(ctx, storedToken.FamilyID); revokeErr != nil {
+			return nil, msg, revokeErr
+		}
+		return nil, errors.MsgRefreshTokenReuse, err
+	}
+	if err != nil {
+		return nil, errors.MsgDatabaseOperation, err
+	}
+
+	// Отмечаем обновление в сессии
+	err = h.service.TouchSession(ctx, storedToken.FamilyID, client.userAgent, client.ip, pair.RefreshExpiresAt)
+	if err != nil {
+		return nil, errors.MsgDatabaseOperation, err
+	}
+
+	return pair, "", nil
 }
 
 // ExtractTokenFromHeader извлекает JWT токен из HTTP заголовка Authorization (публичный метод)
@@ -341,7 +760,45 @@ func (h *Handler) ValidateAccessToken(tokenString string) (int, error) {
 	return h.jwtManager.ValidateAccessToken(tokenString)
 }
 
+// GetJWKS возвращает открытые ключи для проверки подписи токенов
+// GET /auth/.well-known/jwks.json
+func (h *Handler) GetJWKS(c *gin.Context) {
+	c.JSON(200, h.jwtManager.JWKS())
+}
+
 // RequireAuth возвращает middleware для проверки JWT токена
 func (h *Handler) RequireAuth() gin.HandlerFunc {
 	return h.jwtManager.JWTInterceptor()
 }
+
+// tooManyLoginAttempts отправляет ответ 429 с заголовком Retry-After,
+// когда вход временно заблокирован после неудачных попыток
+func (h *Handler) tooManyLoginAttempts(c *gin.Context, retryAfter time.Duration) {
+	h.tooManyRequests(c, errors.MsgTooManyLoginAttempts, retryAfter)
+}
+
+// tooManyRequests отправляет ответ 429 с сообщением message и заголовком Retry-After
+func (h *Handler) tooManyRequests(c *gin.Context, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(429, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
+
+// invalidUsername отправляет ответ 400 с нарушениями правил для имени пользователя
+func (h *Handler) invalidUsername(c *gin.Context, violations []password.Violation) {
+	c.JSON(400, gin.H{
+		"error":  errors.MsgInvalidUsername,
+		"fields": violations,
+	})
+}
+
+// weakPassword отправляет ответ 400 с нарушениями политики паролей по полям
+func (h *Handler) weakPassword(c *gin.Context, violations []password.Violation) {
+	c.JSON(400, gin.H{
+		"error":  errors.MsgWeakPassword,
+		"fields": violations,
+	})
+}
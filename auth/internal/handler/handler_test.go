@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"auth/internal/config"
+	"auth/internal/errors"
+	"auth/internal/service"
+	"context"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRevocations - хранилище отозванных токенов в памяти для тестов
+type memoryRevocations struct {
+	mu       sync.Mutex
+	tokens   map[string]struct{}
+	sessions map[string]struct{}
+	users    map[int]time.Time
+}
+
+func newMemoryRevocations() *memoryRevocations {
+	return &memoryRevocations{
+		tokens:   make(map[string]struct{}),
+		sessions: make(map[string]struct{}),
+		users:    make(map[int]time.Time),
+	}
+}
+
+func (m *memoryRevocations) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[jti] = struct{}{}
+	return nil
+}
+
+func (m *memoryRevocations) RevokeAllUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[userID] = time.Now()
+	return nil
+}
+
+func (m *memoryRevocations) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[sessionID] = struct{}{}
+	return nil
+}
+
+func (m *memoryRevocations) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tokens[jti]; ok {
+		return true, nil
+	}
+	if _, ok := m.sessions[sessionID]; ok {
+		return true, nil
+	}
+	revokedAt, ok := m.users[userID]
+	return ok && issuedAt.Before(revokedAt), nil
+}
+
+// newTestHandler создает обработчик поверх сервиса в памяти
+func newTestHandler(t *testing.T, revocations jwtmanager.RevocationStore) *Handler {
+	t.Helper()
+	cfg := &config.Config{
+		JWTSecretKey:           "test-secret-key-for-handler-tests-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		JWTIssuer:              "auth",
+		JWTAudience:            "notes",
+		TOTPEnabled:            true,
+		TOTPEncryptionKey:      "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		TOTPIssuer:             "Notes",
+		PasswordMinLength:      8,
+		UsernameMinLength:      3,
+		UsernameMaxLength:      32,
+	}
+	h, err := NewHandler(service.NewMemoryService(), cfg, revocations, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	return h
+}
+
+// TestRefreshTokenReuseRevokesSession проверяет, что повторное использование refresh токена
+// отзывает не только семейство refresh токенов, но и уже выпущенные в сессии access токены
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	client := clientInfo{userAgent: "test", ip: "127.0.0.1"}
+	subject := jwtmanager.Subject{UserID: 1}
+
+	first, err := h.startSession(ctx, client, subject)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	stolen, err := h.jwtManager.ValidateRefreshTokenClaims(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("ValidateRefreshTokenClaims: %v", err)
+	}
+
+	// Законный клиент обновляет токены
+	second, _, err := h.rotateTokens(ctx, client, stolen, subject)
+	if err != nil {
+		t.Fatalf("rotateTokens: %v", err)
+	}
+	access, err := h.jwtManager.ValidateAccessTokenClaims(second.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, access); err != nil {
+		t.Fatalf("access токен отозван до повторного использования: %v", err)
+	}
+
+	// Похищенный refresh токен предъявляется повторно
+	_, _, err = h.rotateTokens(ctx, client, stolen, subject)
+	if !stderrors.Is(err, errors.ErrRefreshTokenReuse) {
+		t.Fatalf("rotateTokens повторно: ожидалась ErrRefreshTokenReuse, получено %v", err)
+	}
+
+	for name, token := range map[string]string{"первый": first.AccessToken, "второй": second.AccessToken} {
+		claims, err := h.jwtManager.ValidateAccessTokenClaims(token)
+		if err != nil {
+			t.Fatalf("ValidateAccessTokenClaims(%s): %v", name, err)
+		}
+		if err := h.jwtManager.CheckRevoked(ctx, claims); !stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+			t.Errorf("%s access токен сессии не отозван: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/oauth"
+	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Introspect обрабатывает запрос интроспекции токена (RFC 7662)
+// Позволяет сервисам, которые не могут использовать pkg/jwtmanager, проверять access, refresh
+// и персональные токены. Вызывающий сервис аутентифицируется как конфиденциальный OAuth клиент.
+// Разобранные токены кешируются в памяти, статус отзыва проверяется при каждом запросе
+// POST /auth/introspect
+func (h *Handler) Introspect(c *gin.Context) {
+	// Ответ содержит данные токена и не должен кешироваться
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	client, ok := h.authenticateOAuthClient(ctx, c)
+	if !ok {
+		return
+	}
+	if client.Public {
+		oauthError(c, 401, oauth.ERROR_INVALID_CLIENT, errors.MsgOAuthPublicIntrospection)
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		oauthError(c, 400, oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthMissingParameter)
+		return
+	}
+
+	// Персональные токены проверяются по общему хранилищу
+	if strings.HasPrefix(token, jwtmanager.PAT_PREFIX) {
+		h.introspectPAT(ctx, c, token)
+		return
+	}
+
+	claims, err := h.activeTokenClaims(ctx, token, c.PostForm("token_type_hint"))
+	if err != nil {
+		oauthError(c, 503, oauth.ERROR_TEMPORARILY_UNAVAILABLE, errors.MsgRevocationCheck)
+		return
+	}
+	if claims == nil {
+		c.JSON(200, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active": true,
+		"sub":    claims.Subject,
+		"jti":    claims.ID,
+		"exp":    claims.ExpiresTime().Unix(),
+		"iat":    claims.IssuedTime().Unix(),
+	}
+	if claims.Type == jwtmanager.ACCESS_TOKEN {
+		response["token_type"] = "Bearer"
+	}
+	if claims.Scope != "" {
+		response["scope"] = claims.Scope
+	}
+	if claims.ClientID != "" {
+		response["client_id"] = claims.ClientID
+	}
+	if claims.Issuer != "" {
+		response["iss"] = claims.Issuer
+	}
+	if len(claims.Audience) > 0 {
+		response["aud"] = claims.Audience
+	}
+	if claims.NotBefore != nil {
+		response["nbf"] = claims.NotBefore.Unix()
+	}
+	if claims.SessionID != "" {
+		response["sid"] = claims.SessionID
+	}
+	if len(claims.Roles) > 0 {
+		response["roles"] = claims.Roles
+	}
+	c.JSON(200, response)
+}
+
+// UserInfo возвращает данные пользователя, которому выдан access токен
+// Доступен и с токенами OAuth клиентов
+// GET /auth/userinfo
+func (h *Handler) UserInfo(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	response := gin.H{
+		"sub":                strconv.Itoa(user.ID),
+		"preferred_username": user.Username,
+	}
+	if user.Email != nil {
+		response["email"] = *user.Email
+		response["email_verified"] = user.EmailVerified
+	}
+	c.JSON(200, response)
+}
+
+// introspectPAT отправляет результат интроспекции персонального токена
+func (h *Handler) introspectPAT(ctx context.Context, c *gin.Context, token string) {
+	record, err := h.activePAT(ctx, token)
+	if err != nil {
+		oauthError(c, 503, oauth.ERROR_TEMPORARILY_UNAVAILABLE, errors.MsgPATStore)
+		return
+	}
+	if record == nil {
+		c.JSON(200, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        strconv.Itoa(record.UserID),
+		"jti":        record.ID,
+		"scope":      record.Scope,
+	}
+	if !record.ExpiresAt.IsZero() {
+		response["exp"] = record.ExpiresAt.Unix()
+	}
+	c.JSON(200, response)
+}
+
+// activeTokenClaims возвращает утверждения действительного access или refresh токена
+// Разобранные токены кешируются, статус отзыва проверяется при каждом вызове.
+// Возвращает nil для неверного, отозванного или замененного токена, а также для refresh токена
+// отключенного или удаленного пользователя, и ошибку, если не удалось проверить статус отзыва access токена
+func (h *Handler) activeTokenClaims(ctx context.Context, token, hint string) (*jwtmanager.Claims, error) {
+	claims, found := h.introspect.Get(token)
+	if !found {
+		claims = h.parseIntrospectedToken(token, hint)
+		h.introspect.Set(token, claims)
+	}
+	if claims == nil {
+		return nil, nil
+	}
+
+	switch claims.Type {
+	case jwtmanager.ACCESS_TOKEN:
+		if err := h.jwtManager.CheckRevoked(ctx, claims); err != nil {
+			if stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	case jwtmanager.REFRESH_TOKEN:
+		// Refresh токен действителен, пока он последний в своем семействе и не отозван
+		stored, err := h.service.ReadRefreshToken(ctx, claims.ID)
+		if err != nil || stored.RevokedAt != nil || stored.ReplacedBy != "" {
+			return nil, nil
+		}
+		// Отключенный или удаленный пользователь не может обновить токены,
+		// поэтому его refresh токен не считается действительным
+		user, err := h.service.Read(ctx, claims.UserID)
+		if err != nil || user.Disabled {
+			return nil, nil
+		}
+	}
+	return claims, nil
+}
+
+// activePAT возвращает запись действительного персонального токена
+// и отмечает время его использования
+// Возвращает nil для неизвестного или истекшего токена и ошибку хранилища
+func (h *Handler) activePAT(ctx context.Context, token string) (*jwtmanager.PATRecord, error) {
+	record, err := h.pats.LookupPAT(ctx, jwtmanager.HashPAT(token))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !record.ExpiresAt.IsZero() && !time.Now().Before(record.ExpiresAt) {
+		return nil, nil
+	}
+
+	// Время использования нужно только для отображения, ошибка не влияет на ответ
+	_ = h.pats.TouchPAT(ctx, record.ID, time.Now())
+	return record, nil
+}
+
+// parseIntrospectedToken проверяет подпись и утверждения access или refresh токена
+// Сначала проверяется тип из token_type_hint. Возвращает nil для неверного токена
+func (h *Handler) parseIntrospectedToken(token, hint string) *jwtmanager.Claims {
+	validators := []func(string) (*jwtmanager.Claims, error){
+		h.jwtManager.ValidateAccessTokenClaims,
+		h.jwtManager.ValidateRefreshTokenClaims,
+	}
+	if hint == "refresh_token" {
+		validators[0], validators[1] = validators[1], validators[0]
+	}
+
+	for _, validate := range validators {
+		if claims, err := validate(token); err == nil {
+			return claims
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"auth/internal/introspection"
+	"auth/internal/models"
+	"auth/internal/oauth"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unavailableRevocations - хранилище отозванных токенов, которое всегда недоступно
+type unavailableRevocations struct {
+	*memoryRevocations
+}
+
+func (unavailableRevocations) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	return false, stderrors.New("redis недоступен")
+}
+
+// newIntrospectFixture создает обработчик с включенным кешем интроспекции,
+// конфиденциального OAuth клиента для вызова интроспекции и пользователя с сессией
+func newIntrospectFixture(t *testing.T, revocations jwtmanager.RevocationStore) (*Handler, *models.User, *jwtmanager.TokenPair, func(token string) (int, map[string]any)) {
+	t.Helper()
+	ctx := context.Background()
+	h := newTestHandler(t, revocations)
+	h.introspect = introspection.NewCache(time.Minute, 100)
+
+	owner, err := h.service.Create(ctx, &models.User{Username: "service", UsernameNormalized: "service", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	secret, secretHash, err := oauth.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	client := &models.OAuthClient{
+		ID:         "resource-server",
+		OwnerID:    owner.ID,
+		Name:       "resource server",
+		SecretHash: secretHash,
+		GrantTypes: oauth.GRANT_CLIENT_CREDENTIALS,
+	}
+	if err := h.service.CreateOAuthClient(ctx, client); err != nil {
+		t.Fatalf("CreateOAuthClient: %v", err)
+	}
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	pair, err := h.startSession(ctx, clientInfo{userAgent: "test", ip: "127.0.0.1"}, jwtmanager.Subject{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+
+	introspect := func(token string) (int, map[string]any) {
+		t.Helper()
+		gin.SetMode(gin.TestMode)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		form := url.Values{"token": {token}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/introspect", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request.SetBasicAuth(client.ID, secret)
+		h.Introspect(c)
+		var response map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", recorder.Body, err)
+		}
+		return recorder.Code, response
+	}
+	return h, user, pair, introspect
+}
+
+// TestIntrospectRevokedTokens проверяет, что отозванные access и refresh токены
+// становятся неактивными, даже если их разбор уже закеширован
+func TestIntrospectRevokedTokens(t *testing.T) {
+	ctx := context.Background()
+	revocations := newMemoryRevocations()
+	h, _, pair, introspect := newIntrospectFixture(t, revocations)
+
+	for name, token := range map[string]string{"access": pair.AccessToken, "refresh": pair.RefreshToken} {
+		if status, response := introspect(token); status != http.StatusOK || response["active"] != true {
+			t.Fatalf("%s токен до отзыва: %d %v", name, status, response)
+		}
+	}
+
+	if err := revocations.RevokeSession(ctx, pair.SessionID, time.Hour); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := h.service.RevokeTokenFamily(ctx, pair.SessionID); err != nil {
+		t.Fatalf("RevokeTokenFamily: %v", err)
+	}
+
+	for name, token := range map[string]string{"access": pair.AccessToken, "refresh": pair.RefreshToken} {
+		if status, response := introspect(token); status != http.StatusOK || response["active"] != false {
+			t.Errorf("%s токен после отзыва: %d %v", name, status, response)
+		}
+	}
+	if status, response := introspect("not-a-token"); status != http.StatusOK || response["active"] != false {
+		t.Errorf("неверный токен: %d %v", status, response)
+	}
+}
+
+// TestIntrospectRevocationUnavailable проверяет, что при недоступном хранилище отзыва
+// access токен не признается активным
+func TestIntrospectRevocationUnavailable(t *testing.T) {
+	_, _, pair, introspect := newIntrospectFixture(t, unavailableRevocations{newMemoryRevocations()})
+
+	if status, response := introspect(pair.AccessToken); status != http.StatusServiceUnavailable {
+		t.Errorf("интроспекция при недоступном хранилище: %d %v, ожидался статус 503", status, response)
+	}
+}
+
+// TestIntrospectDisabledUser проверяет, что refresh токен отключенного
+// или удаленного пользователя неактивен
+func TestIntrospectDisabledUser(t *testing.T) {
+	ctx := context.Background()
+	h, user, pair, introspect := newIntrospectFixture(t, newMemoryRevocations())
+
+	if err := h.service.SetUserDisabled(ctx, user.ID, true); err != nil {
+		t.Fatalf("SetUserDisabled: %v", err)
+	}
+	if status, response := introspect(pair.RefreshToken); status != http.StatusOK || response["active"] != false {
+		t.Errorf("refresh токен отключенного пользователя: %d %v", status, response)
+	}
+
+	if err := h.service.SetUserDisabled(ctx, user.ID, false); err != nil {
+		t.Fatalf("SetUserDisabled: %v", err)
+	}
+	if status, response := introspect(pair.RefreshToken); status != http.StatusOK || response["active"] != true {
+		t.Fatalf("refresh токен после включения пользователя: %d %v", status, response)
+	}
+
+	if err := h.service.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if status, response := introspect(pair.RefreshToken); status != http.StatusOK || response["active"] != false {
+		t.Errorf("refresh токен пользователя, ожидающего удаления: %d %v", status, response)
+	}
+}
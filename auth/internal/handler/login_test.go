@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"auth/internal/lockout"
+	"auth/internal/models"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+)
+
+// TestLoginLockout проверяет, что после порога неудачных попыток вход отклоняется
+// с кодом 429 и заголовком Retry-After даже с верным паролем
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	h.lockouts = lockout.NewRedisLockout(client, lockout.Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   100,
+		BaseLockout:     30 * time.Second,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+	})
+
+	if _, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	login := func(password string) (int, http.Header) {
+		recorder := callJSONRecorder(t, h.LoginUser, gin.H{"username": "alice", "password": password})
+		return recorder.Code, recorder.Header()
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, _ := login("wrong-password"); status != http.StatusUnauthorized {
+			t.Fatalf("неудачная попытка #%d: статус %d, ожидался 401", i+1, status)
+		}
+	}
+
+	status, header := login("Secret-password-1")
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("вход после блокировки: статус %d, ожидался 429", status)
+	}
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds <= 0 || seconds > 30 {
+		t.Errorf("Retry-After = %q, ожидалось от 1 до 30 секунд", header.Get("Retry-After"))
+	}
+}
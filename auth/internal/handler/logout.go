@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"context"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logout обрабатывает запрос на выход из системы на текущем устройстве
+// Отзывает переданный refresh токен (вместе с его семейством)
+// и добавляет текущий access токен в список отозванных до истечения его срока действия
+func (h *Handler) Logout(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var logoutRequest struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&logoutRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Refresh токен должен принадлежать текущему пользователю
+	claims, err := h.jwtManager.ValidateRefreshTokenClaims(logoutRequest.RefreshToken)
+	if err != nil || claims.UserID != userID {
+		c.JSON(401, gin.H{
+			"error": errors.MsgRefreshToken,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	refreshToken, err := h.service.ReadRefreshToken(ctx, claims.ID)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgRefreshToken,
+		})
+		return
+	}
+
+	// Отзываем все семейство, чтобы ранее замененные токены этого входа тоже стали недействительны
+	if err := h.service.RevokeTokenFamily(ctx, refreshToken.FamilyID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Отзываем текущий access токен
+	if err := h.revokeCurrentAccessToken(ctx, c); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenRevocation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgLoggedOut,
+	})
+}
+
+// LogoutAll обрабатывает запрос на выход из системы на всех устройствах
+// Отзывает все refresh токены пользователя, все выпущенные до этого момента access токены
+// и персональные токены доступа: они не проходят через хранилище отозванных токенов
+// и иначе остались бы действительными
+func (h *Handler) LogoutAll(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	if err := h.service.RevokeUserTokens(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+	if msg, err := h.revokeUserPATs(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Отметка отзыва хранится столько же, сколько живет access токен
+	if store := h.jwtManager.RevocationStore(); store != nil {
+		if err := store.RevokeAllUserTokens(ctx, userID, h.jwtManager.AccessTokenTTL()); err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgTokenRevocation,
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgLoggedOutAll,
+	})
+}
+
+// revokeCurrentAccessToken добавляет access токен текущего запроса в список отозванных
+// вместе с сессией, к которой он относится
+func (h *Handler) revokeCurrentAccessToken(ctx context.Context, c *gin.Context) error {
+	store := h.jwtManager.RevocationStore()
+	if store == nil {
+		return nil
+	}
+	jti, expiresAt, err := jwtmanager.GetCurrentTokenID(c)
+	if err != nil {
+		return err
+	}
+	if err := store.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	// Остальные access токены этой сессии, выпущенные при предыдущих обновлениях
+	if sessionID := jwtmanager.GetCurrentSessionID(c); sessionID != "" {
+		return store.RevokeSession(ctx, sessionID, h.jwtManager.AccessTokenTTL())
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"auth/internal/models"
+	"context"
+	jwtmanager "jwt_manager"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryPATs - хранилище персональных токенов в памяти для тестов
+type memoryPATs struct {
+	mu      sync.Mutex
+	records map[string]jwtmanager.PATRecord
+}
+
+func newMemoryPATs() *memoryPATs {
+	return &memoryPATs{records: make(map[string]jwtmanager.PATRecord)}
+}
+
+func (m *memoryPATs) SavePAT(ctx context.Context, hash string, record jwtmanager.PATRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[hash] = record
+	return nil
+}
+
+func (m *memoryPATs) DeletePAT(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, hash)
+	return nil
+}
+
+func (m *memoryPATs) LookupPAT(ctx context.Context, hash string) (*jwtmanager.PATRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[hash]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (m *memoryPATs) ListPATHashes(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hashes := make([]string, 0, len(m.records))
+	for hash := range m.records {
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (m *memoryPATs) TouchPAT(ctx context.Context, id string, usedAt time.Time) error {
+	return nil
+}
+
+func (m *memoryPATs) LastUsed(ctx context.Context, ids []string) (map[string]time.Time, error) {
+	return map[string]time.Time{}, nil
+}
+
+// TestLogoutAllRevokesPATs проверяет, что выход на всех устройствах отзывает и персональные токены
+func TestLogoutAllRevokesPATs(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.pats = newMemoryPATs()
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	token, hash, err := jwtmanager.GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+	pat := &models.PersonalAccessToken{ID: "pat", UserID: user.ID, Name: "ci", Scope: "notes:read", TokenHash: hash}
+	if err := h.service.CreatePAT(ctx, pat); err != nil {
+		t.Fatalf("CreatePAT: %v", err)
+	}
+	if err := h.pats.SavePAT(ctx, hash, patRecord(pat)); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	if record, err := h.activePAT(ctx, token); err != nil || record == nil {
+		t.Fatalf("activePAT до выхода: %v, %v", record, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/logout-all", nil)
+	c.Set("user_id", user.ID)
+	h.LogoutAll(c)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("LogoutAll: ожидался статус 200, получен %d: %s", recorder.Code, recorder.Body)
+	}
+
+	if record, err := h.activePAT(ctx, token); err != nil || record != nil {
+		t.Errorf("activePAT после выхода: персональный токен действителен: %v, %v", record, err)
+	}
+	tokens, err := h.service.ListPATs(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListPATs: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("ListPATs после выхода: %d токенов", len(tokens))
+	}
+}
@@ -0,0 +1,462 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/oauth"
+	"context"
+	stderrors "errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authorize обрабатывает запрос авторизации OAuth2 (authorization code с PKCE)
+// Собственной страницы входа и согласия у сервиса нет: это API для фронтенда, который сам
+// показывает пользователю клиента и области доступа и вызывает метод с access токеном пользователя.
+// Токен передается только заголовком Bearer, поэтому сторонний сайт не может выполнить запрос
+// от имени пользователя. Само наличие токена согласием не считается: фронтенд передает
+// approve=true после явного подтверждения, иначе клиент получает access_denied
+// Ошибки в client_id и redirect_uri возвращаются в ответе, остальные - через redirect_uri
+// GET /auth/oauth/authorize?response_type=code&client_id=&redirect_uri=&scope=&state=&code_challenge=&code_challenge_method=S256&approve=true
+func (h *Handler) Authorize(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	client, err := h.service.ReadOAuthClient(ctx, c.Query("client_id"))
+	if err != nil {
+		oauthError(c, 400, oauth.ERROR_INVALID_CLIENT, errors.MsgOAuthClientNotFound)
+		return
+	}
+
+	// Без зарегистрированного адреса возврата перенаправлять пользователя нельзя
+	requestedRedirectURI := c.Query("redirect_uri")
+	redirectURI := requestedRedirectURI
+	registered := client.RedirectURIList()
+	if redirectURI == "" && len(registered) == 1 {
+		redirectURI = registered[0]
+	}
+	if redirectURI == "" || !slices.Contains(registered, redirectURI) {
+		oauthError(c, 400, oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthRedirectMismatch)
+		return
+	}
+
+	state := c.Query("state")
+	fail := func(code, description string) {
+		h.authorizeRedirect(c, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+		}, state)
+	}
+
+	if c.Query("response_type") != "code" {
+		fail(oauth.ERROR_UNSUPPORTED_RESPONSE_TYPE, errors.MsgOAuthUnsupportedResponseType)
+		return
+	}
+	if !slices.Contains(client.GrantTypeList(), oauth.GRANT_AUTHORIZATION_CODE) {
+		fail(oauth.ERROR_UNAUTHORIZED_CLIENT, errors.MsgOAuthGrantNotAllowed)
+		return
+	}
+
+	// PKCE обязателен для публичных клиентов и поддерживается только с методом S256
+	codeChallenge := c.Query("code_challenge")
+	codeChallengeMethod := c.Query("code_challenge_method")
+	if codeChallenge == "" {
+		if client.Public {
+			fail(oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthPKCERequired)
+			return
+		}
+		codeChallengeMethod = ""
+	} else if codeChallengeMethod != oauth.PKCE_S256 || !oauth.ValidPKCEValue(codeChallenge) {
+		fail(oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthInvalidPKCE)
+		return
+	}
+
+	// Пользователь отказал или фронтенд не спросил его согласия
+	if c.Query("approve") != "true" {
+		fail(oauth.ERROR_ACCESS_DENIED, errors.MsgOAuthConsentRequired)
+		return
+	}
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil || user.Disabled {
+		fail(oauth.ERROR_ACCESS_DENIED, errors.MsgUserNotFound)
+		return
+	}
+
+	// Клиент получает только те запрошенные области, которые разрешены и ему, и пользователю
+	requestedScope := c.Query("scope")
+	if requestedScope == "" {
+		requestedScope = client.Scope
+	}
+	scope := oauth.IntersectScopes(requestedScope, client.Scope, user.TokenScope())
+	if scope == "" {
+		fail(oauth.ERROR_INVALID_SCOPE, errors.MsgOAuthInvalidScope)
+		return
+	}
+
+	code, codeHash, err := oauth.GenerateSecret()
+	if err != nil {
+		fail(oauth.ERROR_SERVER_ERROR, errors.MsgTokenGeneration)
+		return
+	}
+	err = h.service.CreateAuthCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:            codeHash,
+		ClientID:            client.ID,
+		UserID:              user.ID,
+		RedirectURI:         requestedRedirectURI,
+		Scope:               scope,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		ExpiresAt:           time.Now().Add(time.Duration(h.cfg.OAuthCodeTTL) * time.Second),
+	})
+	if err != nil {
+		fail(oauth.ERROR_SERVER_ERROR, errors.MsgDatabaseOperation)
+		return
+	}
+
+	h.authorizeRedirect(c, redirectURI, url.Values{"code": {code}}, state)
+}
+
+// Token обрабатывает запрос на выдачу токенов OAuth2
+// Параметры передаются в теле application/x-www-form-urlencoded,
+// клиент аутентифицируется заголовком Basic или параметрами client_id и client_secret
+// POST /auth/oauth/token
+func (h *Handler) Token(c *gin.Context) {
+	// Ответы с токенами и ошибками не должны кешироваться
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	client, ok := h.authenticateOAuthClient(ctx, c)
+	if !ok {
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	if !slices.Contains(oauth.SupportedGrantTypes, grantType) {
+		oauthError(c, 400, oauth.ERROR_UNSUPPORTED_GRANT_TYPE, errors.MsgOAuthUnsupportedGrant)
+		return
+	}
+	if !slices.Contains(client.GrantTypeList(), grantType) {
+		oauthError(c, 400, oauth.ERROR_UNAUTHORIZED_CLIENT, errors.MsgOAuthGrantNotAllowed)
+		return
+	}
+
+	switch grantType {
+	case oauth.GRANT_AUTHORIZATION_CODE:
+		h.exchangeAuthCode(ctx, c, client)
+	case oauth.GRANT_REFRESH_TOKEN:
+		h.refreshClientTokens(ctx, c, client)
+	case oauth.GRANT_CLIENT_CREDENTIALS:
+		h.issueClientCredentials(ctx, c, client)
+	}
+}
+
+// OAuthMetadata возвращает документ обнаружения сервера авторизации (RFC 8414)
+// GET /.well-known/oauth-authorization-server
+func (h *Handler) OAuthMetadata(c *gin.Context) {
+	issuer := h.cfg.OAuthIssuerURL
+	c.JSON(200, gin.H{
+		"issuer":                                         issuer,
+		"authorization_endpoint":                         issuer + "/auth/oauth/authorize",
+		"token_endpoint":                                 issuer + "/auth/oauth/token",
+		"jwks_uri":                                       issuer + "/auth/.well-known/jwks.json",
+		"introspection_endpoint":                         issuer + "/auth/introspect",
+		"userinfo_endpoint":                              issuer + "/auth/userinfo",
+		"scopes_supported":                               models.DefaultScopes,
+		"response_types_supported":                       []string{"code"},
+		"grant_types_supported":                          oauth.SupportedGrantTypes,
+		"code_challenge_methods_supported":               []string{oauth.PKCE_S256},
+		"token_endpoint_auth_methods_supported":          []string{"client_secret_basic", "client_secret_post", "none"},
+		"introspection_endpoint_auth_methods_supported":  []string{"client_secret_basic", "client_secret_post"},
+		"authorization_response_iss_parameter_supported": true,
+	})
+}
+
+// RequireFirstParty возвращает middleware, которое не пускает токены OAuth клиентов
+// к управлению учетной записью: такие токены предназначены только для сервисов с данными
+func (h *Handler) RequireFirstParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := jwtmanager.GetCurrentClaims(c)
+		if err != nil || claims.ClientID != "" {
+			c.JSON(403, gin.H{
+				"error": errors.MsgOAuthClientTokenNotAllowed,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// exchangeAuthCode обменивает код авторизации на пару токенов
+func (h *Handler) exchangeAuthCode(ctx context.Context, c *gin.Context, client *models.OAuthClient) {
+	codeValue := c.PostForm("code")
+	if codeValue == "" {
+		oauthError(c, 400, oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthMissingParameter)
+		return
+	}
+	codeHash := oauth.HashSecret(codeValue)
+
+	// Клиент, redirect_uri и code_verifier проверяются до того, как код будет отмечен использованным:
+	// запрос с неверными параметрами не должен сжигать код законного клиента
+	redirectURI := c.PostForm("redirect_uri")
+	verifier := c.PostForm("code_verifier")
+	code, err := h.service.ConsumeAuthCode(ctx, codeHash, func(code *models.OAuthAuthorizationCode) error {
+		if code.ClientID != client.ID {
+			return errors.ErrInvalidAuthCode
+		}
+		if redirectURI != code.RedirectURI {
+			return errors.ErrRedirectMismatch
+		}
+		// code_verifier без code_challenge тоже отклоняется, чтобы нельзя было понизить защиту
+		if code.CodeChallenge != "" && !oauth.VerifyPKCE(verifier, code.CodeChallenge, code.CodeChallengeMethod) ||
+			code.CodeChallenge == "" && verifier != "" {
+			return errors.ErrInvalidVerifier
+		}
+		return nil
+	})
+	if stderrors.Is(err, errors.ErrAuthCodeReuse) {
+		// Код мог быть перехвачен: отзываем все, что было выдано при первом обмене
+		if code.SessionID != "" {
+			if _, err := h.revokeSession(ctx, code.SessionID); err != nil {
+				oauthError(c, 500, oauth.ERROR_SERVER_ERROR, errors.MsgTokenRevocation)
+				return
+			}
+		}
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgOAuthCodeReuse)
+		return
+	}
+	if stderrors.Is(err, errors.ErrInvalidAuthCode) {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgOAuthInvalidCode)
+		return
+	}
+	if stderrors.Is(err, errors.ErrRedirectMismatch) {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgOAuthRedirectMismatch)
+		return
+	}
+	if stderrors.Is(err, errors.ErrInvalidVerifier) {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgOAuthInvalidVerifier)
+		return
+	}
+	if err != nil {
+		oauthError(c, 500, oauth.ERROR_SERVER_ERROR, errors.MsgDatabaseOperation)
+		return
+	}
+
+	user, err := h.service.Read(ctx, code.UserID)
+	if err != nil || user.Disabled {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgOAuthInvalidCode)
+		return
+	}
+
+	// Области доступа пользователя могли сократиться с момента выдачи кода
+	scope := oauth.IntersectScopes(code.Scope, user.TokenScope())
+	pair, err := h.startSession(ctx, requestClient(c), jwtmanager.Subject{
+		UserID:   user.ID,
+		Scope:    scope,
+		ClientID: client.ID,
+	})
+	if err != nil {
+		oauthError(c, 500, oauth.ERROR_SERVER_ERROR, errors.MsgTokenGeneration)
+		return
+	}
+
+	if err := h.service.SetAuthCodeSession(ctx, codeHash, pair.SessionID); err != nil {
+		oauthError(c, 500, oauth.ERROR_SERVER_ERROR, errors.MsgDatabaseOperation)
+		return
+	}
+
+	h.tokenResponse(c, pair.AccessToken, pair.RefreshToken, scope)
+}
+
+// refreshClientTokens обновляет пару токенов, выданную OAuth клиенту
+// Области доступа не могут расшириться: берутся из refresh токена и сужаются параметром scope
+func (h *Handler) refreshClientTokens(ctx context.Context, c *gin.Context, client *models.OAuthClient) {
+	refreshToken := c.PostForm("refresh_token")
+	if refreshToken == "" {
+		oauthError(c, 400, oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthMissingParameter)
+		return
+	}
+
+	claims, err := h.jwtManager.ValidateRefreshTokenClaims(refreshToken)
+	if err != nil || claims.ClientID != client.ID {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgRefreshToken)
+		return
+	}
+
+	scope := claims.Scope
+	if requested := c.PostForm("scope"); requested != "" {
+		scope = oauth.IntersectScopes(requested, claims.Scope)
+		if len(strings.Fields(scope)) != len(strings.Fields(requested)) {
+			oauthError(c, 400, oauth.ERROR_INVALID_SCOPE, errors.MsgOAuthInvalidScope)
+			return
+		}
+	}
+
+	user, err := h.service.Read(ctx, claims.UserID)
+	if err != nil || user.Disabled {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgRefreshToken)
+		return
+	}
+	scope = oauth.IntersectScopes(scope, user.TokenScope())
+
+	pair, msg, err := h.rotateTokens(ctx, requestClient(c), claims, jwtmanager.Subject{
+		UserID:   user.ID,
+		Scope:    scope,
+		ClientID: client.ID,
+	})
+	if err != nil {
+		if stderrors.Is(err, errors.ErrRefreshTokenReuse) {
+			oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgRefreshTokenReuse)
+			return
+		}
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgRefreshToken)
+			return
+		}
+		oauthError(c, 500, oauth.ERROR_SERVER_ERROR, msg)
+		return
+	}
+
+	h.tokenResponse(c, pair.AccessToken, pair.RefreshToken, scope)
+}
+
+// issueClientCredentials выдает access токен конфиденциальному клиенту без участия пользователя
+// Токен выдается от имени владельца клиента, refresh токен не выдается
+func (h *Handler) issueClientCredentials(ctx context.Context, c *gin.Context, client *models.OAuthClient) {
+	if client.Public {
+		oauthError(c, 400, oauth.ERROR_UNAUTHORIZED_CLIENT, errors.MsgOAuthPublicClientCredentials)
+		return
+	}
+
+	owner, err := h.service.Read(ctx, client.OwnerID)
+	if err != nil || owner.Disabled {
+		oauthError(c, 400, oauth.ERROR_INVALID_GRANT, errors.MsgUserDisabled)
+		return
+	}
+
+	requested := c.PostForm("scope")
+	if requested == "" {
+		requested = client.Scope
+	}
+	scope := oauth.IntersectScopes(requested, client.Scope, owner.TokenScope())
+	if scope == "" {
+		oauthError(c, 400, oauth.ERROR_INVALID_SCOPE, errors.MsgOAuthInvalidScope)
+		return
+	}
+
+	// Токены клиента отзываются по общему sid при удалении клиента
+	accessToken, _, err := h.jwtManager.GenerateAccessToken(jwtmanager.Subject{
+		UserID:    owner.ID,
+		SessionID: oauth.ClientSessionID(client.ID),
+		Scope:     scope,
+		ClientID:  client.ID,
+	})
+	if err != nil {
+		oauthError(c, 500, oauth.ERROR_SERVER_ERROR, errors.MsgTokenGeneration)
+		return
+	}
+
+	h.tokenResponse(c, accessToken, "", scope)
+}
+
+// authenticateOAuthClient находит клиента запроса и проверяет его секрет
+// При ошибке отправляет ответ invalid_client и возвращает false
+func (h *Handler) authenticateOAuthClient(ctx context.Context, c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// В заголовке Basic значения передаются в form-urlencoded виде (RFC 6749, раздел 2.3.1)
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	fail := func() (*models.OAuthClient, bool) {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthError(c, 401, oauth.ERROR_INVALID_CLIENT, errors.MsgOAuthClientAuth)
+		return nil, false
+	}
+
+	client, err := h.service.ReadOAuthClient(ctx, clientID)
+	if err != nil {
+		return fail()
+	}
+	if client.Public {
+		// Публичный клиент идентифицируется только client_id, секрет у него отсутствует
+		if secret != "" {
+			return fail()
+		}
+		return client, true
+	}
+	if !oauth.VerifySecret(secret, client.SecretHash) {
+		return fail()
+	}
+	return client, true
+}
+
+// authorizeRedirect перенаправляет пользователя на адрес возврата клиента с параметрами ответа
+// К параметрам добавляются state и iss (RFC 9207)
+func (h *Handler) authorizeRedirect(c *gin.Context, redirectURI string, params url.Values, state string) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		oauthError(c, 400, oauth.ERROR_INVALID_REQUEST, errors.MsgOAuthInvalidRedirectURI)
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	query.Set("iss", h.cfg.OAuthIssuerURL)
+	target.RawQuery = query.Encode()
+
+	c.Redirect(302, target.String())
+}
+
+// tokenResponse отправляет успешный ответ /oauth/token (RFC 6749, раздел 5.1)
+func (h *Handler) tokenResponse(c *gin.Context, accessToken, refreshToken, scope string) {
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(h.jwtManager.AccessTokenTTL().Seconds()),
+		"scope":        scope,
+	}
+	if refreshToken != "" {
+		response["refresh_token"] = refreshToken
+	}
+	c.JSON(200, response)
+}
+
+// oauthError отправляет ошибку в формате OAuth2 (RFC 6749, раздел 5.2)
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
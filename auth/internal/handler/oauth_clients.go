@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/oauth"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxOAuthClientNameLength - максимальная длина названия OAuth клиента
+const maxOAuthClientNameLength = 100
+
+// RegisterOAuthClient обрабатывает запрос на регистрацию OAuth клиента
+// Клиент получает области доступа не шире, чем у зарегистрировавшего его пользователя,
+// а при client_credentials действует от имени этого пользователя
+// Секрет конфиденциального клиента возвращается только в этом ответе
+// POST /auth/oauth/clients
+func (h *Handler) RegisterOAuthClient(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var clientRequest struct {
+		Name         string   `json:"name" binding:"required"`
+		RedirectURIs []string `json:"redirect_uris"`
+		GrantTypes   []string `json:"grant_types"` // По умолчанию authorization_code и refresh_token
+		Scopes       []string `json:"scopes" binding:"required"`
+		Public       bool     `json:"public"` // Клиент без секрета (SPA, мобильное приложение)
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&clientRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(clientRequest.Name)
+	if name == "" || len(name) > maxOAuthClientNameLength || len(clientRequest.Scopes) == 0 {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidData,
+		})
+		return
+	}
+
+	grantTypes := clientRequest.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{oauth.GRANT_AUTHORIZATION_CODE, oauth.GRANT_REFRESH_TOKEN}
+	}
+	for _, grantType := range grantTypes {
+		if !slices.Contains(oauth.SupportedGrantTypes, grantType) {
+			c.JSON(400, gin.H{
+				"error":      errors.MsgOAuthUnsupportedGrant,
+				"grant_type": grantType,
+			})
+			return
+		}
+	}
+	// Публичный клиент не может хранить секрет, поэтому не может получать токены без пользователя
+	if clientRequest.Public && slices.Contains(grantTypes, oauth.GRANT_CLIENT_CREDENTIALS) {
+		c.JSON(400, gin.H{
+			"error": errors.MsgOAuthPublicClientCredentials,
+		})
+		return
+	}
+
+	if slices.Contains(grantTypes, oauth.GRANT_AUTHORIZATION_CODE) && len(clientRequest.RedirectURIs) == 0 {
+		c.JSON(400, gin.H{
+			"error": errors.MsgOAuthRedirectURIRequired,
+		})
+		return
+	}
+	for _, uri := range clientRequest.RedirectURIs {
+		// Адреса хранятся через пробел, поэтому пробелы в адресе недопустимы
+		if strings.ContainsAny(uri, " \t\n") || !oauth.ValidRedirectURI(uri) {
+			c.JSON(400, gin.H{
+				"error":        errors.MsgOAuthInvalidRedirectURI,
+				"redirect_uri": uri,
+			})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	// Клиент не может получить областей доступа больше, чем есть у пользователя
+	allowed := strings.Fields(user.TokenScope())
+	for _, scope := range clientRequest.Scopes {
+		if !slices.Contains(allowed, scope) {
+			c.JSON(403, gin.H{
+				"error":          errors.MsgOAuthScopeNotAllowed,
+				"scope":          scope,
+				"allowed_scopes": allowed,
+			})
+			return
+		}
+	}
+
+	id, err := jwtmanager.GenerateTokenID()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	client := &models.OAuthClient{
+		ID:           id,
+		OwnerID:      userID,
+		Name:         name,
+		Public:       clientRequest.Public,
+		RedirectURIs: strings.Join(clientRequest.RedirectURIs, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		Scope:        strings.Join(clientRequest.Scopes, " "),
+	}
+
+	var secret string
+	if !client.Public {
+		secret, client.SecretHash, err = oauth.GenerateSecret()
+		if err != nil {
+			c.JSON(500, gin.H{
+				"error":   errors.MsgTokenGeneration,
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.service.CreateOAuthClient(ctx, client); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"message": errors.MsgOAuthClientCreated,
+		"client":  oauthClientResponse(client),
+	}
+	if secret != "" {
+		response["client_secret"] = secret
+	}
+	c.JSON(201, response)
+}
+
+// ListOAuthClients обрабатывает запрос на получение OAuth клиентов пользователя
+// GET /auth/oauth/clients
+func (h *Handler) ListOAuthClients(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	clients, err := h.service.ListOAuthClients(ctx, userID)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response := make([]gin.H, 0, len(clients))
+	for i := range clients {
+		response = append(response, oauthClientResponse(&clients[i]))
+	}
+
+	c.JSON(200, gin.H{
+		"clients": response,
+		"count":   len(response),
+	})
+}
+
+// DeleteOAuthClient обрабатывает запрос на удаление OAuth клиента
+// Все сессии, начатые через клиента, завершаются, а выданные клиенту токены отзываются
+// DELETE /auth/oauth/clients/:id
+func (h *Handler) DeleteOAuthClient(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Клиент должен существовать и принадлежать текущему пользователю
+	client, err := h.service.ReadOAuthClient(ctx, c.Param("id"))
+	if err != nil || client.OwnerID != userID {
+		c.JSON(404, gin.H{
+			"error": errors.MsgOAuthClientNotFound,
+		})
+		return
+	}
+
+	if msg, err := h.deleteOAuthClient(ctx, client.ID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgOAuthClientDeleted,
+	})
+}
+
+// deleteOAuthClient удаляет OAuth клиента и отзывает access токены завершенных сессий
+// и токены, выданные самому клиенту по client_credentials.
+// Возвращает сообщение для ответа клиенту вместе с ошибкой
+func (h *Handler) deleteOAuthClient(ctx context.Context, clientID string) (string, error) {
+	sessionIDs, err := h.service.DeleteOAuthClient(ctx, clientID)
+	if err != nil {
+		return errors.MsgDatabaseOperation, err
+	}
+	sessionIDs = append(sessionIDs, oauth.ClientSessionID(clientID))
+
+	// Отметка отзыва хранится столько же, сколько живет access токен
+	if store := h.jwtManager.RevocationStore(); store != nil {
+		for _, sessionID := range sessionIDs {
+			if err := store.RevokeSession(ctx, sessionID, h.jwtManager.AccessTokenTTL()); err != nil {
+				return errors.MsgTokenRevocation, err
+			}
+		}
+	}
+	return "", nil
+}
+
+// oauthClientResponse формирует описание OAuth клиента для ответа
+func oauthClientResponse(client *models.OAuthClient) gin.H {
+	return gin.H{
+		"client_id":     client.ID,
+		"name":          client.Name,
+		"public":        client.Public,
+		"redirect_uris": client.RedirectURIList(),
+		"grant_types":   client.GrantTypeList(),
+		"scope":         client.Scope,
+		"created_at":    client.CreatedAt,
+	}
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"auth/internal/models"
+	"auth/internal/oauth"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postTokenForm выполняет обмен кода авторизации с параметрами form
+func postTokenForm(h *Handler, client *models.OAuthClient, form url.Values) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/oauth/token", strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.exchangeAuthCode(c.Request.Context(), c, client)
+	return recorder
+}
+
+// TestExchangeAuthCodeChecksBeforeConsume проверяет, что запрос с неверным redirect_uri,
+// code_verifier или чужим клиентом не делает код использованным
+func TestExchangeAuthCodeChecksBeforeConsume(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	client := &models.OAuthClient{ID: "client", OwnerID: user.ID, Public: true, RedirectURIs: "https://example.com/cb"}
+	verifier := "verifier-0123456789-0123456789-0123456789-0123"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	err = h.service.CreateAuthCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:            oauth.HashSecret("code"),
+		ClientID:            client.ID,
+		UserID:              user.ID,
+		RedirectURI:         "https://example.com/cb",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: oauth.PKCE_S256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthCode: %v", err)
+	}
+
+	valid := url.Values{"code": {"code"}, "redirect_uri": {"https://example.com/cb"}, "code_verifier": {verifier}}
+	attempts := map[string]struct {
+		client *models.OAuthClient
+		form   url.Values
+	}{
+		"чужой клиент":         {&models.OAuthClient{ID: "other"}, valid},
+		"неверный redirect":    {client, url.Values{"code": {"code"}, "redirect_uri": {"https://evil.com/cb"}, "code_verifier": {verifier}}},
+		"неверный verifier":    {client, url.Values{"code": {"code"}, "redirect_uri": {"https://example.com/cb"}, "code_verifier": {"wrong"}}},
+		"verifier отсутствует": {client, url.Values{"code": {"code"}, "redirect_uri": {"https://example.com/cb"}}},
+	}
+	for name, attempt := range attempts {
+		if recorder := postTokenForm(h, attempt.client, attempt.form); recorder.Code != http.StatusBadRequest {
+			t.Fatalf("%s: ожидался статус 400, получен %d: %s", name, recorder.Code, recorder.Body)
+		}
+	}
+
+	// Законный клиент по-прежнему может обменять код
+	if recorder := postTokenForm(h, client, valid); recorder.Code != http.StatusOK {
+		t.Fatalf("обмен кода: ожидался статус 200, получен %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+// TestAuthorizeRequiresApproval проверяет, что код выдается только при явном согласии пользователя
+func TestAuthorizeRequiresApproval(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	client := &models.OAuthClient{
+		ID:           "client",
+		OwnerID:      user.ID,
+		Name:         "client",
+		RedirectURIs: "https://example.com/cb",
+		GrantTypes:   oauth.GRANT_AUTHORIZATION_CODE,
+		Scope:        "notes:read",
+	}
+	if err := h.service.CreateOAuthClient(ctx, client); err != nil {
+		t.Fatalf("CreateOAuthClient: %v", err)
+	}
+
+	authorize := func(approve string) *url.URL {
+		t.Helper()
+		query := url.Values{
+			"response_type": {"code"},
+			"client_id":     {client.ID},
+			"redirect_uri":  {"https://example.com/cb"},
+			"state":         {"xyz"},
+		}
+		if approve != "" {
+			query.Set("approve", approve)
+		}
+		gin.SetMode(gin.TestMode)
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodGet, "/auth/oauth/authorize?"+query.Encode(), nil)
+		c.Set("user_id", user.ID)
+		h.Authorize(c)
+		if recorder.Code != http.StatusFound {
+			t.Fatalf("approve=%q: ожидался статус 302, получен %d: %s", approve, recorder.Code, recorder.Body)
+		}
+		location, err := url.Parse(recorder.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("approve=%q: неверный Location: %v", approve, err)
+		}
+		return location
+	}
+
+	for _, approve := range []string{"", "false"} {
+		location := authorize(approve)
+		if location.Query().Get("error") != oauth.ERROR_ACCESS_DENIED || location.Query().Has("code") {
+			t.Errorf("approve=%q: ожидался access_denied, получено %s", approve, location)
+		}
+	}
+
+	location := authorize("true")
+	if location.Query().Get("code") == "" || location.Query().Get("state") != "xyz" {
+		t.Errorf("approve=true: ожидался код, получено %s", location)
+	}
+}
+
+// TestClientCredentialsRevokedOnDelete проверяет, что токены, выданные клиенту
+// по client_credentials, отзываются при удалении клиента
+func TestClientCredentialsRevokedOnDelete(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	client := &models.OAuthClient{
+		ID:         "client",
+		OwnerID:    user.ID,
+		Name:       "client",
+		GrantTypes: oauth.GRANT_CLIENT_CREDENTIALS,
+		Scope:      "notes:read",
+	}
+	if err := h.service.CreateOAuthClient(ctx, client); err != nil {
+		t.Fatalf("CreateOAuthClient: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/oauth/token", strings.NewReader("grant_type=client_credentials"))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.issueClientCredentials(ctx, c, client)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("client_credentials: ожидался статус 200, получен %d: %s", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	claims, err := h.jwtManager.ValidateAccessTokenClaims(response.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, claims); err != nil {
+		t.Fatalf("токен клиента отозван до удаления клиента: %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/auth/oauth/clients/"+client.ID, nil)
+	c.Params = gin.Params{{Key: "id", Value: client.ID}}
+	c.Set("user_id", user.ID)
+	h.DeleteOAuthClient(c)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("DeleteOAuthClient: ожидался статус 200, получен %d: %s", recorder.Code, recorder.Body)
+	}
+
+	if err := h.jwtManager.CheckRevoked(ctx, claims); !stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+		t.Errorf("токен удаленного клиента не отозван: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPATNameLength - максимальная длина названия персонального токена
+const maxPATNameLength = 100
+
+// CreatePAT обрабатывает запрос на создание персонального токена доступа
+// Токен возвращается только в этом ответе, после этого его нельзя получить повторно
+// POST /auth/tokens
+func (h *Handler) CreatePAT(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var createRequest struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0 - бессрочный токен
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(createRequest.Name)
+	if name == "" || len(name) > maxPATNameLength || createRequest.ExpiresInDays < 0 || len(createRequest.Scopes) == 0 {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidData,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+
+	// Токен не может получить областей доступа больше, чем есть у пользователя
+	allowed := strings.Fields(user.TokenScope())
+	for _, scope := range createRequest.Scopes {
+		if !slices.Contains(allowed, scope) {
+			c.JSON(403, gin.H{
+				"error":          errors.MsgPATScopeNotAllowed,
+				"scope":          scope,
+				"allowed_scopes": allowed,
+			})
+			return
+		}
+	}
+
+	id, err := jwtmanager.GenerateTokenID()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+	token, hash, err := jwtmanager.GeneratePAT()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	pat := &models.PersonalAccessToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Scope:     strings.Join(createRequest.Scopes, " "),
+		TokenHash: hash,
+	}
+	if createRequest.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, createRequest.ExpiresInDays)
+		pat.ExpiresAt = &expiresAt
+	}
+
+	if err := h.service.CreatePAT(ctx, pat); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Сохраняем токен в общем хранилище, по которому его проверяют другие сервисы
+	if err := h.pats.SavePAT(ctx, hash, patRecord(pat)); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgPATStore,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message":      errors.MsgPATCreated,
+		"token":        token,
+		"access_token": pat,
+	})
+}
+
+// ListPATs обрабатывает запрос на получение персональных токенов пользователя
+// GET /auth/tokens
+func (h *Handler) ListPATs(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	tokens, err := h.service.ListPATs(ctx, userID)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Время последнего использования записывают сервисы, проверяющие токены
+	ids := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+	}
+	lastUsed, err := h.pats.LastUsed(ctx, ids)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgPATStore,
+			"details": err.Error(),
+		})
+		return
+	}
+	for i := range tokens {
+		if usedAt, ok := lastUsed[tokens[i].ID]; ok {
+			tokens[i].LastUsedAt = &usedAt
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// RevokePAT обрабатывает запрос на отзыв персонального токена
+// DELETE /auth/tokens/:id
+func (h *Handler) RevokePAT(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Токен должен существовать и принадлежать текущему пользователю
+	pat, err := h.service.ReadPAT(ctx, c.Param("id"))
+	if err != nil || pat.UserID != userID || pat.RevokedAt != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgPATNotFound,
+		})
+		return
+	}
+
+	// Сначала удаляем токен из общего хранилища: если отзыв в базе не удастся, токен
+	// останется действующим и восстановится при синхронизации, а не наоборот
+	if err := h.pats.DeletePAT(ctx, pat.TokenHash); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgPATStore,
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := h.service.RevokePAT(ctx, pat.ID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgPATRevoked,
+	})
+}
+
+// SyncPATs приводит общее хранилище персональных токенов в соответствие с базой данных
+// Вызывается при запуске сервиса: восстанавливает действующие токены после очистки Redis
+// и удаляет отозванные и истекшие, которые могли остаться после сбоя при отзыве
+func (h *Handler) SyncPATs(ctx context.Context) error {
+	// Ключи читаются до списка действующих токенов, чтобы не удалить токен, созданный во время синхронизации
+	stored, err := h.pats.ListPATHashes(ctx)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.ListActivePATs(ctx)
+	if err != nil {
+		return err
+	}
+	active := make(map[string]struct{}, len(tokens))
+	for i := range tokens {
+		active[tokens[i].TokenHash] = struct{}{}
+		if err := h.pats.SavePAT(ctx, tokens[i].TokenHash, patRecord(&tokens[i])); err != nil {
+			return err
+		}
+	}
+	for _, hash := range stored {
+		if _, ok := active[hash]; ok {
+			continue
+		}
+		if err := h.pats.DeletePAT(ctx, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeUserPATs отзывает все персональные токены пользователя и удаляет их из общего хранилища
+// Ошибка удаления одного токена не прерывает удаление остальных, оставшиеся удалит SyncPATs
+// Возвращает сообщение для ответа клиенту вместе с первой ошибкой
+func (h *Handler) revokeUserPATs(ctx context.Context, userID int) (string, error) {
+	tokens, err := h.service.RevokeUserPATs(ctx, userID)
+	if err != nil {
+		return errors.MsgDatabaseOperation, err
+	}
+	var firstErr error
+	for _, token := range tokens {
+		if err := h.pats.DeletePAT(ctx, token.TokenHash); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return errors.MsgPATStore, firstErr
+	}
+	return "", nil
+}
+
+// suspendUserPATs удаляет персональные токены пользователя из общего хранилища, не отзывая их в базе
+// Используется при удалении учетной записи: до восстановления токены не действуют,
+// а SyncPATs не возвращает токены пользователей, помеченных удаленными
+// Возвращает сообщение для ответа клиенту вместе с первой ошибкой
+func (h *Handler) suspendUserPATs(ctx context.Context, userID int) (string, error) {
+	tokens, err := h.service.ListPATs(ctx, userID)
+	if err != nil {
+		return errors.MsgDatabaseOperation, err
+	}
+	var firstErr error
+	for _, token := range tokens {
+		if err := h.pats.DeletePAT(ctx, token.TokenHash); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return errors.MsgPATStore, firstErr
+	}
+	return "", nil
+}
+
+// resumeUserPATs возвращает в общее хранилище персональные токены восстановленного пользователя
+func (h *Handler) resumeUserPATs(ctx context.Context, userID int) error {
+	tokens, err := h.service.ListPATs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range tokens {
+		if err := h.pats.SavePAT(ctx, tokens[i].TokenHash, patRecord(&tokens[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patRecord формирует данные токена для общего хранилища
+func patRecord(pat *models.PersonalAccessToken) jwtmanager.PATRecord {
+	record := jwtmanager.PATRecord{
+		ID:     pat.ID,
+		UserID: pat.UserID,
+		Scope:  pat.Scope,
+	}
+	if pat.ExpiresAt != nil {
+		record.ExpiresAt = *pat.ExpiresAt
+	}
+	return record
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	stderrors "errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtmanager "jwt_manager"
+)
+
+// failingPATs - хранилище персональных токенов, в котором удаление выбранных токенов завершается ошибкой
+type failingPATs struct {
+	*memoryPATs
+	failDelete map[string]bool
+}
+
+func (f *failingPATs) DeletePAT(ctx context.Context, hash string) error {
+	if f.failDelete[hash] {
+		return stderrors.New("redis недоступен")
+	}
+	return f.memoryPATs.DeletePAT(ctx, hash)
+}
+
+// createTestPAT создает персональный токен в базе и в общем хранилище
+func createTestPAT(t *testing.T, h *Handler, userID int, id string, expiresAt *time.Time) *models.PersonalAccessToken {
+	t.Helper()
+	ctx := context.Background()
+	_, hash, err := jwtmanager.GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+	pat := &models.PersonalAccessToken{ID: id, UserID: userID, Name: id, Scope: "notes:read", TokenHash: hash, ExpiresAt: expiresAt}
+	if err := h.service.CreatePAT(ctx, pat); err != nil {
+		t.Fatalf("CreatePAT: %v", err)
+	}
+	if err := h.pats.SavePAT(ctx, hash, patRecord(pat)); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	return pat
+}
+
+// TestRevokePATStoreFailure проверяет, что при ошибке общего хранилища токен не отзывается в базе,
+// иначе он остался бы действующим в Redis без возможности повторного отзыва
+func TestRevokePATStoreFailure(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	pats := &failingPATs{memoryPATs: newMemoryPATs(), failDelete: map[string]bool{}}
+	h.pats = pats
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	pat := createTestPAT(t, h, user.ID, "pat", nil)
+	revoke := func() int {
+		return callJSONRecorder(t, h.RevokePAT, nil, func(c *gin.Context) {
+			c.Set("user_id", user.ID)
+			c.Params = gin.Params{{Key: "id", Value: pat.ID}}
+		}).Code
+	}
+
+	pats.failDelete[pat.TokenHash] = true
+	if status := revoke(); status != http.StatusInternalServerError {
+		t.Fatalf("RevokePAT при недоступном хранилище: статус %d, ожидался 500", status)
+	}
+	stored, err := h.service.ReadPAT(ctx, pat.ID)
+	if err != nil {
+		t.Fatalf("ReadPAT: %v", err)
+	}
+	if stored.RevokedAt != nil {
+		t.Fatal("токен отозван в базе, хотя не удален из общего хранилища")
+	}
+
+	// Повторный отзыв после восстановления хранилища проходит
+	pats.failDelete[pat.TokenHash] = false
+	if status := revoke(); status != http.StatusOK {
+		t.Fatalf("повторный RevokePAT: статус %d, ожидался 200", status)
+	}
+	if record, _ := pats.LookupPAT(ctx, pat.TokenHash); record != nil {
+		t.Error("токен остался в общем хранилище после отзыва")
+	}
+	if stored, err := h.service.ReadPAT(ctx, pat.ID); err != nil || stored.RevokedAt == nil {
+		t.Errorf("токен не отозван в базе: %+v, %v", stored, err)
+	}
+}
+
+// TestRevokeUserPATsContinuesOnError проверяет, что ошибка удаления одного токена
+// не оставляет остальные токены пользователя в общем хранилище
+func TestRevokeUserPATsContinuesOnError(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	pats := &failingPATs{memoryPATs: newMemoryPATs(), failDelete: map[string]bool{}}
+	h.pats = pats
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	first := createTestPAT(t, h, user.ID, "first", nil)
+	second := createTestPAT(t, h, user.ID, "second", nil)
+	third := createTestPAT(t, h, user.ID, "third", nil)
+	pats.failDelete[second.TokenHash] = true
+
+	message, err := h.revokeUserPATs(ctx, user.ID)
+	if err == nil || message != errors.MsgPATStore {
+		t.Fatalf("revokeUserPATs = %q, %v, ожидалась ошибка хранилища", message, err)
+	}
+	for _, pat := range []*models.PersonalAccessToken{first, third} {
+		if record, _ := pats.LookupPAT(ctx, pat.TokenHash); record != nil {
+			t.Errorf("токен %s остался в общем хранилище", pat.ID)
+		}
+	}
+
+	// Оставшийся токен удаляется при синхронизации
+	pats.failDelete[second.TokenHash] = false
+	if err := h.SyncPATs(ctx); err != nil {
+		t.Fatalf("SyncPATs: %v", err)
+	}
+	if record, _ := pats.LookupPAT(ctx, second.TokenHash); record != nil {
+		t.Error("SyncPATs не удалил отозванный токен")
+	}
+}
+
+// TestSyncPATs проверяет, что синхронизация восстанавливает действующие токены
+// и удаляет отозванные и истекшие
+func TestSyncPATs(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	pats := newMemoryPATs()
+	h.pats = pats
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	active := createTestPAT(t, h, user.ID, "active", nil)
+	// Отозван в базе, но остался в общем хранилище после сбоя
+	revoked := createTestPAT(t, h, user.ID, "revoked", nil)
+	if err := h.service.RevokePAT(ctx, revoked.ID); err != nil {
+		t.Fatalf("RevokePAT: %v", err)
+	}
+	expiresAt := time.Now().Add(-time.Minute)
+	createTestPAT(t, h, user.ID, "expired", &expiresAt)
+	// Очистка Redis: действующий токен пропал из общего хранилища
+	if err := pats.DeletePAT(ctx, active.TokenHash); err != nil {
+		t.Fatalf("DeletePAT: %v", err)
+	}
+	pats.records["unknown"] = jwtmanager.PATRecord{ID: "unknown", UserID: user.ID}
+
+	if err := h.SyncPATs(ctx); err != nil {
+		t.Fatalf("SyncPATs: %v", err)
+	}
+	hashes, err := pats.ListPATHashes(ctx)
+	if err != nil {
+		t.Fatalf("ListPATHashes: %v", err)
+	}
+	if !slices.Equal(hashes, []string{active.TokenHash}) {
+		t.Errorf("после SyncPATs в хранилище %v, ожидался только действующий токен %s", hashes, active.TokenHash)
+	}
+}
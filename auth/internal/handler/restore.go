@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RestoreUser обрабатывает запрос на восстановление удаленной учетной записи
+// Учетная запись восстанавливается по имени пользователя и паролю до окончания срока ожидания,
+// при включенной двухфакторной аутентификации требуется также код или код восстановления.
+// После восстановления пользователь входит в систему
+// POST /auth/restore
+func (h *Handler) RestoreUser(c *gin.Context) {
+	var restoreRequest struct {
+		Username     string `json:"username" binding:"required"`
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&restoreRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Подбор пароля через восстановление ограничивается так же, как через вход
+	clientIP := c.ClientIP()
+	retryAfter, err := h.lockouts.Check(ctx, restoreRequest.Username, clientIP)
+	if err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+	if retryAfter > 0 {
+		h.tooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := h.service.Authenticate(ctx, restoreRequest.Username, restoreRequest.Password)
+	switch {
+	case err == nil:
+		c.JSON(409, gin.H{
+			"error": errors.MsgUserNotDeleted,
+		})
+		return
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		if _, lockErr := h.lockouts.RegisterFailure(ctx, restoreRequest.Username, clientIP); lockErr != nil {
+			c.JSON(503, gin.H{
+				"error":   errors.MsgLockoutCheck,
+				"details": lockErr.Error(),
+			})
+			return
+		}
+		c.JSON(401, gin.H{
+			"error": errors.MsgInvalidCredentials,
+		})
+		return
+	case !stderrors.Is(err, errors.ErrUserPendingDeletion):
+		c.JSON(401, gin.H{
+			"error": errors.MsgInvalidCredentials,
+		})
+		return
+	}
+
+	// Учетная запись с истекшим сроком будет удалена при следующем запуске очистки
+	if !time.Now().Before(user.PurgeAt(h.deletionGrace())) {
+		c.JSON(410, gin.H{
+			"error": errors.MsgUserRestoreExpired,
+		})
+		return
+	}
+	if user.Disabled {
+		c.JSON(403, gin.H{
+			"error": errors.MsgUserDisabled,
+		})
+		return
+	}
+
+	if user.TOTPEnabled {
+		if err := h.checkSecondFactor(ctx, user, restoreRequest.Code, restoreRequest.RecoveryCode); err != nil {
+			if stderrors.Is(err, errors.ErrInvalidTOTPCode) {
+				if _, lockErr := h.lockouts.RegisterFailure(ctx, user.Username, clientIP); lockErr != nil {
+					c.JSON(503, gin.H{
+						"error":   errors.MsgLockoutCheck,
+						"details": lockErr.Error(),
+					})
+					return
+				}
+			}
+			h.secondFactorError(c, err)
+			return
+		}
+	}
+
+	if err := h.service.RestoreUser(ctx, user.ID); err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(409, gin.H{
+				"error": errors.MsgUserNotDeleted,
+			})
+			return
+		}
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Учетная запись уже восстановлена, поэтому ошибка хранилища не отменяет вход:
+	// токены, которые не удалось вернуть, восстановит SyncPATs при следующем запуске
+	if err := h.resumeUserPATs(ctx, user.ID); err != nil {
+		fmt.Printf("Ошибка восстановления персональных токенов пользователя %d: %v\n", user.ID, err)
+	}
+
+	// Успешное восстановление сбрасывает счетчик неудачных попыток пользователя
+	if err := h.lockouts.RegisterSuccess(ctx, restoreRequest.Username, clientIP); err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Убираем пароль из ответа
+	user.Password = ""
+	user.DeletedAt = gorm.DeletedAt{}
+
+	// Генерируем JWT токены и начинаем новое семейство refresh токенов
+	pair, err := h.issueTokens(ctx, requestClient(c), user)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       errors.MsgUserRestored,
+		"user":          user,
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+	})
+}
+
+// userPendingDeletion отправляет ответ на вход в учетную запись, ожидающую удаления
+// Ответ сообщает, до какого времени учетную запись можно восстановить
+func (h *Handler) userPendingDeletion(c *gin.Context, user *models.User) {
+	c.JSON(403, gin.H{
+		"error":            errors.MsgUserPendingDeletion,
+		"pending_deletion": true,
+		"purge_at":         user.PurgeAt(h.deletionGrace()),
+	})
+}
+
+// deletionGrace возвращает срок, в течение которого удаленную учетную запись можно восстановить
+func (h *Handler) deletionGrace() time.Duration {
+	return time.Duration(h.cfg.AccountDeletionGrace) * time.Hour
+}
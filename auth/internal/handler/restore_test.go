@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"auth/internal/models"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDeleteAndRestoreUser проверяет, что удаление учетной записи только приостанавливает
+// персональные токены и OAuth клиентов, а восстановление возвращает их без повторного выпуска
+func TestDeleteAndRestoreUser(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.lockouts = openLockout{}
+	h.cfg.AccountDeletionGrace = 1
+	pats := newMemoryPATs()
+	h.pats = pats
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	pat := createTestPAT(t, h, user.ID, "pat", nil)
+	client := &models.OAuthClient{ID: "client", OwnerID: user.ID, Name: "client"}
+	if err := h.service.CreateOAuthClient(ctx, client); err != nil {
+		t.Fatalf("CreateOAuthClient: %v", err)
+	}
+
+	recorder := callJSONRecorder(t, h.DeleteUser, nil, func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("DeleteUser: статус %d: %s", recorder.Code, recorder.Body)
+	}
+	if record, _ := pats.LookupPAT(ctx, pat.TokenHash); record != nil {
+		t.Error("персональный токен действует после удаления учетной записи")
+	}
+	if stored, err := h.service.ReadPAT(ctx, pat.ID); err != nil || stored.RevokedAt != nil {
+		t.Errorf("персональный токен отозван при удалении учетной записи: %+v, %v", stored, err)
+	}
+	if _, err := h.service.ReadOAuthClient(ctx, client.ID); err == nil {
+		t.Error("OAuth клиент доступен после удаления учетной записи")
+	}
+
+	// Перезапуск сервиса не возвращает токены удаленного пользователя
+	if err := h.SyncPATs(ctx); err != nil {
+		t.Fatalf("SyncPATs: %v", err)
+	}
+	if record, _ := pats.LookupPAT(ctx, pat.TokenHash); record != nil {
+		t.Error("SyncPATs вернул персональный токен удаленного пользователя")
+	}
+
+	recorder = callJSONRecorder(t, h.RestoreUser, gin.H{"username": "alice", "password": "Secret-password-1"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("RestoreUser: статус %d: %s", recorder.Code, recorder.Body)
+	}
+	if record, _ := pats.LookupPAT(ctx, pat.TokenHash); record == nil || record.ID != pat.ID {
+		t.Errorf("персональный токен не восстановлен: %+v", record)
+	}
+	if _, err := h.service.ReadOAuthClient(ctx, client.ID); err != nil {
+		t.Errorf("OAuth клиент не восстановлен: %v", err)
+	}
+
+	// Повторное восстановление не требуется
+	recorder = callJSONRecorder(t, h.RestoreUser, gin.H{"username": "alice", "password": "Secret-password-1"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("повторный RestoreUser: статус %d, ожидался 409", recorder.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"context"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions обрабатывает запрос на получение активных сессий пользователя
+// GET /auth/sessions
+func (h *Handler) ListSessions(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	sessions, err := h.service.ListSessions(ctx, userID)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Отмечаем сессию, из которой выполнен запрос
+	currentSessionID := jwtmanager.GetCurrentSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	c.JSON(200, gin.H{
+		"message":  errors.MsgSessionsFound,
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession обрабатывает запрос на завершение сессии пользователя
+// Отзывает refresh токены сессии и все выпущенные в ней access токены
+// DELETE /auth/sessions/:id
+func (h *Handler) RevokeSession(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Сессия должна существовать и принадлежать текущему пользователю
+	session, err := h.service.ReadSession(ctx, c.Param("id"))
+	if err != nil || session.UserID != userID {
+		c.JSON(404, gin.H{
+			"error": errors.MsgSessionNotFound,
+		})
+		return
+	}
+
+	if msg, err := h.revokeSession(ctx, session.ID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgSessionRevoked,
+	})
+}
+
+// revokeSession отзывает refresh токены сессии и все выпущенные в ней access токены
+// Возвращает сообщение для ответа клиенту вместе с ошибкой
+func (h *Handler) revokeSession(ctx context.Context, sessionID string) (string, error) {
+	if err := h.service.RevokeTokenFamily(ctx, sessionID); err != nil {
+		return errors.MsgDatabaseOperation, err
+	}
+
+	// Access токены сессии отзываются по утверждению sid
+	if store := h.jwtManager.RevocationStore(); store != nil {
+		if err := store.RevokeSession(ctx, sessionID, h.jwtManager.AccessTokenTTL()); err != nil {
+			return errors.MsgTokenRevocation, err
+		}
+	}
+	return "", nil
+}
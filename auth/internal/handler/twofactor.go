@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/totp"
+	"context"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Параметры двухфакторной аутентификации
+const (
+	recoveryCodesCount = 10 // Число кодов восстановления, выдаваемых при настройке
+	totpSkew           = 1  // Допустимое расхождение часов в шагах TOTP
+)
+
+// SetupTwoFactor обрабатывает запрос на настройку двухфакторной аутентификации
+// Генерирует секрет TOTP и коды восстановления. Второй фактор включается
+// только после подтверждения кодом из приложения в ConfirmTwoFactor
+// POST /auth/2fa/setup
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	if !h.cfg.TOTPEnabled {
+		c.JSON(503, gin.H{
+			"error": errors.MsgTOTPUnavailable,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+	// Повторная настройка включенного второго фактора возможна только после его отключения
+	if user.TOTPEnabled {
+		c.JSON(409, gin.H{
+			"error": errors.MsgTOTPAlreadyEnabled,
+		})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTOTPGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+	recoveryCodes, err := totp.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTOTPGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Коды восстановления хранятся только в виде хешей и показываются пользователю один раз
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, models.HashRecoveryCode(code))
+	}
+
+	// Секрет хранится в базе данных только в зашифрованном виде
+	sealedSecret, err := h.secrets.Seal(secret, userID)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTOTPSecret,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetupTOTP(ctx, userID, sealedSecret, hashes); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":        errors.MsgTOTPSetup,
+		"secret":         secret,
+		"otpauth_uri":    totp.URI(h.cfg.TOTPIssuer, user.Username, secret),
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// ConfirmTwoFactor обрабатывает запрос на подтверждение настройки второго фактора
+// Проверяет код из приложения-аутентификатора и включает второй фактор
+// POST /auth/2fa/confirm
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var confirmRequest struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&confirmRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(409, gin.H{
+			"error": errors.MsgTOTPAlreadyEnabled,
+		})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(400, gin.H{
+			"error": errors.MsgTOTPNotSetup,
+		})
+		return
+	}
+
+	secret, err := h.secrets.Open(user.TOTPSecret, user.ID)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTOTPSecret,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	step, ok := totp.Validate(secret, confirmRequest.Code, time.Now(), totpSkew)
+	if !ok {
+		c.JSON(400, gin.H{
+			"error": errors.MsgInvalidTOTPCode,
+		})
+		return
+	}
+
+	if err := h.service.EnableTOTP(ctx, userID, step); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgTOTPEnabled,
+	})
+}
+
+// DisableTwoFactor обрабатывает запрос на отключение второго фактора
+// Требует действующий код TOTP или неиспользованный код восстановления
+// POST /auth/2fa/disable
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var disableRequest struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&disableRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	user, err := h.service.Read(ctx, userID)
+	if err != nil {
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(400, gin.H{
+			"error": errors.MsgTOTPNotEnabled,
+		})
+		return
+	}
+
+	if err := h.checkSecondFactor(ctx, user, disableRequest.Code, disableRequest.RecoveryCode); err != nil {
+		h.secondFactorError(c, err)
+		return
+	}
+
+	if err := h.service.DisableTOTP(ctx, userID); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgTOTPDisabled,
+	})
+}
+
+// VerifyTwoFactor обрабатывает второй шаг входа для пользователей с включенным вторым фактором
+// Обменивает промежуточный токен из LoginUser и код TOTP (или код восстановления) на пару токенов
+// POST /auth/2fa/verify
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var verifyRequest struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Промежуточный токен подтверждает, что пароль уже проверен
+	claims, err := h.jwtManager.ValidateMFAPendingToken(verifyRequest.MFAToken)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgMFAToken,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Промежуточный токен одноразовый: после успешного входа его jti отзывается
+	store := h.jwtManager.RevocationStore()
+	if store == nil {
+		c.JSON(503, gin.H{
+			"error": errors.MsgMFAUnavailable,
+		})
+		return
+	}
+	if err := h.jwtManager.CheckRevoked(ctx, claims); err != nil {
+		if stderrors.Is(err, jwtmanager.ErrTokenRevoked) {
+			c.JSON(401, gin.H{
+				"error": errors.MsgMFAToken,
+			})
+			return
+		}
+		c.JSON(503, gin.H{
+			"error":   errors.MsgTokenRevocation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.service.Read(ctx, claims.UserID)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgMFAToken,
+		})
+		return
+	}
+	if user.Disabled {
+		c.JSON(403, gin.H{
+			"error": errors.MsgUserDisabled,
+		})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(401, gin.H{
+			"error": errors.MsgMFAToken,
+		})
+		return
+	}
+
+	// Подбор кода ограничивается так же, как подбор пароля
+	clientIP := c.ClientIP()
+	retryAfter, err := h.lockouts.Check(ctx, user.Username, clientIP)
+	if err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+	if retryAfter > 0 {
+		h.tooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	if err := h.checkSecondFactor(ctx, user, verifyRequest.Code, verifyRequest.RecoveryCode); err != nil {
+		if stderrors.Is(err, errors.ErrInvalidTOTPCode) {
+			if _, lockErr := h.lockouts.RegisterFailure(ctx, user.Username, clientIP); lockErr != nil {
+				c.JSON(503, gin.H{
+					"error":   errors.MsgLockoutCheck,
+					"details": lockErr.Error(),
+				})
+				return
+			}
+		}
+		h.secondFactorError(c, err)
+		return
+	}
+
+	// Успешный вход сбрасывает счетчик неудачных попыток пользователя
+	if err := h.lockouts.RegisterSuccess(ctx, user.Username, clientIP); err != nil {
+		c.JSON(503, gin.H{
+			"error":   errors.MsgLockoutCheck,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Токен отзывается до выдачи новых, чтобы его нельзя было предъявить повторно
+	if err := store.RevokeToken(ctx, claims.ID, claims.ExpiresTime()); err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenRevocation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Убираем пароль из ответа
+	user.Password = ""
+
+	// Генерируем JWT токены и начинаем новое семейство refresh токенов
+	pair, err := h.issueTokens(ctx, requestClient(c), user)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error":   errors.MsgTokenGeneration,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       errors.MsgLoginSuccess,
+		"user":          user,
+		"access_token":  pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+	})
+}
+
+// checkSecondFactor проверяет код TOTP или код восстановления пользователя
+// Код TOTP можно использовать только один раз, код восстановления - тоже
+func (h *Handler) checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	switch {
+	case code != "":
+		secret, err := h.secrets.Open(user.TOTPSecret, user.ID)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+		if !ok {
+			return errors.ErrInvalidTOTPCode
+		}
+		return h.service.ConsumeTOTPStep(ctx, user.ID, step)
+	case recoveryCode != "":
+		return h.service.UseRecoveryCode(ctx, user.ID, models.HashRecoveryCode(recoveryCode))
+	default:
+		return errors.ErrInvalidTOTPCode
+	}
+}
+
+// secondFactorError отправляет ответ на ошибку проверки второго фактора
+func (h *Handler) secondFactorError(c *gin.Context, err error) {
+	if stderrors.Is(err, errors.ErrInvalidTOTPCode) {
+		c.JSON(401, gin.H{
+			"error": errors.MsgInvalidTOTPCode,
+		})
+		return
+	}
+	c.JSON(500, gin.H{
+		"error":   errors.MsgDatabaseOperation,
+		"details": err.Error(),
+	})
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"auth/internal/config"
+	"auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/service"
+	"auth/internal/totp"
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openLockout - защита входа, которая никогда не блокирует, для тестов
+type openLockout struct{}
+
+func (openLockout) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (openLockout) RegisterFailure(ctx context.Context, username, ip string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (openLockout) RegisterSuccess(ctx context.Context, username, ip string) error {
+	return nil
+}
+
+// callJSON вызывает обработчик с телом body от имени пользователя userID (0 - без пользователя)
+// и возвращает статус и разобранный ответ
+func callJSON(t *testing.T, handle gin.HandlerFunc, userID int, body any) (int, map[string]any) {
+	t.Helper()
+	recorder := callJSONRecorder(t, handle, body, func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	})
+	var response map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", recorder.Body, err)
+	}
+	return recorder.Code, response
+}
+
+// callJSONRecorder вызывает обработчик с телом body и возвращает записанный ответ
+// setup позволяет подготовить контекст запроса перед вызовом
+func callJSONRecorder(t *testing.T, handle gin.HandlerFunc, body any, setup ...func(c *gin.Context)) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for _, prepare := range setup {
+		prepare(c)
+	}
+	handle(c)
+	return recorder
+}
+
+// TestTwoFactorVerify проверяет включение второго фактора, однократность кодов TOTP,
+// кодов восстановления и промежуточного токена
+func TestTwoFactorVerify(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, newMemoryRevocations())
+	h.lockouts = openLockout{}
+
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	status, setup := callJSON(t, h.SetupTwoFactor, user.ID, nil)
+	if status != http.StatusOK {
+		t.Fatalf("SetupTwoFactor: статус %d: %v", status, setup)
+	}
+	secret := setup["secret"].(string)
+	recoveryCode := setup["recovery_codes"].([]any)[0].(string)
+
+	// В базе данных секрет хранится только в зашифрованном виде
+	stored, err := h.service.Read(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if stored.TOTPSecret == secret || strings.Contains(stored.TOTPSecret, secret) {
+		t.Fatalf("секрет TOTP хранится открытым текстом: %s", stored.TOTPSecret)
+	}
+
+	now := totp.Step(time.Now())
+	code := func(step int64) string {
+		value, err := totp.Code(secret, step)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		return value
+	}
+	if status, body := callJSON(t, h.ConfirmTwoFactor, user.ID, gin.H{"code": code(now)}); status != http.StatusOK {
+		t.Fatalf("ConfirmTwoFactor: статус %d: %v", status, body)
+	}
+
+	mfaToken := func() string {
+		token, err := h.jwtManager.GenerateMFAPendingToken(user.ID, time.Minute)
+		if err != nil {
+			t.Fatalf("GenerateMFAPendingToken: %v", err)
+		}
+		return token
+	}
+	verify := func(token string, factor gin.H) int {
+		factor["mfa_token"] = token
+		status, _ := callJSON(t, h.VerifyTwoFactor, 0, factor)
+		return status
+	}
+
+	// Код подтверждения уже использован и не принимается повторно
+	if status := verify(mfaToken(), gin.H{"code": code(now)}); status != http.StatusUnauthorized {
+		t.Errorf("повтор кода подтверждения: статус %d", status)
+	}
+
+	token := mfaToken()
+	if status := verify(token, gin.H{"code": code(now + 1)}); status != http.StatusOK {
+		t.Fatalf("вход с кодом TOTP: статус %d", status)
+	}
+	// Тот же шаг нельзя использовать повторно
+	if status := verify(mfaToken(), gin.H{"code": code(now + 1)}); status != http.StatusUnauthorized {
+		t.Errorf("повтор кода TOTP: статус %d", status)
+	}
+	// Промежуточный токен одноразовый даже с верным вторым фактором
+	if status := verify(token, gin.H{"recovery_code": recoveryCode}); status != http.StatusUnauthorized {
+		t.Errorf("повтор промежуточного токена: статус %d", status)
+	}
+
+	if status := verify(mfaToken(), gin.H{"recovery_code": recoveryCode}); status != http.StatusOK {
+		t.Fatalf("вход с кодом восстановления: статус %d", status)
+	}
+	if status := verify(mfaToken(), gin.H{"recovery_code": recoveryCode}); status != http.StatusUnauthorized {
+		t.Errorf("повтор кода восстановления: статус %d", status)
+	}
+}
+
+// TestTwoFactorRequiresKey проверяет, что сервис с включенной двухфакторной аутентификацией
+// не запускается без ключа шифрования секретов, а с выключенной не дает ее настроить
+func TestTwoFactorRequiresKey(t *testing.T) {
+	cfg := &config.Config{
+		JWTSecretKey:           "test-secret-key-for-handler-tests-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		TOTPEnabled:            true,
+	}
+	if _, err := NewHandler(service.NewMemoryService(), cfg, newMemoryRevocations(), nil, nil, nil, nil); !stderrors.Is(err, errors.ErrTOTPKeyRequired) {
+		t.Fatalf("NewHandler без ключа: ожидалась ErrTOTPKeyRequired, получено %v", err)
+	}
+
+	cfg.TOTPEnabled = false
+	h, err := NewHandler(service.NewMemoryService(), cfg, newMemoryRevocations(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	user, err := h.service.Create(context.Background(), &models.User{Username: "alice", UsernameNormalized: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if status, response := callJSON(t, h.SetupTwoFactor, user.ID, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("SetupTwoFactor при выключенной 2FA: статус %d: %v", status, response)
+	}
+}
+
+// TestTwoFactorRequiresRevocationStore проверяет, что без хранилища отозванных токенов
+// промежуточный токен не выдается и не принимается: иначе его можно было бы предъявить повторно
+func TestTwoFactorRequiresRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t, nil)
+	h.lockouts = openLockout{}
+
+	// Сервис сам хеширует пароль при создании пользователя
+	user, err := h.service.Create(ctx, &models.User{Username: "alice", UsernameNormalized: "alice", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := h.service.SetupTOTP(ctx, user.ID, "JBSWY3DPEHPK3PXP", nil); err != nil {
+		t.Fatalf("SetupTOTP: %v", err)
+	}
+	if err := h.service.EnableTOTP(ctx, user.ID, 0); err != nil {
+		t.Fatalf("EnableTOTP: %v", err)
+	}
+
+	status, response := callJSON(t, h.LoginUser, 0, gin.H{"username": "alice", "password": "Secret-password-1"})
+	if status != http.StatusServiceUnavailable || response["mfa_token"] != nil {
+		t.Errorf("LoginUser без хранилища: статус %d: %v", status, response)
+	}
+
+	token, err := h.jwtManager.GenerateMFAPendingToken(user.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAPendingToken: %v", err)
+	}
+	if status, response := callJSON(t, h.VerifyTwoFactor, 0, gin.H{"mfa_token": token, "code": "000000"}); status != http.StatusServiceUnavailable {
+		t.Errorf("VerifyTwoFactor без хранилища: статус %d: %v", status, response)
+	}
+}
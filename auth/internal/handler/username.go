@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"auth/internal/errors"
+	"context"
+	stderrors "errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangeUsername обрабатывает запрос на смену имени пользователя
+// Имя проверяется по тем же правилам, что и при регистрации, и должно быть свободно
+// без учета регистра. Повторно сменить имя можно только по истечении USERNAME_CHANGE_COOLDOWN
+// PUT /auth/user/username
+func (h *Handler) ChangeUsername(c *gin.Context) {
+	// Получаем ID пользователя из токена
+	userID, err := h.GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(401, gin.H{
+			"error": errors.MsgAuthRequired,
+		})
+		return
+	}
+
+	var renameRequest struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&renameRequest); err != nil {
+		c.JSON(400, gin.H{
+			"error":   errors.MsgInvalidData,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name, violations := h.usernames.Validate(renameRequest.Username)
+	if len(violations) > 0 {
+		h.invalidUsername(c, violations)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	cooldown := time.Duration(h.cfg.UsernameChangeCooldown) * time.Hour
+	user, err := h.service.RenameUser(ctx, userID, name, cooldown)
+	switch {
+	case stderrors.Is(err, errors.ErrUsernameCooldown):
+		retryAt := user.UsernameChangedAt.Add(cooldown)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(retryAt).Seconds()))))
+		c.JSON(429, gin.H{
+			"error":    errors.MsgUsernameCooldown,
+			"retry_at": retryAt,
+		})
+		return
+	case stderrors.Is(err, gorm.ErrDuplicatedKey):
+		c.JSON(409, gin.H{
+			"error": errors.MsgUsernameTaken,
+		})
+		return
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{
+			"error": errors.MsgUserNotFound,
+		})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{
+			"error":   errors.MsgDatabaseOperation,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Убираем пароль из ответа
+	user.Password = ""
+
+	c.JSON(200, gin.H{
+		"message": errors.MsgUsernameChanged,
+		"user":    user,
+	})
+}
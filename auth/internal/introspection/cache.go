@@ -0,0 +1,106 @@
+package introspection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	jwtmanager "jwt_manager"
+)
+
+// Cache - локальный кеш результатов разбора токенов для эндпоинта интроспекции
+// Хранит утверждения токенов с проверенной подписью, чтобы не проверять подпись
+// при каждом запросе. Неверные токены тоже кешируются (без утверждений).
+// Статус отзыва в кеше не хранится и должен проверяться при каждом запросе.
+type Cache struct {
+	mu         sync.Mutex
+	entries    map[string]entry
+	ttl        time.Duration
+	maxEntries int
+}
+
+// entry - запись кеша
+type entry struct {
+	claims    *jwtmanager.Claims // nil для неверного токена
+	expiresAt time.Time
+}
+
+// NewCache создает кеш с временем жизни записей ttl и не более чем maxEntries записями
+// При ttl <= 0 кеш отключен
+func NewCache(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		entries:    make(map[string]entry),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// Get возвращает утверждения токена из кеша
+// found = false, если токена нет в кеше; claims = nil для токена, признанного неверным
+func (c *Cache) Get(token string) (claims *jwtmanager.Claims, found bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	key := cacheKey(token)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.claims, true
+}
+
+// Set сохраняет результат разбора токена
+// Запись с утверждениями живет не дольше, чем сам токен
+func (c *Cache) Set(token string, claims *jwtmanager.Claims) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(c.ttl)
+	if claims != nil {
+		if tokenExpires := claims.ExpiresTime(); !tokenExpires.IsZero() && tokenExpires.Before(expiresAt) {
+			expiresAt = tokenExpires
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[cacheKey(token)] = entry{claims: claims, expiresAt: expiresAt}
+}
+
+// evict удаляет истекшие записи, а если их нет - произвольную часть записей
+// Вызывается с захваченной блокировкой
+func (c *Cache) evict(now time.Time) {
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	// Порядок обхода map случаен, поэтому удаляется случайная четверть записей
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries*3/4 {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
+
+// cacheKey вычисляет ключ кеша, чтобы не хранить сами токены в памяти
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package introspection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	jwtmanager "jwt_manager"
+)
+
+// TestCacheTTL проверяет, что запись живет не дольше ttl и не дольше самого токена
+func TestCacheTTL(t *testing.T) {
+	cache := NewCache(50*time.Millisecond, 10)
+
+	claims := &jwtmanager.Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}}
+	cache.Set("token", claims)
+	if got, found := cache.Get("token"); !found || got != claims {
+		t.Fatalf("Get сразу после Set = %v, %v", got, found)
+	}
+
+	// Неверный токен тоже кешируется
+	cache.Set("invalid", nil)
+	if got, found := cache.Get("invalid"); !found || got != nil {
+		t.Fatalf("Get(invalid) = %v, %v, ожидалась запись без утверждений", got, found)
+	}
+
+	// Токен, срок которого истекает раньше ttl, не живет в кеше дольше своего срока
+	short := &jwtmanager.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "short",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Second)),
+	}}
+	cache.Set("short", short)
+	if _, found := cache.Get("short"); found {
+		t.Error("истекший токен найден в кеше")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, found := cache.Get("token"); found {
+		t.Error("запись найдена в кеше после истечения ttl")
+	}
+	if _, found := cache.Get("invalid"); found {
+		t.Error("запись неверного токена найдена в кеше после истечения ttl")
+	}
+}
+
+// TestCacheDisabled проверяет, что при ttl <= 0 или maxEntries <= 0 кеш ничего не хранит
+func TestCacheDisabled(t *testing.T) {
+	for _, cache := range []*Cache{NewCache(0, 10), NewCache(time.Minute, 0)} {
+		cache.Set("token", &jwtmanager.Claims{})
+		if _, found := cache.Get("token"); found {
+			t.Errorf("отключенный кеш (ttl %v, maxEntries %d) вернул запись", cache.ttl, cache.maxEntries)
+		}
+	}
+}
+
+// TestCacheEviction проверяет, что размер кеша не превышает maxEntries
+// и что в первую очередь вытесняются истекшие записи
+func TestCacheEviction(t *testing.T) {
+	cache := NewCache(time.Minute, 8)
+
+	expired := &jwtmanager.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Millisecond)),
+	}}
+	cache.Set("expired", expired)
+	for i := 0; i < 6; i++ {
+		cache.Set(string(rune('a'+i)), nil)
+	}
+	cache.Set("fresh", &jwtmanager.Claims{})
+	time.Sleep(20 * time.Millisecond)
+
+	// Кеш заполнен: вставка вытесняет истекшую запись и часть остальных
+	cache.Set("new", &jwtmanager.Claims{})
+	if _, ok := cache.entries[cacheKey("expired")]; ok {
+		t.Error("истекшая запись не вытеснена")
+	}
+	if len(cache.entries) > 8 {
+		t.Errorf("в кеше %d записей, лимит 8", len(cache.entries))
+	}
+	if _, found := cache.Get("new"); !found {
+		t.Error("новая запись не сохранена")
+	}
+
+	for i := 0; i < 100; i++ {
+		cache.Set(string(rune('A'+i)), nil)
+		if len(cache.entries) > 8 {
+			t.Fatalf("в кеше %d записей после %d вставок, лимит 8", len(cache.entries), i+1)
+		}
+	}
+}
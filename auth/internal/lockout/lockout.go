@@ -0,0 +1,148 @@
+package lockout
+
+import (
+	"auth/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Lockout - защита входа от подбора пароля
+// Считает неудачные попытки входа по имени пользователя и по IP клиента
+// и временно блокирует вход с экспоненциально растущей длительностью
+type Lockout interface {
+	// Check возвращает время до снятия блокировки или 0, если вход разрешен
+	Check(ctx context.Context, username, ip string) (time.Duration, error)
+	// RegisterFailure учитывает неудачную попытку входа
+	// Возвращает длительность блокировки, если попытка к ней привела, иначе 0
+	RegisterFailure(ctx context.Context, username, ip string) (time.Duration, error)
+	// RegisterSuccess сбрасывает счетчик неудачных попыток пользователя после успешного входа
+	RegisterSuccess(ctx context.Context, username, ip string) error
+}
+
+// Options - параметры блокировки
+type Options struct {
+	MaxUserAttempts int           // Число неудачных попыток для имени пользователя до блокировки
+	MaxIPAttempts   int           // Число неудачных попыток с одного IP до блокировки
+	BaseLockout     time.Duration // Длительность первой блокировки, каждая следующая вдвое длиннее
+	MaxLockout      time.Duration // Максимальная длительность блокировки
+	Window          time.Duration // Время, через которое счетчик сбрасывается, если неудачных попыток не было
+	KeyPrefix       string        // Префикс ключей Redis, чтобы разные ограничения не делили счетчики; по умолчанию DefaultKeyPrefix
+}
+
+// DefaultKeyPrefix - префикс ключей Redis для защиты входа
+const DefaultKeyPrefix = "auth:login:"
+
+// Части ключей Redis после префикса
+const (
+	failuresKeyPrefix = "failures:" // Счетчики неудачных попыток
+	lockKeyPrefix     = "lock:"     // Активные блокировки
+)
+
+// RedisLockout - реализация Lockout на основе Redis
+// Счетчики хранятся в Redis, поэтому общие для всех реплик сервиса auth
+type RedisLockout struct {
+	client *redis.Client // Клиент Redis
+	opts   Options       // Параметры блокировки
+}
+
+// Проверка, что RedisLockout реализует интерфейс Lockout
+var _ Lockout = (*RedisLockout)(nil)
+
+// NewRedisLockout создает защиту входа на основе клиента Redis
+func NewRedisLockout(client *redis.Client, opts Options) *RedisLockout {
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = DefaultKeyPrefix
+	}
+	return &RedisLockout{
+		client: client,
+		opts:   opts,
+	}
+}
+
+// Check проверяет блокировки имени пользователя и IP за один запрос к Redis
+// Если заблокированы оба, возвращает большее время ожидания
+func (l *RedisLockout) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	pipe := l.client.WithContext(ctx).Pipeline()
+	userTTL := pipe.PTTL(l.userKey(lockKeyPrefix, username))
+	ipTTL := pipe.PTTL(l.ipKey(lockKeyPrefix, ip))
+	if _, err := pipe.Exec(); err != nil {
+		return 0, fmt.Errorf("не удалось проверить блокировку входа: %w", err)
+	}
+
+	// Отрицательное значение означает, что ключа нет
+	return max(userTTL.Val(), ipTTL.Val(), 0), nil
+}
+
+// RegisterFailure увеличивает счетчики неудачных попыток имени пользователя и IP
+// и устанавливает блокировку, если счетчик достиг порога
+func (l *RedisLockout) RegisterFailure(ctx context.Context, username, ip string) (time.Duration, error) {
+	client := l.client.WithContext(ctx)
+
+	pipe := client.TxPipeline()
+	userFailures := pipe.Incr(l.userKey(failuresKeyPrefix, username))
+	pipe.Expire(l.userKey(failuresKeyPrefix, username), l.opts.Window)
+	ipFailures := pipe.Incr(l.ipKey(failuresKeyPrefix, ip))
+	pipe.Expire(l.ipKey(failuresKeyPrefix, ip), l.opts.Window)
+	if _, err := pipe.Exec(); err != nil {
+		return 0, fmt.Errorf("не удалось учесть неудачную попытку входа: %w", err)
+	}
+
+	userLock := l.opts.lockDuration(userFailures.Val(), l.opts.MaxUserAttempts)
+	ipLock := l.opts.lockDuration(ipFailures.Val(), l.opts.MaxIPAttempts)
+
+	pipe = client.Pipeline()
+	if userLock > 0 {
+		pipe.Set(l.userKey(lockKeyPrefix, username), 1, userLock)
+	}
+	if ipLock > 0 {
+		pipe.Set(l.ipKey(lockKeyPrefix, ip), 1, ipLock)
+	}
+	if userLock > 0 || ipLock > 0 {
+		if _, err := pipe.Exec(); err != nil {
+			return 0, fmt.Errorf("не удалось установить блокировку входа: %w", err)
+		}
+	}
+
+	return max(userLock, ipLock), nil
+}
+
+// RegisterSuccess сбрасывает счетчик и блокировку имени пользователя
+// Счетчик IP не сбрасывается, иначе вход в собственную учетную запись
+// позволял бы продолжать подбор паролей к чужим
+func (l *RedisLockout) RegisterSuccess(ctx context.Context, username, ip string) error {
+	err := l.client.WithContext(ctx).Del(
+		l.userKey(failuresKeyPrefix, username),
+		l.userKey(lockKeyPrefix, username),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("не удалось сбросить счетчик неудачных попыток входа: %w", err)
+	}
+	return nil
+}
+
+// lockDuration вычисляет длительность блокировки по числу неудачных попыток
+// Первая блокировка длится BaseLockout, каждая следующая попытка удваивает ее до MaxLockout
+func (o Options) lockDuration(failures int64, maxAttempts int) time.Duration {
+	if maxAttempts <= 0 || failures < int64(maxAttempts) {
+		return 0
+	}
+	duration := o.BaseLockout
+	for i := int64(maxAttempts); i < failures && duration < o.MaxLockout; i++ {
+		duration *= 2
+	}
+	return min(duration, o.MaxLockout)
+}
+
+// userKey формирует ключ Redis для имени пользователя
+// Имя нормализуется, чтобы попытки с тем же именем в другом регистре учитывались вместе
+func (l *RedisLockout) userKey(prefix, username string) string {
+	return l.opts.KeyPrefix + prefix + "user:" + models.NormalizeUsername(username)
+}
+
+// ipKey формирует ключ Redis для IP клиента
+func (l *RedisLockout) ipKey(prefix, ip string) string {
+	return l.opts.KeyPrefix + prefix + "ip:" + ip
+}
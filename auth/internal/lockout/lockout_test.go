@@ -0,0 +1,208 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// newTestLockout создает защиту входа поверх Redis в памяти
+func newTestLockout(t *testing.T, opts Options) (*RedisLockout, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisLockout(client, opts), server
+}
+
+// TestLockoutThreshold проверяет, что вход блокируется только после MaxUserAttempts неудачных попыток
+func TestLockoutThreshold(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLockout(t, Options{
+		MaxUserAttempts: 3,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+	})
+
+	for i := 1; i < 3; i++ {
+		lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("RegisterFailure #%d: %v", i, err)
+		}
+		if lock != 0 {
+			t.Fatalf("RegisterFailure #%d: блокировка %v до достижения порога", i, lock)
+		}
+		if retry, err := l.Check(ctx, "alice", "10.0.0.1"); err != nil || retry != 0 {
+			t.Fatalf("Check после %d попыток = %v, %v", i, retry, err)
+		}
+	}
+
+	lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	if lock != time.Minute {
+		t.Fatalf("первая блокировка = %v, ожидалось %v", lock, time.Minute)
+	}
+	retry, err := l.Check(ctx, "alice", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if retry <= 0 || retry > time.Minute {
+		t.Errorf("Check = %v, ожидалось время до снятия блокировки", retry)
+	}
+
+	// Имя в другом регистре заблокировано вместе с исходным
+	if retry, err := l.Check(ctx, "ALICE", "10.0.0.2"); err != nil || retry <= 0 {
+		t.Errorf("Check(ALICE) = %v, %v, ожидалась блокировка", retry, err)
+	}
+	// Другие пользователи с другого IP не заблокированы
+	if retry, err := l.Check(ctx, "bob", "10.0.0.2"); err != nil || retry != 0 {
+		t.Errorf("Check(bob) = %v, %v, ожидалось отсутствие блокировки", retry, err)
+	}
+}
+
+// TestLockoutBackoff проверяет удвоение длительности блокировки до MaxLockout
+func TestLockoutBackoff(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLockout(t, Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Minute,
+		MaxLockout:      5 * time.Minute,
+		Window:          time.Hour,
+	})
+
+	want := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, expected := range want {
+		lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("RegisterFailure #%d: %v", i+1, err)
+		}
+		if lock != expected {
+			t.Errorf("RegisterFailure #%d = %v, ожидалось %v", i+1, lock, expected)
+		}
+	}
+}
+
+// TestLockDuration проверяет расчет длительности блокировки по числу попыток
+func TestLockDuration(t *testing.T) {
+	opts := Options{BaseLockout: time.Second, MaxLockout: 10 * time.Second}
+	tests := []struct {
+		failures    int64
+		maxAttempts int
+		want        time.Duration
+	}{
+		{failures: 4, maxAttempts: 5, want: 0},
+		{failures: 5, maxAttempts: 5, want: time.Second},
+		{failures: 6, maxAttempts: 5, want: 2 * time.Second},
+		{failures: 8, maxAttempts: 5, want: 8 * time.Second},
+		{failures: 9, maxAttempts: 5, want: 10 * time.Second},
+		{failures: 1000, maxAttempts: 5, want: 10 * time.Second},
+		// Порог 0 отключает блокировку
+		{failures: 1000, maxAttempts: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := opts.lockDuration(tt.failures, tt.maxAttempts); got != tt.want {
+			t.Errorf("lockDuration(%d, %d) = %v, ожидалось %v", tt.failures, tt.maxAttempts, got, tt.want)
+		}
+	}
+}
+
+// TestLockoutSuccessKeepsIPCounter проверяет, что успешный вход сбрасывает счетчик пользователя,
+// но не счетчик IP, иначе вход в свою учетную запись позволял бы подбирать чужие пароли
+func TestLockoutSuccessKeepsIPCounter(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLockout(t, Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   3,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+	})
+
+	if _, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	if _, err := l.RegisterFailure(ctx, "bob", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	if err := l.RegisterSuccess(ctx, "mallory", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterSuccess: %v", err)
+	}
+
+	// Третья неудачная попытка с того же IP блокирует IP несмотря на успешный вход
+	lock, err := l.RegisterFailure(ctx, "carol", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	if lock != time.Minute {
+		t.Fatalf("блокировка IP = %v, ожидалось %v", lock, time.Minute)
+	}
+	if retry, err := l.Check(ctx, "dave", "10.0.0.1"); err != nil || retry <= 0 {
+		t.Errorf("Check(dave) = %v, %v, ожидалась блокировка IP", retry, err)
+	}
+
+	// Счетчик пользователя сбрасывается успешным входом
+	if _, err := l.RegisterFailure(ctx, "erin", "10.0.0.2"); err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	if err := l.RegisterSuccess(ctx, "erin", "10.0.0.2"); err != nil {
+		t.Fatalf("RegisterSuccess: %v", err)
+	}
+	if lock, err := l.RegisterFailure(ctx, "erin", "10.0.0.3"); err != nil || lock != 0 {
+		t.Errorf("RegisterFailure после успешного входа = %v, %v, ожидалось отсутствие блокировки", lock, err)
+	}
+}
+
+// TestLockoutWindow проверяет, что счетчик сбрасывается, если неудачных попыток не было дольше Window
+func TestLockoutWindow(t *testing.T) {
+	ctx := context.Background()
+	l, server := newTestLockout(t, Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          time.Minute,
+	})
+
+	if _, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	server.FastForward(2 * time.Minute)
+	if lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil || lock != 0 {
+		t.Errorf("RegisterFailure после окна = %v, %v, ожидалось отсутствие блокировки", lock, err)
+	}
+}
+
+// TestLockoutKeyPrefix проверяет, что ограничения с разными префиксами не делят счетчики
+func TestLockoutKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{
+		MaxUserAttempts: 1,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+	}
+	login, server := newTestLockout(t, opts)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	opts.KeyPrefix = "auth:email:"
+	email := NewRedisLockout(client, opts)
+
+	if lock, err := email.RegisterFailure(ctx, "alice@example.com", "10.0.0.1"); err != nil || lock != time.Minute {
+		t.Fatalf("RegisterFailure = %v, %v, ожидалась блокировка", lock, err)
+	}
+	if !server.Exists("auth:email:lock:user:alice@example.com") {
+		t.Errorf("блокировка не записана под префиксом auth:email:")
+	}
+	if retry, err := login.Check(ctx, "alice@example.com", "10.0.0.1"); err != nil || retry != 0 {
+		t.Errorf("Check с префиксом по умолчанию = %v, %v, ожидалось отсутствие блокировки", retry, err)
+	}
+}
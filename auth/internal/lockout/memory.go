@@ -0,0 +1,125 @@
+package lockout
+
+import (
+	"auth/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCounter - счетчик неудачных попыток со сроком хранения
+type memoryCounter struct {
+	failures  int64     // Число неудачных попыток
+	expiresAt time.Time // Время сброса счетчика
+}
+
+// MemoryLockout - реализация Lockout в памяти процесса
+// Используется, когда сервис работает без Redis; счетчики не общие для реплик и теряются при перезапуске
+type MemoryLockout struct {
+	mu       sync.Mutex
+	opts     Options
+	counters map[string]memoryCounter // Счетчики неудачных попыток по ключу
+	locks    map[string]time.Time     // Время снятия блокировки по ключу
+	pruned   time.Time                // Время последней очистки истекших записей
+}
+
+// Проверка, что MemoryLockout реализует интерфейс Lockout
+var _ Lockout = (*MemoryLockout)(nil)
+
+// NewMemoryLockout создает защиту входа в памяти процесса
+func NewMemoryLockout(opts Options) *MemoryLockout {
+	return &MemoryLockout{
+		opts:     opts,
+		counters: make(map[string]memoryCounter),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+// Check возвращает большее из времен до снятия блокировок имени пользователя и IP
+func (l *MemoryLockout) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	return max(l.lockedFor(memoryUserKey(username), now), l.lockedFor(memoryIPKey(ip), now)), nil
+}
+
+// RegisterFailure увеличивает счетчики имени пользователя и IP
+// и устанавливает блокировку, если счетчик достиг порога
+func (l *MemoryLockout) RegisterFailure(ctx context.Context, username, ip string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+	userLock := l.opts.lockDuration(l.increment(memoryUserKey(username), now), l.opts.MaxUserAttempts)
+	ipLock := l.opts.lockDuration(l.increment(memoryIPKey(ip), now), l.opts.MaxIPAttempts)
+	if userLock > 0 {
+		l.locks[memoryUserKey(username)] = now.Add(userLock)
+	}
+	if ipLock > 0 {
+		l.locks[memoryIPKey(ip)] = now.Add(ipLock)
+	}
+
+	return max(userLock, ipLock), nil
+}
+
+// RegisterSuccess сбрасывает счетчик и блокировку имени пользователя, счетчик IP сохраняется
+func (l *MemoryLockout) RegisterSuccess(ctx context.Context, username, ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.counters, memoryUserKey(username))
+	delete(l.locks, memoryUserKey(username))
+	return nil
+}
+
+// increment увеличивает счетчик key и продлевает его на Window, как INCR и EXPIRE в Redis
+func (l *MemoryLockout) increment(key string, now time.Time) int64 {
+	counter := l.counters[key]
+	if !now.Before(counter.expiresAt) {
+		counter.failures = 0
+	}
+	counter.failures++
+	counter.expiresAt = now.Add(l.opts.Window)
+	l.counters[key] = counter
+	return counter.failures
+}
+
+// lockedFor возвращает время до снятия блокировки key или 0, если ее нет
+func (l *MemoryLockout) lockedFor(key string, now time.Time) time.Duration {
+	until, ok := l.locks[key]
+	if !ok || !now.Before(until) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// prune удаляет истекшие счетчики и блокировки, чтобы память не росла с числом имен и адресов
+// Очистка выполняется не чаще раза в минуту, чтобы поток неудачных попыток не перебирал все записи
+func (l *MemoryLockout) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+	for key, counter := range l.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(l.counters, key)
+		}
+	}
+	for key, until := range l.locks {
+		if !now.Before(until) {
+			delete(l.locks, key)
+		}
+	}
+}
+
+// memoryUserKey формирует ключ счетчика для нормализованного имени пользователя
+func memoryUserKey(username string) string {
+	return "user:" + models.NormalizeUsername(username)
+}
+
+// memoryIPKey формирует ключ счетчика для IP клиента
+func memoryIPKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,66 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryLockout проверяет порог блокировки, сброс счетчика пользователя успешным входом
+// и окно хранения счетчика в реализации без Redis
+func TestMemoryLockout(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   3,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          time.Hour,
+	})
+
+	if lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil || lock != 0 {
+		t.Fatalf("первая попытка = %v, %v, ожидалось отсутствие блокировки", lock, err)
+	}
+	if lock, err := l.RegisterFailure(ctx, "Alice", "10.0.0.2"); err != nil || lock != time.Minute {
+		t.Fatalf("вторая попытка = %v, %v, ожидалась блокировка на %v", lock, err, time.Minute)
+	}
+	if retry, err := l.Check(ctx, "ALICE", "10.0.0.3"); err != nil || retry <= 0 || retry > time.Minute {
+		t.Errorf("Check = %v, %v, ожидалась блокировка имени", retry, err)
+	}
+
+	// Успешный вход сбрасывает блокировку имени, но не счетчик IP
+	if err := l.RegisterSuccess(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterSuccess: %v", err)
+	}
+	if retry, err := l.Check(ctx, "alice", "10.0.0.3"); err != nil || retry != 0 {
+		t.Errorf("Check после успешного входа = %v, %v, ожидалось отсутствие блокировки", retry, err)
+	}
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := l.RegisterFailure(ctx, name, "10.0.0.1"); err != nil {
+			t.Fatalf("RegisterFailure(%s): %v", name, err)
+		}
+	}
+	if retry, err := l.Check(ctx, "dave", "10.0.0.1"); err != nil || retry <= 0 {
+		t.Errorf("Check(dave) = %v, %v, ожидалась блокировка IP", retry, err)
+	}
+}
+
+// TestMemoryLockoutWindow проверяет, что счетчик сбрасывается, если неудачных попыток не было дольше Window
+func TestMemoryLockoutWindow(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(Options{
+		MaxUserAttempts: 2,
+		MaxIPAttempts:   100,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+		Window:          20 * time.Millisecond,
+	})
+
+	if _, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("RegisterFailure: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if lock, err := l.RegisterFailure(ctx, "alice", "10.0.0.1"); err != nil || lock != 0 {
+		t.Errorf("RegisterFailure после окна = %v, %v, ожидалось отсутствие блокировки", lock, err)
+	}
+}
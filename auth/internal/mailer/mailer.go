@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message - письмо
+type Message struct {
+	To      string // Адрес получателя
+	Subject string // Тема
+	Body    string // Текст письма
+}
+
+// Mailer - интерфейс отправки писем
+// Позволяет использовать настоящий SMTP сервер в production и запись писем в файл при разработке
+type Mailer interface {
+	// Send отправляет письмо
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer - реализация Mailer через SMTP сервер
+type SMTPMailer struct {
+	host string    // Хост SMTP сервера, проверяется в сертификате при STARTTLS
+	addr string    // Адрес SMTP сервера host:port
+	auth smtp.Auth // Аутентификация на сервере (nil, если не требуется)
+	from string    // Адрес отправителя
+}
+
+// Проверка, что SMTPMailer реализует интерфейс Mailer
+var _ Mailer = (*SMTPMailer)(nil)
+
+// NewSMTPMailer создает отправителя писем через SMTP сервер
+// Если username пустой, письма отправляются без аутентификации
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		host: host,
+		addr: host + ":" + port,
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send отправляет письмо через SMTP сервер
+// Соединение ограничено сроком ctx и закрывается при его отмене, поэтому зависший сервер
+// не задерживает отправку дольше таймаута
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := m.send(ctx, msg); err != nil {
+		// Ошибка соединения после отмены ctx вызвана самой отменой
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return fmt.Errorf("не удалось отправить письмо на %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// send выполняет диалог с SMTP сервером так же, как smtp.SendMail, но в рамках ctx
+func (m *SMTPMailer) send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	// Отмена ctx прерывает операцию, заблокированную на соединении
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP сервер не поддерживает аутентификацию")
+		}
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.format(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// format формирует письмо в формате RFC 5322
+func (m *SMTPMailer) format(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogMailer - реализация Mailer, которая записывает письма в файл или стандартный вывод
+// Используется при разработке, чтобы проверять письма без SMTP сервера
+type LogMailer struct {
+	path string     // Файл для записи писем; если пустой, письма выводятся в stdout
+	mu   sync.Mutex // Защищает файл от одновременной записи
+}
+
+// Проверка, что LogMailer реализует интерфейс Mailer
+var _ Mailer = (*LogMailer)(nil)
+
+// NewLogMailer создает отправителя, записывающего письма в файл path
+// Если path пустой, письма выводятся в stdout
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{
+		path: path,
+	}
+}
+
+// Send записывает письмо
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	entry := fmt.Sprintf("=== %s ===\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+
+	if m.path == "" {
+		fmt.Print(entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл писем %s: %w", m.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(entry); err != nil {
+		return fmt.Errorf("не удалось записать письмо в %s: %w", m.path, err)
+	}
+	return nil
+}
+
+// mimeHeader кодирует заголовок для передачи не-ASCII символов (RFC 2047)
+func mimeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
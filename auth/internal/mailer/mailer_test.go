@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer принимает одно письмо по минимальному диалогу SMTP и передает его текст в канал
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- string(data)
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+// TestSMTPMailerSend проверяет отправку письма через SMTP сервер
+func TestSMTPMailerSend(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	mailer := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, Message{To: "alice@example.com", Subject: "Сброс пароля", Body: "Ссылка"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "To: alice@example.com") || !strings.Contains(data, "Ссылка") {
+			t.Errorf("неожиданное письмо:\n%s", data)
+		}
+	default:
+		t.Error("письмо не получено сервером")
+	}
+}
+
+// TestSMTPMailerStalledServer проверяет, что зависший сервер не задерживает отправку дольше срока ctx
+func TestSMTPMailerStalledServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	// Сервер принимает соединение, но не отвечает
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	mailer := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = mailer.Send(ctx, Message{To: "alice@example.com", Subject: "Тема", Body: "Текст"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send = %v, ожидалась context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send завершился через %v", elapsed)
+	}
+
+	// Отмена ctx прерывает отправку до истечения срока
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	if err := mailer.Send(ctx, Message{To: "alice@example.com"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send после отмены = %v, ожидалась context.Canceled", err)
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient представляет приложение, зарегистрированное для получения токенов по OAuth2
+// Конфиденциальный клиент аутентифицируется секретом, в базе данных хранится только его хеш
+// Публичный клиент (SPA, мобильное приложение) секрета не имеет и обязан использовать PKCE
+type OAuthClient struct {
+	ID           string    `json:"client_id" gorm:"primaryKey;size:64"`
+	OwnerID      int       `json:"-" gorm:"index;not null"` // Пользователь, зарегистрировавший клиента
+	Name         string    `json:"name" gorm:"not null"`
+	SecretHash   string    `json:"-"`                                    // Хеш секрета, пустой для публичного клиента
+	Public       bool      `json:"public" gorm:"not null;default:false"` // Клиент без секрета
+	RedirectURIs string    `json:"-" gorm:"not null;default:''"`         // Разрешенные адреса возврата через пробел
+	GrantTypes   string    `json:"-" gorm:"not null;default:''"`         // Разрешенные типы грантов через пробел
+	Scope        string    `json:"scope" gorm:"not null;default:''"`     // Области доступа, которые может запросить клиент
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RedirectURIList возвращает разрешенные адреса возврата в виде списка
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// GrantTypeList возвращает разрешенные типы грантов в виде списка
+func (c *OAuthClient) GrantTypeList() []string {
+	return strings.Fields(c.GrantTypes)
+}
+
+// OAuthAuthorizationCode представляет одноразовый код авторизации,
+// который клиент обменивает на токены в /oauth/token
+// В базе данных хранится только SHA-256 хеш кода
+type OAuthAuthorizationCode struct {
+	CodeHash            string     `gorm:"primaryKey;size:64"`
+	ClientID            string     `gorm:"index;not null;size:64"`
+	UserID              int        `gorm:"index;not null"`
+	RedirectURI         string     `gorm:"not null"`
+	Scope               string     `gorm:"not null;default:''"`
+	CodeChallenge       string     // Пусто, если клиент не использовал PKCE
+	CodeChallengeMethod string     // S256
+	SessionID           string     `gorm:"size:64"` // Сессия, начатая при обмене кода
+	ExpiresAt           time.Time  `gorm:"not null"`
+	UsedAt              *time.Time // Время обмена, nil для неиспользованного кода
+	CreatedAt           time.Time
+}
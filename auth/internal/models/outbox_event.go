@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	jwtmanager "jwt_manager"
+)
+
+// Типы доменных событий пользователя
+const (
+	EVENT_USER_CREATED = "user.created"
+	EVENT_USER_UPDATED = "user.updated"
+	EVENT_USER_DELETED = "user.deleted"
+)
+
+// OutboxEvent представляет доменное событие, записанное в таблицу outbox
+// Событие записывается в той же транзакции, что и изменение данных,
+// а публикуется в брокер отдельно, поэтому событие не теряется при сбое публикации
+type OutboxEvent struct {
+	ID          uint       `gorm:"primaryKey"`                   // Порядковый номер, задает порядок публикации
+	EventID     string     `gorm:"uniqueIndex;size:64;not null"` // Уникальный ID события для идемпотентной обработки
+	Type        string     `gorm:"not null"`                     // Тип события, например user.deleted
+	AggregateID int        `gorm:"not null"`                     // ID пользователя, к которому относится событие
+	Payload     string     `gorm:"type:text;not null"`           // Данные события в JSON
+	CreatedAt   time.Time  `gorm:"not null"`                     // Время возникновения события
+	PublishedAt *time.Time `gorm:"index"`                        // Время публикации, nil для неопубликованного события
+}
+
+// UserEventPayload - данные событий пользователя
+type UserEventPayload struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username,omitempty"`
+}
+
+// NewUserEvent создает событие пользователя со случайным ID
+func NewUserEvent(eventType string, userID int, username string) (*OutboxEvent, error) {
+	eventID, err := jwtmanager.GenerateTokenID()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(UserEventPayload{UserID: userID, Username: username})
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		EventID:     eventID,
+		Type:        eventType,
+		AggregateID: userID,
+		Payload:     string(payload),
+		CreatedAt:   time.Now(),
+	}, nil
+}
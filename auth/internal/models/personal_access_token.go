@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PersonalAccessToken представляет персональный токен доступа для скриптов и CI
+// Токен показывается пользователю один раз, в базе данных хранится только его SHA-256 хеш
+type PersonalAccessToken struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	UserID     int        `json:"-" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null"`
+	Scope      string     `json:"scope" gorm:"not null"` // Области доступа через пробел
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`            // nil для бессрочного токена
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" gorm:"-"` // Берется из общего хранилища токенов
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
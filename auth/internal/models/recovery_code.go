@@ -0,0 +1,27 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// RecoveryCode представляет одноразовый код восстановления доступа
+// Используется вместо кода TOTP, если у пользователя нет доступа к приложению-аутентификатору
+// В базе данных хранится только SHA-256 хеш кода
+type RecoveryCode struct {
+	ID        int        `json:"-" gorm:"primaryKey"`
+	UserID    int        `json:"-" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+// HashRecoveryCode вычисляет хеш кода восстановления для хранения и поиска
+// Код нормализуется: регистр и дефисы не учитываются
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken представляет выпущенный refresh токен, хранящийся на стороне сервера
+// ID совпадает с утверждением jti токена
+// Все токены, полученные цепочкой обновлений от одного логина, образуют семейство (FamilyID)
+// При повторном использовании уже замененного токена отзывается все семейство
+type RefreshToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:64"`
+	UserID     int        `json:"user_id" gorm:"index;not null"`
+	FamilyID   string     `json:"family_id" gorm:"index;size:64;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy string     `json:"replaced_by,omitempty" gorm:"size:64"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
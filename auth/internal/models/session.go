@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session представляет активный вход пользователя с конкретного устройства
+// ID сессии совпадает с FamilyID ее refresh токенов и записывается в утверждение sid токенов
+// Сессия продлевается при каждом обновлении токенов и завершается при отзыве семейства
+type Session struct {
+	ID              string     `json:"id" gorm:"primaryKey;size:64"`
+	UserID          int        `json:"-" gorm:"index;not null"`
+	UserAgent       string     `json:"user_agent"`
+	IP              string     `json:"ip" gorm:"size:64"`
+	ClientID        string     `json:"client_id,omitempty" gorm:"index;size:64"` // OAuth клиент, если сессия начата через /oauth/token
+	CreatedAt       time.Time  `json:"created_at"`
+	LastRefreshedAt time.Time  `json:"last_refreshed_at"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt       *time.Time `json:"-"`
+	Current         bool       `json:"current" gorm:"-"` // Сессия, из которой выполнен запрос
+}
@@ -1,6 +1,16 @@
 package models
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"strings"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
 
 // User представляет модель пользователя в системе
 // Он содержит ID, имя пользователя и пароль
@@ -9,7 +19,68 @@ import "golang.org/x/crypto/bcrypt"
 type User struct {
 	ID       int    `json:"id" gorm:"primaryKey"`
 	Username string `json:"username" gorm:"unique;not null"`
-	Password string `json:"password,omitempty" gorm:"not null"`
+	// Имя в нормализованной форме (NFKC и свертка регистра), по нему ищутся пользователи
+	// и проверяется уникальность, поэтому "Alice" и "alice" - одно и то же имя
+	UsernameNormalized string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsernameChangedAt  *time.Time `json:"username_changed_at,omitempty"` // Время последней смены имени; nil, если имя не менялось
+	Password           string     `json:"password,omitempty" gorm:"not null"`
+	// Адрес электронной почты для восстановления доступа; nil, если не указан
+	Email         *string `json:"email,omitempty" gorm:"uniqueIndex"`
+	EmailVerified bool    `json:"email_verified" gorm:"not null;default:false"` // Адрес подтвержден переходом по ссылке из письма
+	Role          string  `json:"role" gorm:"not null;default:user"`            // Роль пользователя, попадает в токены
+	Scopes        string  `json:"scopes" gorm:"not null;default:''"`            // Области доступа через пробел; если пусто, используются DefaultScopes
+	// Отключенный пользователь не может войти в систему и обновить токены
+	Disabled bool `json:"disabled" gorm:"not null;default:false"`
+	// Пользователь должен сменить пароль, выданный администратором, прежде чем получит доступ к заметкам
+	PasswordResetRequired bool `json:"password_reset_required" gorm:"not null;default:false"`
+	// Двухфакторная аутентификация (TOTP)
+	TOTPSecret   string `json:"-" gorm:"column:totp_secret;not null;default:''"`                // Секрет TOTP в base32
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"column:totp_enabled;not null;default:false"` // Второй фактор подтвержден и требуется при входе
+	TOTPLastStep int64  `json:"-" gorm:"column:totp_last_step;not null;default:0"`              // Последний использованный шаг времени, защищает от повторного использования кода
+	// Время удаления учетной записи; в течение срока ожидания ее можно восстановить,
+	// после него учетная запись удаляется окончательно
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// NormalizeUsername приводит имя пользователя к форме для сравнения:
+// NFKC, свертка регистра и повторная NFKC, так как свертка может нарушить нормализацию
+// Совместимые символы (полноширинные буквы, лигатуры) сводятся к обычным, регистр не учитывается
+func NormalizeUsername(username string) string {
+	normalized := norm.NFKC.String(strings.TrimSpace(username))
+	// Caser хранит состояние и не может использоваться из нескольких горутин
+	return norm.NFKC.String(cases.Fold().String(normalized))
+}
+
+// DefaultScopes - области доступа, которые получает пользователь без явно назначенных областей
+var DefaultScopes = []string{jwtmanager.SCOPE_NOTES_READ, jwtmanager.SCOPE_NOTES_WRITE}
+
+// TokenScope возвращает области доступа пользователя для записи в токен
+// Пока пользователь не сменил временный пароль, токен не получает областей доступа
+func (u *User) TokenScope() string {
+	if u.PasswordResetRequired {
+		return ""
+	}
+	if strings.TrimSpace(u.Scopes) == "" {
+		return strings.Join(DefaultScopes, " ")
+	}
+	return strings.Join(strings.Fields(u.Scopes), " ")
+}
+
+// TokenRoles возвращает роли пользователя для записи в токен
+func (u *User) TokenRoles() []string {
+	if u.Role == "" {
+		return []string{jwtmanager.ROLE_USER}
+	}
+	return []string{u.Role}
+}
+
+// PurgeAt возвращает время окончательного удаления учетной записи с учетом срока ожидания grace
+// Для неудаленной учетной записи возвращает нулевое время
+func (u *User) PurgeAt(grace time.Duration) time.Time {
+	if !u.DeletedAt.Valid {
+		return time.Time{}
+	}
+	return u.DeletedAt.Time.Add(grace)
 }
 
 // bcryptCost определяет стоимость хеширования пароля
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Назначения одноразовых токенов пользователя
+const (
+	TOKEN_EMAIL_VERIFICATION = "email_verification" // Подтверждение адреса электронной почты
+	TOKEN_PASSWORD_RESET     = "password_reset"     // Сброс пароля
+)
+
+// UserToken представляет одноразовый токен, отправляемый пользователю по электронной почте
+// В базе данных хранится только SHA-256 хеш токена
+type UserToken struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    int        `gorm:"index;not null"`
+	Purpose   string     `gorm:"not null"`             // Назначение токена
+	TokenHash string     `gorm:"uniqueIndex;not null"` // Хеш токена
+	Email     string     `gorm:"not null"`             // Адрес, на который отправлен токен
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Время использования, nil для неиспользованного токена
+	CreatedAt time.Time
+}
+
+// GenerateUserToken генерирует случайный токен для отправки пользователю и его хеш для хранения
+func GenerateUserToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashUserToken(token), nil
+}
+
+// HashUserToken вычисляет хеш токена для хранения и поиска
+func HashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Типы грантов, поддерживаемые сервером авторизации
+const (
+	GRANT_AUTHORIZATION_CODE = "authorization_code"
+	GRANT_REFRESH_TOKEN      = "refresh_token"
+	GRANT_CLIENT_CREDENTIALS = "client_credentials"
+)
+
+// SupportedGrantTypes - все поддерживаемые типы грантов
+var SupportedGrantTypes = []string{GRANT_AUTHORIZATION_CODE, GRANT_REFRESH_TOKEN, GRANT_CLIENT_CREDENTIALS}
+
+// PKCE_S256 - единственный поддерживаемый метод PKCE (RFC 7636)
+// Метод plain не поддерживается, так как не защищает от перехвата кода
+const PKCE_S256 = "S256"
+
+// Коды ошибок OAuth2 (RFC 6749, раздел 5.2 и 4.1.2.1)
+const (
+	ERROR_INVALID_REQUEST           = "invalid_request"
+	ERROR_INVALID_CLIENT            = "invalid_client"
+	ERROR_INVALID_GRANT             = "invalid_grant"
+	ERROR_INVALID_SCOPE             = "invalid_scope"
+	ERROR_UNAUTHORIZED_CLIENT       = "unauthorized_client"
+	ERROR_UNSUPPORTED_GRANT_TYPE    = "unsupported_grant_type"
+	ERROR_UNSUPPORTED_RESPONSE_TYPE = "unsupported_response_type"
+	ERROR_ACCESS_DENIED             = "access_denied"
+	ERROR_SERVER_ERROR              = "server_error"
+	ERROR_TEMPORARILY_UNAVAILABLE   = "temporarily_unavailable"
+)
+
+// pkceValue - допустимый формат code_verifier и code_challenge (RFC 7636, раздел 4.1)
+var pkceValue = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// ClientSessionID возвращает sid для access токенов, выданных клиенту по client_credentials
+// У таких токенов нет сессии входа; общий sid клиента позволяет отозвать их все при удалении клиента
+func ClientSessionID(clientID string) string {
+	return "client:" + clientID
+}
+
+// ValidPKCEValue проверяет формат code_verifier или code_challenge
+func ValidPKCEValue(value string) bool {
+	return pkceValue.MatchString(value)
+}
+
+// VerifyPKCE проверяет, что code_verifier соответствует сохраненному code_challenge
+func VerifyPKCE(verifier, challenge, method string) bool {
+	if method != PKCE_S256 || !ValidPKCEValue(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// GenerateSecret генерирует случайное значение (секрет клиента или код авторизации)
+// и его хеш для хранения
+func GenerateSecret() (secret string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	return secret, HashSecret(secret), nil
+}
+
+// HashSecret вычисляет хеш секрета или кода авторизации для хранения и поиска
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecret сравнивает секрет клиента с сохраненным хешем за постоянное время
+func VerifySecret(secret, hash string) bool {
+	if secret == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hash)) == 1
+}
+
+// ValidRedirectURI проверяет адрес возврата при регистрации клиента
+// Допускаются абсолютные адреса https без фрагмента и http только для локального адреса
+func ValidRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	default:
+		return false
+	}
+}
+
+// IntersectScopes возвращает области доступа из requested, которые входят во все списки allowed
+// Порядок и регистр областей сохраняются, повторы удаляются
+func IntersectScopes(requested string, allowed ...string) string {
+	var result []string
+	for _, scope := range strings.Fields(requested) {
+		if slices.Contains(result, scope) {
+			continue
+		}
+		granted := true
+		for _, list := range allowed {
+			if !slices.Contains(strings.Fields(list), scope) {
+				granted = false
+				break
+			}
+		}
+		if granted {
+			result = append(result, scope)
+		}
+	}
+	return strings.Join(result, " ")
+}
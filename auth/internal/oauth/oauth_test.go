@@ -0,0 +1,107 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+		want      bool
+	}{
+		{"верный verifier", verifier, challenge, PKCE_S256, true},
+		{"другой verifier", strings.Replace(verifier, "d", "e", 1), challenge, PKCE_S256, false},
+		{"метод plain не поддерживается", verifier, verifier, "plain", false},
+		{"пустой метод", verifier, challenge, "", false},
+		{"пустой verifier", "", challenge, PKCE_S256, false},
+		{"короткий verifier", "short", challenge, PKCE_S256, false},
+		{"недопустимые символы", verifier[:42] + "+", challenge, PKCE_S256, false},
+		{"пустой challenge", verifier, "", PKCE_S256, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge, tt.method); got != tt.want {
+				t.Errorf("VerifyPKCE = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidPKCEValue(t *testing.T) {
+	for value, want := range map[string]bool{
+		strings.Repeat("a", 42):       false,
+		strings.Repeat("a", 43):       true,
+		strings.Repeat("a", 128):      true,
+		strings.Repeat("a", 129):      false,
+		strings.Repeat("A-._~9", 8):   true,
+		strings.Repeat("a", 42) + "=": false,
+		strings.Repeat("a", 42) + " ": false,
+	} {
+		if got := ValidPKCEValue(value); got != want {
+			t.Errorf("ValidPKCEValue(%q) = %v, ожидалось %v", value, got, want)
+		}
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	secret, hash, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	if hash != HashSecret(secret) || !VerifySecret(secret, hash) {
+		t.Error("VerifySecret: сгенерированный секрет не прошел проверку")
+	}
+	if VerifySecret(secret+"x", hash) || VerifySecret("", hash) || VerifySecret(secret, "") {
+		t.Error("VerifySecret: принят неверный секрет")
+	}
+	if other, _, _ := GenerateSecret(); other == secret {
+		t.Error("GenerateSecret: повторяющийся секрет")
+	}
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	for uri, want := range map[string]bool{
+		"https://example.com/cb":      true,
+		"https://example.com/cb?x=1":  true,
+		"http://localhost:3000/cb":    true,
+		"http://127.0.0.1:8080/cb":    true,
+		"http://[::1]/cb":             true,
+		"http://example.com/cb":       false,
+		"https://example.com/cb#frag": false,
+		"/relative/cb":                false,
+		"custom-app://cb":             false,
+		"https:///cb":                 false,
+	} {
+		if got := ValidRedirectURI(uri); got != want {
+			t.Errorf("ValidRedirectURI(%q) = %v, ожидалось %v", uri, got, want)
+		}
+	}
+}
+
+func TestIntersectScopes(t *testing.T) {
+	tests := []struct {
+		requested string
+		allowed   []string
+		want      string
+	}{
+		{"notes:read notes:write", []string{"notes:read notes:write admin"}, "notes:read notes:write"},
+		{"notes:write notes:read notes:write", []string{"notes:read notes:write"}, "notes:write notes:read"},
+		{"notes:read admin", []string{"notes:read admin", "notes:read"}, "notes:read"},
+		{"Notes:Read", []string{"notes:read"}, ""},
+		{"notes:read", nil, "notes:read"},
+		{"", []string{"notes:read"}, ""},
+	}
+	for _, tt := range tests {
+		if got := IntersectScopes(tt.requested, tt.allowed...); got != tt.want {
+			t.Errorf("IntersectScopes(%q, %q) = %q, ожидалось %q", tt.requested, tt.allowed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// BloomFilter - вероятностное множество для проверки паролей по списку утекших
+// Может ошибочно считать пароль утекшим с вероятностью, заданной при создании,
+// но никогда не пропускает пароль, который был добавлен в фильтр
+type BloomFilter struct {
+	bits   []uint64 // Битовый массив
+	size   uint64   // Число бит
+	hashes uint64   // Число хеш-функций
+}
+
+// NewBloomFilter создает фильтр для expected элементов
+// с вероятностью ложного срабатывания falsePositiveRate
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	// Оптимальные размер фильтра и число хеш-функций
+	n := float64(expected)
+	size := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add добавляет значение в фильтр
+func (b *BloomFilter) Add(value string) {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains проверяет, было ли значение, вероятно, добавлено в фильтр
+func (b *BloomFilter) Contains(value string) bool {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes вычисляет два независимых хеша значения,
+// из которых по схеме двойного хеширования получаются остальные
+func bloomHashes(value string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(value))
+	h1 := binary.LittleEndian.Uint64(sum[0:8])
+	h2 := binary.LittleEndian.Uint64(sum[8:16]) | 1 // шаг не может быть нулевым
+	return h1, h2
+}
+
+// LoadBreachedList строит фильтр из файла утекших паролей, по одному паролю на строку
+// Пустые строки пропускаются
+func LoadBreachedList(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть список утекших паролей %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// Первый проход определяет число паролей и размер фильтра
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimRight(scanner.Text(), "\r") != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список утекших паролей %s: %w", path, err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список утекших паролей %s: %w", path, err)
+	}
+
+	// Второй проход заполняет фильтр
+	filter := NewBloomFilter(count, falsePositiveRate)
+	scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		filter.Add(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список утекших паролей %s: %w", path, err)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,109 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды нарушений политики паролей
+const (
+	CODE_REQUIRED          = "required"          // Пароль не указан
+	CODE_MIN_LENGTH        = "min_length"        // Пароль короче минимальной длины
+	CODE_MAX_LENGTH        = "max_length"        // Пароль длиннее, чем может обработать bcrypt
+	CODE_UPPERCASE         = "uppercase"         // Нет заглавной буквы
+	CODE_LOWERCASE         = "lowercase"         // Нет строчной буквы
+	CODE_DIGIT             = "digit"             // Нет цифры
+	CODE_SYMBOL            = "symbol"            // Нет специального символа
+	CODE_CONTAINS_USERNAME = "contains_username" // Пароль содержит имя пользователя
+	CODE_BREACHED          = "breached"          // Пароль найден в списке утекших
+)
+
+// maxLength - bcrypt учитывает только первые 72 байта пароля
+const maxLength = 72
+
+// Violation - нарушение правила валидации для отдельного поля запроса
+type Violation struct {
+	Field   string `json:"field"`   // Поле запроса
+	Code    string `json:"code"`    // Машиночитаемый код нарушения
+	Message string `json:"message"` // Описание нарушения
+}
+
+// Policy - политика паролей
+type Policy struct {
+	MinLength      int          // Минимальная длина в символах
+	RequireUpper   bool         // Требуется заглавная буква
+	RequireLower   bool         // Требуется строчная буква
+	RequireDigit   bool         // Требуется цифра
+	RequireSymbol  bool         // Требуется специальный символ
+	ForbidUsername bool         // Пароль не может содержать имя пользователя
+	Breached       *BloomFilter // Список утекших паролей; если nil, проверка не выполняется
+}
+
+// Validate проверяет пароль пользователя username на соответствие политике
+// Возвращает все найденные нарушения или nil, если пароль подходит
+func (p *Policy) Validate(username, password string) []Violation {
+	if password == "" {
+		return []Violation{violation(CODE_REQUIRED, "пароль не указан")}
+	}
+
+	var violations []Violation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, violation(CODE_MIN_LENGTH,
+			fmt.Sprintf("пароль должен содержать не менее %d символов", p.MinLength)))
+	}
+	if len(password) > maxLength {
+		violations = append(violations, violation(CODE_MAX_LENGTH,
+			fmt.Sprintf("пароль не должен быть длиннее %d байт", maxLength)))
+	}
+
+	// Определяем, какие классы символов присутствуют в пароле
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, violation(CODE_UPPERCASE, "пароль должен содержать заглавную букву"))
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, violation(CODE_LOWERCASE, "пароль должен содержать строчную букву"))
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, violation(CODE_DIGIT, "пароль должен содержать цифру"))
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, violation(CODE_SYMBOL, "пароль должен содержать специальный символ"))
+	}
+
+	// Имя пользователя сравнивается без учета регистра
+	if p.ForbidUsername && username != "" &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, violation(CODE_CONTAINS_USERNAME, "пароль не должен содержать имя пользователя"))
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, violation(CODE_BREACHED, "пароль найден в списке утекших паролей"))
+	}
+
+	return violations
+}
+
+// violation создает нарушение для поля password
+func violation(code, message string) Violation {
+	return Violation{
+		Field:   "password",
+		Code:    code,
+		Message: message,
+	}
+}
@@ -0,0 +1,123 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// codes возвращает коды нарушений
+func codes(violations []Violation) []string {
+	result := make([]string, 0, len(violations))
+	for _, v := range violations {
+		result = append(result, v.Code)
+	}
+	return result
+}
+
+func TestPolicyValidate(t *testing.T) {
+	breached := NewBloomFilter(10, 0.001)
+	breached.Add("Password1")
+
+	policy := &Policy{
+		MinLength:      8,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		ForbidUsername: true,
+		Breached:       breached,
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     []string
+	}{
+		{"подходящий пароль", "alice", "Correct-Horse7", nil},
+		{"пустой пароль", "alice", "", []string{CODE_REQUIRED}},
+		{"короткий пароль", "alice", "Ab1!", []string{CODE_MIN_LENGTH}},
+		{"длина в символах, а не в байтах", "alice", "Пароль1!", nil},
+		{"длиннее 72 байт", "alice", "Aa1!" + strings.Repeat("x", 69), []string{CODE_MAX_LENGTH}},
+		{"нет заглавной", "alice", "correct-horse7", []string{CODE_UPPERCASE}},
+		{"нет строчной", "alice", "CORRECT-HORSE7", []string{CODE_LOWERCASE}},
+		{"нет цифры", "alice", "Correct-Horse", []string{CODE_DIGIT}},
+		{"нет символа", "alice", "CorrectHorse7", []string{CODE_SYMBOL}},
+		{"пробел считается символом", "alice", "Correct Horse7", nil},
+		{"содержит имя без учета регистра", "alice", "My-ALICE-pass7", []string{CODE_CONTAINS_USERNAME}},
+		{"утекший пароль", "alice", "Password1", []string{CODE_SYMBOL, CODE_BREACHED}},
+		{"несколько нарушений", "alice", "abc", []string{CODE_MIN_LENGTH, CODE_UPPERCASE, CODE_DIGIT, CODE_SYMBOL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(policy.Validate(tt.username, tt.password))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate(%q) = %v, ожидалось %v", tt.password, got, tt.want)
+			}
+		})
+	}
+
+	// Без требований политика проверяет только наличие пароля и ограничение bcrypt
+	if got := (&Policy{}).Validate("alice", "alice"); got != nil {
+		t.Errorf("Validate пустой политики = %v", codes(got))
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := range 1000 {
+		filter.Add(fmt.Sprintf("breached-%d", i))
+	}
+	for i := range 1000 {
+		value := fmt.Sprintf("breached-%d", i)
+		if !filter.Contains(value) {
+			t.Fatalf("Contains(%q): добавленное значение не найдено", value)
+		}
+	}
+
+	// Доля ложных срабатываний близка к заданной
+	falsePositives := 0
+	for i := range 10000 {
+		if filter.Contains(fmt.Sprintf("unique-%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.03 {
+		t.Errorf("доля ложных срабатываний %.4f, ожидалось около 0.01", rate)
+	}
+
+	// Неверные параметры заменяются допустимыми
+	small := NewBloomFilter(0, 2)
+	small.Add("value")
+	if !small.Contains("value") {
+		t.Error("Contains: фильтр с параметрами по умолчанию не нашел значение")
+	}
+}
+
+func TestLoadBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("123456\r\npassword\n\nqwerty\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	filter, err := LoadBreachedList(path, 0.001)
+	if err != nil {
+		t.Fatalf("LoadBreachedList: %v", err)
+	}
+	for _, value := range []string{"123456", "password", "qwerty"} {
+		if !filter.Contains(value) {
+			t.Errorf("Contains(%q): пароль из списка не найден", value)
+		}
+	}
+	if filter.Contains("123456\r") {
+		t.Error("Contains: окончание строки \\r попало в фильтр")
+	}
+
+	if _, err := LoadBreachedList(filepath.Join(t.TempDir(), "missing.txt"), 0.001); err == nil {
+		t.Error("LoadBreachedList: ожидалась ошибка для отсутствующего файла")
+	}
+}
@@ -0,0 +1,64 @@
+package purger
+
+import (
+	"auth/internal/service"
+	"context"
+	"fmt"
+	"time"
+)
+
+// batchSize - число учетных записей, удаляемых за одну транзакцию
+const batchSize = 100
+
+// Purger периодически окончательно удаляет учетные записи, срок восстановления которых истек
+// Удаление записывает событие user.deleted в outbox, по которому сервис заметок удаляет данные пользователя
+type Purger struct {
+	service  service.Service
+	grace    time.Duration // Срок, в течение которого удаленную учетную запись можно восстановить
+	interval time.Duration // Период запуска очистки
+}
+
+// NewPurger создает задачу очистки удаленных учетных записей
+func NewPurger(service service.Service, grace, interval time.Duration) *Purger {
+	return &Purger{
+		service:  service,
+		grace:    grace,
+		interval: interval,
+	}
+}
+
+// Run удаляет учетные записи с истекшим сроком до отмены ctx
+// Ошибки выводятся в лог, неудаленные учетные записи обрабатываются при следующем запуске
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purgeExpired(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired удаляет порции учетных записей, пока остаются учетные записи с истекшим сроком
+func (p *Purger) purgeExpired(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		purged, err := p.service.PurgeDeletedUsers(ctx, time.Now().Add(-p.grace), batchSize)
+		if err != nil {
+			fmt.Printf("Ошибка удаления учетных записей: %v\n", err)
+			break
+		}
+		total += purged
+		if purged < batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		fmt.Printf("Окончательно удалено учетных записей: %d\n", total)
+	}
+}
@@ -0,0 +1,114 @@
+package purger
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"auth/internal/service"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testPassword = "Secret-password-1"
+
+// createDeletedUser создает пользователя и помечает его удаленным
+func createDeletedUser(t *testing.T, s service.Service, username string) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := s.Create(ctx, &models.User{Username: username, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := s.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+}
+
+// purged проверяет, что учетная запись удалена окончательно, а не ожидает удаления
+func purged(t *testing.T, s service.Service, username string) bool {
+	t.Helper()
+	_, err := s.Authenticate(context.Background(), username, testPassword)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return true
+	case errors.Is(err, autherrors.ErrUserPendingDeletion):
+		return false
+	default:
+		t.Fatalf("Authenticate(%q): %v", username, err)
+		return false
+	}
+}
+
+// TestPurgeExpired проверяет, что очистка удаляет только учетные записи с истекшим сроком
+// и записывает для них событие user.deleted
+func TestPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	s := service.NewMemoryService()
+	createDeletedUser(t, s, "alice")
+	createDeletedUser(t, s, "bob")
+
+	// Срок ожидания не истек
+	NewPurger(s, time.Hour, time.Minute).purgeExpired(ctx)
+	if purged(t, s, "alice") || purged(t, s, "bob") {
+		t.Fatal("учетная запись удалена до истечения срока")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	createDeletedUser(t, s, "carol")
+	NewPurger(s, 10*time.Millisecond, time.Minute).purgeExpired(ctx)
+
+	if !purged(t, s, "alice") || !purged(t, s, "bob") {
+		t.Error("учетная запись не удалена после истечения срока")
+	}
+	if purged(t, s, "carol") {
+		t.Error("удалена учетная запись, срок которой не истек")
+	}
+
+	deleted := 0
+	_, err := s.PublishOutbox(ctx, 100, func(ctx context.Context, events []models.OutboxEvent) error {
+		for _, event := range events {
+			if event.Type == models.EVENT_USER_DELETED {
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("событий user.deleted: %d, ожидалось 2", deleted)
+	}
+}
+
+// TestRunStopsOnCancel проверяет, что Run выполняет очистку сразу и завершается при отмене контекста
+func TestRunStopsOnCancel(t *testing.T) {
+	s := service.NewMemoryService()
+	createDeletedUser(t, s, "alice")
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewPurger(s, time.Millisecond, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !purged(t, s, "alice") {
+		if time.Now().After(deadline) {
+			t.Fatal("Run не удалил учетную запись")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run не завершился после отмены контекста")
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"auth/internal/handler"
+	"auth/proto/authv1"
+
+	"google.golang.org/grpc"
+)
+
+// SetupGRPCServer создает gRPC сервер с API сервиса авторизации
+// Токен из метаданных проверяется interceptor'ами JWT менеджера,
+// публичные методы (регистрация, вход, обновление и проверка токена) вызываются без него
+func SetupGRPCServer(h *handler.Handler) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(h.UnaryAuthInterceptor()),
+		grpc.ChainStreamInterceptor(h.StreamAuthInterceptor()),
+	)
+	authv1.RegisterAuthServiceServer(server, handler.NewGRPCHandler(h))
+	return server
+}
@@ -12,21 +12,62 @@ import (
 func SetupRouter(h *handler.Handler) *gin.Engine {
 	router := gin.Default()
 
+	// Документ обнаружения сервера авторизации OAuth2 (RFC 8414)
+	router.GET("/.well-known/oauth-authorization-server", h.OAuthMetadata)
+
 	// Группа маршрутов для аутентификации
 	auth := router.Group("/auth")
 	{
 		// Публичные endpoints (без авторизации)
 		auth.POST("/register", h.RegisterUser)
 		auth.POST("/login", h.LoginUser)
+		auth.POST("/restore", h.RestoreUser)
 		auth.POST("/refresh", h.RefreshToken)
+		auth.GET("/.well-known/jwks.json", h.GetJWKS)
+		auth.POST("/2fa/verify", h.VerifyTwoFactor)
+		auth.POST("/email/verify", h.VerifyEmail)
+		auth.POST("/password/forgot", h.ForgotPassword)
+		auth.POST("/password/reset", h.ResetPassword)
+		auth.GET("/.well-known/oauth-authorization-server", h.OAuthMetadata)
+		auth.POST("/oauth/token", h.Token)
+		auth.POST("/introspect", h.Introspect)
+		auth.GET("/userinfo", h.RequireAuth(), h.UserInfo) // Доступен и с токенами OAuth клиентов
 
 		// Защищенные endpoints (требуют авторизации)
 		protected := auth.Group("/")
-		protected.Use(h.RequireAuth()) // Применяем middleware аутентификации
+		protected.Use(h.RequireAuth(), h.RequireFirstParty()) // Применяем middleware аутентификации
 		{
 			protected.GET("/user", h.GetUserInfo)
 			protected.PUT("/user", h.UpdateUser)
+			protected.PUT("/user/username", h.ChangeUsername)
 			protected.DELETE("/user", h.DeleteUser)
+			protected.POST("/logout", h.Logout)
+			protected.POST("/logout-all", h.LogoutAll)
+			protected.GET("/sessions", h.ListSessions)
+			protected.DELETE("/sessions/:id", h.RevokeSession)
+			protected.POST("/2fa/setup", h.SetupTwoFactor)
+			protected.POST("/2fa/confirm", h.ConfirmTwoFactor)
+			protected.POST("/2fa/disable", h.DisableTwoFactor)
+			protected.POST("/email/resend", h.ResendVerification)
+			protected.POST("/tokens", h.CreatePAT)
+			protected.GET("/tokens", h.ListPATs)
+			protected.DELETE("/tokens/:id", h.RevokePAT)
+			protected.GET("/oauth/authorize", h.Authorize)
+			protected.POST("/oauth/clients", h.RegisterOAuthClient)
+			protected.GET("/oauth/clients", h.ListOAuthClients)
+			protected.DELETE("/oauth/clients/:id", h.DeleteOAuthClient)
+		}
+
+		// Администрирование пользователей (требует роли admin)
+		admin := auth.Group("/admin")
+		admin.Use(h.RequireAuth(), h.RequireFirstParty(), h.RequireAdmin())
+		{
+			admin.GET("/users", h.ListUsers)
+			admin.GET("/users/:id", h.GetUser)
+			admin.POST("/users/:id/disable", h.DisableUser)
+			admin.POST("/users/:id/enable", h.EnableUser)
+			admin.POST("/users/:id/reset-password", h.ResetUserPassword)
+			admin.DELETE("/users/:id/sessions", h.RevokeUserSessions)
 		}
 	}
 
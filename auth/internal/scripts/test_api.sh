@@ -18,7 +18,7 @@ curl -X POST "$BASE_URL/register" \
      -H "Content-Type: application/json" \
      -d '{
            "username": "testuser",
-           "password": "password123"
+           "password": "SecurePass123"
          }' \
      -w "\n📊 HTTP Статус: %{http_code}\n" \
      -s
@@ -35,7 +35,7 @@ echo "Ответ:"
 # Сохраняем ответ логина для извлечения токена
 LOGIN_RESPONSE=$(curl -X "POST" "$BASE_URL/login" \
      -H "Content-Type: application/json" \
-     -d '{"username": "testuser","password":"password123"}' \
+     -d '{"username": "testuser","password":"SecurePass123"}' \
      -w "\n📊 HTTP Статус: %{http_code}\n" \
      -s)
 
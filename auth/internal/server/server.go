@@ -1,14 +1,25 @@
 package server
 
 import (
+	"auth/internal/caching"
 	"auth/internal/config"
 	"auth/internal/errors"
+	"auth/internal/events"
 	"auth/internal/handler"
+	"auth/internal/lockout"
+	"auth/internal/mailer"
+	"auth/internal/purger"
 	"auth/internal/routes"
 	"auth/internal/service"
+	"context"
 	"fmt"
+	"net"
+	"time"
+
+	jwtmanager "jwt_manager"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 // Структура сервера
@@ -17,6 +28,14 @@ type Server struct {
 	cfg *config.Config
 	// router - маршрутизатор Gin
 	router *gin.Engine // Новое поле для маршрутизатора
+	// grpcServer - gRPC API рядом с HTTP; nil, если gRPC отключен
+	grpcServer *grpc.Server
+	// relay - ретранслятор доменных событий из outbox в Redis Stream
+	relay *events.Relay
+	// purger - окончательное удаление учетных записей с истекшим сроком восстановления
+	purger *purger.Purger
+	// stopBackground останавливает фоновые задачи сервера
+	stopBackground context.CancelFunc
 }
 
 // NewServer - конструктор сервера
@@ -30,32 +49,177 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("%w: %v", errors.ErrServiceCreation, err)
 	}
 
-	// Создаем новый экземпляр обработчика с базой данных и конфигурацией
-	handler := handler.NewHandler(service, cfg)
+	// Хранилища отозванных токенов, блокировок и персональных токенов
+	stores, err := newSharedStores(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	// Письма отправляются через SMTP или записываются в лог при разработке
+	var mail mailer.Mailer
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("для отправки писем через SMTP нужно задать SMTP_HOST")
+		}
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "log":
+		mail = mailer.NewLogMailer(cfg.MailLogFile)
+	case "":
+		return nil, fmt.Errorf("не задан способ отправки писем: укажите MAIL_DRIVER=smtp или MAIL_DRIVER=log")
+	default:
+		return nil, fmt.Errorf("неизвестный способ отправки писем: %s", cfg.MailDriver)
+	}
+
+	// Создаем новый экземпляр обработчика с базой данных и конфигурацией
+	handler, err := handler.NewHandler(service, cfg, stores.revocations, stores.lockouts, stores.mailLimits, mail, stores.pats)
 	// Проверяем, что обработчик успешно создан
-	if handler == nil {
-		return nil, fmt.Errorf("не удалось создать обработчик сервера")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать обработчик сервера: %w", err)
+	}
+
+	// Восстанавливаем персональные токены в общем хранилище, если оно было очищено
+	syncCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.DBTimeout)*time.Second)
+	defer cancel()
+	if err := handler.SyncPATs(syncCtx); err != nil {
+		return nil, fmt.Errorf("не удалось восстановить персональные токены: %w", err)
+	}
+	// Назначаем роль admin пользователю из конфигурации
+	if err := handler.BootstrapAdmin(syncCtx); err != nil {
+		return nil, fmt.Errorf("не удалось назначить администратора: %w", err)
 	}
 	fmt.Println("Обработчик сервера успешно создан")
+
+	// События пользователей переносятся из outbox в брокер
+	relay := events.NewRelay(
+		service,
+		stores.publisher,
+		time.Duration(cfg.OutboxPollInterval)*time.Second,
+		cfg.OutboxBatchSize,
+		time.Duration(cfg.OutboxRetention)*time.Hour,
+	)
+
+	// Удаленные учетные записи удаляются окончательно после срока восстановления
+	purger := purger.NewPurger(
+		service,
+		time.Duration(cfg.AccountDeletionGrace)*time.Hour,
+		time.Duration(cfg.AccountPurgeInterval)*time.Second,
+	)
+
 	// Создаем новый экземпляр маршрутизатора
 	router := routes.SetupRouter(handler) // Новое
+	// gRPC API использует тот же обработчик, что и HTTP
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = routes.SetupGRPCServer(handler)
+	}
 	// Создаем новый экземпляр сервера
 	return &Server{
-		router: router, // Новое
-		cfg:    cfg,
+		router:     router, // Новое
+		grpcServer: grpcServer,
+		cfg:        cfg,
+		relay:      relay,
+		purger:     purger,
 	}, nil
 
 }
 
+// sharedStores - хранилища, общие для реплик сервиса auth и сервиса notes
+type sharedStores struct {
+	revocations jwtmanager.RevocationStore // Отозванные access токены
+	pats        jwtmanager.PATStore        // Персональные токены доступа
+	lockouts    lockout.Lockout            // Защита входа от подбора пароля
+	mailLimits  lockout.Lockout            // Ограничение запросов писем
+	publisher   events.Publisher           // Публикация доменных событий
+}
+
+// newSharedStores создает хранилища в Redis
+// Для хранилищ пользователей sqlite и memory без заданного REDIS_HOST хранилища создаются
+// в памяти процесса: так сервис запускается одним экземпляром без Redis,
+// но сервис notes не видит его токены и события
+func newSharedStores(cfg *config.Config) (*sharedStores, error) {
+	// Счетчики неудачных попыток входа общие для всех реплик
+	loginOptions := lockout.Options{
+		MaxUserAttempts: cfg.LoginMaxUserAttempts,
+		MaxIPAttempts:   cfg.LoginMaxIPAttempts,
+		BaseLockout:     time.Duration(cfg.LoginLockoutBase) * time.Second,
+		MaxLockout:      time.Duration(cfg.LoginLockoutMax) * time.Second,
+		Window:          time.Duration(cfg.LoginAttemptWindow) * time.Second,
+	}
+	// Запросы писем ограничиваются на адрес и на IP до конца окна, счетчики отдельные от счетчиков входа
+	mailWindow := time.Duration(cfg.EmailSendWindow) * time.Second
+	mailOptions := lockout.Options{
+		MaxUserAttempts: cfg.EmailMaxAddressSends,
+		MaxIPAttempts:   cfg.EmailMaxIPSends,
+		BaseLockout:     mailWindow,
+		MaxLockout:      mailWindow,
+		Window:          mailWindow,
+		KeyPrefix:       "auth:email:",
+	}
+
+	if cfg.RedisHost == "" && cfg.StoreBackend != config.STORE_POSTGRES {
+		fmt.Println("REDIS_HOST не задан, отозванные токены, блокировки входа и персональные токены хранятся в памяти процесса")
+		return &sharedStores{
+			revocations: jwtmanager.NewMemoryRevocationStore(),
+			pats:        jwtmanager.NewMemoryPATStore(),
+			lockouts:    lockout.NewMemoryLockout(loginOptions),
+			mailLimits:  lockout.NewMemoryLockout(mailOptions),
+			publisher:   events.LogPublisher{},
+		}, nil
+	}
+
+	// Подключаемся к Redis, где хранятся отозванные access токены
+	cache, err := caching.NewCaching(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrCacheConnection, err)
+	}
+	return &sharedStores{
+		revocations: jwtmanager.NewRedisRevocationStore(cache),
+		pats:        jwtmanager.NewRedisPATStore(cache),
+		lockouts:    lockout.NewRedisLockout(cache, loginOptions),
+		mailLimits:  lockout.NewRedisLockout(cache, mailOptions),
+		// События пользователей публикуются в Redis Stream, откуда их читает сервис notes
+		publisher: events.NewRedisStreamPublisher(cache, cfg.EventsStream, int64(cfg.EventsStreamMaxLen)),
+	}, nil
+}
+
 // Stop - остановка сервера
 func (s *Server) Stop() error {
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+	// Дожидаемся завершения текущих gRPC вызовов
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
 	fmt.Println("Сервер остановлен")
 	return nil
 }
 
 // Serve - основной метод сервера
 func (s *Server) Serve() error {
+	// Запускаем публикацию событий outbox и очистку удаленных учетных записей в фоне
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+	go s.relay.Run(backgroundCtx)
+	go s.purger.Run(backgroundCtx)
+
+	// gRPC API слушает отдельный порт; ошибка при запуске останавливает сервер
+	if s.grpcServer != nil {
+		grpcAddress := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.GRPCPort)
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("не удалось запустить gRPC сервер на %s: %w", grpcAddress, err)
+		}
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				fmt.Printf("gRPC сервер остановлен с ошибкой: %v\n", err)
+			}
+		}()
+		fmt.Printf("gRPC сервер готов к обработке запросов на %s...\n", grpcAddress)
+	}
+
 	// Запускаем сервер
 	address := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port)
 	fmt.Printf("Сервер готов к обработке запросов на %s...\n", address)
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"auth/internal/config"
+	"testing"
+)
+
+// TestNewServerMailDriver проверяет, что сервер не запускается без явно выбранного способа отправки писем
+// и без SMTP_HOST для драйвера smtp
+func TestNewServerMailDriver(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		driver   string
+		smtpHost string
+	}{
+		{name: "драйвер не задан"},
+		{name: "smtp без хоста", driver: "smtp"},
+		{name: "неизвестный драйвер", driver: "stdout", smtpHost: "smtp.example.com"},
+	} {
+		cfg := &config.Config{
+			JWTSecretKey: "test-secret-key-for-server-tests-0123456789",
+			StoreBackend: config.STORE_MEMORY,
+			MailDriver:   tt.driver,
+			SMTPHost:     tt.smtpHost,
+		}
+		if _, err := NewServer(cfg); err == nil {
+			t.Errorf("%s: NewServer без ошибки", tt.name)
+		}
+	}
+}
+
+// TestNewServerWithoutRedis проверяет, что с хранилищем пользователей в памяти
+// сервер создается без Redis
+func TestNewServerWithoutRedis(t *testing.T) {
+	cfg := &config.Config{
+		JWTSecretKey:           "test-secret-key-for-server-tests-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		DBTimeout:              5,
+		MailDriver:             "log",
+		StoreBackend:           config.STORE_MEMORY,
+		UsernameMinLength:      3,
+		UsernameMaxLength:      32,
+	}
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := server.Stop(); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+
+	// Для PostgreSQL Redis по-прежнему обязателен
+	stores, err := newSharedStores(&config.Config{StoreBackend: config.STORE_POSTGRES, RedisHost: "", RedisPort: "1"})
+	if err == nil {
+		t.Errorf("newSharedStores для postgres без Redis = %+v, ожидалась ошибка", stores)
+	}
+}
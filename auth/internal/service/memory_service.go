@@ -0,0 +1,1341 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwtmanager "jwt_manager"
+
+	"gorm.io/gorm"
+)
+
+// MemoryService - реализация интерфейса Service, хранящая данные в памяти процесса
+// Предназначена для локального запуска и тестов: данные теряются при перезапуске
+// и не разделяются между экземплярами сервиса. Все операции выполняются под одной блокировкой,
+// поэтому каждая из них атомарна так же, как транзакция в DBService
+type MemoryService struct {
+	mu        sync.Mutex
+	publishMu sync.Mutex // Очередь публикации outbox, см. PublishOutbox
+
+	users         map[int]models.User
+	refreshTokens map[string]models.RefreshToken
+	sessions      map[string]models.Session
+	recoveryCodes map[int]models.RecoveryCode
+	userTokens    map[int]models.UserToken
+	pats          map[string]models.PersonalAccessToken
+	oauthClients  map[string]models.OAuthClient
+	authCodes     map[string]models.OAuthAuthorizationCode
+	outbox        []models.OutboxEvent // События в порядке записи
+
+	// Последние выданные значения автоинкрементных ключей
+	lastUserID         int
+	lastRecoveryCodeID int
+	lastUserTokenID    int
+	lastEventID        uint
+}
+
+// Проверка, что MemoryService реализует интерфейс Service
+var _ Service = (*MemoryService)(nil)
+
+// NewMemoryService создает пустое хранилище в памяти
+func NewMemoryService() *MemoryService {
+	return &MemoryService{
+		users:         make(map[int]models.User),
+		refreshTokens: make(map[string]models.RefreshToken),
+		sessions:      make(map[string]models.Session),
+		recoveryCodes: make(map[int]models.RecoveryCode),
+		userTokens:    make(map[int]models.UserToken),
+		pats:          make(map[string]models.PersonalAccessToken),
+		oauthClients:  make(map[string]models.OAuthClient),
+		authCodes:     make(map[string]models.OAuthAuthorizationCode),
+	}
+}
+
+// Create создает нового пользователя
+func (m *MemoryService) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	if user == nil {
+		return nil, gorm.ErrInvalidData
+	}
+
+	// Хешируем пароль до блокировки, bcrypt выполняется долго
+	hashedPassword, err := user.HashPassword(user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	normalized := models.NormalizeUsername(user.Username)
+	if m.usernameTaken(normalized, 0) || m.emailTaken(user.Email, 0) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	event, err := models.NewUserEvent(models.EVENT_USER_CREATED, m.lastUserID+1, user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = hashedPassword
+	// Новый пользователь всегда получает роль по умолчанию
+	user.Role = jwtmanager.ROLE_USER
+	user.Scopes = ""
+	user.Disabled = false
+	user.PasswordResetRequired = false
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.EmailVerified = false
+	user.UsernameNormalized = normalized
+	user.UsernameChangedAt = nil
+	user.DeletedAt = gorm.DeletedAt{}
+
+	m.lastUserID++
+	user.ID = m.lastUserID
+	m.users[user.ID] = cloneUser(*user)
+	m.appendEvent(event)
+
+	return user, nil
+}
+
+// Read находит пользователя по ID
+func (m *MemoryService) Read(ctx context.Context, id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// Update обновляет пароль и адрес электронной почты пользователя
+func (m *MemoryService) Update(ctx context.Context, user *models.User) error {
+	if user == nil || user.ID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	var hashedPassword string
+	if user.Password != "" {
+		var err error
+		if hashedPassword, err = user.HashPassword(user.Password); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.activeUser(user.ID)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Username = stored.Username
+	if hashedPassword == "" && user.Email == nil {
+		return nil
+	}
+	if m.emailTaken(user.Email, user.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	event, err := models.NewUserEvent(models.EVENT_USER_UPDATED, user.ID, stored.Username)
+	if err != nil {
+		return err
+	}
+
+	// Смена пароля снимает требование сброса временного пароля
+	if hashedPassword != "" {
+		user.Password = hashedPassword
+		user.PasswordResetRequired = false
+		stored.Password = hashedPassword
+		stored.PasswordResetRequired = false
+	}
+	// Новый адрес электронной почты требует повторного подтверждения
+	if user.Email != nil {
+		user.EmailVerified = false
+		email := *user.Email
+		// Ссылки сброса пароля, отправленные на прежний адрес, перестают действовать
+		if stored.Email == nil || *stored.Email != email {
+			m.useUserTokens(user.ID, models.TOKEN_PASSWORD_RESET, time.Now())
+		}
+		stored.Email = &email
+		stored.EmailVerified = false
+	}
+
+	m.users[user.ID] = stored
+	m.appendEvent(event)
+	return nil
+}
+
+// RenameUser меняет имя пользователя, если с прошлой смены имени прошло не меньше cooldown
+// Если имя менялось недавно, возвращает пользователя вместе с ErrUsernameCooldown
+func (m *MemoryService) RenameUser(ctx context.Context, id int, username string, cooldown time.Duration) (*models.User, error) {
+	normalized := models.NormalizeUsername(username)
+	if id <= 0 || normalized == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if user.Username == username {
+		return &user, nil
+	}
+
+	now := time.Now()
+	if user.UsernameChangedAt != nil && now.Before(user.UsernameChangedAt.Add(cooldown)) {
+		return &user, autherrors.ErrUsernameCooldown
+	}
+	if m.usernameTaken(normalized, id) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	event, err := models.NewUserEvent(models.EVENT_USER_UPDATED, id, username)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Username = username
+	user.UsernameNormalized = normalized
+	user.UsernameChangedAt = &now
+	m.users[id] = cloneUser(user)
+	m.appendEvent(event)
+	return &user, nil
+}
+
+// Delete помечает пользователя удаленным и завершает его сессии
+func (m *MemoryService) Delete(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	m.users[id] = user
+
+	for key, token := range m.refreshTokens {
+		if token.UserID == id && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(now)
+			m.refreshTokens[key] = token
+		}
+	}
+	for key, session := range m.sessions {
+		if session.UserID == id && session.RevokedAt == nil {
+			session.RevokedAt = timePtr(now)
+			m.sessions[key] = session
+		}
+	}
+	for key, token := range m.userTokens {
+		if token.UserID == id && token.UsedAt == nil {
+			token.UsedAt = timePtr(now)
+			m.userTokens[key] = token
+		}
+	}
+	for key, code := range m.authCodes {
+		if code.UserID == id {
+			delete(m.authCodes, key)
+		}
+	}
+	return nil
+}
+
+// RestoreUser снимает с пользователя отметку удаления
+func (m *MemoryService) RestoreUser(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	m.users[id] = user
+	return nil
+}
+
+// PurgeDeletedUsers окончательно удаляет до limit пользователей, помеченных удаленными раньше before
+func (m *MemoryService) PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for id, user := range m.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	// События создаются заранее, чтобы ошибка не оставила удаление наполовину выполненным
+	events := make([]*models.OutboxEvent, 0, len(ids))
+	for _, id := range ids {
+		event, err := models.NewUserEvent(models.EVENT_USER_DELETED, id, "")
+		if err != nil {
+			return 0, err
+		}
+		events = append(events, event)
+	}
+
+	for i, id := range ids {
+		m.purgeUser(id)
+		m.appendEvent(events[i])
+	}
+	return len(ids), nil
+}
+
+// Authenticate аутентифицирует пользователя по имени пользователя и паролю
+func (m *MemoryService) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	if username == "" || password == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	user, ok := m.userByUsername(username)
+	m.mu.Unlock()
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	// Проверяем соответствие пароля хешу
+	if !user.CheckPassword(password, user.Password) {
+		return nil, gorm.ErrRecordNotFound // Возвращаем ошибку "не найден" для безопасности
+	}
+	if user.DeletedAt.Valid {
+		return &user, autherrors.ErrUserPendingDeletion
+	}
+	if user.Disabled {
+		return nil, autherrors.ErrUserDisabled
+	}
+	return &user, nil
+}
+
+// ReadByUsername находит пользователя по имени пользователя
+func (m *MemoryService) ReadByUsername(ctx context.Context, username string) (*models.User, error) {
+	if username == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.userByUsername(username)
+	if !ok || user.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// CreateRefreshToken сохраняет новый refresh токен
+func (m *MemoryService) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token == nil || token.ID == "" || token.FamilyID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertRefreshToken(token)
+}
+
+// RotateRefreshToken заменяет refresh токен oldID на next в рамках одного семейства
+// При повторном использовании уже замененного токена отзывает все семейство
+func (m *MemoryService) RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) error {
+	if oldID == "" || next == nil || next.ID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.refreshTokens[oldID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if old.RevokedAt != nil {
+		m.revokeFamily(old.FamilyID, time.Now())
+		return autherrors.ErrRefreshTokenReuse
+	}
+	if _, exists := m.refreshTokens[next.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+
+	old.RevokedAt = timePtr(time.Now())
+	old.ReplacedBy = next.ID
+	m.refreshTokens[oldID] = old
+
+	next.UserID = old.UserID
+	next.FamilyID = old.FamilyID
+	return m.insertRefreshToken(next)
+}
+
+// ReadRefreshToken находит refresh токен по его jti
+func (m *MemoryService) ReadRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.refreshTokens[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// RevokeTokenFamily отзывает все refresh токены семейства и завершает соответствующую сессию
+func (m *MemoryService) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	if familyID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeFamily(familyID, time.Now())
+	return nil
+}
+
+// RevokeUserTokens отзывает все refresh токены и сессии пользователя
+func (m *MemoryService) RevokeUserTokens(ctx context.Context, userID int) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, token := range m.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(now)
+			m.refreshTokens[key] = token
+		}
+	}
+	for key, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = timePtr(now)
+			m.sessions[key] = session
+		}
+	}
+	return nil
+}
+
+// CreateSession сохраняет новую сессию
+func (m *MemoryService) CreateSession(ctx context.Context, session *models.Session) error {
+	if session == nil || session.ID == "" || session.UserID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sessions[session.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	m.sessions[session.ID] = *session
+	return nil
+}
+
+// TouchSession обновляет время последнего обновления, срок действия и данные клиента сессии
+func (m *MemoryService) TouchSession(ctx context.Context, id, userAgent, ip string, expiresAt time.Time) error {
+	if id == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		session.UserAgent = userAgent
+		session.IP = ip
+		session.LastRefreshedAt = time.Now()
+		session.ExpiresAt = expiresAt
+		m.sessions[id] = session
+	}
+	return nil
+}
+
+// ReadSession находит сессию по ID
+func (m *MemoryService) ReadSession(ctx context.Context, id string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+// ListSessions возвращает не отозванные и не истекшие сессии пользователя,
+// начиная с последней обновленной
+func (m *MemoryService) ListSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var sessions []models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastRefreshedAt.After(sessions[j].LastRefreshedAt)
+	})
+	return sessions, nil
+}
+
+// ListUsers возвращает страницу пользователей, отсортированных по ID,
+// и общее количество пользователей, подходящих под фильтр
+func (m *MemoryService) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, int64, error) {
+	if filter.Limit <= 0 || filter.Offset < 0 {
+		return nil, 0, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	substring := models.NormalizeUsername(filter.Username)
+	var matched []models.User
+	for _, user := range m.users {
+		if user.DeletedAt.Valid || !strings.Contains(user.UsernameNormalized, substring) {
+			continue
+		}
+		matched = append(matched, cloneUser(user))
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return []models.User{}, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// SetUserDisabled отключает или включает учетную запись пользователя
+func (m *MemoryService) SetUserDisabled(ctx context.Context, id int, disabled bool) error {
+	if id <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Disabled = disabled
+	m.users[id] = user
+	return nil
+}
+
+// SetUserRole назначает пользователю роль
+func (m *MemoryService) SetUserRole(ctx context.Context, id int, role string) error {
+	if id <= 0 || role == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Role = role
+	m.users[id] = user
+	return nil
+}
+
+// ForcePasswordReset заменяет пароль пользователя временным и требует его смены при следующем входе
+func (m *MemoryService) ForcePasswordReset(ctx context.Context, id int, tempPassword string) error {
+	if id <= 0 || tempPassword == "" {
+		return gorm.ErrInvalidData
+	}
+
+	var user models.User
+	hashedPassword, err := user.HashPassword(tempPassword)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Password = hashedPassword
+	user.PasswordResetRequired = true
+	m.users[id] = user
+	return nil
+}
+
+// SetupTOTP сохраняет новый, еще не подтвержденный секрет TOTP и заменяет коды восстановления
+func (m *MemoryService) SetupTOTP(ctx context.Context, userID int, secret string, recoveryCodeHashes []string) error {
+	if userID <= 0 || secret == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(userID)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.TOTPSecret = secret
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	m.users[userID] = user
+
+	m.deleteRecoveryCodes(userID)
+	now := time.Now()
+	for _, hash := range recoveryCodeHashes {
+		m.lastRecoveryCodeID++
+		m.recoveryCodes[m.lastRecoveryCodeID] = models.RecoveryCode{
+			ID:        m.lastRecoveryCodeID,
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: now,
+		}
+	}
+	return nil
+}
+
+// EnableTOTP включает второй фактор, если секрет был сохранен
+func (m *MemoryService) EnableTOTP(ctx context.Context, userID int, step int64) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.activeUser(userID)
+	if !ok || user.TOTPSecret == "" {
+		return autherrors.ErrTOTPNotSetup
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	m.users[userID] = user
+	return nil
+}
+
+// DisableTOTP отключает второй фактор, удаляет секрет и коды восстановления
+func (m *MemoryService) DisableTOTP(ctx context.Context, userID int) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.activeUser(userID); ok {
+		user.TOTPSecret = ""
+		user.TOTPEnabled = false
+		user.TOTPLastStep = 0
+		m.users[userID] = user
+	}
+	m.deleteRecoveryCodes(userID)
+	return nil
+}
+
+// ConsumeTOTPStep отмечает шаг времени использованным
+// Если шаг уже был использован, возвращает ErrInvalidTOTPCode
+func (m *MemoryService) ConsumeTOTPStep(ctx context.Context, userID int, step int64) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Второй фактор проверяется и при восстановлении удаленной учетной записи
+	user, ok := m.users[userID]
+	if !ok || user.TOTPLastStep >= step {
+		return autherrors.ErrInvalidTOTPCode
+	}
+	user.TOTPLastStep = step
+	m.users[userID] = user
+	return nil
+}
+
+// UseRecoveryCode отмечает код восстановления использованным
+// Если кода нет или он уже использован, возвращает ErrInvalidTOTPCode
+func (m *MemoryService) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	if userID <= 0 || codeHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, code := range m.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = timePtr(time.Now())
+			m.recoveryCodes[id] = code
+			return nil
+		}
+	}
+	return autherrors.ErrInvalidTOTPCode
+}
+
+// ReadByEmail находит пользователя по адресу электронной почты
+func (m *MemoryService) ReadByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if !user.DeletedAt.Valid && user.Email != nil && *user.Email == email {
+			user = cloneUser(user)
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// CreateUserToken сохраняет одноразовый токен, отменяя ранее выданные токены того же назначения
+func (m *MemoryService) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	if token == nil || token.UserID <= 0 || token.Purpose == "" || token.TokenHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.userTokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	m.useUserTokens(token.UserID, token.Purpose, now)
+
+	m.lastUserTokenID++
+	token.ID = m.lastUserTokenID
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now
+	}
+	m.userTokens[token.ID] = *token
+	return nil
+}
+
+// useUserTokens отмечает неиспользованные токены пользователя с назначением purpose использованными
+func (m *MemoryService) useUserTokens(userID int, purpose string, now time.Time) {
+	for id, existing := range m.userTokens {
+		if existing.UserID == userID && existing.Purpose == purpose && existing.UsedAt == nil {
+			existing.UsedAt = timePtr(now)
+			m.userTokens[id] = existing
+		}
+	}
+}
+
+// ReadUserToken находит неиспользованный и не истекший токен по назначению и хешу
+func (m *MemoryService) ReadUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	if purpose == "" || tokenHash == "" {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.validUserToken(purpose, tokenHash)
+	if !ok {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+	return &token, nil
+}
+
+// ConfirmEmail использует токен подтверждения и отмечает адрес пользователя подтвержденным
+// Если после отправки письма пользователь сменил адрес, токен недействителен
+func (m *MemoryService) ConfirmEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.validUserToken(models.TOKEN_EMAIL_VERIFICATION, tokenHash)
+	if !ok {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+	user, ok := m.activeUser(token.UserID)
+	if !ok || user.Email == nil || *user.Email != token.Email {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+
+	token.UsedAt = timePtr(time.Now())
+	m.userTokens[token.ID] = token
+	user.EmailVerified = true
+	m.users[user.ID] = cloneUser(user)
+	return &user, nil
+}
+
+// ResetPassword использует токен сброса и устанавливает новый пароль
+// Если после отправки письма пользователь сменил адрес, токен недействителен
+func (m *MemoryService) ResetPassword(ctx context.Context, tokenHash, newPassword string) (*models.User, error) {
+	if newPassword == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var user models.User
+	hashedPassword, err := user.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.validUserToken(models.TOKEN_PASSWORD_RESET, tokenHash)
+	if !ok {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+	user, ok = m.activeUser(token.UserID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if user.Email == nil || *user.Email != token.Email {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+
+	token.UsedAt = timePtr(time.Now())
+	m.userTokens[token.ID] = token
+	user.Password = hashedPassword
+	user.PasswordResetRequired = false
+	m.users[user.ID] = cloneUser(user)
+	return &user, nil
+}
+
+// CreatePAT сохраняет новый персональный токен доступа
+func (m *MemoryService) CreatePAT(ctx context.Context, token *models.PersonalAccessToken) error {
+	if token == nil || token.ID == "" || token.UserID <= 0 || token.TokenHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, existing := range m.pats {
+		if id == token.ID || existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	stored := *token
+	stored.LastUsedAt = nil // Не хранится в базе данных
+	m.pats[token.ID] = stored
+	return nil
+}
+
+// ListPATs возвращает не отозванные персональные токены пользователя, начиная с новых
+func (m *MemoryService) ListPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []models.PersonalAccessToken
+	for _, token := range m.pats {
+		if token.UserID == userID && token.RevokedAt == nil {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// ListActivePATs возвращает все не отозванные и не истекшие персональные токены
+// пользователей, не помеченных удаленными
+func (m *MemoryService) ListActivePATs(ctx context.Context) ([]models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var tokens []models.PersonalAccessToken
+	for _, token := range m.pats {
+		if _, active := m.activeUser(token.UserID); !active {
+			continue
+		}
+		if token.RevokedAt == nil && (token.ExpiresAt == nil || token.ExpiresAt.After(now)) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// ReadPAT находит персональный токен по ID
+func (m *MemoryService) ReadPAT(ctx context.Context, id string) (*models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.pats[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// RevokePAT отзывает персональный токен
+func (m *MemoryService) RevokePAT(ctx context.Context, id string) error {
+	if id == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if token, ok := m.pats[id]; ok && token.RevokedAt == nil {
+		token.RevokedAt = timePtr(time.Now())
+		m.pats[id] = token
+	}
+	return nil
+}
+
+// RevokeUserPATs отзывает все персональные токены пользователя и возвращает их
+func (m *MemoryService) RevokeUserPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var tokens []models.PersonalAccessToken
+	for id, token := range m.pats {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(now)
+			m.pats[id] = token
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// CreateOAuthClient сохраняет нового OAuth клиента
+func (m *MemoryService) CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error {
+	if client == nil || client.ID == "" || client.OwnerID <= 0 || client.Name == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.oauthClients[client.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if client.CreatedAt.IsZero() {
+		client.CreatedAt = time.Now()
+	}
+	m.oauthClients[client.ID] = *client
+	return nil
+}
+
+// ReadOAuthClient находит OAuth клиента по client_id
+func (m *MemoryService) ReadOAuthClient(ctx context.Context, id string) (*models.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.oauthClients[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// Клиенты пользователей, помеченных удаленными, недоступны до восстановления учетной записи
+	if _, active := m.activeUser(client.OwnerID); !active {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &client, nil
+}
+
+// ListOAuthClients возвращает OAuth клиентов пользователя, начиная с новых
+func (m *MemoryService) ListOAuthClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	if ownerID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var clients []models.OAuthClient
+	for _, client := range m.oauthClients {
+		if client.OwnerID == ownerID {
+			clients = append(clients, client)
+		}
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].CreatedAt.After(clients[j].CreatedAt)
+	})
+	return clients, nil
+}
+
+// DeleteOAuthClient удаляет OAuth клиента, его коды авторизации и завершает выданные ему сессии
+// Возвращает ID завершенных сессий
+func (m *MemoryService) DeleteOAuthClient(ctx context.Context, id string) ([]string, error) {
+	if id == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.oauthClients[id]; !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.deleteOAuthClient(id, time.Now()), nil
+}
+
+// deleteOAuthClient удаляет OAuth клиента, его коды авторизации и завершает выданные ему сессии
+// Возвращает ID завершенных сессий. Вызывается с захваченной блокировкой
+func (m *MemoryService) deleteOAuthClient(id string, now time.Time) []string {
+	var sessionIDs []string
+	for key, session := range m.sessions {
+		if session.ClientID == id && session.RevokedAt == nil {
+			session.RevokedAt = timePtr(now)
+			m.sessions[key] = session
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+	// ID семейства refresh токенов совпадает с ID сессии
+	for _, sessionID := range sessionIDs {
+		m.revokeFamily(sessionID, now)
+	}
+	for key, code := range m.authCodes {
+		if code.ClientID == id {
+			delete(m.authCodes, key)
+		}
+	}
+	delete(m.oauthClients, id)
+
+	sort.Strings(sessionIDs)
+	return sessionIDs
+}
+
+// CreateAuthCode сохраняет новый код авторизации
+func (m *MemoryService) CreateAuthCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	if code == nil || code.CodeHash == "" || code.ClientID == "" || code.UserID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.authCodes[code.CodeHash]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if code.CreatedAt.IsZero() {
+		code.CreatedAt = time.Now()
+	}
+	m.authCodes[code.CodeHash] = *code
+	return nil
+}
+
+// ConsumeAuthCode проверяет код авторизации функцией check, отмечает его использованным и возвращает его
+// При повторном использовании возвращает код вместе с ErrAuthCodeReuse
+func (m *MemoryService) ConsumeAuthCode(ctx context.Context, codeHash string, check func(code *models.OAuthAuthorizationCode) error) (*models.OAuthAuthorizationCode, error) {
+	if codeHash == "" {
+		return nil, autherrors.ErrInvalidAuthCode
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, ok := m.authCodes[codeHash]
+	if !ok {
+		return nil, autherrors.ErrInvalidAuthCode
+	}
+	if code.UsedAt != nil {
+		return &code, autherrors.ErrAuthCodeReuse
+	}
+	if !time.Now().Before(code.ExpiresAt) {
+		return nil, autherrors.ErrInvalidAuthCode
+	}
+	if check != nil {
+		if err := check(&code); err != nil {
+			return nil, err
+		}
+	}
+
+	code.UsedAt = timePtr(time.Now())
+	m.authCodes[codeHash] = code
+	return &code, nil
+}
+
+// SetAuthCodeSession запоминает сессию, начатую при обмене кода авторизации
+func (m *MemoryService) SetAuthCodeSession(ctx context.Context, codeHash, sessionID string) error {
+	if codeHash == "" || sessionID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if code, ok := m.authCodes[codeHash]; ok {
+		code.SessionID = sessionID
+		m.authCodes[codeHash] = code
+	}
+	return nil
+}
+
+// PublishOutbox передает функции publish до limit неопубликованных событий в порядке их записи
+// и отмечает их опубликованными, если publish завершилась без ошибки
+// publish обращается к сети и вызывается без общей блокировки, чтобы остальные операции
+// не ждали ее завершения. Публикации выполняются по очереди, поэтому одно событие не публикуется дважды
+func (m *MemoryService) PublishOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error) {
+	if limit <= 0 || publish == nil {
+		return 0, gorm.ErrInvalidData
+	}
+
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	m.mu.Lock()
+	var events []models.OutboxEvent
+	for _, event := range m.outbox {
+		if len(events) == limit {
+			break
+		}
+		if event.PublishedAt == nil {
+			events = append(events, event)
+		}
+	}
+	m.mu.Unlock()
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, events); err != nil {
+		return 0, err
+	}
+
+	// Пока события публиковались, outbox мог измениться, поэтому события ищутся по ID
+	published := make(map[uint]struct{}, len(events))
+	for _, event := range events {
+		published[event.ID] = struct{}{}
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.outbox {
+		if _, ok := published[m.outbox[i].ID]; ok {
+			m.outbox[i].PublishedAt = timePtr(now)
+		}
+	}
+	return len(events), nil
+}
+
+// PurgeOutbox удаляет события, опубликованные раньше before
+func (m *MemoryService) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.outbox[:0]
+	var purged int64
+	for _, event := range m.outbox {
+		if event.PublishedAt != nil && event.PublishedAt.Before(before) {
+			purged++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.outbox = kept
+	return purged, nil
+}
+
+// Close ничего не делает: хранилищу в памяти нечего закрывать
+func (m *MemoryService) Close() error {
+	return nil
+}
+
+// activeUser возвращает копию пользователя, не помеченного удаленным
+func (m *MemoryService) activeUser(id int) (models.User, bool) {
+	user, ok := m.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return models.User{}, false
+	}
+	return cloneUser(user), true
+}
+
+// userByUsername возвращает копию пользователя по имени без учета регистра,
+// включая помеченных удаленными
+func (m *MemoryService) userByUsername(username string) (models.User, bool) {
+	normalized := models.NormalizeUsername(username)
+	for _, user := range m.users {
+		if user.UsernameNormalized == normalized {
+			return cloneUser(user), true
+		}
+	}
+	return models.User{}, false
+}
+
+// usernameTaken проверяет, занято ли имя в нормализованной форме другим пользователем, кроме exceptID
+// Имя удаленного пользователя остается занятым до окончательного удаления
+func (m *MemoryService) usernameTaken(normalized string, exceptID int) bool {
+	for id, user := range m.users {
+		if id != exceptID && user.UsernameNormalized == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// emailTaken проверяет, занят ли адрес другим пользователем, кроме exceptID
+func (m *MemoryService) emailTaken(email *string, exceptID int) bool {
+	if email == nil {
+		return false
+	}
+	for id, user := range m.users {
+		if id != exceptID && user.Email != nil && *user.Email == *email {
+			return true
+		}
+	}
+	return false
+}
+
+// validUserToken находит неиспользованный и не истекший одноразовый токен
+func (m *MemoryService) validUserToken(purpose, tokenHash string) (models.UserToken, bool) {
+	if tokenHash == "" {
+		return models.UserToken{}, false
+	}
+	now := time.Now()
+	for _, token := range m.userTokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash && token.UsedAt == nil && token.ExpiresAt.After(now) {
+			return token, true
+		}
+	}
+	return models.UserToken{}, false
+}
+
+// insertRefreshToken сохраняет refresh токен, проверяя уникальность ID
+func (m *MemoryService) insertRefreshToken(token *models.RefreshToken) error {
+	if _, exists := m.refreshTokens[token.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	m.refreshTokens[token.ID] = *token
+	return nil
+}
+
+// revokeFamily отзывает действующие refresh токены семейства и сессию с тем же ID
+func (m *MemoryService) revokeFamily(familyID string, now time.Time) {
+	for key, token := range m.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(now)
+			m.refreshTokens[key] = token
+		}
+	}
+	if session, ok := m.sessions[familyID]; ok && session.RevokedAt == nil {
+		session.RevokedAt = timePtr(now)
+		m.sessions[familyID] = session
+	}
+}
+
+// deleteRecoveryCodes удаляет все коды восстановления пользователя
+func (m *MemoryService) deleteRecoveryCodes(userID int) {
+	for id, code := range m.recoveryCodes {
+		if code.UserID == userID {
+			delete(m.recoveryCodes, id)
+		}
+	}
+}
+
+// purgeUser удаляет пользователя вместе со всеми его данными
+func (m *MemoryService) purgeUser(id int) {
+	for key, token := range m.refreshTokens {
+		if token.UserID == id {
+			delete(m.refreshTokens, key)
+		}
+	}
+	for key, session := range m.sessions {
+		if session.UserID == id {
+			delete(m.sessions, key)
+		}
+	}
+	m.deleteRecoveryCodes(id)
+	for key, token := range m.userTokens {
+		if token.UserID == id {
+			delete(m.userTokens, key)
+		}
+	}
+	for key, token := range m.pats {
+		if token.UserID == id {
+			delete(m.pats, key)
+		}
+	}
+	for key, code := range m.authCodes {
+		if code.UserID == id {
+			delete(m.authCodes, key)
+		}
+	}
+	for clientID, client := range m.oauthClients {
+		if client.OwnerID == id {
+			m.deleteOAuthClient(clientID, time.Now())
+		}
+	}
+	delete(m.users, id)
+}
+
+// appendEvent записывает событие в outbox, назначая ему порядковый номер
+func (m *MemoryService) appendEvent(event *models.OutboxEvent) {
+	m.lastEventID++
+	event.ID = m.lastEventID
+	m.outbox = append(m.outbox, *event)
+}
+
+// cloneUser копирует пользователя вместе с адресом электронной почты,
+// чтобы изменения копии не затрагивали хранилище
+func cloneUser(user models.User) models.User {
+	if user.Email != nil {
+		email := *user.Email
+		user.Email = &email
+	}
+	return user
+}
+
+// timePtr возвращает указатель на копию t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"auth/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RestoreUser снимает с пользователя отметку удаления
+// Возвращает gorm.ErrRecordNotFound, если пользователь не помечен удаленным
+func (p *DBService) RestoreUser(ctx context.Context, id int) error {
+	if id <= 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	result := p.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedUsers окончательно удаляет до limit пользователей, помеченных удаленными раньше before,
+// вместе со всеми их данными, включая персональные токены и OAuth клиентов.
+// Для каждого пользователя записывается событие user.deleted
+// Возвращает число удаленных пользователей
+func (p *DBService) PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, gorm.ErrInvalidData
+	}
+
+	var ids []int
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.User{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := purgeUser(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// purgeUser удаляет пользователя вместе с его refresh токенами, сессиями, OAuth клиентами
+// и остальными данными в рамках транзакции tx и записывает событие user.deleted
+func purgeUser(tx *gorm.DB, id int) error {
+	// Клиенты пользователя удаляются вместе с сессиями, которые через них начали другие пользователи.
+	// Их access токены к этому времени истекли: клиенты недоступны с момента пометки удаления
+	var clientIDs []string
+	if err := tx.Model(&models.OAuthClient{}).Where("owner_id = ?", id).Pluck("id", &clientIDs).Error; err != nil {
+		return err
+	}
+	if len(clientIDs) > 0 {
+		if _, err := revokeOAuthClientGrants(tx, clientIDs, time.Now()); err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", clientIDs).Delete(&models.OAuthClient{}).Error; err != nil {
+			return err
+		}
+	}
+
+	related := []interface{}{
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.RecoveryCode{},
+		&models.UserToken{},
+		&models.PersonalAccessToken{},
+		&models.OAuthAuthorizationCode{},
+	}
+	for _, model := range related {
+		if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Unscoped().Delete(&models.User{ID: id}).Error; err != nil {
+		return err
+	}
+	// Сервис заметок удалит данные пользователя, получив это событие
+	return recordUserEvent(tx, models.EVENT_USER_DELETED, id, "")
+}
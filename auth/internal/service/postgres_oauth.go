@@ -0,0 +1,187 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateOAuthClient сохраняет нового OAuth клиента
+func (p *DBService) CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error {
+	if client == nil || client.ID == "" || client.OwnerID <= 0 || client.Name == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Create(client).Error
+}
+
+// ReadOAuthClient находит OAuth клиента по client_id
+// Клиенты пользователей, помеченных удаленными, не находятся до восстановления учетной записи
+func (p *DBService) ReadOAuthClient(ctx context.Context, id string) (*models.OAuthClient, error) {
+	if id == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var client models.OAuthClient
+	err := p.db.WithContext(ctx).
+		Where("owner_id IN (?)", p.db.Model(&models.User{}).Select("id")).
+		First(&client, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// ListOAuthClients возвращает OAuth клиентов пользователя, начиная с новых
+func (p *DBService) ListOAuthClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	if ownerID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var clients []models.OAuthClient
+	err := p.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC").
+		Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// DeleteOAuthClient удаляет OAuth клиента вместе с кодами авторизации
+// и отзывает refresh токены и сессии, начатые через этого клиента
+// Возвращает ID отозванных сессий, чтобы отозвать и их access токены
+func (p *DBService) DeleteOAuthClient(ctx context.Context, id string) ([]string, error) {
+	if id == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var sessionIDs []string
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		revoked, err := revokeOAuthClientGrants(tx, []string{id}, time.Now())
+		if err != nil {
+			return err
+		}
+		sessionIDs = revoked
+
+		result := tx.Delete(&models.OAuthClient{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionIDs, nil
+}
+
+// revokeOAuthClientGrants завершает сессии и отзывает refresh токены, выданные клиентам clientIDs,
+// и удаляет их коды авторизации в рамках транзакции tx
+// Возвращает ID завершенных сессий
+func revokeOAuthClientGrants(tx *gorm.DB, clientIDs []string, now time.Time) ([]string, error) {
+	var sessions []models.Session
+	err := tx.Model(&sessions).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("client_id IN ? AND revoked_at IS NULL", clientIDs).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// ID семейства refresh токенов совпадает с ID сессии
+	var sessionIDs []string
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+	if len(sessionIDs) > 0 {
+		err := tx.Model(&models.RefreshToken{}).
+			Where("family_id IN ? AND revoked_at IS NULL", sessionIDs).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Where("client_id IN ?", clientIDs).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
+
+// CreateAuthCode сохраняет новый код авторизации
+func (p *DBService) CreateAuthCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	if code == nil || code.CodeHash == "" || code.ClientID == "" || code.UserID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Create(code).Error
+}
+
+// ConsumeAuthCode проверяет код авторизации функцией check, отмечает его использованным и возвращает его
+// Код блокируется на время транзакции, поэтому одновременный обмен одного кода невозможен,
+// а код, не прошедший проверку, остается неиспользованным
+// Повторно предъявленный код возвращается вместе с ErrAuthCodeReuse,
+// чтобы можно было отозвать сессию, начатую при первом обмене
+func (p *DBService) ConsumeAuthCode(ctx context.Context, codeHash string, check func(code *models.OAuthAuthorizationCode) error) (*models.OAuthAuthorizationCode, error) {
+	if codeHash == "" {
+		return nil, autherrors.ErrInvalidAuthCode
+	}
+
+	var code models.OAuthAuthorizationCode
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&code, "code_hash = ?", codeHash).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return autherrors.ErrInvalidAuthCode
+		}
+		if err != nil {
+			return err
+		}
+
+		if code.UsedAt != nil {
+			return autherrors.ErrAuthCodeReuse
+		}
+		if !time.Now().Before(code.ExpiresAt) {
+			return autherrors.ErrInvalidAuthCode
+		}
+		if check != nil {
+			if err := check(&code); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&code).Update("used_at", time.Now()).Error
+	})
+	if errors.Is(err, autherrors.ErrAuthCodeReuse) {
+		return &code, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// SetAuthCodeSession запоминает сессию, начатую при обмене кода авторизации
+func (p *DBService) SetAuthCodeSession(ctx context.Context, codeHash, sessionID string) error {
+	if codeHash == "" || sessionID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Model(&models.OAuthAuthorizationCode{}).
+		Where("code_hash = ?", codeHash).
+		Update("session_id", sessionID).Error
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"auth/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordUserEvent записывает событие пользователя в outbox в рамках транзакции tx
+func recordUserEvent(tx *gorm.DB, eventType string, userID int, username string) error {
+	event, err := models.NewUserEvent(eventType, userID, username)
+	if err != nil {
+		return err
+	}
+	return tx.Create(event).Error
+}
+
+// PublishOutbox передает функции publish до limit неопубликованных событий в порядке их записи
+// и отмечает их опубликованными, если publish завершилась без ошибки
+// События блокируются на время публикации (SKIP LOCKED), поэтому несколько экземпляров
+// сервиса не публикуют одно событие одновременно. Возвращает число опубликованных событий
+func (p *DBService) PublishOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error) {
+	if limit <= 0 || publish == nil {
+		return 0, gorm.ErrInvalidData
+	}
+
+	var events []models.OutboxEvent
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err := publish(ctx, events); err != nil {
+			return err
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("published_at", time.Now()).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+// PurgeOutbox удаляет события, опубликованные раньше before
+func (p *DBService) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	result := p.db.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", before).
+		Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"auth/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreatePAT сохраняет новый персональный токен доступа
+func (p *DBService) CreatePAT(ctx context.Context, token *models.PersonalAccessToken) error {
+	if token == nil || token.ID == "" || token.UserID <= 0 || token.TokenHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Create(token).Error
+}
+
+// ListPATs возвращает не отозванные персональные токены пользователя, начиная с новых
+func (p *DBService) ListPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var tokens []models.PersonalAccessToken
+	err := p.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// ListActivePATs возвращает все не отозванные и не истекшие персональные токены
+// пользователей, не помеченных удаленными
+// Используется для восстановления общего хранилища токенов при запуске сервиса
+func (p *DBService) ListActivePATs(ctx context.Context) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := p.db.WithContext(ctx).
+		Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Where("user_id IN (?)", p.db.Model(&models.User{}).Select("id")).
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// ReadPAT находит персональный токен по ID
+func (p *DBService) ReadPAT(ctx context.Context, id string) (*models.PersonalAccessToken, error) {
+	if id == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var token models.PersonalAccessToken
+	if err := p.db.WithContext(ctx).First(&token, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokePAT отзывает персональный токен
+func (p *DBService) RevokePAT(ctx context.Context, id string) error {
+	if id == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserPATs отзывает все персональные токены пользователя
+// Возвращает отозванные токены, чтобы их можно было удалить из общего хранилища
+func (p *DBService) RevokeUserPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var tokens []models.PersonalAccessToken
+	err := p.db.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
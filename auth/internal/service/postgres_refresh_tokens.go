@@ -0,0 +1,117 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateRefreshToken сохраняет новый refresh токен в базе данных
+func (p *DBService) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token == nil || token.ID == "" || token.FamilyID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	// Используем контекст для управления временем выполнения операции
+	return p.db.WithContext(ctx).Create(token).Error
+}
+
+// RotateRefreshToken заменяет refresh токен oldID на next
+// Новый токен наследует пользователя и семейство старого токена
+// Если старый токен уже был отозван или заменен, то это повторное использование:
+// отзываем все семейство и возвращаем ErrRefreshTokenReuse
+func (p *DBService) RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) error {
+	if oldID == "" || next == nil || next.ID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	var familyID string
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old models.RefreshToken
+		if err := tx.First(&old, "id = ?", oldID).Error; err != nil {
+			return err
+		}
+		familyID = old.FamilyID
+
+		// Помечаем старый токен замененным только если он еще не был отозван.
+		// Условие в WHERE делает операцию атомарной при конкурентных запросах
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", oldID).
+			Updates(map[string]any{"revoked_at": time.Now(), "replaced_by": next.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return autherrors.ErrRefreshTokenReuse
+		}
+
+		next.UserID = old.UserID
+		next.FamilyID = old.FamilyID
+		return tx.Create(next).Error
+	})
+
+	// Отзыв семейства выполняется вне транзакции, чтобы он не был отменен вместе с ней
+	if errors.Is(err, autherrors.ErrRefreshTokenReuse) {
+		if revokeErr := p.RevokeTokenFamily(ctx, familyID); revokeErr != nil {
+			return revokeErr
+		}
+	}
+	return err
+}
+
+// ReadRefreshToken находит refresh токен по его jti
+func (p *DBService) ReadRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	if id == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var token models.RefreshToken
+	if err := p.db.WithContext(ctx).First(&token, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokeTokenFamily отзывает все еще действующие refresh токены семейства
+// и завершает сессию с тем же ID
+func (p *DBService) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	if familyID == "" {
+		return gorm.ErrInvalidData
+	}
+
+	now := time.Now()
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeUserTokens отзывает все еще действующие refresh токены и сессии пользователя
+func (p *DBService) RevokeUserTokens(ctx context.Context, userID int) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	now := time.Now()
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"auth/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateSession сохраняет новую сессию в базе данных
+func (p *DBService) CreateSession(ctx context.Context, session *models.Session) error {
+	if session == nil || session.ID == "" || session.UserID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Create(session).Error
+}
+
+// TouchSession отмечает обновление токенов в рамках сессии
+func (p *DBService) TouchSession(ctx context.Context, id, userAgent, ip string, expiresAt time.Time) error {
+	if id == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"user_agent":        userAgent,
+			"ip":                ip,
+			"last_refreshed_at": time.Now(),
+			"expires_at":        expiresAt,
+		}).Error
+}
+
+// ReadSession находит сессию по ID
+func (p *DBService) ReadSession(ctx context.Context, id string) (*models.Session, error) {
+	if id == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var session models.Session
+	if err := p.db.WithContext(ctx).First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListSessions возвращает не отозванные и не истекшие сессии пользователя,
+// начиная с последней обновленной
+func (p *DBService) ListSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	if userID <= 0 {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var sessions []models.Session
+	err := p.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_refreshed_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetupTOTP сохраняет новый секрет TOTP (второй фактор остается выключенным до подтверждения)
+// и заменяет все коды восстановления пользователя новыми
+func (p *DBService) SetupTOTP(ctx context.Context, userID int, secret string, recoveryCodeHashes []string) error {
+	if userID <= 0 || secret == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{
+				"totp_secret":    secret,
+				"totp_enabled":   false,
+				"totp_last_step": 0,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(recoveryCodeHashes) == 0 {
+			return nil
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(recoveryCodeHashes))
+		for _, hash := range recoveryCodeHashes {
+			codes = append(codes, models.RecoveryCode{
+				UserID:   userID,
+				CodeHash: hash,
+			})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// EnableTOTP включает второй фактор, если секрет был сохранен
+func (p *DBService) EnableTOTP(ctx context.Context, userID int, step int64) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	result := p.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_secret <> ''", userID).
+		Updates(map[string]any{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return autherrors.ErrTOTPNotSetup
+	}
+	return nil
+}
+
+// DisableTOTP отключает второй фактор, удаляет секрет и коды восстановления
+func (p *DBService) DisableTOTP(ctx context.Context, userID int) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{
+				"totp_secret":    "",
+				"totp_enabled":   false,
+				"totp_last_step": 0,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ConsumeTOTPStep атомарно отмечает шаг времени использованным
+// Если шаг уже был использован (код перехвачен и отправлен повторно), возвращает ErrInvalidTOTPCode
+func (p *DBService) ConsumeTOTPStep(ctx context.Context, userID int, step int64) error {
+	if userID <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	// Второй фактор проверяется и при восстановлении удаленной учетной записи
+	result := p.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return autherrors.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// UseRecoveryCode атомарно отмечает код восстановления использованным
+// Если кода нет или он уже использован, возвращает ErrInvalidTOTPCode
+func (p *DBService) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	if userID <= 0 || codeHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	result := p.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return autherrors.ErrInvalidTOTPCode
+	}
+	return nil
+}
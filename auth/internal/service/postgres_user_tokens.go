@@ -0,0 +1,159 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadByEmail находит пользователя по адресу электронной почты
+func (p *DBService) ReadByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var user models.User
+	if err := p.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CreateUserToken сохраняет новый одноразовый токен
+// Ранее выданные неиспользованные токены того же назначения становятся недействительными
+func (p *DBService) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	if token == nil || token.UserID <= 0 || token.Purpose == "" || token.TokenHash == "" {
+		return gorm.ErrInvalidData
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// ReadUserToken находит неиспользованный и не истекший токен по назначению и хешу
+func (p *DBService) ReadUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	if purpose == "" || tokenHash == "" {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+
+	var token models.UserToken
+	err := p.db.WithContext(ctx).
+		Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, time.Now()).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ConfirmEmail использует токен подтверждения и отмечает адрес пользователя подтвержденным
+// Если после отправки письма пользователь сменил адрес, токен недействителен
+func (p *DBService) ConfirmEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, models.TOKEN_EMAIL_VERIFICATION, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserID, token.Email).
+			Update("email_verified", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return autherrors.ErrInvalidUserToken
+		}
+
+		return tx.First(&user, token.UserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ResetPassword использует токен сброса пароля и устанавливает новый пароль
+// Сброс также снимает требование смены временного пароля
+// Если после отправки письма пользователь сменил адрес, токен недействителен
+func (p *DBService) ResetPassword(ctx context.Context, tokenHash, newPassword string) (*models.User, error) {
+	if newPassword == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var user models.User
+	hashedPassword, err := user.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, models.TOKEN_PASSWORD_RESET, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserID, token.Email).
+			Updates(map[string]any{
+				"password":                hashedPassword,
+				"password_reset_required": false,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return autherrors.ErrInvalidUserToken
+		}
+
+		return tx.First(&user, token.UserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// consumeUserToken блокирует действующий токен в транзакции tx и отмечает его использованным
+// Блокировка строки гарантирует, что токен нельзя использовать дважды при параллельных запросах
+func consumeUserToken(tx *gorm.DB, purpose, tokenHash string) (*models.UserToken, error) {
+	if tokenHash == "" {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+
+	var token models.UserToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, time.Now()).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, autherrors.ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&token).Update("used_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
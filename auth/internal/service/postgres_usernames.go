@@ -0,0 +1,59 @@
+package service
+
+import (
+	autherrors "auth/internal/errors"
+	"auth/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RenameUser меняет имя пользователя, если с прошлой смены имени прошло не меньше cooldown
+// Уникальность проверяется по нормализованной форме имени, поэтому занятое имя
+// в другом регистре возвращает gorm.ErrDuplicatedKey
+// Если имя менялось недавно, возвращает пользователя вместе с ErrUsernameCooldown
+func (p *DBService) RenameUser(ctx context.Context, id int, username string, cooldown time.Duration) (*models.User, error) {
+	normalized := models.NormalizeUsername(username)
+	if id <= 0 || normalized == "" {
+		return nil, gorm.ErrInvalidData
+	}
+
+	var user models.User
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки не дает двум одновременным запросам обойти ограничение частоты
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Username == username {
+			return nil
+		}
+
+		now := time.Now()
+		if user.UsernameChangedAt != nil && now.Before(user.UsernameChangedAt.Add(cooldown)) {
+			return autherrors.ErrUsernameCooldown
+		}
+
+		err := tx.Model(&user).Updates(map[string]any{
+			"username":            username,
+			"username_normalized": normalized,
+			"username_changed_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		user.Username = username
+		user.UsernameNormalized = normalized
+		user.UsernameChangedAt = &now
+		return recordUserEvent(tx, models.EVENT_USER_UPDATED, user.ID, username)
+	})
+	if errors.Is(err, autherrors.ErrUsernameCooldown) {
+		return &user, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"auth/internal/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ListUsers возвращает страницу пользователей, отсортированных по ID,
+// и общее количество пользователей, подходящих под фильтр
+func (p *DBService) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, int64, error) {
+	if filter.Limit <= 0 || filter.Offset < 0 {
+		return nil, 0, gorm.ErrInvalidData
+	}
+
+	query := p.db.WithContext(ctx).Model(&models.User{})
+	if filter.Username != "" {
+		// Поиск по нормализованной форме не зависит от регистра во всех хранилищах
+		query = query.Where("username_normalized LIKE ?", "%"+models.NormalizeUsername(filter.Username)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.
+		Order("id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SetUserDisabled отключает или включает учетную запись пользователя
+func (p *DBService) SetUserDisabled(ctx context.Context, id int, disabled bool) error {
+	if id <= 0 {
+		return gorm.ErrInvalidData
+	}
+
+	result := p.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Update("disabled", disabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetUserRole назначает пользователю роль
+func (p *DBService) SetUserRole(ctx context.Context, id int, role string) error {
+	if id <= 0 || role == "" {
+		return gorm.ErrInvalidData
+	}
+
+	result := p.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ForcePasswordReset заменяет пароль пользователя временным
+// и требует его смены при следующем входе
+func (p *DBService) ForcePasswordReset(ctx context.Context, id int, tempPassword string) error {
+	if id <= 0 || tempPassword == "" {
+		return gorm.ErrInvalidData
+	}
+
+	var user models.User
+	hashedPassword, err := user.HashPassword(tempPassword)
+	if err != nil {
+		return err
+	}
+
+	result := p.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"password":                hashedPassword,
+			"password_reset_required": true,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
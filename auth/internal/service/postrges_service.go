@@ -3,8 +3,12 @@ package service
 import (
 	"auth/internal/config"
 	"auth/internal/database"
+	autherrors "auth/internal/errors"
 	"auth/internal/models"
 	"context"
+	"fmt"
+	jwtmanager "jwt_manager"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -21,10 +25,31 @@ type DBService struct {
 var _ Service = (*DBService)(nil)
 
 // NewService - конструктор для создания нового экземпляра Service
-// Он принимает конфигурацию и возвращает указатель на текущею реализацию
-// сервиса или ошибку, если она произошла
+// Он принимает конфигурацию и возвращает указатель на реализацию
+// сервиса для выбранного хранилища или ошибку, если она произошла
 func NewService(cfg *config.Config) (Service, error) {
-	db, err := database.NewDatabase(cfg, &models.User{})
+	switch cfg.StoreBackend {
+	case config.STORE_POSTGRES:
+		db, err := database.NewDatabase(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &DBService{
+			db: db,
+		}, nil
+	case config.STORE_SQLITE:
+		return NewSQLiteService(cfg.SQLitePath)
+	case config.STORE_MEMORY:
+		return NewMemoryService(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", autherrors.ErrUnknownStoreBackend, cfg.StoreBackend)
+	}
+}
+
+// NewSQLiteService создает реализацию Service поверх базы SQLite по пути path
+// Используется для локального запуска и тестов без контейнера PostgreSQL
+func NewSQLiteService(path string) (Service, error) {
+	db, err := database.NewSQLiteDatabase(path, schemaModels...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,6 +58,19 @@ func NewService(cfg *config.Config) (Service, error) {
 	}, nil
 }
 
+// schemaModels - модели, хранящиеся в базе данных сервиса
+var schemaModels = []any{
+	&models.User{},
+	&models.RefreshToken{},
+	&models.Session{},
+	&models.RecoveryCode{},
+	&models.UserToken{},
+	&models.PersonalAccessToken{},
+	&models.OAuthClient{},
+	&models.OAuthAuthorizationCode{},
+	&models.OutboxEvent{},
+}
+
 // Create создает нового пользователя в базе данных
 // Он принимает контекст и указатель на модель User
 // Возвращает созданного пользователя или ошибку, если она произошла
@@ -47,9 +85,26 @@ func (p *DBService) Create(ctx context.Context, user *models.User) (*models.User
 		return nil, err
 	}
 	user.Password = hashedPassword
+	// Новый пользователь всегда получает роль по умолчанию
+	user.Role = jwtmanager.ROLE_USER
+	user.Scopes = ""
+	user.Disabled = false
+	user.PasswordResetRequired = false
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.EmailVerified = false
+	user.UsernameNormalized = models.NormalizeUsername(user.Username)
+	user.UsernameChangedAt = nil
 
-	// Используем контекст для управления временем выполнения операции
-	if err := p.db.WithContext(ctx).Create(user).Error; err != nil {
+	// Пользователь и событие о его создании сохраняются в одной транзакции
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return recordUserEvent(tx, models.EVENT_USER_CREATED, user.ID, user.Username)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -57,18 +112,40 @@ func (p *DBService) Create(ctx context.Context, user *models.User) (*models.User
 	return user, nil
 }
 
-// Delete Удаляет пользователя из базы данных по ID
+// Delete помечает пользователя удаленным (мягкое удаление)
+// Сессии, refresh токены и одноразовые ссылки пользователя отзываются сразу,
+// а сама учетная запись удаляется окончательно в PurgeDeletedUsers после срока ожидания
 func (p *DBService) Delete(ctx context.Context, id int) error {
 	if id <= 0 {
 		return gorm.ErrRecordNotFound
 	}
 
-	// Используем контекст для управления временем выполнения операции
-	if err := p.db.WithContext(ctx).Delete(&models.User{ID: id}).Error; err != nil {
-		return err
-	}
-
-	return nil
+	now := time.Now()
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND used_at IS NULL", id).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", id).Delete(&models.OAuthAuthorizationCode{}).Error
+	})
 }
 
 // Read - находит пользователя по ID в базе данных
@@ -86,12 +163,16 @@ func (p *DBService) Read(ctx context.Context, id int) (*models.User, error) {
 	return &user, nil
 }
 
-// Update - обновляет данные пользователя в базе данных
+// Update обновляет пароль и адрес электронной почты пользователя
+// Меняются только переданные поля: роль, статус, второй фактор и имя пользователя
+// меняются отдельными методами и не перезаписываются при обновлении профиля
 func (p *DBService) Update(ctx context.Context, user *models.User) error {
 	if user == nil || user.ID <= 0 {
 		return gorm.ErrInvalidData
 	}
 
+	updates := make(map[string]any)
+
 	// Если пароль не пустой, значит он был изменен и нужно его хешировать
 	if user.Password != "" {
 		hashedPassword, err := user.HashPassword(user.Password)
@@ -99,14 +180,42 @@ func (p *DBService) Update(ctx context.Context, user *models.User) error {
 			return err
 		}
 		user.Password = hashedPassword
+		// Смена пароля снимает требование сброса временного пароля
+		user.PasswordResetRequired = false
+		updates["password"] = hashedPassword
+		updates["password_reset_required"] = false
 	}
 
-	// Используем контекст для управления временем выполнения операции
-	if err := p.db.WithContext(ctx).Save(user).Error; err != nil {
-		return err
+	// Новый адрес электронной почты требует повторного подтверждения
+	if user.Email != nil {
+		user.EmailVerified = false
+		updates["email"] = *user.Email
+		updates["email_verified"] = false
 	}
 
-	return nil
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.User
+		if err := tx.Select("id", "username", "email").First(&current, user.ID).Error; err != nil {
+			return err
+		}
+		user.Username = current.Username
+		if len(updates) == 0 {
+			return nil
+		}
+		// Ссылки сброса пароля, отправленные на прежний адрес, перестают действовать
+		if user.Email != nil && (current.Email == nil || *current.Email != *user.Email) {
+			err := tx.Model(&models.UserToken{}).
+				Where("user_id = ? AND purpose = ? AND used_at IS NULL", current.ID, models.TOKEN_PASSWORD_RESET).
+				Update("used_at", time.Now()).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&current).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordUserEvent(tx, models.EVENT_USER_UPDATED, current.ID, current.Username)
+	})
 }
 
 // ReadByUsername - находит пользователя по имени пользователя в базе данных
@@ -117,7 +226,8 @@ func (p *DBService) ReadByUsername(ctx context.Context, username string) (*model
 
 	var user models.User
 	// Используем контекст для управления временем выполнения операции
-	if err := p.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	// Имя сравнивается в нормализованной форме, без учета регистра
+	if err := p.db.WithContext(ctx).Where("username_normalized = ?", models.NormalizeUsername(username)).First(&user).Error; err != nil {
 		return nil, err
 	}
 
@@ -131,8 +241,8 @@ func (p *DBService) Authenticate(ctx context.Context, username, password string)
 	}
 
 	var user models.User
-	// Ищем пользователя по имени пользователя
-	if err := p.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	// Ищем пользователя по имени пользователя, включая ожидающих удаления
+	if err := p.db.WithContext(ctx).Unscoped().Where("username_normalized = ?", models.NormalizeUsername(username)).First(&user).Error; err != nil {
 		return nil, err
 	}
 
@@ -141,6 +251,16 @@ func (p *DBService) Authenticate(ctx context.Context, username, password string)
 		return nil, gorm.ErrRecordNotFound // Возвращаем ошибку "не найден" для безопасности
 	}
 
+	// Удаленную учетную запись можно только восстановить, пользователь возвращается вместе с ошибкой
+	if user.DeletedAt.Valid {
+		return &user, autherrors.ErrUserPendingDeletion
+	}
+
+	// Отключенный пользователь не может войти даже с верным паролем
+	if user.Disabled {
+		return nil, autherrors.ErrUserDisabled
+	}
+
 	return &user, nil
 }
 
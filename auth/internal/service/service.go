@@ -3,6 +3,7 @@ package service
 import (
 	"auth/internal/models"
 	"context"
+	"time"
 )
 
 // Service - интерфейс для управления пользователями
@@ -14,14 +15,111 @@ type Service interface {
 	Create(ctx context.Context, user *models.User) (*models.User, error)
 	// Находит пользователя по ID
 	Read(ctx context.Context, id int) (*models.User, error)
-	// Обновляет данные пользователя
+	// Обновляет пароль и адрес электронной почты пользователя
 	Update(ctx context.Context, user *models.User) error
-	// Удаляет пользователя по ID
+	// Меняет имя пользователя, если с прошлой смены имени прошло не меньше cooldown
+	// Если имя менялось недавно, возвращает пользователя вместе с ErrUsernameCooldown
+	RenameUser(ctx context.Context, id int, username string, cooldown time.Duration) (*models.User, error)
+	// Помечает пользователя удаленным и завершает его сессии
 	Delete(ctx context.Context, id int) error
+	// Восстанавливает пользователя, помеченного удаленным
+	RestoreUser(ctx context.Context, id int) error
+	// Окончательно удаляет до limit пользователей, помеченных удаленными раньше before,
+	// вместе с их персональными токенами и OAuth клиентами
+	PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int, error)
 	// Аутентифицирует пользователя по имени пользователя и паролю
+	// Для учетной записи, ожидающей удаления, возвращает пользователя вместе с ErrUserPendingDeletion
 	Authenticate(ctx context.Context, username, password string) (*models.User, error)
 	// Находит пользователя по имени пользователя
 	ReadByUsername(ctx context.Context, username string) (*models.User, error)
+	// Сохраняет новый refresh токен
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// Заменяет refresh токен oldID на next в рамках одного семейства
+	// При повторном использовании уже замененного токена отзывает все семейство
+	RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) error
+	// Находит refresh токен по его jti
+	ReadRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error)
+	// Отзывает все refresh токены семейства и завершает соответствующую сессию
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	// Отзывает все refresh токены и сессии пользователя
+	RevokeUserTokens(ctx context.Context, userID int) error
+	// Создает новую сессию
+	CreateSession(ctx context.Context, session *models.Session) error
+	// Обновляет время последнего обновления, срок действия и данные клиента сессии
+	TouchSession(ctx context.Context, id, userAgent, ip string, expiresAt time.Time) error
+	// Находит сессию по ID
+	ReadSession(ctx context.Context, id string) (*models.Session, error)
+	// Возвращает активные сессии пользователя
+	ListSessions(ctx context.Context, userID int) ([]models.Session, error)
+	// Возвращает страницу пользователей по фильтру и общее число подходящих пользователей
+	ListUsers(ctx context.Context, filter UserFilter) ([]models.User, int64, error)
+	// Отключает или включает учетную запись пользователя
+	SetUserDisabled(ctx context.Context, id int, disabled bool) error
+	// Назначает пользователю роль
+	SetUserRole(ctx context.Context, id int, role string) error
+	// Устанавливает временный пароль и требует его смены при следующем входе
+	ForcePasswordReset(ctx context.Context, id int, tempPassword string) error
+	// Сохраняет новый, еще не подтвержденный секрет TOTP и заменяет коды восстановления
+	SetupTOTP(ctx context.Context, userID int, secret string, recoveryCodeHashes []string) error
+	// Включает второй фактор после подтверждения кодом с шагом времени step
+	EnableTOTP(ctx context.Context, userID int, step int64) error
+	// Отключает второй фактор и удаляет коды восстановления
+	DisableTOTP(ctx context.Context, userID int) error
+	// Отмечает шаг времени использованным; код того же или более раннего шага повторно не принимается
+	ConsumeTOTPStep(ctx context.Context, userID int, step int64) error
+	// Отмечает код восстановления использованным
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	// Находит пользователя по адресу электронной почты
+	ReadByEmail(ctx context.Context, email string) (*models.User, error)
+	// Сохраняет одноразовый токен, отменяя ранее выданные токены того же назначения
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	// Находит действующий одноразовый токен по назначению и хешу
+	ReadUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	// Использует токен подтверждения и отмечает адрес электронной почты подтвержденным
+	ConfirmEmail(ctx context.Context, tokenHash string) (*models.User, error)
+	// Использует токен сброса и устанавливает новый пароль
+	ResetPassword(ctx context.Context, tokenHash, newPassword string) (*models.User, error)
+	// Сохраняет новый персональный токен доступа
+	CreatePAT(ctx context.Context, token *models.PersonalAccessToken) error
+	// Возвращает не отозванные персональные токены пользователя
+	ListPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error)
+	// Возвращает все действующие персональные токены пользователей, не помеченных удаленными
+	ListActivePATs(ctx context.Context) ([]models.PersonalAccessToken, error)
+	// Находит персональный токен по ID
+	ReadPAT(ctx context.Context, id string) (*models.PersonalAccessToken, error)
+	// Отзывает персональный токен
+	RevokePAT(ctx context.Context, id string) error
+	// Отзывает все персональные токены пользователя и возвращает их
+	RevokeUserPATs(ctx context.Context, userID int) ([]models.PersonalAccessToken, error)
+	// Сохраняет нового OAuth клиента
+	CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error
+	// Находит OAuth клиента по client_id
+	// Клиенты пользователей, помеченных удаленными, не находятся до восстановления учетной записи
+	ReadOAuthClient(ctx context.Context, id string) (*models.OAuthClient, error)
+	// Возвращает OAuth клиентов, зарегистрированных пользователем
+	ListOAuthClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error)
+	// Удаляет OAuth клиента, его коды авторизации и завершает выданные ему сессии
+	// Возвращает ID завершенных сессий
+	DeleteOAuthClient(ctx context.Context, id string) ([]string, error)
+	// Сохраняет новый код авторизации
+	CreateAuthCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	// Проверяет код авторизации функцией check и отмечает его использованным
+	// Код, не прошедший проверку, остается неиспользованным, а ошибка check возвращается как есть
+	// При повторном использовании возвращает код вместе с ErrAuthCodeReuse
+	ConsumeAuthCode(ctx context.Context, codeHash string, check func(code *models.OAuthAuthorizationCode) error) (*models.OAuthAuthorizationCode, error)
+	// Запоминает сессию, начатую при обмене кода авторизации
+	SetAuthCodeSession(ctx context.Context, codeHash, sessionID string) error
+	// Публикует очередную порцию событий outbox функцией publish и отмечает их опубликованными
+	PublishOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error)
+	// Удаляет события outbox, опубликованные раньше before
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
 	// Close закрывает соединение с базой данных
 	Close() error
 }
+
+// UserFilter - параметры выборки пользователей для администрирования
+type UserFilter struct {
+	Username string // Подстрока имени пользователя (без учета регистра)
+	Limit    int    // Максимальное число пользователей на странице
+	Offset   int    // Число пропускаемых пользователей
+}
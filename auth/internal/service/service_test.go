@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/models"
+	"auth/internal/service"
+	"auth/internal/service/servicetest"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryService(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T) service.Service {
+		return service.NewMemoryService()
+	})
+}
+
+// TestMemoryPublishOutboxUnlocked проверяет, что во время публикации событий
+// хранилище в памяти доступно для других операций
+func TestMemoryPublishOutboxUnlocked(t *testing.T) {
+	ctx := context.Background()
+	s := service.NewMemoryService()
+	user, err := s.Create(ctx, &models.User{Username: "alice", Password: "Secret-password-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.PublishOutbox(ctx, 10, func(ctx context.Context, events []models.OutboxEvent) error {
+			// Публикация обращается к сети; другие операции не должны ее ждать
+			_, err := s.Read(ctx, user.ID)
+			return err
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("PublishOutbox: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PublishOutbox держит блокировку хранилища во время публикации")
+	}
+
+	// Опубликованные события не публикуются повторно
+	count, err := s.PublishOutbox(ctx, 10, func(ctx context.Context, events []models.OutboxEvent) error {
+		return nil
+	})
+	if err != nil || count != 0 {
+		t.Errorf("повторный PublishOutbox = %d, %v", count, err)
+	}
+}
+
+func TestSQLiteService(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T) service.Service {
+		s, err := service.NewSQLiteService(filepath.Join(t.TempDir(), "auth.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteService: %v", err)
+		}
+		return s
+	})
+}
+
+// TestPostgresService проверяет реализацию для PostgreSQL, если задан AUTH_TEST_POSTGRES_DSN
+// Все таблицы базы очищаются перед каждым подтестом, поэтому нельзя указывать рабочую базу
+func TestPostgresService(t *testing.T) {
+	dsn := os.Getenv("AUTH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("AUTH_TEST_POSTGRES_DSN не задан")
+	}
+
+	cfg := &config.Config{
+		StoreBackend:  config.STORE_POSTGRES,
+		DBDSN:         dsn,
+		DBAutoMigrate: true,
+		Timeout:       10,
+	}
+	servicetest.Run(t, func(t *testing.T) service.Service {
+		s, err := service.NewService(cfg)
+		if err != nil {
+			t.Fatalf("NewService: %v", err)
+		}
+		truncatePostgres(t, cfg)
+		return s
+	})
+}
+
+// truncatePostgres очищает таблицы сервиса и сбрасывает последовательности ID
+func truncatePostgres(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db, err := database.Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	err = db.WithContext(ctx).Exec(`TRUNCATE users, refresh_tokens, sessions, recovery_codes, user_tokens,
+    personal_access_tokens, o_auth_clients, o_auth_authorization_codes, outbox_events RESTART IDENTITY`).Error
+	if err != nil {
+		t.Fatalf("TRUNCATE: %v", err)
+	}
+}
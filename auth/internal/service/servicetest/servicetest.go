@@ -0,0 +1,843 @@
+// Package servicetest содержит общий набор проверок реализаций service.Service
+// Каждое хранилище (PostgreSQL, SQLite, память) должно проходить один и тот же набор,
+// чтобы обработчики вели себя одинаково независимо от выбранного STORE_BACKEND
+package servicetest
+
+import (
+	"auth/internal/models"
+	"auth/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	autherrors "auth/internal/errors"
+	jwtmanager "jwt_manager"
+
+	"gorm.io/gorm"
+)
+
+// Factory создает пустое хранилище для одного подтеста
+// Хранилище закрывается набором после завершения подтеста
+type Factory func(t *testing.T) service.Service
+
+// Run запускает набор проверок для хранилища, создаваемого newService
+func Run(t *testing.T, newService Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s service.Service)
+	}{
+		{"Users", testUsers},
+		{"UpdateUser", testUpdateUser},
+		{"RenameUser", testRenameUser},
+		{"ListUsers", testListUsers},
+		{"Admin", testAdmin},
+		{"SoftDelete", testSoftDelete},
+		{"PurgeDeletedUsers", testPurgeDeletedUsers},
+		{"RefreshTokens", testRefreshTokens},
+		{"Sessions", testSessions},
+		{"TOTP", testTOTP},
+		{"UserTokens", testUserTokens},
+		{"PATs", testPATs},
+		{"OAuth", testOAuth},
+		{"Outbox", testOutbox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			tt.fn(t, s)
+		})
+	}
+}
+
+// testPassword - пароль всех пользователей, создаваемых набором
+const testPassword = "password123"
+
+// createUser создает пользователя с паролем testPassword
+func createUser(t *testing.T, s service.Service, username string) *models.User {
+	t.Helper()
+	user, err := s.Create(context.Background(), &models.User{Username: username, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Create(%q): %v", username, err)
+	}
+	if user.ID <= 0 {
+		t.Fatalf("Create(%q): ID не назначен", username)
+	}
+	return user
+}
+
+// expectError проверяет, что err соответствует target
+func expectError(t *testing.T, op string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: ожидалась ошибка %v, получено %v", op, target, err)
+	}
+}
+
+// expectNoError останавливает подтест при ошибке
+func expectNoError(t *testing.T, op string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", op, err)
+	}
+}
+
+// strPtr возвращает указатель на копию s
+func strPtr(s string) *string {
+	return &s
+}
+
+func testUsers(t *testing.T, s service.Service) {
+	ctx := context.Background()
+
+	user, err := s.Create(ctx, &models.User{
+		Username: "alice",
+		Password: testPassword,
+		Role:     "admin",
+		Scopes:   "notes:read",
+		Email:    strPtr("alice@example.com"),
+	})
+	expectNoError(t, "Create", err)
+	if user.Role != "user" || user.Scopes != "" {
+		t.Fatalf("Create: роль и области доступа должны сбрасываться, получено %q, %q", user.Role, user.Scopes)
+	}
+	if user.Password == testPassword {
+		t.Fatal("Create: пароль сохранен без хеширования")
+	}
+
+	_, err = s.Create(ctx, &models.User{Username: "alice", Password: testPassword})
+	expectError(t, "Create с занятым именем", err, gorm.ErrDuplicatedKey)
+	// Имена сравниваются после NFKC и свертки регистра: полноширинные буквы сводятся к обычным
+	_, err = s.Create(ctx, &models.User{Username: "ＡＬＩＣＥ", Password: testPassword})
+	expectError(t, "Create с занятым именем в другом регистре", err, gorm.ErrDuplicatedKey)
+	_, err = s.Create(ctx, &models.User{Username: "alice2", Password: testPassword, Email: strPtr("alice@example.com")})
+	expectError(t, "Create с занятым адресом", err, gorm.ErrDuplicatedKey)
+
+	read, err := s.Read(ctx, user.ID)
+	expectNoError(t, "Read", err)
+	if read.Username != "alice" || read.Email == nil || *read.Email != "alice@example.com" {
+		t.Fatalf("Read: неожиданный пользователь %+v", read)
+	}
+	_, err = s.Read(ctx, user.ID+1000)
+	expectError(t, "Read несуществующего", err, gorm.ErrRecordNotFound)
+
+	byName, err := s.ReadByUsername(ctx, "ALICE")
+	expectNoError(t, "ReadByUsername", err)
+	if byName.ID != user.ID || byName.Username != "alice" {
+		t.Fatalf("ReadByUsername: ID %d, ожидался %d", byName.ID, user.ID)
+	}
+	_, err = s.ReadByUsername(ctx, "bob")
+	expectError(t, "ReadByUsername несуществующего", err, gorm.ErrRecordNotFound)
+
+	byEmail, err := s.ReadByEmail(ctx, "alice@example.com")
+	expectNoError(t, "ReadByEmail", err)
+	if byEmail.ID != user.ID {
+		t.Fatalf("ReadByEmail: ID %d, ожидался %d", byEmail.ID, user.ID)
+	}
+	_, err = s.ReadByEmail(ctx, "bob@example.com")
+	expectError(t, "ReadByEmail несуществующего", err, gorm.ErrRecordNotFound)
+
+	authenticated, err := s.Authenticate(ctx, "Alice", testPassword)
+	expectNoError(t, "Authenticate", err)
+	if authenticated.ID != user.ID {
+		t.Fatalf("Authenticate: ID %d, ожидался %d", authenticated.ID, user.ID)
+	}
+	_, err = s.Authenticate(ctx, "alice", "wrong-password")
+	expectError(t, "Authenticate с неверным паролем", err, gorm.ErrRecordNotFound)
+	_, err = s.Authenticate(ctx, "bob", testPassword)
+	expectError(t, "Authenticate несуществующего", err, gorm.ErrRecordNotFound)
+}
+
+func testUpdateUser(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: bob.ID, Email: strPtr("bob@example.com")}))
+
+	err := s.Update(ctx, &models.User{ID: alice.ID, Email: strPtr("bob@example.com")})
+	expectError(t, "Update на занятый адрес", err, gorm.ErrDuplicatedKey)
+
+	// Имя пользователя не меняется через Update
+	update := &models.User{ID: alice.ID, Username: "carol", Password: "new-password", Email: strPtr("alice@example.com")}
+	expectNoError(t, "Update", s.Update(ctx, update))
+	if update.Username != "alice" {
+		t.Fatalf("Update: в пользователе имя %q, ожидалось текущее имя", update.Username)
+	}
+
+	updated, err := s.Read(ctx, alice.ID)
+	expectNoError(t, "Read", err)
+	if updated.Username != "alice" || updated.Email == nil || *updated.Email != "alice@example.com" || updated.EmailVerified {
+		t.Fatalf("Update: неожиданный пользователь %+v", updated)
+	}
+	if _, err := s.Authenticate(ctx, "alice", "new-password"); err != nil {
+		t.Fatalf("Authenticate с новым паролем: %v", err)
+	}
+
+	// Без нового пароля прежний пароль сохраняется
+	expectNoError(t, "Update без пароля", s.Update(ctx, &models.User{ID: alice.ID, Email: strPtr("alice@example.org")}))
+	if _, err := s.Authenticate(ctx, "alice", "new-password"); err != nil {
+		t.Fatalf("Authenticate после Update без пароля: %v", err)
+	}
+
+	err = s.Update(ctx, &models.User{ID: alice.ID + 1000, Password: "new-password"})
+	expectError(t, "Update несуществующего", err, gorm.ErrRecordNotFound)
+}
+
+func testRenameUser(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	alice := createUser(t, s, "alice")
+	createUser(t, s, "bob")
+
+	_, err := s.RenameUser(ctx, alice.ID, "BOB", time.Hour)
+	expectError(t, "RenameUser на занятое имя в другом регистре", err, gorm.ErrDuplicatedKey)
+
+	// Первая смена имени не ограничена
+	renamed, err := s.RenameUser(ctx, alice.ID, "Alice", time.Hour)
+	expectNoError(t, "RenameUser", err)
+	if renamed.Username != "Alice" || renamed.UsernameChangedAt == nil {
+		t.Fatalf("RenameUser: неожиданный пользователь %+v", renamed)
+	}
+	read, err := s.ReadByUsername(ctx, "alice")
+	expectNoError(t, "ReadByUsername", err)
+	if read.ID != alice.ID || read.Username != "Alice" {
+		t.Fatalf("ReadByUsername после RenameUser: неожиданный пользователь %+v", read)
+	}
+
+	// То же имя не считается сменой
+	_, err = s.RenameUser(ctx, alice.ID, "Alice", time.Hour)
+	expectNoError(t, "RenameUser на текущее имя", err)
+
+	limited, err := s.RenameUser(ctx, alice.ID, "carol", time.Hour)
+	expectError(t, "RenameUser до истечения срока", err, autherrors.ErrUsernameCooldown)
+	if limited == nil || limited.UsernameChangedAt == nil {
+		t.Fatalf("RenameUser до истечения срока: неожиданный пользователь %+v", limited)
+	}
+
+	renamed, err = s.RenameUser(ctx, alice.ID, "carol", 0)
+	expectNoError(t, "RenameUser без ограничения", err)
+	if renamed.Username != "carol" {
+		t.Fatalf("RenameUser: имя %q, ожидалось carol", renamed.Username)
+	}
+	if _, err := s.Authenticate(ctx, "Carol", testPassword); err != nil {
+		t.Fatalf("Authenticate с новым именем: %v", err)
+	}
+	_, err = s.ReadByUsername(ctx, "alice")
+	expectError(t, "ReadByUsername прежнего имени", err, gorm.ErrRecordNotFound)
+
+	_, err = s.RenameUser(ctx, alice.ID+1000, "dave", 0)
+	expectError(t, "RenameUser несуществующего", err, gorm.ErrRecordNotFound)
+}
+
+func testListUsers(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "bob", "malice"} {
+		createUser(t, s, name)
+	}
+
+	users, total, err := s.ListUsers(ctx, service.UserFilter{Username: "ALI", Limit: 10})
+	expectNoError(t, "ListUsers", err)
+	if total != 2 || len(users) != 2 || users[0].Username != "Alice" || users[1].Username != "malice" {
+		t.Fatalf("ListUsers: получено %d из %d: %+v", len(users), total, users)
+	}
+
+	users, total, err = s.ListUsers(ctx, service.UserFilter{Limit: 1, Offset: 1})
+	expectNoError(t, "ListUsers со смещением", err)
+	if total != 3 || len(users) != 1 || users[0].Username != "bob" {
+		t.Fatalf("ListUsers со смещением: получено %d из %d: %+v", len(users), total, users)
+	}
+
+	_, _, err = s.ListUsers(ctx, service.UserFilter{Limit: 0})
+	expectError(t, "ListUsers без лимита", err, gorm.ErrInvalidData)
+}
+
+func testAdmin(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+
+	expectNoError(t, "SetUserDisabled", s.SetUserDisabled(ctx, user.ID, true))
+	_, err := s.Authenticate(ctx, "alice", testPassword)
+	expectError(t, "Authenticate отключенного", err, autherrors.ErrUserDisabled)
+	expectNoError(t, "SetUserDisabled(false)", s.SetUserDisabled(ctx, user.ID, false))
+	expectError(t, "SetUserDisabled несуществующего", s.SetUserDisabled(ctx, user.ID+1000, true), gorm.ErrRecordNotFound)
+
+	expectNoError(t, "SetUserRole", s.SetUserRole(ctx, user.ID, jwtmanager.ROLE_ADMIN))
+	promoted, err := s.Read(ctx, user.ID)
+	expectNoError(t, "Read после SetUserRole", err)
+	if promoted.Role != jwtmanager.ROLE_ADMIN {
+		t.Fatalf("SetUserRole: роль %q, ожидалась %q", promoted.Role, jwtmanager.ROLE_ADMIN)
+	}
+	expectError(t, "SetUserRole несуществующего", s.SetUserRole(ctx, user.ID+1000, jwtmanager.ROLE_ADMIN), gorm.ErrRecordNotFound)
+	expectError(t, "SetUserRole без роли", s.SetUserRole(ctx, user.ID, ""), gorm.ErrInvalidData)
+
+	expectNoError(t, "ForcePasswordReset", s.ForcePasswordReset(ctx, user.ID, "temporary"))
+	authenticated, err := s.Authenticate(ctx, "alice", "temporary")
+	expectNoError(t, "Authenticate с временным паролем", err)
+	if !authenticated.PasswordResetRequired {
+		t.Fatal("ForcePasswordReset: не установлено требование смены пароля")
+	}
+	expectError(t, "ForcePasswordReset несуществующего", s.ForcePasswordReset(ctx, user.ID+1000, "temporary"), gorm.ErrRecordNotFound)
+
+	// Смена пароля снимает требование сброса
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: user.ID, Password: testPassword}))
+	read, err := s.Read(ctx, user.ID)
+	expectNoError(t, "Read", err)
+	if read.PasswordResetRequired {
+		t.Fatal("Update: требование смены пароля не снято")
+	}
+}
+
+func testSoftDelete(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+
+	expiresAt := time.Now().Add(time.Hour)
+	expectNoError(t, "CreateSession", s.CreateSession(ctx, &models.Session{ID: "session-1", UserID: user.ID, ExpiresAt: expiresAt}))
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "refresh-1", UserID: user.ID, FamilyID: "session-1", ExpiresAt: expiresAt}))
+	expectNoError(t, "CreatePAT", s.CreatePAT(ctx, &models.PersonalAccessToken{ID: "pat-1", UserID: user.ID, Name: "ci", TokenHash: "hash-1"}))
+	expectNoError(t, "CreateOAuthClient", s.CreateOAuthClient(ctx, &models.OAuthClient{ID: "client-1", OwnerID: user.ID, Name: "client"}))
+
+	expectNoError(t, "Delete", s.Delete(ctx, user.ID))
+	expectError(t, "Delete повторно", s.Delete(ctx, user.ID), gorm.ErrRecordNotFound)
+
+	_, err := s.Read(ctx, user.ID)
+	expectError(t, "Read удаленного", err, gorm.ErrRecordNotFound)
+	_, err = s.ReadByUsername(ctx, "alice")
+	expectError(t, "ReadByUsername удаленного", err, gorm.ErrRecordNotFound)
+
+	pending, err := s.Authenticate(ctx, "alice", testPassword)
+	expectError(t, "Authenticate удаленного", err, autherrors.ErrUserPendingDeletion)
+	if pending == nil || pending.ID != user.ID || !pending.DeletedAt.Valid {
+		t.Fatalf("Authenticate удаленного: неожиданный пользователь %+v", pending)
+	}
+
+	// Имя удаленного пользователя остается занятым до окончательного удаления
+	_, err = s.Create(ctx, &models.User{Username: "alice", Password: testPassword})
+	expectError(t, "Create с именем удаленного", err, gorm.ErrDuplicatedKey)
+
+	session, err := s.ReadSession(ctx, "session-1")
+	expectNoError(t, "ReadSession", err)
+	if session.RevokedAt == nil {
+		t.Fatal("Delete: сессия не отозвана")
+	}
+	token, err := s.ReadRefreshToken(ctx, "refresh-1")
+	expectNoError(t, "ReadRefreshToken", err)
+	if token.RevokedAt == nil {
+		t.Fatal("Delete: refresh токен не отозван")
+	}
+
+	// Персональные токены и OAuth клиенты не удаляются, но недоступны до восстановления
+	pat, err := s.ReadPAT(ctx, "pat-1")
+	expectNoError(t, "ReadPAT", err)
+	if pat.RevokedAt != nil {
+		t.Fatal("Delete: персональный токен отозван")
+	}
+	active, err := s.ListActivePATs(ctx)
+	expectNoError(t, "ListActivePATs", err)
+	if len(active) != 0 {
+		t.Fatalf("ListActivePATs удаленного: получено %+v", active)
+	}
+	_, err = s.ReadOAuthClient(ctx, "client-1")
+	expectError(t, "ReadOAuthClient удаленного", err, gorm.ErrRecordNotFound)
+
+	expectNoError(t, "RestoreUser", s.RestoreUser(ctx, user.ID))
+	expectError(t, "RestoreUser повторно", s.RestoreUser(ctx, user.ID), gorm.ErrRecordNotFound)
+	if _, err := s.Authenticate(ctx, "alice", testPassword); err != nil {
+		t.Fatalf("Authenticate восстановленного: %v", err)
+	}
+	active, err = s.ListActivePATs(ctx)
+	expectNoError(t, "ListActivePATs", err)
+	if len(active) != 1 || active[0].ID != "pat-1" {
+		t.Fatalf("ListActivePATs восстановленного: получено %+v", active)
+	}
+	if _, err := s.ReadOAuthClient(ctx, "client-1"); err != nil {
+		t.Fatalf("ReadOAuthClient восстановленного: %v", err)
+	}
+}
+
+func testPurgeDeletedUsers(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	deleted := createUser(t, s, "alice")
+	kept := createUser(t, s, "bob")
+	expectNoError(t, "CreatePAT", s.CreatePAT(ctx, &models.PersonalAccessToken{ID: "pat-1", UserID: deleted.ID, Name: "ci", TokenHash: "hash-1"}))
+	// Другой пользователь вошел через OAuth клиента удаляемого пользователя
+	expiresAt := time.Now().Add(time.Hour)
+	expectNoError(t, "CreateOAuthClient", s.CreateOAuthClient(ctx, &models.OAuthClient{ID: "client-1", OwnerID: deleted.ID, Name: "client"}))
+	expectNoError(t, "CreateSession", s.CreateSession(ctx, &models.Session{ID: "oauth-session", UserID: kept.ID, ClientID: "client-1", ExpiresAt: expiresAt}))
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "oauth-token", UserID: kept.ID, FamilyID: "oauth-session", ExpiresAt: expiresAt}))
+	expectNoError(t, "Delete", s.Delete(ctx, deleted.ID))
+
+	_, err := s.PurgeDeletedUsers(ctx, time.Now(), 0)
+	expectError(t, "PurgeDeletedUsers без лимита", err, gorm.ErrInvalidData)
+
+	// Срок ожидания еще не истек
+	purged, err := s.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour), 10)
+	expectNoError(t, "PurgeDeletedUsers", err)
+	if purged != 0 {
+		t.Fatalf("PurgeDeletedUsers до истечения срока: удалено %d", purged)
+	}
+
+	purged, err = s.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour), 10)
+	expectNoError(t, "PurgeDeletedUsers", err)
+	if purged != 1 {
+		t.Fatalf("PurgeDeletedUsers: удалено %d, ожидалось 1", purged)
+	}
+	if _, err := s.Authenticate(ctx, "alice", testPassword); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Authenticate окончательно удаленного: %v", err)
+	}
+	_, err = s.ReadPAT(ctx, "pat-1")
+	expectError(t, "ReadPAT окончательно удаленного", err, gorm.ErrRecordNotFound)
+	clients, err := s.ListOAuthClients(ctx, deleted.ID)
+	expectNoError(t, "ListOAuthClients", err)
+	if len(clients) != 0 {
+		t.Fatalf("ListOAuthClients окончательно удаленного: получено %+v", clients)
+	}
+	session, err := s.ReadSession(ctx, "oauth-session")
+	expectNoError(t, "ReadSession", err)
+	if session.RevokedAt == nil {
+		t.Fatal("PurgeDeletedUsers: сессия через клиента удаленного пользователя не отозвана")
+	}
+	token, err := s.ReadRefreshToken(ctx, "oauth-token")
+	expectNoError(t, "ReadRefreshToken", err)
+	if token.RevokedAt == nil {
+		t.Fatal("PurgeDeletedUsers: refresh токен через клиента удаленного пользователя не отозван")
+	}
+	expectError(t, "RestoreUser окончательно удаленного", s.RestoreUser(ctx, deleted.ID), gorm.ErrRecordNotFound)
+	if _, err := s.Read(ctx, kept.ID); err != nil {
+		t.Fatalf("Read не удаленного: %v", err)
+	}
+
+	// Имя освобождается после окончательного удаления
+	createUser(t, s, "alice")
+
+	var types []string
+	_, err = s.PublishOutbox(ctx, 100, func(ctx context.Context, events []models.OutboxEvent) error {
+		for _, event := range events {
+			if event.AggregateID == deleted.ID {
+				types = append(types, event.Type)
+			}
+		}
+		return nil
+	})
+	expectNoError(t, "PublishOutbox", err)
+	if fmt.Sprint(types) != fmt.Sprint([]string{models.EVENT_USER_CREATED, models.EVENT_USER_DELETED}) {
+		t.Fatalf("PurgeDeletedUsers: события пользователя %v", types)
+	}
+}
+
+func testRefreshTokens(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+	expiresAt := time.Now().Add(time.Hour)
+
+	expectNoError(t, "CreateSession", s.CreateSession(ctx, &models.Session{ID: "family-1", UserID: user.ID, ExpiresAt: expiresAt}))
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "token-1", UserID: user.ID, FamilyID: "family-1", ExpiresAt: expiresAt}))
+	expectError(t, "CreateRefreshToken повторно",
+		s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "token-1", UserID: user.ID, FamilyID: "family-1", ExpiresAt: expiresAt}),
+		gorm.ErrDuplicatedKey)
+
+	next := &models.RefreshToken{ID: "token-2", ExpiresAt: expiresAt}
+	expectNoError(t, "RotateRefreshToken", s.RotateRefreshToken(ctx, "token-1", next))
+	if next.UserID != user.ID || next.FamilyID != "family-1" {
+		t.Fatalf("RotateRefreshToken: новый токен не унаследовал семейство: %+v", next)
+	}
+	old, err := s.ReadRefreshToken(ctx, "token-1")
+	expectNoError(t, "ReadRefreshToken", err)
+	if old.RevokedAt == nil || old.ReplacedBy != "token-2" {
+		t.Fatalf("RotateRefreshToken: старый токен не заменен: %+v", old)
+	}
+	expectError(t, "RotateRefreshToken несуществующего",
+		s.RotateRefreshToken(ctx, "missing", &models.RefreshToken{ID: "token-x", ExpiresAt: expiresAt}),
+		gorm.ErrRecordNotFound)
+
+	// Повторное использование замененного токена отзывает семейство и сессию
+	expectError(t, "RotateRefreshToken повторно",
+		s.RotateRefreshToken(ctx, "token-1", &models.RefreshToken{ID: "token-3", ExpiresAt: expiresAt}),
+		autherrors.ErrRefreshTokenReuse)
+	current, err := s.ReadRefreshToken(ctx, "token-2")
+	expectNoError(t, "ReadRefreshToken", err)
+	if current.RevokedAt == nil {
+		t.Fatal("RotateRefreshToken повторно: семейство не отозвано")
+	}
+	session, err := s.ReadSession(ctx, "family-1")
+	expectNoError(t, "ReadSession", err)
+	if session.RevokedAt == nil {
+		t.Fatal("RotateRefreshToken повторно: сессия не отозвана")
+	}
+	_, err = s.ReadRefreshToken(ctx, "token-3")
+	expectError(t, "ReadRefreshToken отклоненного", err, gorm.ErrRecordNotFound)
+
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "token-4", UserID: user.ID, FamilyID: "family-2", ExpiresAt: expiresAt}))
+	expectNoError(t, "RevokeTokenFamily", s.RevokeTokenFamily(ctx, "family-2"))
+	token, err := s.ReadRefreshToken(ctx, "token-4")
+	expectNoError(t, "ReadRefreshToken", err)
+	if token.RevokedAt == nil {
+		t.Fatal("RevokeTokenFamily: токен не отозван")
+	}
+
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "token-5", UserID: user.ID, FamilyID: "family-3", ExpiresAt: expiresAt}))
+	expectNoError(t, "RevokeUserTokens", s.RevokeUserTokens(ctx, user.ID))
+	token, err = s.ReadRefreshToken(ctx, "token-5")
+	expectNoError(t, "ReadRefreshToken", err)
+	if token.RevokedAt == nil {
+		t.Fatal("RevokeUserTokens: токен не отозван")
+	}
+}
+
+func testSessions(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+	now := time.Now()
+
+	sessions := []*models.Session{
+		{ID: "older", UserID: user.ID, LastRefreshedAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ID: "newer", UserID: user.ID, LastRefreshedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ID: "expired", UserID: user.ID, LastRefreshedAt: now, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "revoked", UserID: user.ID, LastRefreshedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}
+	for _, session := range sessions {
+		expectNoError(t, "CreateSession", s.CreateSession(ctx, session))
+	}
+	expectError(t, "CreateSession повторно", s.CreateSession(ctx, &models.Session{ID: "older", UserID: user.ID, ExpiresAt: now}), gorm.ErrDuplicatedKey)
+	expectNoError(t, "RevokeTokenFamily", s.RevokeTokenFamily(ctx, "revoked"))
+
+	listed, err := s.ListSessions(ctx, user.ID)
+	expectNoError(t, "ListSessions", err)
+	if len(listed) != 2 || listed[0].ID != "newer" || listed[1].ID != "older" {
+		t.Fatalf("ListSessions: получено %+v", listed)
+	}
+
+	expectNoError(t, "TouchSession", s.TouchSession(ctx, "older", "curl", "10.0.0.1", now.Add(2*time.Hour)))
+	touched, err := s.ReadSession(ctx, "older")
+	expectNoError(t, "ReadSession", err)
+	if touched.UserAgent != "curl" || touched.IP != "10.0.0.1" || touched.LastRefreshedAt.Before(now) {
+		t.Fatalf("TouchSession: неожиданная сессия %+v", touched)
+	}
+	listed, err = s.ListSessions(ctx, user.ID)
+	expectNoError(t, "ListSessions", err)
+	if len(listed) != 2 || listed[0].ID != "older" {
+		t.Fatalf("ListSessions после TouchSession: получено %+v", listed)
+	}
+
+	_, err = s.ReadSession(ctx, "missing")
+	expectError(t, "ReadSession несуществующей", err, gorm.ErrRecordNotFound)
+}
+
+func testTOTP(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+
+	expectError(t, "EnableTOTP без секрета", s.EnableTOTP(ctx, user.ID, 1), autherrors.ErrTOTPNotSetup)
+
+	expectNoError(t, "SetupTOTP", s.SetupTOTP(ctx, user.ID, "SECRET", []string{"old-code"}))
+	// Повторная настройка заменяет коды восстановления
+	expectNoError(t, "SetupTOTP повторно", s.SetupTOTP(ctx, user.ID, "SECRET", []string{"code-1", "code-2"}))
+	expectError(t, "UseRecoveryCode замененного", s.UseRecoveryCode(ctx, user.ID, "old-code"), autherrors.ErrInvalidTOTPCode)
+
+	expectNoError(t, "EnableTOTP", s.EnableTOTP(ctx, user.ID, 100))
+	read, err := s.Read(ctx, user.ID)
+	expectNoError(t, "Read", err)
+	if !read.TOTPEnabled || read.TOTPSecret != "SECRET" || read.TOTPLastStep != 100 {
+		t.Fatalf("EnableTOTP: неожиданный пользователь %+v", read)
+	}
+
+	expectError(t, "ConsumeTOTPStep того же шага", s.ConsumeTOTPStep(ctx, user.ID, 100), autherrors.ErrInvalidTOTPCode)
+	expectNoError(t, "ConsumeTOTPStep", s.ConsumeTOTPStep(ctx, user.ID, 101))
+	expectError(t, "ConsumeTOTPStep раннего шага", s.ConsumeTOTPStep(ctx, user.ID, 99), autherrors.ErrInvalidTOTPCode)
+
+	expectNoError(t, "UseRecoveryCode", s.UseRecoveryCode(ctx, user.ID, "code-1"))
+	expectError(t, "UseRecoveryCode повторно", s.UseRecoveryCode(ctx, user.ID, "code-1"), autherrors.ErrInvalidTOTPCode)
+
+	// Второй фактор проверяется и у учетной записи, ожидающей удаления
+	expectNoError(t, "Delete", s.Delete(ctx, user.ID))
+	expectNoError(t, "ConsumeTOTPStep удаленного", s.ConsumeTOTPStep(ctx, user.ID, 102))
+	expectNoError(t, "RestoreUser", s.RestoreUser(ctx, user.ID))
+
+	expectNoError(t, "DisableTOTP", s.DisableTOTP(ctx, user.ID))
+	read, err = s.Read(ctx, user.ID)
+	expectNoError(t, "Read", err)
+	if read.TOTPEnabled || read.TOTPSecret != "" {
+		t.Fatalf("DisableTOTP: неожиданный пользователь %+v", read)
+	}
+	expectError(t, "UseRecoveryCode после DisableTOTP", s.UseRecoveryCode(ctx, user.ID, "code-2"), autherrors.ErrInvalidTOTPCode)
+}
+
+func testUserTokens(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+	expiresAt := time.Now().Add(time.Hour)
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: user.ID, Username: "alice", Email: strPtr("alice@example.com")}))
+
+	first := &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_EMAIL_VERIFICATION, TokenHash: "verify-1", Email: "alice@example.com", ExpiresAt: expiresAt}
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, first))
+	second := &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_EMAIL_VERIFICATION, TokenHash: "verify-2", Email: "alice@example.com", ExpiresAt: expiresAt}
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, second))
+
+	// Новый токен отменяет ранее выданный токен того же назначения
+	_, err := s.ReadUserToken(ctx, models.TOKEN_EMAIL_VERIFICATION, "verify-1")
+	expectError(t, "ReadUserToken отмененного", err, autherrors.ErrInvalidUserToken)
+	token, err := s.ReadUserToken(ctx, models.TOKEN_EMAIL_VERIFICATION, "verify-2")
+	expectNoError(t, "ReadUserToken", err)
+	if token.UserID != user.ID || token.Email != "alice@example.com" {
+		t.Fatalf("ReadUserToken: неожиданный токен %+v", token)
+	}
+	_, err = s.ReadUserToken(ctx, models.TOKEN_PASSWORD_RESET, "verify-2")
+	expectError(t, "ReadUserToken другого назначения", err, autherrors.ErrInvalidUserToken)
+
+	confirmed, err := s.ConfirmEmail(ctx, "verify-2")
+	expectNoError(t, "ConfirmEmail", err)
+	if !confirmed.EmailVerified {
+		t.Fatal("ConfirmEmail: адрес не отмечен подтвержденным")
+	}
+	_, err = s.ConfirmEmail(ctx, "verify-2")
+	expectError(t, "ConfirmEmail повторно", err, autherrors.ErrInvalidUserToken)
+
+	// Токен, отправленный на прежний адрес, недействителен после смены адреса
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_EMAIL_VERIFICATION, TokenHash: "verify-3", Email: "alice@example.com", ExpiresAt: expiresAt}))
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: user.ID, Username: "alice", Email: strPtr("alice@example.org")}))
+	_, err = s.ConfirmEmail(ctx, "verify-3")
+	expectError(t, "ConfirmEmail после смены адреса", err, autherrors.ErrInvalidUserToken)
+
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_PASSWORD_RESET, TokenHash: "expired", Email: "alice@example.org", ExpiresAt: time.Now().Add(-time.Minute)}))
+	_, err = s.ResetPassword(ctx, "expired", "new-password")
+	expectError(t, "ResetPassword истекшим токеном", err, autherrors.ErrInvalidUserToken)
+
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_PASSWORD_RESET, TokenHash: "reset-1", Email: "alice@example.org", ExpiresAt: expiresAt}))
+	_, err = s.ResetPassword(ctx, "reset-1", "new-password")
+	expectNoError(t, "ResetPassword", err)
+	if _, err := s.Authenticate(ctx, "alice", "new-password"); err != nil {
+		t.Fatalf("Authenticate с новым паролем: %v", err)
+	}
+	_, err = s.ResetPassword(ctx, "reset-1", "another-password")
+	expectError(t, "ResetPassword повторно", err, autherrors.ErrInvalidUserToken)
+
+	// Сохранение того же адреса не отменяет ссылку сброса пароля, смена адреса отменяет
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_PASSWORD_RESET, TokenHash: "reset-2", Email: "alice@example.org", ExpiresAt: expiresAt}))
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: user.ID, Username: "alice", Email: strPtr("alice@example.org")}))
+	_, err = s.ReadUserToken(ctx, models.TOKEN_PASSWORD_RESET, "reset-2")
+	expectNoError(t, "ReadUserToken после сохранения того же адреса", err)
+	expectNoError(t, "Update", s.Update(ctx, &models.User{ID: user.ID, Username: "alice", Email: strPtr("alice@example.net")}))
+	_, err = s.ReadUserToken(ctx, models.TOKEN_PASSWORD_RESET, "reset-2")
+	expectError(t, "ReadUserToken после смены адреса", err, autherrors.ErrInvalidUserToken)
+	_, err = s.ResetPassword(ctx, "reset-2", "another-password")
+	expectError(t, "ResetPassword после смены адреса", err, autherrors.ErrInvalidUserToken)
+
+	// Токен, выданный не на текущий адрес, не меняет пароль и остается неиспользованным
+	expectNoError(t, "CreateUserToken", s.CreateUserToken(ctx, &models.UserToken{UserID: user.ID, Purpose: models.TOKEN_PASSWORD_RESET, TokenHash: "reset-3", Email: "alice@example.org", ExpiresAt: expiresAt}))
+	_, err = s.ResetPassword(ctx, "reset-3", "another-password")
+	expectError(t, "ResetPassword на прежний адрес", err, autherrors.ErrInvalidUserToken)
+	if _, err := s.Authenticate(ctx, "alice", "new-password"); err != nil {
+		t.Fatalf("Authenticate после отклоненного сброса: %v", err)
+	}
+	_, err = s.ReadUserToken(ctx, models.TOKEN_PASSWORD_RESET, "reset-3")
+	expectNoError(t, "ReadUserToken после отклоненного сброса", err)
+}
+
+func testPATs(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+
+	tokens := []*models.PersonalAccessToken{
+		{ID: "pat-old", UserID: user.ID, Name: "old", Scope: "notes:read", TokenHash: "hash-old", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "pat-new", UserID: user.ID, Name: "new", Scope: "notes:read", TokenHash: "hash-new", CreatedAt: now.Add(-time.Hour)},
+		{ID: "pat-expired", UserID: user.ID, Name: "expired", Scope: "notes:read", TokenHash: "hash-expired", ExpiresAt: &expired, CreatedAt: now.Add(-3 * time.Hour)},
+	}
+	for _, token := range tokens {
+		expectNoError(t, "CreatePAT", s.CreatePAT(ctx, token))
+	}
+	expectError(t, "CreatePAT с занятым хешем",
+		s.CreatePAT(ctx, &models.PersonalAccessToken{ID: "pat-dup", UserID: user.ID, Name: "dup", TokenHash: "hash-old"}),
+		gorm.ErrDuplicatedKey)
+
+	listed, err := s.ListPATs(ctx, user.ID)
+	expectNoError(t, "ListPATs", err)
+	if len(listed) != 3 || listed[0].ID != "pat-new" || listed[1].ID != "pat-old" || listed[2].ID != "pat-expired" {
+		t.Fatalf("ListPATs: получено %+v", listed)
+	}
+
+	active, err := s.ListActivePATs(ctx)
+	expectNoError(t, "ListActivePATs", err)
+	if len(active) != 2 {
+		t.Fatalf("ListActivePATs: получено %+v", active)
+	}
+
+	expectNoError(t, "RevokePAT", s.RevokePAT(ctx, "pat-old"))
+	revoked, err := s.ReadPAT(ctx, "pat-old")
+	expectNoError(t, "ReadPAT", err)
+	if revoked.RevokedAt == nil {
+		t.Fatal("RevokePAT: токен не отозван")
+	}
+	_, err = s.ReadPAT(ctx, "missing")
+	expectError(t, "ReadPAT несуществующего", err, gorm.ErrRecordNotFound)
+
+	revokedAll, err := s.RevokeUserPATs(ctx, user.ID)
+	expectNoError(t, "RevokeUserPATs", err)
+	if len(revokedAll) != 2 {
+		t.Fatalf("RevokeUserPATs: отозвано %+v, ожидалось 2 токена", revokedAll)
+	}
+	for _, token := range revokedAll {
+		if token.RevokedAt == nil {
+			t.Fatalf("RevokeUserPATs: токен %s возвращен без времени отзыва", token.ID)
+		}
+	}
+	listed, err = s.ListPATs(ctx, user.ID)
+	expectNoError(t, "ListPATs", err)
+	if len(listed) != 0 {
+		t.Fatalf("ListPATs после RevokeUserPATs: получено %+v", listed)
+	}
+}
+
+func testOAuth(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	owner := createUser(t, s, "alice")
+	now := time.Now()
+
+	clients := []*models.OAuthClient{
+		{ID: "client-old", OwnerID: owner.ID, Name: "old", CreatedAt: now.Add(-time.Hour)},
+		{ID: "client-new", OwnerID: owner.ID, Name: "new", CreatedAt: now},
+	}
+	for _, client := range clients {
+		expectNoError(t, "CreateOAuthClient", s.CreateOAuthClient(ctx, client))
+	}
+	expectError(t, "CreateOAuthClient повторно",
+		s.CreateOAuthClient(ctx, &models.OAuthClient{ID: "client-old", OwnerID: owner.ID, Name: "dup"}),
+		gorm.ErrDuplicatedKey)
+
+	listed, err := s.ListOAuthClients(ctx, owner.ID)
+	expectNoError(t, "ListOAuthClients", err)
+	if len(listed) != 2 || listed[0].ID != "client-new" || listed[1].ID != "client-old" {
+		t.Fatalf("ListOAuthClients: получено %+v", listed)
+	}
+	_, err = s.ReadOAuthClient(ctx, "missing")
+	expectError(t, "ReadOAuthClient несуществующего", err, gorm.ErrRecordNotFound)
+
+	code := &models.OAuthAuthorizationCode{CodeHash: "code-1", ClientID: "client-old", UserID: owner.ID, RedirectURI: "https://example.com/cb", ExpiresAt: now.Add(time.Minute)}
+	expectNoError(t, "CreateAuthCode", s.CreateAuthCode(ctx, code))
+
+	// Код, не прошедший проверку, остается неиспользованным
+	errMismatch := errors.New("redirect_uri не совпадает")
+	_, err = s.ConsumeAuthCode(ctx, "code-1", func(code *models.OAuthAuthorizationCode) error {
+		return errMismatch
+	})
+	expectError(t, "ConsumeAuthCode с неудачной проверкой", err, errMismatch)
+
+	consumed, err := s.ConsumeAuthCode(ctx, "code-1", func(code *models.OAuthAuthorizationCode) error {
+		if code.RedirectURI != "https://example.com/cb" {
+			return errMismatch
+		}
+		return nil
+	})
+	expectNoError(t, "ConsumeAuthCode", err)
+	if consumed.ClientID != "client-old" || consumed.UsedAt == nil {
+		t.Fatalf("ConsumeAuthCode: неожиданный код %+v", consumed)
+	}
+	expectNoError(t, "SetAuthCodeSession", s.SetAuthCodeSession(ctx, "code-1", "oauth-session"))
+
+	// Повторный обмен возвращает код, чтобы отозвать выданную по нему сессию
+	reused, err := s.ConsumeAuthCode(ctx, "code-1", nil)
+	expectError(t, "ConsumeAuthCode повторно", err, autherrors.ErrAuthCodeReuse)
+	if reused == nil || reused.SessionID != "oauth-session" {
+		t.Fatalf("ConsumeAuthCode повторно: неожиданный код %+v", reused)
+	}
+	_, err = s.ConsumeAuthCode(ctx, "missing", nil)
+	expectError(t, "ConsumeAuthCode несуществующего", err, autherrors.ErrInvalidAuthCode)
+
+	expectNoError(t, "CreateAuthCode", s.CreateAuthCode(ctx, &models.OAuthAuthorizationCode{CodeHash: "code-expired", ClientID: "client-old", UserID: owner.ID, RedirectURI: "https://example.com/cb", ExpiresAt: now.Add(-time.Minute)}))
+	_, err = s.ConsumeAuthCode(ctx, "code-expired", nil)
+	expectError(t, "ConsumeAuthCode истекшего", err, autherrors.ErrInvalidAuthCode)
+
+	expiresAt := now.Add(time.Hour)
+	expectNoError(t, "CreateSession", s.CreateSession(ctx, &models.Session{ID: "oauth-session", UserID: owner.ID, ClientID: "client-old", ExpiresAt: expiresAt}))
+	expectNoError(t, "CreateRefreshToken", s.CreateRefreshToken(ctx, &models.RefreshToken{ID: "oauth-token", UserID: owner.ID, FamilyID: "oauth-session", ExpiresAt: expiresAt}))
+	expectNoError(t, "CreateSession", s.CreateSession(ctx, &models.Session{ID: "other-session", UserID: owner.ID, ClientID: "client-new", ExpiresAt: expiresAt}))
+
+	sessionIDs, err := s.DeleteOAuthClient(ctx, "client-old")
+	expectNoError(t, "DeleteOAuthClient", err)
+	if fmt.Sprint(sessionIDs) != "[oauth-session]" {
+		t.Fatalf("DeleteOAuthClient: завершены сессии %v", sessionIDs)
+	}
+	token, err := s.ReadRefreshToken(ctx, "oauth-token")
+	expectNoError(t, "ReadRefreshToken", err)
+	if token.RevokedAt == nil {
+		t.Fatal("DeleteOAuthClient: refresh токен клиента не отозван")
+	}
+	other, err := s.ReadSession(ctx, "other-session")
+	expectNoError(t, "ReadSession", err)
+	if other.RevokedAt != nil {
+		t.Fatal("DeleteOAuthClient: отозвана сессия другого клиента")
+	}
+	_, err = s.ConsumeAuthCode(ctx, "code-1", nil)
+	expectError(t, "ConsumeAuthCode удаленного клиента", err, autherrors.ErrInvalidAuthCode)
+	_, err = s.ReadOAuthClient(ctx, "client-old")
+	expectError(t, "ReadOAuthClient удаленного", err, gorm.ErrRecordNotFound)
+	_, err = s.DeleteOAuthClient(ctx, "client-old")
+	expectError(t, "DeleteOAuthClient повторно", err, gorm.ErrRecordNotFound)
+}
+
+func testOutbox(t *testing.T, s service.Service) {
+	ctx := context.Background()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		createUser(t, s, name)
+	}
+
+	_, err := s.PublishOutbox(ctx, 0, func(ctx context.Context, events []models.OutboxEvent) error { return nil })
+	expectError(t, "PublishOutbox без лимита", err, gorm.ErrInvalidData)
+
+	// Ошибка публикации оставляет события неопубликованными
+	failure := errors.New("publish failed")
+	_, err = s.PublishOutbox(ctx, 10, func(ctx context.Context, events []models.OutboxEvent) error { return failure })
+	expectError(t, "PublishOutbox с ошибкой", err, failure)
+
+	var usernames []string
+	collect := func(ctx context.Context, events []models.OutboxEvent) error {
+		for _, event := range events {
+			if event.Type != models.EVENT_USER_CREATED || event.EventID == "" {
+				return fmt.Errorf("неожиданное событие %+v", event)
+			}
+			var payload models.UserEventPayload
+			if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+				return err
+			}
+			usernames = append(usernames, payload.Username)
+		}
+		return nil
+	}
+	published, err := s.PublishOutbox(ctx, 2, collect)
+	expectNoError(t, "PublishOutbox", err)
+	if published != 2 {
+		t.Fatalf("PublishOutbox: опубликовано %d, ожидалось 2", published)
+	}
+	published, err = s.PublishOutbox(ctx, 2, collect)
+	expectNoError(t, "PublishOutbox", err)
+	if published != 1 {
+		t.Fatalf("PublishOutbox: опубликовано %d, ожидался 1", published)
+	}
+	if fmt.Sprint(usernames) != "[alice bob carol]" {
+		t.Fatalf("PublishOutbox: события опубликованы в порядке %v", usernames)
+	}
+	published, err = s.PublishOutbox(ctx, 2, collect)
+	expectNoError(t, "PublishOutbox", err)
+	if published != 0 {
+		t.Fatalf("PublishOutbox без новых событий: опубликовано %d", published)
+	}
+
+	purged, err := s.PurgeOutbox(ctx, time.Now().Add(-time.Hour))
+	expectNoError(t, "PurgeOutbox", err)
+	if purged != 0 {
+		t.Fatalf("PurgeOutbox до срока: удалено %d", purged)
+	}
+	purged, err = s.PurgeOutbox(ctx, time.Now().Add(time.Hour))
+	expectNoError(t, "PurgeOutbox", err)
+	if purged != 3 {
+		t.Fatalf("PurgeOutbox: удалено %d, ожидалось 3", purged)
+	}
+}
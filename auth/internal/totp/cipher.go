@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// sealedPrefix - префикс зашифрованного секрета и версия формата
+const sealedPrefix = "enc:v1:"
+
+// KeySize - размер ключа шифрования секретов в байтах (AES-256)
+const KeySize = 32
+
+// Ошибки шифрования секретов
+var (
+	ErrInvalidKey   = errors.New("ключ шифрования секретов TOTP должен быть 32 байта в base64")
+	ErrNoKey        = errors.New("ключ шифрования секретов TOTP не задан")
+	ErrSealedSecret = errors.New("не удалось расшифровать секрет TOTP")
+)
+
+// SecretCipher шифрует секреты TOTP для хранения в базе данных (AES-256-GCM)
+// Секрет привязывается к ID пользователя, поэтому его нельзя перенести в запись другого пользователя
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher создает шифр по ключу в кодировке base64
+func NewSecretCipher(encodedKey string) (*SecretCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretCipher{aead: aead}, nil
+}
+
+// Seal шифрует секрет пользователя userID
+func (s *SecretCipher) Seal(secret string, userID int) (string, error) {
+	if s == nil {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), userData(userID))
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает сохраненный секрет пользователя userID
+// Секрет без префикса не принимается: секреты хранятся только в зашифрованном виде
+func (s *SecretCipher) Open(stored string, userID int) (string, error) {
+	if s == nil {
+		return "", ErrNoKey
+	}
+	encoded, sealed := strings.CutPrefix(stored, sealedPrefix)
+	if !sealed {
+		return "", ErrSealedSecret
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", ErrSealedSecret
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, userData(userID))
+	if err != nil {
+		return "", ErrSealedSecret
+	}
+	return string(secret), nil
+}
+
+// userData возвращает дополнительные данные шифрования для пользователя userID
+func userData(userID int) []byte {
+	return []byte("user:" + strconv.Itoa(userID))
+}
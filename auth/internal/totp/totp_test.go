@@ -0,0 +1,127 @@
+package totp
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - секрет тестовых векторов RFC 6238 (приложение B) для HMAC-SHA1 в base32
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// Последние Digits цифр восьмизначных кодов из RFC 6238
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, ожидалось %s", tt.unix, got, tt.want)
+		}
+	}
+
+	if _, err := Code("не base32", 1); err == nil {
+		t.Error("Code: ожидалась ошибка для неверного секрета")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	previous, _ := Code(rfcSecret, current-1)
+	old, _ := Code(rfcSecret, current-2)
+
+	step, ok := Validate(rfcSecret, "005924", now, 1)
+	if !ok || step != current {
+		t.Errorf("Validate текущего кода: шаг %d, %v", step, ok)
+	}
+	step, ok = Validate(rfcSecret, " "+previous+" ", now, 1)
+	if !ok || step != current-1 {
+		t.Errorf("Validate предыдущего кода: шаг %d, %v", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, old, now, 1); ok {
+		t.Error("Validate: принят код за пределами допустимого расхождения")
+	}
+	for _, code := range []string{"", "12345", "1234567", "000000"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate(%q): принят неверный код", code)
+		}
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || strings.ToLower(code) != code {
+			t.Errorf("GenerateRecoveryCodes: неверный формат %q", code)
+		}
+		if seen[code] {
+			t.Errorf("GenerateRecoveryCodes: повторяющийся код %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestSecretCipher(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	secrets, err := NewSecretCipher(key)
+	if err != nil {
+		t.Fatalf("NewSecretCipher: %v", err)
+	}
+
+	sealed, err := secrets.Seal(rfcSecret, 7)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if strings.Contains(sealed, rfcSecret) || !strings.HasPrefix(sealed, sealedPrefix) {
+		t.Fatalf("Seal: секрет не зашифрован: %s", sealed)
+	}
+	if again, _ := secrets.Seal(rfcSecret, 7); again == sealed {
+		t.Error("Seal: одинаковый результат для двух шифрований")
+	}
+
+	opened, err := secrets.Open(sealed, 7)
+	if err != nil || opened != rfcSecret {
+		t.Fatalf("Open: %q, %v", opened, err)
+	}
+	if _, err := secrets.Open(sealed, 8); err != ErrSealedSecret {
+		t.Errorf("Open для другого пользователя: ожидалась ErrSealedSecret, получено %v", err)
+	}
+	if _, err := secrets.Open(sealed[:len(sealed)-2], 7); err != ErrSealedSecret {
+		t.Errorf("Open поврежденного секрета: ожидалась ErrSealedSecret, получено %v", err)
+	}
+
+	// Секрет открытым текстом не принимается
+	if _, err := secrets.Open(rfcSecret, 7); err != ErrSealedSecret {
+		t.Errorf("Open открытого секрета: ожидалась ErrSealedSecret, получено %v", err)
+	}
+
+	var missing *SecretCipher
+	if _, err := missing.Seal(rfcSecret, 7); err != ErrNoKey {
+		t.Errorf("Seal без ключа: ожидалась ErrNoKey, получено %v", err)
+	}
+	if _, err := missing.Open(sealed, 7); err != ErrNoKey {
+		t.Errorf("Open без ключа: ожидалась ErrNoKey, получено %v", err)
+	}
+
+	for _, bad := range []string{"", "не base64", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewSecretCipher(bad); err != ErrInvalidKey {
+			t.Errorf("NewSecretCipher(%q): ожидалась ErrInvalidKey, получено %v", bad, err)
+		}
+	}
+}
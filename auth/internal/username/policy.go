@@ -0,0 +1,188 @@
+package username
+
+import (
+	"auth/internal/models"
+	"auth/internal/password"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Коды нарушений правил имени пользователя
+const (
+	CODE_REQUIRED   = "required"   // Имя не указано
+	CODE_MIN_LENGTH = "min_length" // Имя короче минимальной длины
+	CODE_MAX_LENGTH = "max_length" // Имя длиннее максимальной длины
+	CODE_CHARACTERS = "characters" // Имя содержит недопустимые символы
+	CODE_SEPARATORS = "separators" // Разделитель в начале, в конце или два разделителя подряд
+	CODE_SCRIPTS    = "scripts"    // Буквы разных алфавитов в одном имени
+	CODE_RESERVED   = "reserved"   // Имя зарезервировано
+)
+
+// separators - символы, допустимые между буквами и цифрами
+const separators = "._-"
+
+// cjkScripts - сочетания письменностей, принятые в японском, китайском и корейском письме
+// Имя может использовать любое подмножество одного сочетания (UTS #39, уровень Highly Restrictive без латиницы)
+var cjkScripts = [][]string{
+	{"Han", "Hiragana", "Katakana"},
+	{"Han", "Bopomofo"},
+	{"Han", "Hangul"},
+}
+
+// defaultReserved - имена, которые нельзя занять: служебные адреса и имена,
+// которые пользователи могут принять за сообщения от администрации
+var defaultReserved = []string{
+	"admin", "administrator", "root", "system", "support", "help", "security",
+	"moderator", "staff", "auth", "api", "oauth", "me", "user", "users",
+	"null", "undefined", "anonymous", "noreply", "no-reply", "postmaster", "webmaster",
+}
+
+// Policy - правила для имен пользователей
+type Policy struct {
+	MinLength int                 // Минимальная длина в символах
+	MaxLength int                 // Максимальная длина в символах
+	reserved  map[string]struct{} // Зарезервированные имена в нормализованной форме
+}
+
+// NewPolicy создает политику с длиной имени от minLength до maxLength символов
+// extraReserved дополняет встроенный список зарезервированных имен
+func NewPolicy(minLength, maxLength int, extraReserved []string) *Policy {
+	reserved := make(map[string]struct{}, len(defaultReserved)+len(extraReserved))
+	for _, names := range [][]string{defaultReserved, extraReserved} {
+		for _, name := range names {
+			if normalized := models.NormalizeUsername(name); normalized != "" {
+				reserved[normalized] = struct{}{}
+			}
+		}
+	}
+	return &Policy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		reserved:  reserved,
+	}
+}
+
+// ParseReserved разбирает список зарезервированных имен, разделенных запятыми
+func ParseReserved(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Validate проверяет имя пользователя и возвращает его каноническую форму для сохранения:
+// без пробелов по краям и в NFKC, с сохранением регистра
+// Возвращает все найденные нарушения или nil, если имя подходит
+func (p *Policy) Validate(raw string) (string, []password.Violation) {
+	name := norm.NFKC.String(strings.TrimSpace(raw))
+	if name == "" {
+		return "", []password.Violation{violation(CODE_REQUIRED, "имя пользователя не указано")}
+	}
+
+	var violations []password.Violation
+
+	length := utf8.RuneCountInString(name)
+	if length < p.MinLength {
+		violations = append(violations, violation(CODE_MIN_LENGTH,
+			fmt.Sprintf("имя пользователя должно содержать не менее %d символов", p.MinLength)))
+	}
+	if length > p.MaxLength {
+		violations = append(violations, violation(CODE_MAX_LENGTH,
+			fmt.Sprintf("имя пользователя должно содержать не более %d символов", p.MaxLength)))
+	}
+
+	// Допустимы буквы одного алфавита, цифры и разделители между ними
+	var invalidChars, invalidSeparators bool
+	scripts := make(map[string]struct{})
+	previousSeparator := true // Имя не может начинаться с разделителя
+	for _, r := range name {
+		isSeparator := strings.ContainsRune(separators, r)
+		switch {
+		case isSeparator:
+			if previousSeparator {
+				invalidSeparators = true
+			}
+		case unicode.IsMark(r):
+			// Диакритический знак относится к предыдущей букве
+			if previousSeparator {
+				invalidChars = true
+			}
+		case unicode.IsLetter(r):
+			scripts[scriptOf(r)] = struct{}{}
+		case !unicode.IsDigit(r):
+			invalidChars = true
+		}
+		previousSeparator = isSeparator
+	}
+	if previousSeparator {
+		invalidSeparators = true
+	}
+	if invalidChars {
+		violations = append(violations, violation(CODE_CHARACTERS,
+			"имя пользователя может содержать только буквы, цифры и символы "+separators))
+	}
+	if invalidSeparators {
+		violations = append(violations, violation(CODE_SEPARATORS,
+			"имя пользователя не может начинаться или заканчиваться символами "+separators+" и содержать их подряд"))
+	}
+	// Смешение алфавитов позволяет выдать себя за другого пользователя,
+	// например "аdmin" с кириллической "а" нормализация не приводит к "admin"
+	if mixedScripts(scripts) {
+		violations = append(violations, violation(CODE_SCRIPTS,
+			"имя пользователя должно быть записано буквами одного алфавита"))
+	}
+
+	if _, ok := p.reserved[models.NormalizeUsername(name)]; ok {
+		violations = append(violations, violation(CODE_RESERVED, "имя пользователя зарезервировано"))
+	}
+
+	return name, violations
+}
+
+// scriptOf возвращает название письменности буквы r
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// mixedScripts проверяет, что буквы имени относятся к разным письменностям
+// Иероглифы допускаются вместе с каной, хангылем или бопомофо
+func mixedScripts(scripts map[string]struct{}) bool {
+	if len(scripts) <= 1 {
+		return false
+	}
+	for _, combination := range cjkScripts {
+		allowed := true
+		for script := range scripts {
+			if !slices.Contains(combination, script) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// violation создает нарушение для поля username
+func violation(code, message string) password.Violation {
+	return password.Violation{
+		Field:   "username",
+		Code:    code,
+		Message: message,
+	}
+}
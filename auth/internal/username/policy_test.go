@@ -0,0 +1,99 @@
+package username
+
+import (
+	"auth/internal/models"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"регистр не учитывается", "Alice", "alice"},
+		{"пробелы по краям", "  alice  ", "alice"},
+		{"полноширинные буквы", "ａｌｉｃｅ", "alice"},
+		{"лигатура", "ﬁona", "fiona"},
+		{"составной символ", "jose\u0301", "jos\u00e9"},
+		{"кириллица", "Борис", "борис"},
+		{"эсцет сворачивается в ss", "Straße", "strasse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.NormalizeUsername(tt.raw); got != tt.want {
+				t.Errorf("NormalizeUsername(%q) = %q, ожидалось %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+// codes возвращает коды нарушений
+func codes(name string, p *Policy) (string, []string) {
+	canonical, violations := p.Validate(name)
+	result := make([]string, 0, len(violations))
+	for _, v := range violations {
+		result = append(result, v.Code)
+	}
+	return canonical, result
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := NewPolicy(3, 20, ParseReserved(" notes , , Billing"))
+
+	tests := []struct {
+		raw       string
+		canonical string
+		want      []string
+	}{
+		{"Alice", "Alice", nil},
+		{"  alice.smith  ", "alice.smith", nil},
+		{"ｂｏｂ", "bob", nil},
+		{"jos\u00e9", "jos\u00e9", nil},
+		{"jose\u0301", "jos\u00e9", nil},
+		{"Борис_1", "Борис_1", nil},
+		{"", "", []string{CODE_REQUIRED}},
+		{"   ", "", []string{CODE_REQUIRED}},
+		{"ab", "ab", []string{CODE_MIN_LENGTH}},
+		{strings.Repeat("a", 21), strings.Repeat("a", 21), []string{CODE_MAX_LENGTH}},
+		{"al ice", "al ice", []string{CODE_CHARACTERS}},
+		{"alice!", "alice!", []string{CODE_CHARACTERS}},
+		{"\u0301alice", "\u0301alice", []string{CODE_CHARACTERS}},
+		{".alice", ".alice", []string{CODE_SEPARATORS}},
+		{"alice-", "alice-", []string{CODE_SEPARATORS}},
+		{"al..ice", "al..ice", []string{CODE_SEPARATORS}},
+		{"ADMIN", "ADMIN", []string{CODE_RESERVED}},
+		{"ａｄｍｉｎ", "admin", []string{CODE_RESERVED}},
+		{"no-reply", "no-reply", []string{CODE_RESERVED}},
+		{"Notes", "Notes", []string{CODE_RESERVED}},
+		{"billing", "billing", []string{CODE_RESERVED}},
+		{"-a", "-a", []string{CODE_MIN_LENGTH, CODE_SEPARATORS}},
+		// Буквы разных алфавитов в одном имени не допускаются
+		{"\u0430dmin", "\u0430dmin", []string{CODE_SCRIPTS}},
+		{"p\u0430ypal", "p\u0430ypal", []string{CODE_SCRIPTS}},
+		{"Борис_Smith", "Борис_Smith", []string{CODE_SCRIPTS}},
+		{"αlice", "αlice", []string{CODE_SCRIPTS}},
+		{"山田たろう", "山田たろう", nil},
+		{"山田タロウ_1", "山田タロウ_1", nil},
+		{"김철수", "김철수", nil},
+		{"たろうタロウ", "たろうタロウ", nil},
+		{"たろうキム김", "たろうキム김", []string{CODE_SCRIPTS}},
+	}
+	for _, tt := range tests {
+		canonical, got := codes(tt.raw, policy)
+		if canonical != tt.canonical || !slices.Equal(got, tt.want) {
+			t.Errorf("Validate(%q) = %q, %v, ожидалось %q, %v", tt.raw, canonical, got, tt.canonical, tt.want)
+		}
+	}
+}
+
+func TestParseReserved(t *testing.T) {
+	if got := ParseReserved(" a, b ,,c "); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("ParseReserved = %q", got)
+	}
+	if got := ParseReserved(""); got != nil {
+		t.Errorf("ParseReserved пустой строки = %q", got)
+	}
+}
@@ -2,23 +2,55 @@ package main
 
 import (
 	"auth/internal/config"
+	"auth/internal/database"
 	"auth/internal/server"
+	"auth/internal/service"
+	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	jwtmanager "jwt_manager"
 )
 
 func main() {
 	// Инициализируем конфигурации сервера
 	cfg := config.NewConfig()
 
+	// Команда "migrate" управляет схемой базы данных без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(cfg, os.Args[2:]); err != nil {
+			fmt.Printf("Ошибка миграции: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Команда "promote" назначает пользователю роль admin без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		if err := runPromoteCommand(cfg, os.Args[2:]); err != nil {
+			fmt.Printf("Ошибка назначения администратора: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Printf("=== Server Configuration ===\n")
 	fmt.Printf("Host: %s\n", cfg.Host)
 	fmt.Printf("Port: %s\n", cfg.Port)
+	fmt.Printf("gRPC Port: %s\n", cfg.GRPCPort)
 	fmt.Printf("Database URL: %s\n", cfg.DBDSN)
 	fmt.Printf("Access Token Expiration: %d hours\n", cfg.AccessTokenExpiration)
 	fmt.Printf("Refresh Token Expiration: %d hours\n", cfg.RefreshTokenExpiration)
 	fmt.Printf("Server Timeout: %d seconds\n", cfg.Timeout)
 	fmt.Printf("Database Timeout: %d seconds\n", cfg.DBTimeout)
-	fmt.Printf("JWT Secret Key: %s\n", cfg.JWTSecretKey)
+	fmt.Printf("JWT Algorithm: %s\n", cfg.JWTAlgorithm)
+	fmt.Printf("JWT Keys File: %s\n", cfg.JWTKeysFile)
+	fmt.Printf("JWT Issuer: %s\n", cfg.JWTIssuer)
+	fmt.Printf("JWT Audience: %s\n", cfg.JWTAudience)
+	fmt.Printf("Redis Host: %s\n", cfg.RedisHost)
+	fmt.Printf("Redis Port: %s\n", cfg.RedisPort)
 	fmt.Printf("=============================\n")
 
 	server, err := server.NewServer(cfg)
@@ -34,3 +66,115 @@ func main() {
 	}
 	fmt.Printf("Сервер запущен успешно\n")
 }
+
+// runMigrateCommand выполняет команду миграций:
+//
+//	migrate up         - применить все непримененные миграции
+//	migrate down [N]   - откатить N последних миграций (по умолчанию одну)
+//	migrate status     - показать состояние миграций
+func runMigrateCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("укажите команду: up, down [N] или status")
+	}
+	// Схема SQLite создается при запуске, хранилищу в памяти схема не нужна
+	if cfg.StoreBackend != config.STORE_POSTGRES {
+		return fmt.Errorf("миграции применяются только к хранилищу %s, выбрано %s", config.STORE_POSTGRES, cfg.StoreBackend)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	db, err := database.Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	// Сама миграция может выполняться дольше подключения
+	migrateCtx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(migrateCtx)
+		for _, migration := range applied {
+			fmt.Printf("Применена миграция %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Нет новых миграций")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("неверное число миграций для отката: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(migrateCtx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Откачена миграция %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("Нет примененных миграций")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(migrateCtx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "не применена"
+			if status.AppliedAt != nil {
+				state = "применена " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				state += " (нет в исполняемом файле)"
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестная команда migrate: %s", args[0])
+	}
+}
+
+// runPromoteCommand назначает роль admin пользователю:
+//
+//	promote <username> - назначить роль admin существующему пользователю
+func runPromoteCommand(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("укажите имя пользователя: promote <username>")
+	}
+	// Хранилище в памяти принадлежит процессу сервера, для него используется ADMIN_USERNAME
+	if cfg.StoreBackend == config.STORE_MEMORY {
+		return fmt.Errorf("для хранилища %s администратор задается переменными ADMIN_USERNAME и ADMIN_PASSWORD", config.STORE_MEMORY)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	s, err := service.NewService(cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	user, err := s.ReadByUsername(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("пользователь %s не найден: %w", args[0], err)
+	}
+	if err := s.SetUserRole(ctx, user.ID, jwtmanager.ROLE_ADMIN); err != nil {
+		return err
+	}
+	fmt.Printf("Пользователь %s получил роль %s\n", user.Username, jwtmanager.ROLE_ADMIN)
+	return nil
+}
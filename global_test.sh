@@ -22,7 +22,7 @@ curl -X POST "$BASE_URL/$SERVICE_NAME_AUTH/register" \
      -H "Content-Type: application/json" \
      -d '{
            "username": "'$RANDOM_USERNAME'",
-           "password": "password123"
+           "password": "SecurePass123"
          }' \
      -w "\n📊 HTTP Статус: %{http_code}\n" \
      -s
@@ -39,7 +39,7 @@ echo "Ответ:"
 # Сохраняем ответ логина для извлечения токена
 LOGIN_RESPONSE=$(curl -X "POST" "$BASE_URL/$SERVICE_NAME_AUTH/login" \
      -H "Content-Type: application/json" \
-     -d '{"username": "'$RANDOM_USERNAME'","password":"password123"}' \
+     -d '{"username": "'$RANDOM_USERNAME'","password":"SecurePass123"}' \
      -w "\n📊 HTTP Статус: %{http_code}\n" \
      -s)
 
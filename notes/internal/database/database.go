@@ -7,33 +7,100 @@ import (
 	"notes/internal/errors"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Параметры текстового индекса заметок
+const (
+	TEXT_INDEX_NAME     = "notes_text" // Имя индекса
+	TEXT_INDEX_LANGUAGE = "russian"    // Язык для словоформ и стоп-слов
+)
+
 // NewDatabase - функция для создания нового подключения к базе данных
 // Принимает контекст, DSN (строку подключения)
 // Возвращает указатель на gorm.DB или ошибку, если она произошла
 func NewDatabase(cfg *config.Config) (*mongo.Client, error) {
-	// Проверяем, что DSN не пустой
-	if cfg.DBDSN == "" {
-		return nil, fmt.Errorf("%w", errors.ErrEmptyDSN)
-	}
-
 	// Создаем контекст с таймаутом для инициализации БД
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
 	// Отмена контекста при завершении работы функции
 	defer cancel()
 
+	db, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем индексы коллекции заметок, создание существующих индексов ничего не меняет
+	collection := db.Database(cfg.DB_NAME).Collection(cfg.DB_COLLECTION)
+	if err := ensureIndexes(ctx, collection); err != nil {
+		return nil, err
+	}
+
+	// Миграции данных могут выполняться дольше подключения
+	if err := runMigrations(context.Background(), collection); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseMigration, err)
+	}
+
+	return db, nil
+}
+
+// runMigrations применяет миграции, еще не примененные к коллекции заметок
+func runMigrations(ctx context.Context, collection *mongo.Collection) error {
+	applied, err := NewMigrator(collection).Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		fmt.Printf("Применена миграция %04d_%s\n", migration.Version, migration.Name)
+	}
+	fmt.Println("Все миграции успешно выполнены")
+	return nil
+}
+
+// Connect подключается к MongoDB без создания индексов и миграций
+// Используется командой migrate, сервер подключается через NewDatabase
+func Connect(ctx context.Context, cfg *config.Config) (*mongo.Client, error) {
+	// Проверяем, что DSN не пустой
+	if cfg.DBDSN == "" {
+		return nil, fmt.Errorf("%w", errors.ErrEmptyDSN)
+	}
+
 	// Применяем контекст к подключению к БД
 	db, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.DBDSN))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseConnection, err)
 	}
-
 	return db, nil
 }
 
+// ensureIndexes создает индексы для списка и поиска заметок автора
+// Каждый индекс списка покрывает фильтр по автору и одну из сортировок,
+// ID заметки в конце индекса нужен для выборки страниц по курсору.
+// Текстовый индекс начинается с автора, поэтому поиск всегда ограничен заметками одного автора
+func ensureIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "tags", Value: 1}, {Key: "_id", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "name", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName(TEXT_INDEX_NAME).
+				// Совпадение в названии важнее совпадения в содержимом
+				SetWeights(bson.D{{Key: "name", Value: 5}, {Key: "content", Value: 1}}).
+				SetDefaultLanguage(TEXT_INDEX_LANGUAGE),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrDatabaseIndexes, err)
+	}
+	return nil
+}
+
 // CloseDB также должен работать с переданным db
 func CloseDB(db *mongo.Client, cgf *config.Config) error {
 	// Создаем новый контекст с таймаутом и предусматриваем его корректное завершение.
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Коллекция истории миграций и блокировка на время их применения
+const (
+	MIGRATIONS_COLLECTION = "schema_migrations" // Коллекция истории примененных миграций
+	MIGRATION_LOCK_ID     = "lock"              // _id документа блокировки в коллекции истории
+	MIGRATION_LOCK_TTL    = 15 * time.Minute    // Через сколько блокировка упавшего экземпляра считается брошенной
+	MIGRATION_LOCK_POLL   = time.Second         // Как часто проверять, освободилась ли блокировка
+)
+
+// Migration - одна версионированная миграция данных заметок
+// У MongoDB нет схемы и транзакционных DDL, поэтому миграция - функция над коллекцией заметок.
+// Одновременно запущенные экземпляры сервиса применяют миграции по очереди под блокировкой.
+// Миграция должна быть идемпотентной: прерванная миграция выполняется заново целиком
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, notes *mongo.Collection) error
+}
+
+// MigrationStatus - состояние миграции в базе данных
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil, если миграция не применена
+	Missing   bool       // Миграция применена, но отсутствует в исполняемом файле
+}
+
+// migrationLock - документ блокировки в коллекции schema_migrations
+// Вставка документа с уникальным _id захватывает блокировку, удаление освобождает
+type migrationLock struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`      // Случайный идентификатор захватившего экземпляра
+	ExpiresAt time.Time `bson:"expires_at"` // После этого времени блокировку может забрать другой экземпляр
+}
+
+// schemaMigration - запись о примененной миграции в коллекции schema_migrations
+type schemaMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// migrations - все миграции по возрастанию версии
+var migrations = []Migration{
+	{Version: 1, Name: "note_timestamps", Up: backfillNoteTimestamps},
+}
+
+// Migrator применяет миграции к коллекции заметок
+type Migrator struct {
+	notes   *mongo.Collection // Коллекция заметок
+	history *mongo.Collection // Коллекция истории миграций
+}
+
+// NewMigrator создает мигратор для коллекции заметок notes
+// История миграций хранится в той же базе данных
+func NewMigrator(notes *mongo.Collection) *Migrator {
+	return &Migrator{
+		notes:   notes,
+		history: notes.Database().Collection(MIGRATIONS_COLLECTION),
+	}
+}
+
+// Up применяет все еще не примененные миграции по возрастанию версии
+// Список примененных миграций читается уже под блокировкой, поэтому экземпляр,
+// дождавшийся блокировки, не повторяет миграции, примененные другим экземпляром.
+// Возвращает примененные миграции
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func() error {
+		done, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := migration.Up(ctx, m.notes); err != nil {
+				return fmt.Errorf("миграция %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err := m.history.InsertOne(ctx, schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("миграция %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// withLock выполняет fn под блокировкой миграций
+// Если блокировка занята, ждет ее освобождения или истечения MIGRATION_LOCK_TTL
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	owner := primitive.NewObjectID().Hex()
+	for {
+		_, err := m.history.InsertOne(ctx, migrationLock{
+			ID:        MIGRATION_LOCK_ID,
+			Owner:     owner,
+			ExpiresAt: time.Now().Add(MIGRATION_LOCK_TTL),
+		})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+
+		// Блокировку держит другой экземпляр; брошенную блокировку удаляем и пробуем снова
+		_, err = m.history.DeleteOne(ctx, bson.M{"_id": MIGRATION_LOCK_ID, "expires_at": bson.M{"$lt": time.Now()}})
+		if err != nil {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", ctx.Err())
+		case <-time.After(MIGRATION_LOCK_POLL):
+		}
+	}
+	// Контекст может быть уже отменен, блокировка освобождается в любом случае
+	defer m.history.DeleteOne(context.Background(), bson.M{"_id": MIGRATION_LOCK_ID, "owner": owner})
+
+	return fn()
+}
+
+// Status возвращает состояние всех известных и примененных миграций по возрастанию версии
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	done, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := done[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	// Миграции, примененные более новой версией сервиса
+	for _, record := range done {
+		statuses = append(statuses, MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			AppliedAt: &record.AppliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// appliedVersions возвращает примененные миграции по версиям
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int]schemaMigration, error) {
+	// В коллекции истории кроме записей миграций лежит документ блокировки со строковым _id
+	cursor, err := m.history.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, err
+	}
+	var records []schemaMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	done := make(map[int]schemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
+
+// backfillNoteTimestamps заполняет время создания и изменения и автора изменения у существующих заметок
+// Время создания берется из ObjectID; время изменения неизвестно и считается равным времени создания,
+// если его еще нет. Автором изменения считается автор заметки, у обезличенных заметок поле не заполняется
+func backfillNoteTimestamps(ctx context.Context, notes *mongo.Collection) error {
+	steps := []struct {
+		filter bson.M
+		set    bson.M
+	}{
+		{
+			filter: bson.M{"created_at": bson.M{"$exists": false}},
+			set:    bson.M{"created_at": bson.M{"$toDate": "$_id"}},
+		},
+		{
+			filter: bson.M{"updated_at": bson.M{"$exists": false}},
+			set:    bson.M{"updated_at": "$created_at"},
+		},
+		{
+			filter: bson.M{"last_edited_by": bson.M{"$exists": false}, "author_id": bson.M{"$exists": true}},
+			set:    bson.M{"last_edited_by": "$author_id"},
+		},
+	}
+	for _, step := range steps {
+		// Обновление конвейером позволяет вычислить значение из полей самого документа
+		if _, err := notes.UpdateMany(ctx, step.filter, mongo.Pipeline{{{Key: "$set", Value: step.set}}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMigrationsOrder(t *testing.T) {
+	names := make(map[string]bool, len(migrations))
+	for i, migration := range migrations {
+		if migration.Version <= 0 || i > 0 && migration.Version <= migrations[i-1].Version {
+			t.Errorf("миграция %04d_%s: версии должны строго возрастать", migration.Version, migration.Name)
+		}
+		if migration.Name == "" || names[migration.Name] {
+			t.Errorf("миграция %04d: пустое или повторяющееся имя %q", migration.Version, migration.Name)
+		}
+		if migration.Up == nil {
+			t.Errorf("миграция %04d_%s: не задана функция Up", migration.Version, migration.Name)
+		}
+		names[migration.Name] = true
+	}
+}
+
+// TestMigratorMongo проверяет миграции на MongoDB, если задан NOTES_TEST_MONGO_URI
+// Для каждого запуска создается отдельная база, которая удаляется после теста
+func TestMigratorMongo(t *testing.T) {
+	uri := os.Getenv("NOTES_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("NOTES_TEST_MONGO_URI не задан")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("notes_migrate_test_" + primitive.NewObjectID().Hex())
+	defer db.Drop(context.Background())
+	notes := db.Collection("notes")
+
+	legacyID := primitive.NewObjectIDFromTimestamp(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	editedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	anonymousID := primitive.NewObjectID()
+	_, err = notes.InsertMany(ctx, []interface{}{
+		bson.M{"_id": legacyID, "name": "старая", "author_id": 7},
+		bson.M{"_id": primitive.NewObjectID(), "name": "измененная", "author_id": 8, "updated_at": editedAt, "last_edited_by": 9},
+		bson.M{"_id": anonymousID, "name": "без автора"},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	// Одновременно запущенные экземпляры применяют миграции ровно один раз
+	migrator := NewMigrator(notes)
+	var wg sync.WaitGroup
+	applied := make([][]Migration, 3)
+	errs := make([]error, len(applied))
+	for i := range applied {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range applied {
+		if errs[i] != nil {
+			t.Fatalf("Up: %v", errs[i])
+		}
+		total += len(applied[i])
+	}
+	if total != len(migrations) {
+		t.Errorf("Up: применено %d миграций, ожидалось %d", total, len(migrations))
+	}
+
+	var legacy bson.M
+	if err := notes.FindOne(ctx, bson.M{"_id": legacyID}).Decode(&legacy); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	createdAt, _ := legacy["created_at"].(primitive.DateTime)
+	if !createdAt.Time().Equal(legacyID.Timestamp()) {
+		t.Errorf("created_at = %v, ожидалось %v", createdAt.Time(), legacyID.Timestamp())
+	}
+	if legacy["updated_at"] != legacy["created_at"] {
+		t.Errorf("updated_at = %v, ожидалось время создания", legacy["updated_at"])
+	}
+	if legacy["last_edited_by"] != int32(7) {
+		t.Errorf("last_edited_by = %v, ожидался автор заметки", legacy["last_edited_by"])
+	}
+
+	// Существующие значения не перезаписываются
+	var edited bson.M
+	if err := notes.FindOne(ctx, bson.M{"name": "измененная"}).Decode(&edited); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if updatedAt, _ := edited["updated_at"].(primitive.DateTime); !updatedAt.Time().Equal(editedAt) || edited["last_edited_by"] != int32(9) {
+		t.Errorf("перезаписаны поля измененной заметки: %v", edited)
+	}
+
+	// У обезличенной заметки автор изменения не заполняется
+	var anonymous bson.M
+	if err := notes.FindOne(ctx, bson.M{"_id": anonymousID}).Decode(&anonymous); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if _, ok := anonymous["last_edited_by"]; ok {
+		t.Errorf("last_edited_by заполнен у заметки без автора: %v", anonymous)
+	}
+
+	// Повторный запуск ничего не применяет
+	again, err := migrator.Up(ctx)
+	if err != nil || len(again) != 0 {
+		t.Errorf("повторный Up = %d миграций, %v", len(again), err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil || status.Missing {
+			t.Errorf("Status: миграция %04d_%s не отмечена примененной", status.Version, status.Name)
+		}
+	}
+	if n, err := db.Collection(MIGRATIONS_COLLECTION).CountDocuments(ctx, bson.M{"_id": MIGRATION_LOCK_ID}); err != nil || n != 0 {
+		t.Errorf("блокировка миграций не освобождена: %d, %v", n, err)
+	}
+}
@@ -13,6 +13,13 @@ var (
 	ErrNoteUpdate        = errors.New("ошибка обновления заметки")
 	ErrNoteDeletion      = errors.New("ошибка удаления заметки")
 
+	// Ошибки списка заметок
+	ErrInvalidListQuery = errors.New("неверные параметры списка заметок")
+	ErrInvalidCursor    = errors.New("неверный курсор страницы")
+
+	// Ошибки поиска заметок
+	ErrInvalidSearchQuery = errors.New("неверный поисковый запрос")
+
 	// Ошибки авторизации
 	ErrMissingAuthHeader = errors.New("отсутствует заголовок Authorization")
 	ErrInvalidAuthFormat = errors.New("неверный формат токена")
@@ -37,6 +44,8 @@ var (
 	ErrCacheSerialization = errors.New("ошибка сериализации данных для кэша")
 	ErrIterationNotes     = errors.New("ошибка итерации по заметкам")
 	ErrDecodeNote         = errors.New("ошибка декодирования заметки")
+	ErrDatabaseIndexes    = errors.New("ошибка создания индексов базы данных")
+	ErrDatabaseMigration  = errors.New("ошибка миграции базы данных")
 
 	// Ошибки конфигурации
 	ErrMissingEnvVar = errors.New("переменная окружения не установлена")
@@ -57,6 +66,16 @@ const (
 	MsgNoteCreation      = "Ошибка создания заметки"
 	MsgNoteUpdate        = "Ошибка обновления заметки"
 	MsgNoteDeletion      = "Ошибка удаления заметки"
+	MsgNoteForbidden     = "Заметка принадлежит другому пользователю"
+
+	// Сообщения для списка заметок
+	MsgInvalidListQuery = "Неверные параметры списка заметок"
+	MsgInvalidCursor    = "Неверный курсор страницы, запросите список с первой страницы"
+	MsgInvalidTags      = "Неверные метки: не больше 20 меток длиной до 32 символов"
+	MsgConflictingTags  = "Нельзя одновременно передать метки и удалить все метки"
+
+	// Сообщения для поиска заметок
+	MsgInvalidSearchQuery = "Неверный поисковый запрос"
 
 	// Сообщения для авторизации
 	MsgMissingAuthHeader = "Отсутствует заголовок Authorization"
@@ -92,4 +111,5 @@ const (
 	MsgNoteDeleted = "Заметка успешно удалена"
 	MsgNoteFound   = "Заметка найдена"
 	MsgNotesFound  = "Заметки получены"
+	MsgNotesSearch = "Поиск выполнен"
 )
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"notes/internal/config"
+	"notes/internal/service"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Типы событий, публикуемых сервисом auth
+const (
+	EVENT_USER_CREATED = "user.created"
+	EVENT_USER_UPDATED = "user.updated"
+	EVENT_USER_DELETED = "user.deleted"
+)
+
+const (
+	readBlock    = 5 * time.Second // Сколько ждать новых событий за одно чтение по умолчанию
+	readCount    = 100             // Максимальное число событий за одно чтение
+	retryBackoff = 5 * time.Second // Пауза перед повторной обработкой после ошибки по умолчанию
+)
+
+// userEventPayload - полезная нагрузка событий пользователя
+type userEventPayload struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username,omitempty"`
+}
+
+// Consumer читает доменные события сервиса auth из Redis Stream в группе потребителей
+// Событие подтверждается (XACK) только после успешной обработки, поэтому при сбое
+// оно будет обработано повторно. Обработчики идемпотентны
+type Consumer struct {
+	client  *redis.Client
+	service service.Service
+	stream  string
+	group   string
+	name    string        // Имя потребителя в группе
+	policy  string        // Политика обработки заметок удаленного пользователя
+	block   time.Duration // Сколько ждать новых событий за одно чтение
+	backoff time.Duration // Пауза перед повторной обработкой после ошибки
+}
+
+// NewConsumer создает потребителя событий по настройкам из конфигурации
+func NewConsumer(client *redis.Client, service service.Service, cfg *config.Config) *Consumer {
+	return &Consumer{
+		client:  client,
+		service: service,
+		stream:  cfg.EventsStream,
+		group:   cfg.EventsGroup,
+		name:    cfg.EventsConsumer,
+		policy:  cfg.UserDeletedPolicy,
+		block:   readBlock,
+		backoff: retryBackoff,
+	}
+}
+
+// Run обрабатывает события до отмены ctx
+// Сначала дочитываются события, полученные ранее, но не подтвержденные, затем новые
+func (c *Consumer) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := c.createGroup(ctx)
+		if err == nil {
+			break
+		}
+		fmt.Printf("Ошибка создания группы потребителей событий: %v\n", err)
+		c.sleep(ctx, c.backoff)
+	}
+
+	// "0" - неподтвержденные события этого потребителя, ">" - новые события
+	lastID := "0"
+	for ctx.Err() == nil {
+		next, ok := c.poll(ctx, lastID)
+		if !ok {
+			c.sleep(ctx, c.backoff)
+		}
+		lastID = next
+	}
+}
+
+// createGroup создает группу потребителей и поток, если их еще нет
+func (c *Consumer) createGroup(ctx context.Context) error {
+	err := c.client.WithContext(ctx).XGroupCreateMkStream(c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// poll читает и обрабатывает одну порцию событий, начиная с lastID
+// Возвращает ID, с которого читать дальше, и false, если нужно сделать паузу перед повтором
+func (c *Consumer) poll(ctx context.Context, lastID string) (string, bool) {
+	client := c.client.WithContext(ctx)
+
+	streams, err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  []string{c.stream, lastID},
+		Count:    readCount,
+		Block:    c.block,
+	}).Result()
+	if err == redis.Nil {
+		return lastID, true
+	}
+	if err != nil {
+		fmt.Printf("Ошибка чтения событий: %v\n", err)
+		return lastID, false
+	}
+
+	pending := 0
+	for _, stream := range streams {
+		pending += len(stream.Messages)
+		for _, message := range stream.Messages {
+			if err := c.handle(ctx, message); err != nil {
+				// Необработанное событие остается в списке ожидающих и будет прочитано снова
+				fmt.Printf("Ошибка обработки события %s: %v\n", message.ID, err)
+				return "0", false
+			}
+			if err := client.XAck(c.stream, c.group, message.ID).Err(); err != nil {
+				fmt.Printf("Ошибка подтверждения события %s: %v\n", message.ID, err)
+			}
+		}
+	}
+
+	if lastID == "0" && pending == 0 {
+		return ">", true
+	}
+	return lastID, true
+}
+
+// handle применяет событие к заметкам
+// Событие с неверной полезной нагрузкой выводится в лог и подтверждается, так как повтор не поможет
+func (c *Consumer) handle(ctx context.Context, message redis.XMessage) error {
+	eventType, _ := message.Values["type"].(string)
+	if eventType != EVENT_USER_DELETED {
+		// События создания и изменения пользователя сервису заметок не нужны
+		return nil
+	}
+
+	raw, _ := message.Values["payload"].(string)
+	var payload userEventPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil || payload.UserID == 0 {
+		fmt.Printf("Пропущено событие %s с неверными данными: %q\n", message.ID, raw)
+		return nil
+	}
+
+	switch c.policy {
+	case config.USER_DELETED_ANONYMIZE:
+		count, err := c.service.AnonymizeByAuthor(ctx, payload.UserID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Заметки удаленного пользователя %d отвязаны от автора: %d\n", payload.UserID, count)
+	default:
+		count, err := c.service.DeleteByAuthor(ctx, payload.UserID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Заметки удаленного пользователя %d удалены: %d\n", payload.UserID, count)
+	}
+	return nil
+}
+
+// sleep ждет d или отмены ctx
+func (c *Consumer) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
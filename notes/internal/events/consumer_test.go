@@ -0,0 +1,234 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"notes/internal/config"
+	"notes/internal/service"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// stubNotes - сервис заметок, запоминающий удаление и обезличивание заметок авторов
+type stubNotes struct {
+	service.Service
+	mu         sync.Mutex
+	deleted    []int
+	anonymized []int
+	failures   int // Сколько следующих вызовов завершатся ошибкой
+}
+
+func (s *stubNotes) DeleteByAuthor(ctx context.Context, authorID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return 0, errors.New("mongo недоступен")
+	}
+	s.deleted = append(s.deleted, authorID)
+	return 1, nil
+}
+
+func (s *stubNotes) AnonymizeByAuthor(ctx context.Context, authorID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anonymized = append(s.anonymized, authorID)
+	return 1, nil
+}
+
+// newTestConsumer создает потребителя поверх Redis в памяти с уже созданной группой
+func newTestConsumer(t *testing.T, notes *stubNotes, policy string) (*Consumer, *redis.Client) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	consumer := NewConsumer(client, notes, &config.Config{
+		EventsStream:      "auth.events",
+		EventsGroup:       "notes",
+		EventsConsumer:    "notes-1",
+		UserDeletedPolicy: policy,
+	})
+	consumer.block = 10 * time.Millisecond
+	if err := consumer.createGroup(context.Background()); err != nil {
+		t.Fatalf("createGroup: %v", err)
+	}
+	// Повторное создание группы при перезапуске не является ошибкой
+	if err := consumer.createGroup(context.Background()); err != nil {
+		t.Fatalf("createGroup повторно: %v", err)
+	}
+	return consumer, client
+}
+
+// addEvent добавляет событие в поток в том же формате, что и публикатор сервиса auth
+func addEvent(t *testing.T, client *redis.Client, eventType string, userID int, payload string) {
+	t.Helper()
+	err := client.XAdd(&redis.XAddArgs{
+		Stream: "auth.events",
+		ID:     "*",
+		Values: map[string]interface{}{
+			"event_id":     "event-" + strconv.Itoa(userID) + "-" + eventType,
+			"type":         eventType,
+			"aggregate_id": strconv.Itoa(userID),
+			"payload":      payload,
+			"occurred_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		t.Fatalf("XAdd: %v", err)
+	}
+}
+
+// pendingCount возвращает число полученных, но не подтвержденных событий группы
+func pendingCount(t *testing.T, client *redis.Client) int64 {
+	t.Helper()
+	pending, err := client.XPending("auth.events", "notes").Result()
+	if err != nil {
+		t.Fatalf("XPending: %v", err)
+	}
+	return pending.Count
+}
+
+// TestConsumerUserDeleted проверяет, что по событию user.deleted удаляются заметки пользователя,
+// остальные события и события с неверными данными пропускаются, и все события подтверждаются
+func TestConsumerUserDeleted(t *testing.T) {
+	ctx := context.Background()
+	notes := &stubNotes{}
+	consumer, client := newTestConsumer(t, notes, config.USER_DELETED_DELETE)
+
+	addEvent(t, client, EVENT_USER_CREATED, 1, `{"user_id":1,"username":"alice"}`)
+	addEvent(t, client, EVENT_USER_DELETED, 1, `{"user_id":1}`)
+	addEvent(t, client, EVENT_USER_DELETED, 2, `not json`)
+
+	// Неподтвержденных событий нет, потребитель переходит к новым
+	lastID, ok := consumer.poll(ctx, "0")
+	if !ok || lastID != ">" {
+		t.Fatalf("poll(0) = %q, %v, ожидался переход к новым событиям", lastID, ok)
+	}
+	if lastID, ok = consumer.poll(ctx, lastID); !ok || lastID != ">" {
+		t.Fatalf("poll(>) = %q, %v", lastID, ok)
+	}
+
+	if len(notes.deleted) != 1 || notes.deleted[0] != 1 {
+		t.Errorf("удалены заметки авторов %v, ожидалось [1]", notes.deleted)
+	}
+	if count := pendingCount(t, client); count != 0 {
+		t.Errorf("неподтвержденных событий: %d", count)
+	}
+
+	// Новых событий нет: чтение завершается по таймауту без ошибки
+	if lastID, ok = consumer.poll(ctx, lastID); !ok || lastID != ">" {
+		t.Errorf("poll без событий = %q, %v", lastID, ok)
+	}
+}
+
+// TestConsumerAnonymize проверяет политику обезличивания заметок удаленного пользователя
+func TestConsumerAnonymize(t *testing.T) {
+	notes := &stubNotes{}
+	consumer, client := newTestConsumer(t, notes, config.USER_DELETED_ANONYMIZE)
+	addEvent(t, client, EVENT_USER_DELETED, 3, `{"user_id":3}`)
+
+	if _, ok := consumer.poll(context.Background(), ">"); !ok {
+		t.Fatal("poll: ошибка обработки")
+	}
+	if len(notes.anonymized) != 1 || notes.anonymized[0] != 3 || len(notes.deleted) != 0 {
+		t.Errorf("обезличены %v, удалены %v, ожидалось обезличивание заметок автора 3", notes.anonymized, notes.deleted)
+	}
+}
+
+// TestConsumerRedelivery проверяет, что событие, обработка которого завершилась ошибкой,
+// остается неподтвержденным и обрабатывается повторно, в том числе после перезапуска потребителя
+func TestConsumerRedelivery(t *testing.T) {
+	ctx := context.Background()
+	notes := &stubNotes{failures: 2}
+	consumer, client := newTestConsumer(t, notes, config.USER_DELETED_DELETE)
+	addEvent(t, client, EVENT_USER_DELETED, 1, `{"user_id":1}`)
+
+	lastID, ok := consumer.poll(ctx, ">")
+	if ok || lastID != "0" {
+		t.Fatalf("poll с ошибкой обработки = %q, %v, ожидался повтор неподтвержденных", lastID, ok)
+	}
+	if count := pendingCount(t, client); count != 1 {
+		t.Fatalf("неподтвержденных событий: %d, ожидалось 1", count)
+	}
+
+	// Повторная ошибка оставляет событие в списке ожидающих
+	if lastID, ok = consumer.poll(ctx, lastID); ok || lastID != "0" {
+		t.Fatalf("повторный poll с ошибкой = %q, %v", lastID, ok)
+	}
+
+	// Перезапущенный потребитель с тем же именем дочитывает неподтвержденное событие
+	restarted := NewConsumer(client, notes, &config.Config{
+		EventsStream:      "auth.events",
+		EventsGroup:       "notes",
+		EventsConsumer:    "notes-1",
+		UserDeletedPolicy: config.USER_DELETED_DELETE,
+	})
+	restarted.block = 10 * time.Millisecond
+	if lastID, ok = restarted.poll(ctx, "0"); !ok || lastID != "0" {
+		t.Fatalf("poll после перезапуска = %q, %v", lastID, ok)
+	}
+	if len(notes.deleted) != 1 || notes.deleted[0] != 1 {
+		t.Fatalf("удалены заметки авторов %v, ожидалось [1]", notes.deleted)
+	}
+	if count := pendingCount(t, client); count != 0 {
+		t.Errorf("неподтвержденных событий после обработки: %d", count)
+	}
+	if lastID, ok = restarted.poll(ctx, lastID); !ok || lastID != ">" {
+		t.Errorf("poll после обработки неподтвержденных = %q, %v, ожидался переход к новым событиям", lastID, ok)
+	}
+}
+
+// TestConsumerRun проверяет, что Run создает группу, обрабатывает события и завершается при отмене ctx
+func TestConsumerRun(t *testing.T) {
+	notes := &stubNotes{}
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	consumer := NewConsumer(client, notes, &config.Config{
+		EventsStream:      "auth.events",
+		EventsGroup:       "notes",
+		EventsConsumer:    "notes-1",
+		UserDeletedPolicy: config.USER_DELETED_DELETE,
+	})
+	consumer.block = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		consumer.Run(ctx)
+		close(done)
+	}()
+
+	// Событие, опубликованное после запуска, обрабатывается
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Exists("auth.events").Val() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Run не создал поток")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	addEvent(t, client, EVENT_USER_DELETED, 5, `{"user_id":5}`)
+	for {
+		notes.mu.Lock()
+		handled := len(notes.deleted) == 1
+		notes.mu.Unlock()
+		if handled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Run не обработал событие")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run не завершился после отмены контекста")
+	}
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	stderrors "errors"
+	jwtmanager "jwt_manager"
+	"notes/internal/errors"
+	"notes/internal/models"
+	"notes/proto/notesv1"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Размер страницы потока ListNotes
+const (
+	DEFAULT_LIST_PAGE_SIZE = 100  // Размер страницы, если клиент его не указал
+	MAX_LIST_PAGE_SIZE     = 1000 // Максимальный размер страницы
+)
+
+// GRPCHandler реализует gRPC API сервиса заметок
+// Использует сервис и JWT менеджер обработчика HTTP, поэтому
+// правила доступа к заметкам у обоих API одинаковые
+type GRPCHandler struct {
+	notesv1.UnimplementedNotesServiceServer
+	h *Handler
+}
+
+// Проверка, что GRPCHandler реализует сгенерированный интерфейс сервера
+var _ notesv1.NotesServiceServer = (*GRPCHandler)(nil)
+
+// NewGRPCHandler создает обработчик gRPC API поверх обработчика HTTP
+func NewGRPCHandler(h *Handler) *GRPCHandler {
+	return &GRPCHandler{h: h}
+}
+
+// UnaryAuthInterceptor возвращает gRPC interceptor для проверки токена в унарных вызовах
+func (h *Handler) UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return h.jwtManager.UnaryServerInterceptor()
+}
+
+// StreamAuthInterceptor возвращает gRPC interceptor для проверки токена в потоковых вызовах
+func (h *Handler) StreamAuthInterceptor() grpc.StreamServerInterceptor {
+	return h.jwtManager.StreamServerInterceptor()
+}
+
+// CreateNote создает заметку, как и POST /notes/note
+func (g *GRPCHandler) CreateNote(ctx context.Context, req *notesv1.CreateNoteRequest) (*notesv1.CreateNoteResponse, error) {
+	authorID, err := authorize(ctx, jwtmanager.SCOPE_NOTES_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, ok := models.NormalizeTags(req.GetTags())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidTags)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	createdNote, err := g.h.service.Create(ctx, models.Note{
+		Name:     req.GetName(),
+		Content:  req.GetContent(),
+		AuthorID: authorID,
+		Tags:     tags,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.MsgNoteCreation)
+	}
+
+	return &notesv1.CreateNoteResponse{
+		Note: noteMessage(createdNote),
+	}, nil
+}
+
+// GetNote возвращает заметку текущего пользователя, как и GET /notes/note/:id
+func (g *GRPCHandler) GetNote(ctx context.Context, req *notesv1.GetNoteRequest) (*notesv1.GetNoteResponse, error) {
+	authorID, err := authorize(ctx, jwtmanager.SCOPE_NOTES_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	note, err := g.ownNote(ctx, req.GetId(), authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.GetNoteResponse{
+		Note: noteMessage(note),
+	}, nil
+}
+
+// UpdateNote меняет заметку текущего пользователя, как и PUT /notes/note/:id
+func (g *GRPCHandler) UpdateNote(ctx context.Context, req *notesv1.UpdateNoteRequest) (*notesv1.UpdateNoteResponse, error) {
+	authorID, err := authorize(ctx, jwtmanager.SCOPE_NOTES_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	// Метки меняются, только если они переданы или задан clear_tags, как nil и пустой список в HTTP API
+	var tags []string
+	switch {
+	case req.GetClearTags() && len(req.GetTags()) > 0:
+		return nil, status.Error(codes.InvalidArgument, errors.MsgConflictingTags)
+	case req.GetClearTags():
+		tags = []string{}
+	case len(req.GetTags()) > 0:
+		var ok bool
+		if tags, ok = models.NormalizeTags(req.GetTags()); !ok {
+			return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidTags)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Сначала проверяем, что заметка существует и принадлежит пользователю
+	if _, err := g.ownNote(ctx, req.GetId(), authorID); err != nil {
+		return nil, err
+	}
+
+	updatedNote, err := g.h.service.Update(ctx, models.Note{
+		ID:           req.GetId(),
+		Name:         req.GetName(),
+		Content:      req.GetContent(),
+		AuthorID:     authorID,
+		LastEditedBy: authorID,
+		Tags:         tags,
+	})
+	if err != nil {
+		return nil, serviceError(err, errors.MsgNoteUpdate)
+	}
+
+	return &notesv1.UpdateNoteResponse{
+		Note: noteMessage(updatedNote),
+	}, nil
+}
+
+// DeleteNote удаляет заметку текущего пользователя, как и DELETE /notes/note/:id
+func (g *GRPCHandler) DeleteNote(ctx context.Context, req *notesv1.DeleteNoteRequest) (*notesv1.DeleteNoteResponse, error) {
+	authorID, err := authorize(ctx, jwtmanager.SCOPE_NOTES_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(g.h.cfg.DBTimeout)*time.Second)
+	defer cancel()
+
+	// Сначала проверяем, что заметка существует и принадлежит пользователю
+	if _, err := g.ownNote(ctx, req.GetId(), authorID); err != nil {
+		return nil, err
+	}
+
+	if err := g.h.service.Delete(ctx, req.GetId()); err != nil {
+		return nil, serviceError(err, errors.MsgNoteDeletion)
+	}
+
+	return &notesv1.DeleteNoteResponse{}, nil
+}
+
+// ListNotes передает заметки текущего пользователя страницами по page_size
+// Заметки читаются из курсора MongoDB по мере отправки, в памяти хранится не больше одной страницы.
+// Поток не ограничен таймаутом базы данных и завершается вместе с контекстом вызова
+func (g *GRPCHandler) ListNotes(req *notesv1.ListNotesRequest, stream grpc.ServerStreamingServer[notesv1.ListNotesResponse]) error {
+	authorID, err := authorize(stream.Context(), jwtmanager.SCOPE_NOTES_READ)
+	if err != nil {
+		return err
+	}
+
+	pageSize := req.GetPageSize()
+	if pageSize <= 0 {
+		pageSize = DEFAULT_LIST_PAGE_SIZE
+	}
+	if pageSize > MAX_LIST_PAGE_SIZE {
+		pageSize = MAX_LIST_PAGE_SIZE
+	}
+
+	page := make([]*notesv1.Note, 0, pageSize)
+	err = g.h.service.ForEachByAuthor(stream.Context(), authorID, pageSize, func(note models.Note) error {
+		page = append(page, noteMessage(&note))
+		if len(page) < int(pageSize) {
+			return nil
+		}
+		if err := stream.Send(&notesv1.ListNotesResponse{Notes: page}); err != nil {
+			return err
+		}
+		page = make([]*notesv1.Note, 0, pageSize)
+		return nil
+	})
+	if err != nil {
+		// Клиент отменил вызов или истек его срок
+		if ctxErr := stream.Context().Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		// Ошибка отправки уже содержит статус gRPC
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, errors.MsgDatabaseOperation)
+	}
+
+	// Последняя неполная страница
+	if len(page) > 0 {
+		return stream.Send(&notesv1.ListNotesResponse{Notes: page})
+	}
+	return nil
+}
+
+// ownNote получает заметку и проверяет, что она принадлежит автору
+func (g *GRPCHandler) ownNote(ctx context.Context, id string, authorID int) (*models.Note, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, errors.MsgInvalidNoteID)
+	}
+
+	note, err := g.h.service.GetByID(ctx, id)
+	if err != nil {
+		return nil, serviceError(err, errors.MsgDatabaseOperation)
+	}
+
+	// Проверяем владельца
+	if note.AuthorID != authorID {
+		return nil, status.Error(codes.PermissionDenied, errors.MsgNoteForbidden)
+	}
+	return note, nil
+}
+
+// authorize возвращает ID автора из токена, если токен содержит область доступа scope
+// Токен проверяется interceptor'ом, который сохраняет утверждения в контексте
+func authorize(ctx context.Context, scope string) (int, error) {
+	claims, err := jwtmanager.ClaimsFromContext(ctx)
+	if err != nil {
+		return 0, status.Error(codes.Unauthenticated, errors.MsgMissingUserID)
+	}
+	if !claims.HasScope(scope) {
+		return 0, status.Error(codes.PermissionDenied, jwtmanager.MsgInsufficientScope)
+	}
+	return claims.UserID, nil
+}
+
+// serviceError преобразует ошибку сервиса в ошибку gRPC
+// message используется для ошибок, которые не связаны с запросом клиента
+func serviceError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, errors.ErrInvalidNoteID):
+		return status.Error(codes.InvalidArgument, errors.MsgInvalidNoteID)
+	case stderrors.Is(err, errors.ErrNoteNotFound):
+		return status.Error(codes.NotFound, errors.MsgNoteNotFound)
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+// noteMessage преобразует модель заметки в сообщение gRPC API
+func noteMessage(note *models.Note) *notesv1.Note {
+	message := &notesv1.Note{
+		Id:           note.ID,
+		Name:         note.Name,
+		Content:      note.Content,
+		AuthorId:     int64(note.AuthorID),
+		Tags:         note.Tags,
+		LastEditedBy: int64(note.LastEditedBy),
+	}
+	if note.CreatedAt != nil {
+		message.CreatedAt = timestamppb.New(*note.CreatedAt)
+	}
+	if note.UpdatedAt != nil {
+		message.UpdatedAt = timestamppb.New(*note.UpdatedAt)
+	}
+	return message
+}
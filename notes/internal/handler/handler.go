@@ -2,12 +2,16 @@ package handler
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	jwtmanager "jwt_manager"
 	"net/http"
 	"notes/internal/config"
 	"notes/internal/errors"
 	"notes/internal/models"
 	"notes/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,20 +24,44 @@ type Handler struct {
 }
 
 // NewHandler создает новый экземпляр обработчика заметок
-func NewHandler(cfg *config.Config, service service.Service) *Handler {
+// revocations - общее с сервисом auth хранилище отозванных токенов
+// pats - общее с сервисом auth хранилище персональных токенов доступа
+func NewHandler(cfg *config.Config, service service.Service, revocations jwtmanager.RevocationStore, pats jwtmanager.PATStore) (*Handler, error) {
 	// Создаем JWT менеджер
 	jwtConfig := jwtmanager.JWTConfig{
 		SecretKey:              cfg.JWTSecretKey,
 		AccessTokenExpiration:  24,  // 24 часа по умолчанию
 		RefreshTokenExpiration: 168, // 7 дней по умолчанию
+		Issuer:                 cfg.JWTIssuer,
+		Audience:               cfg.JWTAudience,
+		ClockSkew:              time.Duration(cfg.JWTClockSkew) * time.Second,
+		RevocationStore:        revocations,
+		PATStore:               pats,
+		LegacyScope:            cfg.JWTLegacyScope,
+	}
+	// Если указан JWKS, сервис проверяет токены только открытыми ключами auth
+	// и не хранит секрет, которым можно выпустить токен
+	if cfg.JWKSURL != "" {
+		jwtConfig.SecretKey = ""
+		jwtConfig.KeySource = jwtmanager.NewJWKSClient(cfg.JWKSURL, time.Duration(cfg.JWKSCacheTTL)*time.Second)
+	} else if cfg.JWTKeysFile != "" {
+		// Связка ключей позволяет принимать токены, подписанные как новым, так и предыдущим ключом
+		keys, err := jwtmanager.LoadKeysFile(cfg.JWTKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		jwtConfig.Keys = keys
+	}
+	jwtManager, err := jwtmanager.NewJWTManager(jwtConfig)
+	if err != nil {
+		return nil, err
 	}
-	jwtManager := jwtmanager.NewJWTManager(jwtConfig)
 
 	return &Handler{
 		cfg:        cfg,
 		jwtManager: jwtManager,
 		service:    service,
-	}
+	}, nil
 }
 
 // GetJWTMiddleware возвращает JWT middleware для использования в роутах
@@ -69,6 +97,18 @@ func (h *Handler) CreateNote(c *gin.Context) {
 		return
 	}
 
+	// Метки хранятся в нижнем регистре без повторов
+	if note.Tags != nil {
+		tags, ok := models.NormalizeTags(note.Tags)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors.MsgInvalidTags,
+			})
+			return
+		}
+		note.Tags = tags
+	}
+
 	// Устанавливаем ID автора из токена
 	note.AuthorID = authorID
 	// Создаем контекст для работы с сервисом
@@ -179,9 +219,21 @@ func (h *Handler) UpdateNote(c *gin.Context) {
 		})
 		return
 	}
+	// Метки меняются, только если они переданы; пустой список удаляет все метки
+	if note.Tags != nil {
+		tags, ok := models.NormalizeTags(note.Tags)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors.MsgInvalidTags,
+			})
+			return
+		}
+		note.Tags = tags
+	}
 	// Устанавливаем ID заметки и ID автора
 	note.ID = id
-	note.AuthorID = authorID // Убеждаемся, что автор не изменился
+	note.AuthorID = authorID     // Убеждаемся, что автор не изменился
+	note.LastEditedBy = authorID // Изменение записывается на текущего пользователя
 	// Вызываем сервис для обновления заметки
 	updatedNote, err := h.service.Update(ctx, note)
 	if err != nil {
@@ -251,8 +303,11 @@ func (h *Handler) DeleteNote(c *gin.Context) {
 	})
 }
 
-// GetAllNotes получает список всех заметок текущего пользователя
-// GET /notes
+// GetAllNotes получает страницу заметок текущего пользователя
+// GET /notes?limit=&cursor=&sort=created|updated|name&order=asc|desc&created_after=&created_before=
+// &updated_after=&updated_before=&tag=&last_edited_by=
+// Время в created_* и updated_* передается в формате RFC3339.
+// Следующая страница запрашивается с теми же параметрами и cursor из next_cursor предыдущего ответа
 func (h *Handler) GetAllNotes(c *gin.Context) {
 	// Извлекаем ID автора из JWT токена
 	authorID, err := h.extractAuthorID(c)
@@ -263,22 +318,161 @@ func (h *Handler) GetAllNotes(c *gin.Context) {
 		})
 		return
 	}
+	// Разбираем параметры страницы
+	query, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   errors.MsgInvalidListQuery,
+			"details": err.Error(),
+		})
+		return
+	}
 	// Создаем контекст для работы с сервисом
 	ctx := context.Background()
-	// Вызываем сервис для получения всех заметок текущего пользователя
-	notes, err := h.service.GetAll(ctx, authorID)
+	// Вызываем сервис для получения страницы заметок текущего пользователя
+	page, err := h.service.List(ctx, authorID, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   errors.MsgDatabaseOperation,
+		switch {
+		case stderrors.Is(err, errors.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors.MsgInvalidCursor,
+			})
+		case stderrors.Is(err, errors.ErrInvalidListQuery):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   errors.MsgInvalidListQuery,
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   errors.MsgDatabaseOperation,
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	// Пустой курсор означает, что страница последняя
+	var nextCursor any
+	if page.NextCursor != "" {
+		nextCursor = page.NextCursor
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     errors.MsgNotesFound,
+		"notes":       page.Notes,
+		"count":       len(page.Notes),
+		"author_id":   authorID,
+		"next_cursor": nextCursor,
+	})
+}
+
+// SearchNotes ищет заметки текущего пользователя по названию и содержимому
+// GET /search?q=&limit=&cursor=
+// Запрос поддерживает "фразы в кавычках" и исключение слов минусом: q=отчет "второй квартал" -черновик.
+// Следующая страница запрашивается с тем же q и cursor из next_cursor предыдущего ответа
+func (h *Handler) SearchNotes(c *gin.Context) {
+	// Извлекаем ID автора из JWT токена
+	authorID, err := h.extractAuthorID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   errors.MsgMissingUserID,
 			"details": err.Error(),
 		})
 		return
 	}
-	// Проверяем, что заметки найдены
+	// Разбираем параметры поиска
+	query := service.SearchQuery{
+		Text:   c.Query("q"),
+		Cursor: c.Query("cursor"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   errors.MsgInvalidSearchQuery,
+				"details": "limit должен быть числом",
+			})
+			return
+		}
+		query.Limit = limit
+	}
+	// Создаем контекст для работы с сервисом
+	ctx := context.Background()
+	// Вызываем сервис для поиска по заметкам текущего пользователя
+	page, err := h.service.Search(ctx, authorID, query)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, errors.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors.MsgInvalidCursor,
+			})
+		case stderrors.Is(err, errors.ErrInvalidSearchQuery):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   errors.MsgInvalidSearchQuery,
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   errors.MsgDatabaseOperation,
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	// Пустой курсор означает, что страница последняя
+	var nextCursor any
+	if page.NextCursor != "" {
+		nextCursor = page.NextCursor
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":   errors.MsgNotesFound,
-		"notes":     notes,
-		"count":     len(notes),
-		"author_id": authorID,
+		"message":     errors.MsgNotesSearch,
+		"results":     page.Results,
+		"count":       len(page.Results),
+		"author_id":   authorID,
+		"next_cursor": nextCursor,
 	})
 }
+
+// parseListQuery разбирает параметры страницы списка из строки запроса
+// Значения по умолчанию и допустимые значения проверяет сервис
+func parseListQuery(c *gin.Context) (service.ListQuery, error) {
+	query := service.ListQuery{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Tag:    c.Query("tag"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("limit должен быть числом: %w", err)
+		}
+		query.Limit = limit
+	}
+	if value := c.Query("last_edited_by"); value != "" {
+		editorID, err := strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("last_edited_by должен быть числом: %w", err)
+		}
+		query.LastEditedBy = editorID
+	}
+	timeParams := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"created_after", &query.CreatedAfter},
+		{"created_before", &query.CreatedBefore},
+		{"updated_after", &query.UpdatedAfter},
+		{"updated_before", &query.UpdatedBefore},
+	}
+	for _, param := range timeParams {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("%s должен быть в формате RFC3339: %w", param.name, err)
+		}
+		*param.value = parsed
+	}
+	return query, nil
+}
@@ -1,7 +1,19 @@
 package models
 
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения для меток заметки
+const (
+	MAX_NOTE_TAGS  = 20 // Максимальное число меток у заметки
+	MAX_TAG_LENGTH = 32 // Максимальная длина метки в символах
+)
+
 // Note - структура для представления заметки
-// Содержит поля ID, Name, Content и AuthorID
+// Содержит поля ID, Name, Content, AuthorID, метки и служебные поля времени и авторства изменений
 // Используется для сериализации в JSON и BSON
 // Используется в качестве модели для работы с базой данных
 type Note struct {
@@ -13,4 +25,38 @@ type Note struct {
 	Content string `json:"content,omitempty" bson:"content,omitempty"`
 	//  AuthorID - идентификатор автора заметки
 	AuthorID int `json:"author_id,omitempty" bson:"author_id,omitempty"`
+	//  Tags - метки заметки в нижнем регистре, по ним фильтруется список
+	Tags []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	//  CreatedAt - время создания, устанавливается сервисом
+	CreatedAt *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	//  UpdatedAt - время последнего изменения, устанавливается сервисом
+	UpdatedAt *time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	//  LastEditedBy - ID пользователя, который последним создал или изменил заметку, устанавливается сервисом
+	LastEditedBy int `json:"last_edited_by,omitempty" bson:"last_edited_by,omitempty"`
+}
+
+// NormalizeTags приводит метки к нижнему регистру, убирает пробелы по краям,
+// пустые метки и повторы. Возвращает false, если меток больше MAX_NOTE_TAGS
+// или какая-то метка длиннее MAX_TAG_LENGTH
+func NormalizeTags(tags []string) ([]string, bool) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MAX_TAG_LENGTH {
+			return nil, false
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MAX_NOTE_TAGS {
+		return nil, false
+	}
+	return normalized, true
 }
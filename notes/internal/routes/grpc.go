@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"notes/internal/handler"
+	"notes/proto/notesv1"
+
+	"google.golang.org/grpc"
+)
+
+// SetupGRPCServer создает gRPC сервер с API сервиса заметок
+// Все методы требуют токен, который проверяется interceptor'ами JWT менеджера
+func SetupGRPCServer(noteHandler *handler.Handler) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(noteHandler.UnaryAuthInterceptor()),
+		grpc.ChainStreamInterceptor(noteHandler.StreamAuthInterceptor()),
+	)
+	notesv1.RegisterNotesServiceServer(server, handler.NewGRPCHandler(noteHandler))
+	return server
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	jwtmanager "jwt_manager"
 	"notes/internal/handler"
 
 	"github.com/gin-gonic/gin"
@@ -16,16 +17,23 @@ func SetupRouter(noteHandler *handler.Handler) *gin.Engine {
 	noteAPI := router.Group("/notes")
 	noteAPI.Use(noteHandler.GetJWTMiddleware()) // Применяем JWT middleware ко всем роутам
 	{
+		// Чтение заметок требует области notes:read
+		read := jwtmanager.RequireScopes(jwtmanager.SCOPE_NOTES_READ)
+		// Изменение заметок требует области notes:write
+		write := jwtmanager.RequireScopes(jwtmanager.SCOPE_NOTES_WRITE)
+
 		// Создание заметки
-		noteAPI.POST("/note", noteHandler.CreateNote)
+		noteAPI.POST("/note", write, noteHandler.CreateNote)
 		// Получение заметки по ID
-		noteAPI.GET("/note/:id", noteHandler.GetNoteByID)
+		noteAPI.GET("/note/:id", read, noteHandler.GetNoteByID)
 		// Редактирование заметки
-		noteAPI.PUT("/note/:id", noteHandler.UpdateNote)
+		noteAPI.PUT("/note/:id", write, noteHandler.UpdateNote)
 		// Удаление заметки
-		noteAPI.DELETE("/note/:id", noteHandler.DeleteNote)
+		noteAPI.DELETE("/note/:id", write, noteHandler.DeleteNote)
 		// Получение списка всех заметок
-		noteAPI.GET("/notes", noteHandler.GetAllNotes)
+		noteAPI.GET("/notes", read, noteHandler.GetAllNotes)
+		// Полнотекстовый поиск по заметкам
+		noteAPI.GET("/search", read, noteHandler.SearchNotes)
 	}
 
 	return router
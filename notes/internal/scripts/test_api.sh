@@ -19,7 +19,7 @@ echo "Ответ:"
 # Сохраняем ответ логина для извлечения токена - отдельно тело и статус
 LOGIN_RESPONSE=$(curl -X "POST" "$AUTH_BASE_URL/login" \
      -H "Content-Type: application/json" \
-     -d '{"username": "testuser","password":"password123"}' \
+     -d '{"username": "testuser","password":"SecurePass123"}' \
      -s)
 
 echo "$LOGIN_RESPONSE"
@@ -27,7 +27,7 @@ echo "$LOGIN_RESPONSE"
 # Получаем статус отдельно
 LOGIN_STATUS=$(curl -X "POST" "$AUTH_BASE_URL/login" \
      -H "Content-Type: application/json" \
-     -d '{"username": "testuser","password":"password123"}' \
+     -d '{"username": "testuser","password":"SecurePass123"}' \
      -w "%{http_code}" \
      -s -o /dev/null)
 
@@ -51,7 +51,7 @@ echo "Ответ:"
 CREATE_RESPONSE=$(curl -X "POST" "$BASE_URL/note" \
      -H "Authorization: Bearer $TOKEN" \
      -H "Content-Type: application/json" \
-     -d '{"name":"Test Note","content":"Test Content"}' \
+     -d '{"name":"Test Note","content":"Test Content","tags":["test"]}' \
      -w "\n📊 HTTP Статус: %{http_code}\n")
      
 # Извлекаем ID созданной заметки из ответа
@@ -63,10 +63,23 @@ echo "-------------------------------------------"
 sleep 2
 # Тест 2: Получение списка всех заметок
 echo ""
-echo "🔍 Получение списка всех заметок"
-echo "Запрос: GET $BASE_URL/notes"
+echo "🔍 Получение первой страницы заметок с меткой test"
+echo "Запрос: GET $BASE_URL/notes?limit=10&sort=updated&tag=test"
 echo "Ответ:"
-curl -X "GET" "$BASE_URL/notes" \
+curl -X "GET" "$BASE_URL/notes?limit=10&sort=updated&tag=test" \
+     -H "Authorization: Bearer $TOKEN" \
+     -H "Content-Type: application/json" \
+     -w "\n📊 HTTP Статус: %{http_code}\n"
+echo "-------------------------------------------"
+
+# Небольшая пауза между запросами
+sleep 2
+# Поиск по заметкам
+echo ""
+echo "🔍 Поиск заметок по тексту"
+echo "Запрос: GET $BASE_URL/search?q=test"
+echo "Ответ:"
+curl -X "GET" "$BASE_URL/search?q=test" \
      -H "Authorization: Bearer $TOKEN" \
      -H "Content-Type: application/json" \
      -w "\n📊 HTTP Статус: %{http_code}\n"
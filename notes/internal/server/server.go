@@ -1,14 +1,21 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	jwtmanager "jwt_manager"
+	"net"
 
+	"notes/internal/caching"
 	"notes/internal/config"
+	"notes/internal/errors"
+	"notes/internal/events"
 	"notes/internal/handler"
 	"notes/internal/routes"
 	"notes/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 // Структура сервера
@@ -17,6 +24,12 @@ type Server struct {
 	cfg *config.Config
 	// Маршрутизатор Gin
 	router *gin.Engine // Новое поле для маршрутизатора
+	// grpcServer - gRPC API рядом с HTTP; nil, если gRPC отключен
+	grpcServer *grpc.Server
+	// consumer - потребитель событий пользователей из сервиса auth
+	consumer *events.Consumer
+	// stopConsumer останавливает потребителя событий
+	stopConsumer context.CancelFunc
 }
 
 // NewServer - конструктор сервера
@@ -29,19 +42,36 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("не удалось создать сервис: %w", err)
 	}
+	// Отозванные токены хранятся в Redis, который используется совместно с сервисом auth
+	cache, err := caching.NewCaching(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrCacheConnection, err)
+	}
+	revocations := jwtmanager.NewRedisRevocationStore(cache)
+	// Персональные токены выпускает сервис auth, заметки проверяют их по тому же Redis
+	pats := jwtmanager.NewRedisPATStore(cache)
 	// Создаем новый экземпляр обработчика
-	handler := handler.NewHandler(cfg, service)
+	handler, err := handler.NewHandler(cfg, service, revocations, pats)
 	// Проверяем, что обработчик успешно создан
-	if handler == nil {
-		return nil, fmt.Errorf("не удалось создать обработчик сервера")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать обработчик сервера: %w", err)
 	}
 	fmt.Println("Обработчик сервера успешно создан")
 	// Создаем новый экземпляр маршрутизатора
 	router := routes.SetupRouter(handler)
+	// gRPC API использует тот же обработчик, что и HTTP
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = routes.SetupGRPCServer(handler)
+	}
+	// Удаление пользователя в auth приходит событием user.deleted через Redis Stream
+	consumer := events.NewConsumer(cache, service, cfg)
 	// Создаем новый экземпляр сервера
 	return &Server{
-		router: router,
-		cfg:    cfg,
+		router:     router,
+		grpcServer: grpcServer,
+		cfg:        cfg,
+		consumer:   consumer,
 	}, nil
 }
 
@@ -53,6 +83,13 @@ func (s *Server) Start() error {
 
 // Stop - остановка сервера
 func (s *Server) Stop() error {
+	if s.stopConsumer != nil {
+		s.stopConsumer()
+	}
+	// Дожидаемся завершения текущих gRPC вызовов
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
 	fmt.Println("Сервер остановлен")
 	return nil
 }
@@ -63,6 +100,27 @@ func (s *Server) Serve() error {
 		return err
 	}
 
+	// Обрабатываем события пользователей в фоне
+	consumerCtx, cancel := context.WithCancel(context.Background())
+	s.stopConsumer = cancel
+	go s.consumer.Run(consumerCtx)
+
+	// gRPC API слушает отдельный порт; ошибка при запуске останавливает сервер
+	if s.grpcServer != nil {
+		grpcAddress := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.GRPCPort)
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("не удалось запустить gRPC сервер на %s: %w", grpcAddress, err)
+		}
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				fmt.Printf("gRPC сервер остановлен с ошибкой: %v\n", err)
+			}
+		}()
+		fmt.Printf("gRPC сервер готов к обработке запросов на %s...\n", grpcAddress)
+	}
+
 	// Запускаем сервер
 	address := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port)
 	fmt.Printf("Сервер готов к обработке запросов на %s...\n", address)
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"notes/internal/errors"
+	"notes/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Время жизни кэша списков заметок
+const (
+	// CACHE_PAGE_TTL - сколько хранится страница списка
+	CACHE_PAGE_TTL = 10 * time.Minute
+	// CACHE_VERSION_TTL - сколько хранится версия списка без изменений заметок автора.
+	// Больше CACHE_PAGE_TTL, поэтому к моменту сброса версии все страницы старых версий уже удалены
+	CACHE_VERSION_TTL = 24 * time.Hour
+)
+
+// noteDocument - заметка в том виде, в котором она хранится в MongoDB
+// ID заметки хранится в поле _id и не попадает в models.Note при декодировании
+type noteDocument struct {
+	ObjectID    primitive.ObjectID `bson:"_id"`
+	models.Note `bson:",inline"`
+}
+
+// note возвращает заметку с заполненным ID
+func (d *noteDocument) note() models.Note {
+	note := d.Note
+	note.ID = d.ObjectID.Hex()
+	return note
+}
+
+// List возвращает страницу заметок автора
+// Страницы выбираются по курсору (позиции последней заметки предыдущей страницы), а не смещением,
+// поэтому заметки, добавленные между запросами, не сдвигают и не дублируют страницы
+func (m *MongoService) List(ctx context.Context, authorID int, query ListQuery) (*NotePage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	var after *listCursor
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, query.fingerprint())
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+
+	// Страницы кэшируются под текущей версией списка автора
+	version, cacheable := m.authorCacheVersion(authorID)
+	pageKey := m.getPageCacheKey(authorID, version, &query)
+	if cacheable {
+		if page, found := m.getCachedPage(pageKey); found {
+			return page, nil
+		}
+	}
+
+	conditions := bson.A{bson.M{"author_id": authorID}}
+	conditions = appendTimeRange(conditions, "created_at", query.CreatedAfter, query.CreatedBefore)
+	conditions = appendTimeRange(conditions, "updated_at", query.UpdatedAfter, query.UpdatedBefore)
+	if query.Tag != "" {
+		conditions = append(conditions, bson.M{"tags": query.Tag})
+	}
+	if query.LastEditedBy != 0 {
+		conditions = append(conditions, bson.M{"last_edited_by": query.LastEditedBy})
+	}
+	if after != nil {
+		seek, err := seekCondition(&query, after)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, seek)
+	}
+
+	// Запрашиваем на одну заметку больше, чтобы узнать, есть ли следующая страница
+	opts := options.Find().
+		SetSort(sortFields(&query)).
+		SetLimit(int64(query.Limit) + 1)
+	cursor, err := m.collection.Find(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+	}
+	defer cursor.Close(ctx)
+
+	page := &NotePage{Notes: make([]models.Note, 0, query.Limit)}
+	var last *noteDocument
+	lastEmpty := false
+	hasMore := false
+	for cursor.Next(ctx) {
+		if len(page.Notes) == query.Limit {
+			hasMore = true
+			break
+		}
+		var document noteDocument
+		if err := cursor.Decode(&document); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrDecodeNote, err)
+		}
+		page.Notes = append(page.Notes, document.note())
+		last = &document
+		// Отсутствующее поле и null декодируются как пустое значение, но сортируются отдельно
+		value, err := cursor.Current.LookupErr(sortField(&query))
+		lastEmpty = err != nil || value.Type == bsontype.Null
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrIterationNotes, err)
+	}
+
+	if hasMore {
+		next := listCursor{
+			Query: query.fingerprint(),
+			ID:    last.ObjectID.Hex(),
+			Empty: lastEmpty,
+		}
+		switch query.Sort {
+		case SORT_NAME:
+			next.Name = last.Name
+		case SORT_CREATED:
+			next.Time = last.CreatedAt
+		case SORT_UPDATED:
+			next.Time = last.UpdatedAt
+		}
+		page.NextCursor, err = encodeCursor(next)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+		}
+	}
+
+	if cacheable {
+		m.cachePage(pageKey, page)
+	}
+	return page, nil
+}
+
+// appendTimeRange добавляет к условиям ограничение поля времени field
+// Нулевое время не ограничивает выборку с соответствующей стороны
+func appendTimeRange(conditions bson.A, field string, after, before time.Time) bson.A {
+	if !after.IsZero() {
+		conditions = append(conditions, bson.M{field: bson.M{"$gte": after}})
+	}
+	if !before.IsZero() {
+		conditions = append(conditions, bson.M{field: bson.M{"$lt": before}})
+	}
+	return conditions
+}
+
+// sortField возвращает поле документа, по которому сортирует запрос
+func sortField(query *ListQuery) string {
+	switch query.Sort {
+	case SORT_NAME:
+		return "name"
+	case SORT_UPDATED:
+		return "updated_at"
+	default:
+		return "created_at"
+	}
+}
+
+// sortFields возвращает порядок сортировки запроса
+// ID заметки завершает порядок, чтобы заметки с одинаковым значением поля не терялись между страницами
+func sortFields(query *ListQuery) bson.D {
+	direction := -1
+	if query.Order == ORDER_ASC {
+		direction = 1
+	}
+	return bson.D{{Key: sortField(query), Value: direction}, {Key: "_id", Value: direction}}
+}
+
+// seekCondition возвращает условие выборки заметок, которые идут после позиции курсора
+func seekCondition(query *ListQuery, after *listCursor) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(after.ID)
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	compare := "$lt"
+	if query.Order == ORDER_ASC {
+		compare = "$gt"
+	}
+	field := sortField(query)
+
+	// Значение поля последней заметки; nil, если поля у нее нет
+	var value any
+	switch {
+	case after.Empty:
+	case query.Sort == SORT_NAME:
+		value = after.Name
+	case after.Time != nil:
+		value = *after.Time
+	}
+
+	// Заметки без поля сортировки (старые документы без названия или времени) не должны
+	// ломать страницы: null меньше любого значения, такие заметки идут первыми
+	// по возрастанию и последними по убыванию
+	if value == nil {
+		if query.Order == ORDER_ASC {
+			return bson.M{"$or": bson.A{
+				bson.M{field: bson.M{"$ne": nil}},
+				bson.M{field: nil, "_id": bson.M{"$gt": id}},
+			}}, nil
+		}
+		return bson.M{field: nil, "_id": bson.M{"$lt": id}}, nil
+	}
+	conditions := bson.A{
+		bson.M{field: bson.M{compare: value}},
+		bson.M{field: value, "_id": bson.M{compare: id}},
+	}
+	if query.Order == ORDER_DESC {
+		conditions = append(conditions, bson.M{field: nil})
+	}
+	return bson.M{"$or": conditions}, nil
+}
+
+// getVersionCacheKey возвращает ключ версии списка заметок автора
+// Версия увеличивается при каждом изменении заметок автора, страницы старых версий больше не читаются
+func (m *MongoService) getVersionCacheKey(authorID int) string {
+	return fmt.Sprintf("notes:author:%d:version", authorID)
+}
+
+// getPageCacheKey возвращает ключ страницы списка заметок автора для версии списка version
+func (m *MongoService) getPageCacheKey(authorID int, version int64, query *ListQuery) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%s", query.fingerprint(), query.Limit, query.Cursor))
+	return fmt.Sprintf("notes:author:%d:page:%d:%s", authorID, version, hex.EncodeToString(sum[:16]))
+}
+
+// authorCacheVersion возвращает текущую версию списка заметок автора
+// Возвращает false, если кэш недоступен и страницы не нужно ни читать, ни сохранять
+func (m *MongoService) authorCacheVersion(authorID int) (int64, bool) {
+	if m.caching == nil {
+		return 0, false
+	}
+	value, err := m.caching.Get(m.getVersionCacheKey(authorID)).Result()
+	if err == redis.Nil {
+		return 0, true
+	}
+	if err != nil {
+		fmt.Printf("Ошибка при получении версии кэша для автора с ID %d: %v\n", authorID, err)
+		return 0, false
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// invalidateAuthorCache инвалидирует кэш списков заметок автора
+// Страницы не удаляются по одной: новая версия делает их недоступными, а удаляются они по TTL
+func (m *MongoService) invalidateAuthorCache(authorID int) {
+	if m.caching == nil {
+		return
+	}
+	versionKey := m.getVersionCacheKey(authorID)
+	pipe := m.caching.TxPipeline()
+	pipe.Incr(versionKey)
+	pipe.Expire(versionKey, CACHE_VERSION_TTL)
+	if _, err := pipe.Exec(); err != nil {
+		fmt.Printf("Ошибка при инвалидации кэша для автора с ID %d: %v\n", authorID, err)
+		return
+	}
+	fmt.Println("Кэш для автора с ID", authorID, "был успешно инвалидирован")
+}
+
+// purgeAuthorCache удаляет все ключи кэша автора: версию и страницы всех версий списка
+// Используется при удалении пользователя, чтобы его заметки не оставались в кэше до истечения TTL
+func (m *MongoService) purgeAuthorCache(authorID int) {
+	if m.caching == nil {
+		return
+	}
+	pattern := fmt.Sprintf("notes:author:%d:*", authorID)
+	var cursor uint64
+	for {
+		keys, next, err := m.caching.Scan(cursor, pattern, 1000).Result()
+		if err != nil {
+			fmt.Printf("Ошибка при поиске ключей кэша для автора с ID %d: %v\n", authorID, err)
+			return
+		}
+		if len(keys) > 0 {
+			if err := m.caching.Del(keys...).Err(); err != nil {
+				fmt.Printf("Ошибка при удалении кэша для автора с ID %d: %v\n", authorID, err)
+				return
+			}
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	fmt.Println("Кэш для автора с ID", authorID, "был удален")
+}
+
+// cachePage сохраняет страницу списка в кэш
+func (m *MongoService) cachePage(key string, page *NotePage) {
+	data, err := json.Marshal(page)
+	if err != nil {
+		fmt.Printf("%v: %v\n", errors.ErrCacheSerialization, err)
+		return
+	}
+	if err := m.caching.Set(key, data, CACHE_PAGE_TTL).Err(); err != nil {
+		fmt.Printf("%v: %v\n", errors.ErrCacheSet, err)
+	}
+}
+
+// getCachedPage получает страницу списка из кэша
+func (m *MongoService) getCachedPage(key string) (*NotePage, bool) {
+	data, err := m.caching.Get(key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			fmt.Printf("%v: %v\n", errors.ErrCacheGet, err)
+		}
+		return nil, false
+	}
+	var page NotePage
+	if err := json.Unmarshal(data, &page); err != nil {
+		fmt.Printf("%v: %v\n", errors.ErrCacheSerialization, err)
+		return nil, false
+	}
+	return &page, true
+}
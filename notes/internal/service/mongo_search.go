@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"notes/internal/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scoredDocument - найденная заметка с релевантностью из текстового индекса
+type scoredDocument struct {
+	noteDocument `bson:",inline"`
+	Score        float64 `bson:"score"`
+}
+
+// Search ищет заметки автора по текстовому индексу названия и содержимого
+// Результаты упорядочены по убыванию релевантности, при равной релевантности - от новых к старым.
+// Результаты поиска не кэшируются: запросы редко повторяются, а ключ зависел бы от текста запроса
+func (m *MongoService) Search(ctx context.Context, authorID int, query SearchQuery) (*SearchPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	// $text должен быть в первой стадии, автор ограничивает выборку до ранжирования
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"author_id": authorID,
+			"$text":     bson.M{"$search": query.Text},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, query.fingerprint())
+		if err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(after.ID)
+		if err != nil {
+			return nil, errors.ErrInvalidCursor
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"score": bson.M{"$lt": after.Score}},
+			bson.M{"score": after.Score, "_id": bson.M{"$lt": id}},
+		}}}})
+	}
+
+	// Запрашиваем на один результат больше, чтобы узнать, есть ли следующая страница
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: query.Limit + 1}},
+	)
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+	}
+	defer cursor.Close(ctx)
+
+	terms := parseSearchTerms(query.Text)
+	page := &SearchPage{Results: make([]SearchResult, 0, query.Limit)}
+	var last *scoredDocument
+	hasMore := false
+	for cursor.Next(ctx) {
+		if len(page.Results) == query.Limit {
+			hasMore = true
+			break
+		}
+		var document scoredDocument
+		if err := cursor.Decode(&document); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrDecodeNote, err)
+		}
+		note := document.note()
+		page.Results = append(page.Results, SearchResult{
+			Note:       note,
+			Score:      document.Score,
+			Highlights: buildHighlights(&note, terms),
+		})
+		last = &document
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrIterationNotes, err)
+	}
+
+	if hasMore {
+		page.NextCursor, err = encodeCursor(listCursor{
+			Query: query.fingerprint(),
+			ID:    last.ObjectID.Hex(),
+			Score: last.Score,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+		}
+	}
+	return page, nil
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"notes/internal/caching"
 	"notes/internal/config"
@@ -15,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoService - реализация интерфейса Service для работы с базой данных MongoDB
@@ -61,63 +61,24 @@ func (m *MongoService) Close() error {
 	return database.CloseDB(m.db, &config.Config{Timeout: 10})
 }
 
-// getCacheKey генерирует ключ кэша для заметок автора
-func (m *MongoService) getCacheKey(authorID int) string {
-	return fmt.Sprintf("notes:author:%d", authorID)
-}
-
-// invalidateAuthorCache инвалидирует кэш для конкретного автора
-func (m *MongoService) invalidateAuthorCache(authorID int) {
-	if m.caching != nil {
-		cacheKey := m.getCacheKey(authorID)
-		m.caching.Del(cacheKey)
-		fmt.Println("Кэш для автора с ID", authorID, "был успешно инвалидирован")
-	}
-	fmt.Println("Кэш для автора с ID", authorID, "не найден или не был инвалидирован")
-}
-
-// cacheNotes сохраняет заметки в кэш
-func (m *MongoService) cacheNotes(authorID int, notes []models.Note) {
-	if m.caching != nil {
-		cacheKey := m.getCacheKey(authorID)
-		notesJSON, err := json.Marshal(notes)
-		if err == nil {
-			// Устанавливаем TTL для кэша (100 минут)
-			m.caching.Set(cacheKey, notesJSON, 100*time.Minute)
-			fmt.Println("Заметки для автора с ID", authorID, "успешно сохранены в кэш")
-		}
-	}
-}
-
-// getCachedNotes получает заметки из кэша
-func (m *MongoService) getCachedNotes(authorID int) ([]models.Note, bool) {
-	// Получаем ключ кэша для заметок автора
-	cacheKey := m.getCacheKey(authorID)
-	// Получаем заметки из кэша
-	cachedData, err := m.caching.Get(cacheKey).Result()
-	if err != nil {
-		fmt.Printf("Ошибка при получении кэша для автора с ID %d: %v\n", authorID, err)
-		return nil, false
+// Create создает новую заметку в базе данных
+// Время создания и изменения и автора изменения устанавливает сервис, значения из note не используются
+func (m *MongoService) Create(ctx context.Context, note models.Note) (*models.Note, error) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	document := bson.M{
+		"name":           note.Name,
+		"content":        note.Content,
+		"author_id":      note.AuthorID,
+		"created_at":     now,
+		"updated_at":     now,
+		"last_edited_by": note.AuthorID,
 	}
-	// Разбираем JSON в слайс заметок
-	var cachedNotes []models.Note
-	if err := json.Unmarshal([]byte(cachedData), &cachedNotes); err != nil {
-		fmt.Printf("Ошибка при разборе кэша для автора с ID %d: %v\n", authorID, err)
-		return nil, false
+	if len(note.Tags) > 0 {
+		document["tags"] = note.Tags
 	}
-	// Возвращаем заметки и true, если заметки найдены
-	fmt.Println("Заметки для автора с ID", authorID, "успешно получены из кэша")
-	return cachedNotes, true
-}
 
-// Create создает новую заметку в базе данных
-func (m *MongoService) Create(ctx context.Context, note models.Note) (*models.Note, error) {
 	// Создаем новый ObjectId для заметки
-	result, err := m.collection.InsertOne(ctx, bson.M{
-		"name":      note.Name,
-		"content":   note.Content,
-		"author_id": note.AuthorID,
-	})
+	result, err := m.collection.InsertOne(ctx, document)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errors.ErrNoteCreation, err)
 	}
@@ -125,6 +86,9 @@ func (m *MongoService) Create(ctx context.Context, note models.Note) (*models.No
 	// Получаем ID созданной заметки
 	insertedID := result.InsertedID.(primitive.ObjectID)
 	note.ID = insertedID.Hex() // Преобразуем ObjectID в строку
+	note.CreatedAt = &now
+	note.UpdatedAt = &now
+	note.LastEditedBy = note.AuthorID
 
 	// Инвалидируем кэш для этого автора
 	m.invalidateAuthorCache(note.AuthorID)
@@ -155,44 +119,37 @@ func (m *MongoService) GetByID(ctx context.Context, id string) (*models.Note, er
 	return &note, nil
 }
 
-// GetAll получает все заметки из базы данных для конкретного автора
-func (m *MongoService) GetAll(ctx context.Context, authorId int) ([]models.Note, error) {
-	// Попытаемся получить заметки из кэша
-	if cachedNotes, found := m.getCachedNotes(authorId); found {
-		// Если заметки найдены в кэше, возвращаем их
-		// Если нет, то продолжаем с запросом к базе данных
-		fmt.Println("Заметки получены из кэша")
-		return cachedNotes, nil
-	}
-
-	// Запрос к базе данных
-	filter := bson.M{"author_id": authorId}
-
-	cursor, err := m.collection.Find(ctx, filter)
+// ForEachByAuthor читает заметки автора курсором и вызывает fn для каждой заметки
+// Курсор запрашивает у MongoDB по batchSize документов, поэтому в памяти
+// не хранится весь список. Кэш не используется. Ошибка fn прерывает чтение и возвращается как есть
+func (m *MongoService) ForEachByAuthor(ctx context.Context, authorID int, batchSize int32, fn func(models.Note) error) error {
+	opts := options.Find().SetBatchSize(batchSize).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.collection.Find(ctx, bson.M{"author_id": authorID}, opts)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+		return fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
 	}
 	defer cursor.Close(ctx)
 
-	var notes []models.Note
 	for cursor.Next(ctx) {
-		var note models.Note
-		if err := cursor.Decode(&note); err != nil {
-			return nil, fmt.Errorf("%w: %v", errors.ErrDecodeNote, err)
+		var document noteDocument
+		if err := cursor.Decode(&document); err != nil {
+			return fmt.Errorf("%w: %v", errors.ErrDecodeNote, err)
+		}
+		if err := fn(document.note()); err != nil {
+			return err
 		}
-		notes = append(notes, note)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("%w: %v", errors.ErrIterationNotes, err)
+		return fmt.Errorf("%w: %v", errors.ErrIterationNotes, err)
 	}
-	// Если изначально кэш не был найден, сохраняем результат в кэш
-	m.cacheNotes(authorId, notes)
-
-	return notes, nil
+	return nil
 }
 
 // Update обновляет существующую заметку
+// Метки меняются, только если note.Tags не nil; пустой слайс удаляет все метки.
+// Автором изменения записывается note.LastEditedBy, а если он не указан - note.AuthorID.
+// Возвращает заметку в том виде, в котором она сохранена после изменения
 func (m *MongoService) Update(ctx context.Context, note models.Note) (*models.Note, error) {
 	// Преобразуем строку ID в ObjectID
 	objectID, err := primitive.ObjectIDFromHex(note.ID)
@@ -200,39 +157,40 @@ func (m *MongoService) Update(ctx context.Context, note models.Note) (*models.No
 		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidNoteID, err)
 	}
 
-	// Сначала получаем существующую заметку для получения AuthorID
-	var existingNote models.Note
-	err = m.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&existingNote)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("%w: заметка с ID %s не найдена", errors.ErrNoteNotFound, note.ID)
-		}
-		return nil, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+	editorID := note.LastEditedBy
+	if editorID == 0 {
+		editorID = note.AuthorID
 	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"name":    note.Name,
-			"content": note.Content,
-		},
+	set := bson.M{
+		"name":           note.Name,
+		"content":        note.Content,
+		"updated_at":     time.Now().UTC().Truncate(time.Millisecond),
+		"last_edited_by": editorID,
+	}
+	update := bson.M{"$set": set}
+	if note.Tags != nil {
+		if len(note.Tags) > 0 {
+			set["tags"] = note.Tags
+		} else {
+			update["$unset"] = bson.M{"tags": ""}
+		}
 	}
 
-	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var document noteDocument
+	err = m.collection.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&document)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: заметка с ID %s не найдена", errors.ErrNoteNotFound, note.ID)
+		}
 		return nil, fmt.Errorf("%w: %v", errors.ErrNoteUpdate, err)
 	}
 
-	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("%w: заметка с ID %s не найдена", errors.ErrNoteNotFound, note.ID)
-	}
-
 	// Инвалидируем кэш
-	m.invalidateAuthorCache(existingNote.AuthorID)
-
-	// Устанавливаем AuthorID из существующей заметки
-	note.AuthorID = existingNote.AuthorID
+	m.invalidateAuthorCache(document.AuthorID)
 
-	return &note, nil
+	updatedNote := document.note()
+	return &updatedNote, nil
 }
 
 // Delete удаляет заметку по идентификатору
@@ -266,3 +224,63 @@ func (m *MongoService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// DeleteByAuthor удаляет все заметки автора
+// Возвращает число удаленных заметок. Повторный вызов безопасен
+func (m *MongoService) DeleteByAuthor(ctx context.Context, authorID int) (int64, error) {
+	result, err := m.collection.DeleteMany(ctx, bson.M{"author_id": authorID})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errors.ErrNoteDeletion, err)
+	}
+	m.purgeAuthorCache(authorID)
+
+	return result.DeletedCount, nil
+}
+
+// AnonymizeByAuthor удаляет у заметок ссылки на автора, сохраняя сами заметки
+// Ссылка удаляется и из автора изменения, в том числе в заметках других авторов.
+// Возвращает число измененных заметок. Повторный вызов безопасен
+func (m *MongoService) AnonymizeByAuthor(ctx context.Context, authorID int) (int64, error) {
+	// Кэш списков хранится по авторам заметок, поэтому сначала находим авторов,
+	// чьи заметки последним изменял удаляемый пользователь
+	editedAuthors, err := m.collection.Distinct(ctx, "author_id", bson.M{"last_edited_by": authorID})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errors.ErrDatabaseOperation, err)
+	}
+
+	// $$REMOVE удаляет поле, только если оно ссылается на автора, остальные поля не меняются
+	unsetIfAuthor := func(field string) bson.M {
+		return bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$" + field, authorID}}, "$$REMOVE", "$" + field}}
+	}
+	result, err := m.collection.UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"author_id": authorID}, bson.M{"last_edited_by": authorID}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"author_id":      unsetIfAuthor("author_id"),
+			"last_edited_by": unsetIfAuthor("last_edited_by"),
+		}}}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errors.ErrNoteUpdate, err)
+	}
+
+	m.purgeAuthorCache(authorID)
+	for _, value := range editedAuthors {
+		if id, ok := documentInt(value); ok && id != authorID {
+			m.invalidateAuthorCache(id)
+		}
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// documentInt приводит целое число из документа MongoDB к int
+func documentInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
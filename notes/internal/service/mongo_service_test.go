@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testCollection возвращает коллекцию заметок в отдельной базе MongoDB из NOTES_TEST_MONGO_URI
+// База удаляется после теста. Если NOTES_TEST_MONGO_URI не задан, тест пропускается
+func testCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+	uri := os.Getenv("NOTES_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("NOTES_TEST_MONGO_URI не задан")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	db := client.Database("notes_service_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db.Collection("notes")
+}
+
+func TestDeleteByAuthor(t *testing.T) {
+	collection := testCollection(t)
+	m := &MongoService{collection: collection}
+	ctx := context.Background()
+
+	_, err := collection.InsertMany(ctx, []any{
+		bson.M{"name": "первая", "author_id": 7},
+		bson.M{"name": "вторая", "author_id": 7},
+		bson.M{"name": "чужая", "author_id": 8, "last_edited_by": 7},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	count, err := m.DeleteByAuthor(ctx, 7)
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteByAuthor = %d, %v; ожидалось 2 заметки", count, err)
+	}
+	if n, _ := collection.CountDocuments(ctx, bson.M{"author_id": 7}); n != 0 {
+		t.Errorf("осталось %d заметок удаленного пользователя", n)
+	}
+	if n, _ := collection.CountDocuments(ctx, bson.M{"author_id": 8}); n != 1 {
+		t.Errorf("удалены заметки другого автора: осталось %d", n)
+	}
+
+	// Повторная обработка события безопасна
+	if count, err := m.DeleteByAuthor(ctx, 7); err != nil || count != 0 {
+		t.Errorf("повторный DeleteByAuthor = %d, %v", count, err)
+	}
+}
+
+func TestAnonymizeByAuthor(t *testing.T) {
+	collection := testCollection(t)
+	m := &MongoService{collection: collection}
+	ctx := context.Background()
+
+	_, err := collection.InsertMany(ctx, []any{
+		bson.M{"name": "своя", "author_id": 7, "last_edited_by": 7},
+		bson.M{"name": "чужая, изменена удаляемым", "author_id": 8, "last_edited_by": 7},
+		bson.M{"name": "чужая", "author_id": 8, "last_edited_by": 9},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	count, err := m.AnonymizeByAuthor(ctx, 7)
+	if err != nil || count != 2 {
+		t.Fatalf("AnonymizeByAuthor = %d, %v; ожидалось 2 заметки", count, err)
+	}
+	if n, _ := collection.CountDocuments(ctx, bson.M{"$or": bson.A{bson.M{"author_id": 7}, bson.M{"last_edited_by": 7}}}); n != 0 {
+		t.Errorf("осталось %d заметок со ссылкой на удаленного пользователя", n)
+	}
+
+	// Ссылки на других пользователей сохраняются
+	var edited, other bson.M
+	collection.FindOne(ctx, bson.M{"name": "чужая, изменена удаляемым"}).Decode(&edited)
+	collection.FindOne(ctx, bson.M{"name": "чужая"}).Decode(&other)
+	if edited["author_id"] != int32(8) || other["author_id"] != int32(8) || other["last_edited_by"] != int32(9) {
+		t.Errorf("изменены ссылки на других пользователей: %v, %v", edited, other)
+	}
+	if _, ok := edited["last_edited_by"]; ok {
+		t.Errorf("last_edited_by не удален: %v", edited)
+	}
+
+	if count, err := m.AnonymizeByAuthor(ctx, 7); err != nil || count != 0 {
+		t.Errorf("повторный AnonymizeByAuthor = %d, %v", count, err)
+	}
+}
+
+func TestDocumentInt(t *testing.T) {
+	for _, value := range []any{int32(5), int64(5)} {
+		if id, ok := documentInt(value); !ok || id != 5 {
+			t.Errorf("documentInt(%T) = %d, %v", value, id, ok)
+		}
+	}
+	if _, ok := documentInt("5"); ok {
+		t.Error("documentInt: строка приведена к числу")
+	}
+}
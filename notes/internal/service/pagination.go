@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"notes/internal/errors"
+	"notes/internal/models"
+	"strings"
+	"time"
+)
+
+// Поля сортировки списка заметок
+const (
+	SORT_CREATED = "created" // По времени создания
+	SORT_UPDATED = "updated" // По времени последнего изменения
+	SORT_NAME    = "name"    // По названию
+)
+
+// Направления сортировки
+const (
+	ORDER_ASC  = "asc"  // По возрастанию
+	ORDER_DESC = "desc" // По убыванию
+)
+
+// Размер страницы списка заметок
+const (
+	DEFAULT_LIST_LIMIT = 20  // Размер страницы, если клиент его не указал
+	MAX_LIST_LIMIT     = 100 // Максимальный размер страницы
+)
+
+// ListQuery - параметры запроса страницы заметок автора
+type ListQuery struct {
+	Limit         int       // Число заметок на странице, от 1 до MAX_LIST_LIMIT
+	Sort          string    // Поле сортировки: SORT_CREATED, SORT_UPDATED или SORT_NAME
+	Order         string    // Направление сортировки: ORDER_ASC или ORDER_DESC
+	Cursor        string    // Курсор следующей страницы из предыдущего ответа; пустой для первой страницы
+	CreatedAfter  time.Time // Только заметки, созданные не раньше этого времени; нулевое время не ограничивает
+	CreatedBefore time.Time // Только заметки, созданные раньше этого времени; нулевое время не ограничивает
+	UpdatedAfter  time.Time // Только заметки, измененные не раньше этого времени; нулевое время не ограничивает
+	UpdatedBefore time.Time // Только заметки, измененные раньше этого времени; нулевое время не ограничивает
+	Tag           string    // Только заметки с этой меткой; пустая строка не ограничивает
+	LastEditedBy  int       // Только заметки, последним изменил этот пользователь; 0 не ограничивает
+}
+
+// NotePage - страница списка заметок
+type NotePage struct {
+	Notes      []models.Note `json:"notes"`       // Заметки страницы
+	NextCursor string        `json:"next_cursor"` // Курсор следующей страницы; пустой, если страница последняя
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры запроса
+// Сортировка по названию по умолчанию идет по возрастанию, по времени - от новых к старым
+func (q *ListQuery) Normalize() error {
+	if q.Limit == 0 {
+		q.Limit = DEFAULT_LIST_LIMIT
+	}
+	if q.Limit < 0 || q.Limit > MAX_LIST_LIMIT {
+		return fmt.Errorf("%w: limit должен быть от 1 до %d", errors.ErrInvalidListQuery, MAX_LIST_LIMIT)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = SORT_CREATED
+	case SORT_CREATED, SORT_UPDATED, SORT_NAME:
+	default:
+		return fmt.Errorf("%w: неизвестное поле сортировки %q", errors.ErrInvalidListQuery, q.Sort)
+	}
+
+	switch q.Order {
+	case "":
+		q.Order = ORDER_DESC
+		if q.Sort == SORT_NAME {
+			q.Order = ORDER_ASC
+		}
+	case ORDER_ASC, ORDER_DESC:
+	default:
+		return fmt.Errorf("%w: неизвестное направление сортировки %q", errors.ErrInvalidListQuery, q.Order)
+	}
+
+	// Метки хранятся в нижнем регистре
+	q.Tag = strings.ToLower(strings.TrimSpace(q.Tag))
+
+	if !q.CreatedAfter.IsZero() && !q.CreatedBefore.IsZero() && !q.CreatedAfter.Before(q.CreatedBefore) {
+		return fmt.Errorf("%w: created_after должен быть раньше created_before", errors.ErrInvalidListQuery)
+	}
+	if !q.UpdatedAfter.IsZero() && !q.UpdatedBefore.IsZero() && !q.UpdatedAfter.Before(q.UpdatedBefore) {
+		return fmt.Errorf("%w: updated_after должен быть раньше updated_before", errors.ErrInvalidListQuery)
+	}
+	if q.LastEditedBy < 0 {
+		return fmt.Errorf("%w: неверный ID пользователя в last_edited_by", errors.ErrInvalidListQuery)
+	}
+	return nil
+}
+
+// fingerprint - отпечаток сортировки и фильтров запроса
+// Курсор действителен только для запроса с тем же отпечатком
+func (q *ListQuery) fingerprint() string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%s|%s|%s|%s|%s|%d", q.Sort, q.Order,
+		q.CreatedAfter.Format(time.RFC3339Nano), q.CreatedBefore.Format(time.RFC3339Nano),
+		q.UpdatedAfter.Format(time.RFC3339Nano), q.UpdatedBefore.Format(time.RFC3339Nano),
+		q.Tag, q.LastEditedBy))
+	return hex.EncodeToString(sum[:8])
+}
+
+// listCursor - позиция последней заметки страницы списка или результатов поиска
+// Следующая страница начинается после заметки с этим значением поля сортировки и ID
+type listCursor struct {
+	Query string     `json:"q"`           // Отпечаток запроса
+	ID    string     `json:"id"`          // ID последней заметки
+	Name  string     `json:"n,omitempty"` // Название последней заметки для SORT_NAME
+	Empty bool       `json:"e,omitempty"` // У последней заметки нет значения поля сортировки
+	Time  *time.Time `json:"t,omitempty"` // Время создания или изменения последней заметки для SORT_CREATED и SORT_UPDATED
+	Score float64    `json:"s,omitempty"` // Релевантность последней заметки результатов поиска
+}
+
+// encodeCursor кодирует позицию заметки в непрозрачную для клиента строку
+func encodeCursor(cursor listCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor разбирает курсор и проверяет, что он выдан для запроса с отпечатком fingerprint
+func decodeCursor(value string, fingerprint string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, errors.ErrInvalidCursor
+	}
+	if cursor.Query != fingerprint {
+		return nil, errors.ErrInvalidCursor
+	}
+	return &cursor, nil
+}
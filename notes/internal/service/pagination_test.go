@@ -0,0 +1,219 @@
+package service
+
+import (
+	stderrors "errors"
+	"notes/internal/errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListQueryNormalize(t *testing.T) {
+	query := ListQuery{Tag: "  Work "}
+	if err := query.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if query.Limit != DEFAULT_LIST_LIMIT || query.Sort != SORT_CREATED || query.Order != ORDER_DESC || query.Tag != "work" {
+		t.Errorf("Normalize: значения по умолчанию %+v", query)
+	}
+
+	byName := ListQuery{Sort: SORT_NAME}
+	if err := byName.Normalize(); err != nil || byName.Order != ORDER_ASC {
+		t.Errorf("Normalize по названию: направление %q, %v", byName.Order, err)
+	}
+
+	now := time.Now()
+	invalid := []ListQuery{
+		{Limit: -1},
+		{Limit: MAX_LIST_LIMIT + 1},
+		{Sort: "author"},
+		{Order: "up"},
+		{CreatedAfter: now, CreatedBefore: now},
+		{UpdatedAfter: now, UpdatedBefore: now.Add(-time.Hour)},
+		{LastEditedBy: -1},
+	}
+	for _, query := range invalid {
+		if err := query.Normalize(); !stderrors.Is(err, errors.ErrInvalidListQuery) {
+			t.Errorf("Normalize(%+v): ожидалась ErrInvalidListQuery, получено %v", query, err)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	query := ListQuery{Sort: SORT_UPDATED, Tag: "work"}
+	if err := query.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	cursor := listCursor{Query: query.fingerprint(), ID: primitive.NewObjectID().Hex(), Time: &at}
+
+	value, err := encodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	decoded, err := decodeCursor(value, query.fingerprint())
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if decoded.ID != cursor.ID || !decoded.Time.Equal(at) {
+		t.Errorf("decodeCursor = %+v, ожидалось %+v", decoded, cursor)
+	}
+
+	// Курсор нельзя использовать с другой сортировкой или другими фильтрами
+	for _, other := range []ListQuery{
+		{Sort: SORT_CREATED, Tag: "work"},
+		{Sort: SORT_UPDATED, Order: ORDER_ASC, Tag: "work"},
+		{Sort: SORT_UPDATED, Tag: "home"},
+		{Sort: SORT_UPDATED, Tag: "work", LastEditedBy: 2},
+		{Sort: SORT_UPDATED, Tag: "work", CreatedAfter: at},
+	} {
+		if err := other.Normalize(); err != nil {
+			t.Fatalf("Normalize: %v", err)
+		}
+		if _, err := decodeCursor(value, other.fingerprint()); !stderrors.Is(err, errors.ErrInvalidCursor) {
+			t.Errorf("decodeCursor для запроса %+v: ожидалась ErrInvalidCursor, получено %v", other, err)
+		}
+	}
+
+	noID, _ := encodeCursor(listCursor{Query: query.fingerprint()})
+	for _, bad := range []string{"!!!", "bm90IGpzb24", noID} {
+		if _, err := decodeCursor(bad, query.fingerprint()); !stderrors.Is(err, errors.ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q): ожидалась ErrInvalidCursor, получено %v", bad, err)
+		}
+	}
+}
+
+func TestSeekCondition(t *testing.T) {
+	id := primitive.NewObjectID()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		query  ListQuery
+		cursor listCursor
+		want   bson.M
+	}{
+		{
+			name:   "по времени от новых к старым",
+			query:  ListQuery{Sort: SORT_CREATED, Order: ORDER_DESC},
+			cursor: listCursor{ID: id.Hex(), Time: &at},
+			want: bson.M{"$or": bson.A{
+				bson.M{"created_at": bson.M{"$lt": at}},
+				bson.M{"created_at": at, "_id": bson.M{"$lt": id}},
+				bson.M{"created_at": nil},
+			}},
+		},
+		{
+			name:   "по названию по возрастанию",
+			query:  ListQuery{Sort: SORT_NAME, Order: ORDER_ASC},
+			cursor: listCursor{ID: id.Hex(), Name: "b"},
+			want: bson.M{"$or": bson.A{
+				bson.M{"name": bson.M{"$gt": "b"}},
+				bson.M{"name": "b", "_id": bson.M{"$gt": id}},
+			}},
+		},
+		{
+			name:   "пустое название по возрастанию",
+			query:  ListQuery{Sort: SORT_NAME, Order: ORDER_ASC},
+			cursor: listCursor{ID: id.Hex(), Empty: true},
+			want: bson.M{"$or": bson.A{
+				bson.M{"name": bson.M{"$ne": nil}},
+				bson.M{"name": nil, "_id": bson.M{"$gt": id}},
+			}},
+		},
+		{
+			name:   "пустое название по убыванию",
+			query:  ListQuery{Sort: SORT_NAME, Order: ORDER_DESC},
+			cursor: listCursor{ID: id.Hex(), Empty: true},
+			want:   bson.M{"name": nil, "_id": bson.M{"$lt": id}},
+		},
+		{
+			name:   "заметка без времени изменения",
+			query:  ListQuery{Sort: SORT_UPDATED, Order: ORDER_DESC},
+			cursor: listCursor{ID: id.Hex()},
+			want:   bson.M{"updated_at": nil, "_id": bson.M{"$lt": id}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := seekCondition(&tt.query, &tt.cursor)
+			if err != nil {
+				t.Fatalf("seekCondition: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("seekCondition = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := seekCondition(&ListQuery{}, &listCursor{ID: "not-an-object-id"}); !stderrors.Is(err, errors.ErrInvalidCursor) {
+		t.Errorf("seekCondition с неверным ID: ожидалась ErrInvalidCursor, получено %v", err)
+	}
+}
+
+func TestPageCacheKey(t *testing.T) {
+	m := &MongoService{}
+	query := ListQuery{Sort: SORT_NAME, Order: ORDER_ASC, Limit: 10}
+	key := m.getPageCacheKey(1, 3, &query)
+	if key != m.getPageCacheKey(1, 3, &query) {
+		t.Error("getPageCacheKey: разные ключи для одного запроса")
+	}
+
+	// Страница другой версии списка, другого автора, размера, курсора или фильтра - другой ключ
+	next := query
+	next.Cursor = "cursor"
+	smaller := query
+	smaller.Limit = 5
+	tagged := query
+	tagged.Tag = "work"
+	for name, other := range map[string]string{
+		"версия": m.getPageCacheKey(1, 4, &query),
+		"автор":  m.getPageCacheKey(2, 3, &query),
+		"курсор": m.getPageCacheKey(1, 3, &next),
+		"размер": m.getPageCacheKey(1, 3, &smaller),
+		"фильтр": m.getPageCacheKey(1, 3, &tagged),
+	} {
+		if other == key {
+			t.Errorf("getPageCacheKey: ключ не зависит от параметра %q", name)
+		}
+	}
+
+	// Без Redis кэш не читается и не сохраняется
+	if _, cacheable := m.authorCacheVersion(1); cacheable {
+		t.Error("authorCacheVersion: кэш доступен без Redis")
+	}
+	m.invalidateAuthorCache(1)
+}
+
+func TestPurgeAuthorCache(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	m := &MongoService{caching: client}
+
+	// Страницы нескольких версий списка удаленного автора и страница автора с похожим ID
+	query := ListQuery{Sort: SORT_NAME, Order: ORDER_ASC, Limit: 10}
+	for version := int64(0); version < 3; version++ {
+		m.cachePage(m.getPageCacheKey(1, version, &query), &NotePage{})
+		m.invalidateAuthorCache(1)
+	}
+	other := m.getPageCacheKey(12, 0, &query)
+	m.cachePage(other, &NotePage{})
+
+	m.purgeAuthorCache(1)
+
+	keys, err := client.Keys("notes:author:1:*").Result()
+	if err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("purgeAuthorCache: остались ключи %v", keys)
+	}
+	if _, ok := m.getCachedPage(other); !ok {
+		t.Error("purgeAuthorCache: удалена страница другого автора")
+	}
+}
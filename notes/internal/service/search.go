@@ -0,0 +1,304 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"notes/internal/errors"
+	"notes/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения поискового запроса
+const (
+	MAX_SEARCH_QUERY_LENGTH = 256 // Максимальная длина поискового запроса в символах
+	DEFAULT_SEARCH_LIMIT    = 20  // Размер страницы результатов, если клиент его не указал
+	MAX_SEARCH_LIMIT        = 50  // Максимальный размер страницы результатов
+)
+
+// Фрагменты текста в результатах поиска
+const (
+	SNIPPET_LENGTH  = 160 // Длина фрагмента содержимого в символах
+	SNIPPET_CONTEXT = 40  // Сколько символов показывать перед первым совпадением
+	HIGHLIGHT_OPEN  = "<mark>"
+	HIGHLIGHT_CLOSE = "</mark>"
+)
+
+// SearchQuery - параметры полнотекстового поиска по заметкам автора
+// Text использует синтаксис $text MongoDB: слова ищутся с учетом словоформ,
+// "фраза в кавычках" ищется целиком, а слово или фраза с минусом исключают заметки
+type SearchQuery struct {
+	Text   string // Поисковый запрос
+	Limit  int    // Число результатов на странице, от 1 до MAX_SEARCH_LIMIT
+	Cursor string // Курсор следующей страницы из предыдущего ответа; пустой для первой страницы
+}
+
+// SearchResult - найденная заметка
+type SearchResult struct {
+	Note       models.Note `json:"note"`       // Заметка
+	Score      float64     `json:"score"`      // Релевантность, чем больше, тем выше результат
+	Highlights Highlights  `json:"highlights"` // Фрагменты с выделенными совпадениями
+}
+
+// Highlights - фрагменты заметки, в которых совпадения обернуты в HIGHLIGHT_OPEN и HIGHLIGHT_CLOSE
+// Остальной текст экранирован как HTML, поэтому фрагменты можно вставлять в разметку как есть
+type Highlights struct {
+	Name    string `json:"name"`    // Название целиком
+	Content string `json:"content"` // Фрагмент содержимого вокруг первого совпадения
+}
+
+// SearchPage - страница результатов поиска
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`     // Результаты по убыванию релевантности
+	NextCursor string         `json:"next_cursor"` // Курсор следующей страницы; пустой, если страница последняя
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры поиска
+func (q *SearchQuery) Normalize() error {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return fmt.Errorf("%w: параметр q не указан", errors.ErrInvalidSearchQuery)
+	}
+	if utf8.RuneCountInString(q.Text) > MAX_SEARCH_QUERY_LENGTH {
+		return fmt.Errorf("%w: запрос длиннее %d символов", errors.ErrInvalidSearchQuery, MAX_SEARCH_QUERY_LENGTH)
+	}
+	if len(parseSearchTerms(q.Text)) == 0 {
+		return fmt.Errorf("%w: запрос не содержит слов для поиска", errors.ErrInvalidSearchQuery)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DEFAULT_SEARCH_LIMIT
+	}
+	if q.Limit < 0 || q.Limit > MAX_SEARCH_LIMIT {
+		return fmt.Errorf("%w: limit должен быть от 1 до %d", errors.ErrInvalidSearchQuery, MAX_SEARCH_LIMIT)
+	}
+	return nil
+}
+
+// fingerprint - отпечаток поискового запроса
+// Курсор действителен только для того же запроса
+func (q *SearchQuery) fingerprint() string {
+	sum := sha256.Sum256([]byte("search|" + q.Text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// searchTerm - слово или фраза запроса, которые выделяются во фрагментах
+type searchTerm struct {
+	words  [][]rune // Слова в нижнем регистре
+	phrase bool     // Фраза совпадает только целиком, слово - по началу словоформы
+}
+
+// parseSearchTerms разбирает запрос на искомые слова и фразы
+// Исключенные слова и фразы (с минусом) не выделяются
+func parseSearchTerms(text string) []searchTerm {
+	var terms []searchTerm
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"' || (runes[i] == '-' && i+1 < len(runes) && runes[i+1] == '"'):
+			negated := runes[i] == '-'
+			if negated {
+				i++
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			words := splitWords(runes[i+1 : min(end, len(runes))])
+			if !negated && len(words) > 0 {
+				terms = append(terms, searchTerm{words: words, phrase: true})
+			}
+			i = end + 1
+		default:
+			negated := runes[i] == '-'
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			if !negated {
+				for _, word := range splitWords(runes[i:end]) {
+					terms = append(terms, searchTerm{words: [][]rune{word}})
+				}
+			}
+			i = end
+		}
+	}
+	return terms
+}
+
+// splitWords делит текст на слова из букв и цифр в нижнем регистре
+func splitWords(text []rune) [][]rune {
+	var words [][]rune
+	var word []rune
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, unicode.ToLower(r))
+			continue
+		}
+		if len(word) > 0 {
+			words = append(words, word)
+			word = nil
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+	return words
+}
+
+// wordSpan - положение слова в тексте
+type wordSpan struct {
+	start, end int    // Границы слова в рунах текста
+	lower      []rune // Слово в нижнем регистре
+}
+
+// textWords находит слова текста
+func textWords(text []rune) []wordSpan {
+	var spans []wordSpan
+	for i := 0; i < len(text); {
+		if !unicode.IsLetter(text[i]) && !unicode.IsDigit(text[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsDigit(text[i])) {
+			i++
+		}
+		lower := make([]rune, 0, i-start)
+		for _, r := range text[start:i] {
+			lower = append(lower, unicode.ToLower(r))
+		}
+		spans = append(spans, wordSpan{start: start, end: i, lower: lower})
+	}
+	return spans
+}
+
+// stem отбрасывает окончание слова, чтобы выделять и другие словоформы
+// Индекс MongoDB находит словоформы по основе, фрагменты выделяются приблизительно
+func stem(word []rune) []rune {
+	switch {
+	case len(word) >= 6:
+		return word[:len(word)-2]
+	case len(word) == 5:
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
+
+// hasPrefix проверяет, что слово начинается с prefix
+func hasPrefix(word, prefix []rune) bool {
+	if len(word) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if word[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRanges возвращает отсортированные непересекающиеся границы совпадений в рунах текста
+func matchRanges(text []rune, terms []searchTerm) [][2]int {
+	words := textWords(text)
+	var ranges [][2]int
+	for _, term := range terms {
+		if !term.phrase {
+			prefix := stem(term.words[0])
+			for _, word := range words {
+				if hasPrefix(word.lower, prefix) {
+					ranges = append(ranges, [2]int{word.start, word.end})
+				}
+			}
+			continue
+		}
+		// Фраза совпадает, если ее слова идут в тексте подряд
+		for i := 0; i+len(term.words) <= len(words); i++ {
+			matched := true
+			for j, word := range term.words {
+				if string(words[i+j].lower) != string(word) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				ranges = append(ranges, [2]int{words[i].start, words[i+len(term.words)-1].end})
+			}
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// highlight экранирует текст и выделяет в нем совпадения
+func highlight(text []rune, ranges [][2]int) string {
+	var b strings.Builder
+	pos := 0
+	for _, r := range ranges {
+		b.WriteString(html.EscapeString(string(text[pos:r[0]])))
+		b.WriteString(HIGHLIGHT_OPEN)
+		b.WriteString(html.EscapeString(string(text[r[0]:r[1]])))
+		b.WriteString(HIGHLIGHT_CLOSE)
+		pos = r[1]
+	}
+	b.WriteString(html.EscapeString(string(text[pos:])))
+	return b.String()
+}
+
+// buildHighlights выделяет совпадения в названии и выбирает фрагмент содержимого
+// Фрагмент начинается незадолго до первого совпадения, а если его нет - с начала содержимого
+func buildHighlights(note *models.Note, terms []searchTerm) Highlights {
+	name := []rune(note.Name)
+	content := []rune(note.Content)
+	ranges := matchRanges(content, terms)
+
+	start, end := 0, len(content)
+	if len(content) > SNIPPET_LENGTH {
+		if len(ranges) > 0 {
+			start = max(0, ranges[0][0]-SNIPPET_CONTEXT)
+			// Не разрываем слово в начале фрагмента
+			for start > 0 && !unicode.IsSpace(content[start-1]) {
+				start--
+			}
+		}
+		end = min(len(content), start+SNIPPET_LENGTH)
+	}
+
+	// Оставляем совпадения внутри фрагмента и сдвигаем их к его началу
+	var snippetRanges [][2]int
+	for _, r := range ranges {
+		if r[0] < start || r[1] > end {
+			continue
+		}
+		snippetRanges = append(snippetRanges, [2]int{r[0] - start, r[1] - start})
+	}
+
+	snippet := highlight(content[start:end], snippetRanges)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(content) {
+		snippet += "…"
+	}
+
+	return Highlights{
+		Name:    highlight(name, matchRanges(name, terms)),
+		Content: snippet,
+	}
+}
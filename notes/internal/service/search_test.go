@@ -0,0 +1,117 @@
+package service
+
+import (
+	stderrors "errors"
+	"notes/internal/errors"
+	"notes/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// termStrings представляет термины запроса в виде строк, фразы - в кавычках
+func termStrings(terms []searchTerm) []string {
+	result := make([]string, 0, len(terms))
+	for _, term := range terms {
+		words := make([]string, 0, len(term.words))
+		for _, word := range term.words {
+			words = append(words, string(word))
+		}
+		value := strings.Join(words, " ")
+		if term.phrase {
+			value = `"` + value + `"`
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestParseSearchTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Заметки", []string{"заметки"}},
+		{"покупки  молоко", []string{"покупки", "молоко"}},
+		{`"список покупок" хлеб`, []string{`"список покупок"`, "хлеб"}},
+		{`хлеб -молоко`, []string{"хлеб"}},
+		{`хлеб -"список покупок"`, []string{"хлеб"}},
+		{`"незакрытая фраза`, []string{`"незакрытая фраза"`}},
+		{"e-mail, C++", []string{"e", "mail", "c"}},
+		{`"" - -`, []string{}},
+		{"тест\"фраза\"", []string{"тест", `"фраза"`}},
+	}
+	for _, tt := range tests {
+		if got := termStrings(parseSearchTerms(tt.text)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSearchTerms(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuildHighlights(t *testing.T) {
+	note := &models.Note{
+		Name:    "Список <покупок>",
+		Content: "Купить молоко & хлеб. Молочные продукты в списке покупок.",
+	}
+
+	got := buildHighlights(note, parseSearchTerms(`молоко "списке покупок" -хлеб`))
+	if got.Name != "Список &lt;покупок&gt;" {
+		t.Errorf("Name = %q", got.Name)
+	}
+	wantContent := "Купить <mark>молоко</mark> &amp; хлеб. <mark>Молочные</mark> продукты в <mark>списке покупок</mark>."
+	if got.Content != wantContent {
+		t.Errorf("Content = %q, ожидалось %q", got.Content, wantContent)
+	}
+
+	// Словоформы выделяются по основе слова
+	got = buildHighlights(note, parseSearchTerms("покупка"))
+	if got.Name != "Список &lt;<mark>покупок</mark>&gt;" || !strings.Contains(got.Content, "<mark>покупок</mark>") {
+		t.Errorf("выделение словоформ: %+v", got)
+	}
+
+	// Длинное содержимое сокращается до фрагмента вокруг первого совпадения
+	long := &models.Note{Content: strings.Repeat("слово ", 60) + "искомое " + strings.Repeat("текст ", 60)}
+	got = buildHighlights(long, parseSearchTerms("искомое"))
+	if !strings.HasPrefix(got.Content, "…слово") || !strings.HasSuffix(got.Content, "…") ||
+		!strings.Contains(got.Content, "<mark>искомое</mark>") {
+		t.Errorf("фрагмент длинного содержимого: %q", got.Content)
+	}
+	plain := strings.NewReplacer("…", "", "<mark>", "", "</mark>", "").Replace(got.Content)
+	if n := len([]rune(plain)); n > SNIPPET_LENGTH {
+		t.Errorf("фрагмент длиннее %d символов: %d", SNIPPET_LENGTH, n)
+	}
+
+	// Без совпадений фрагмент начинается с начала содержимого
+	got = buildHighlights(long, parseSearchTerms("отсутствует"))
+	if strings.HasPrefix(got.Content, "…") || strings.Contains(got.Content, "<mark>") || !strings.HasSuffix(got.Content, "…") {
+		t.Errorf("фрагмент без совпадений: %q", got.Content)
+	}
+}
+
+func TestSearchQueryNormalize(t *testing.T) {
+	query := SearchQuery{Text: "  молоко  "}
+	if err := query.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if query.Text != "молоко" || query.Limit != DEFAULT_SEARCH_LIMIT {
+		t.Errorf("Normalize: %+v", query)
+	}
+
+	for _, invalid := range []SearchQuery{
+		{Text: "   "},
+		{Text: "-молоко"},
+		{Text: strings.Repeat("а", MAX_SEARCH_QUERY_LENGTH+1)},
+		{Text: "молоко", Limit: MAX_SEARCH_LIMIT + 1},
+		{Text: "молоко", Limit: -1},
+	} {
+		if err := invalid.Normalize(); !stderrors.Is(err, errors.ErrInvalidSearchQuery) {
+			t.Errorf("Normalize(%+v): ожидалась ErrInvalidSearchQuery, получено %v", invalid, err)
+		}
+	}
+
+	// Курсор одного запроса не подходит для другого
+	other := SearchQuery{Text: "хлеб"}
+	if query.fingerprint() == other.fingerprint() {
+		t.Error("fingerprint: одинаковый отпечаток разных запросов")
+	}
+}
@@ -11,7 +11,14 @@ type Service interface {
 	Close() error                                                       // Закрывает соединение с базой данных
 	Create(ctx context.Context, note models.Note) (*models.Note, error) // Создает новую заметку в базе данных
 	GetByID(ctx context.Context, id string) (*models.Note, error)       // Получает заметку по идентификатору
-	GetAll(ctx context.Context, authorId int) ([]models.Note, error)    // Получает все заметки из базы данных
 	Update(ctx context.Context, note models.Note) (*models.Note, error) // Обновляет существующую заметку
 	Delete(ctx context.Context, id string) error                        // Удаляет заметку по идентификатору
+	DeleteByAuthor(ctx context.Context, authorID int) (int64, error)    // Удаляет все заметки автора
+	AnonymizeByAuthor(ctx context.Context, authorID int) (int64, error) // Отвязывает все заметки от автора
+	// List возвращает страницу заметок автора с сортировкой и фильтрами query
+	List(ctx context.Context, authorID int, query ListQuery) (*NotePage, error)
+	// Search ищет заметки автора по названию и содержимому, результаты упорядочены по релевантности
+	Search(ctx context.Context, authorID int, query SearchQuery) (*SearchPage, error)
+	// ForEachByAuthor читает заметки автора курсором пачками по batchSize и вызывает fn для каждой
+	ForEachByAuthor(ctx context.Context, authorID int, batchSize int32, fn func(models.Note) error) error
 }
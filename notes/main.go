@@ -1,25 +1,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"notes/internal/config"
+	"notes/internal/database"
 	"notes/internal/server"
+	"os"
+	"time"
 )
 
 func main() {
 	cfg := config.NewConfig()
 
+	// Команда "migrate" применяет миграции данных без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(cfg, os.Args[2:]); err != nil {
+			fmt.Printf("Ошибка миграции: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Printf("=== notes Server Configuration ===\n")
 	fmt.Printf("Host: %s\n", cfg.Host)
 	fmt.Printf("Port: %s\n", cfg.Port)
+	fmt.Printf("gRPC Port: %s\n", cfg.GRPCPort)
 	fmt.Printf("Database URL: %s\n", cfg.DBDSN)
 	fmt.Printf("Database SSL: %s\n", cfg.DBSSL)
 	fmt.Printf("Server Timeout: %d seconds\n", cfg.Timeout)
 	fmt.Printf("Database Timeout: %d seconds\n", cfg.DBTimeout)
 	fmt.Printf("Redis Host: %s\n", cfg.RedisHost)
 	fmt.Printf("Redis Port: %s\n", cfg.RedisPort)
-	fmt.Printf("Redis Password: %s\n", cfg.RedisPassword)
-	fmt.Printf("JWT Secret Key: %s\n", cfg.JWTSecretKey)
+	fmt.Printf("JWKS URL: %s\n", cfg.JWKSURL)
+	fmt.Printf("JWT Issuer: %s\n", cfg.JWTIssuer)
+	fmt.Printf("JWT Audience: %s\n", cfg.JWTAudience)
 	fmt.Printf("=============================\n")
 
 	server, err := server.NewServer(cfg)
@@ -35,3 +50,59 @@ func main() {
 	}
 	fmt.Printf("Сервер запущен успешно\n")
 }
+
+// runMigrateCommand выполняет команду миграций:
+//
+//	migrate up         - применить все непримененные миграции
+//	migrate status     - показать состояние миграций
+//
+// Миграции данных MongoDB не откатываются, поэтому команды down нет
+func runMigrateCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("укажите команду: up или status")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	db, err := database.Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer database.CloseDB(db, cfg)
+
+	migrator := database.NewMigrator(db.Database(cfg.DB_NAME).Collection(cfg.DB_COLLECTION))
+
+	// Сама миграция может выполняться дольше подключения
+	migrateCtx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(migrateCtx)
+		for _, migration := range applied {
+			fmt.Printf("Применена миграция %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Нет новых миграций")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(migrateCtx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "не применена"
+			if status.AppliedAt != nil {
+				state = "применена " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				state += " (нет в исполняемом файле)"
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестная команда migrate: %s", args[0])
+	}
+}
@@ -0,0 +1,4 @@
+// Пакет notesv1 содержит код, сгенерированный из notes.proto
+package notesv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative notes.proto
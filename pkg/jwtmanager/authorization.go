@@ -0,0 +1,86 @@
+package jwtmanager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Области доступа, которые понимают сервисы
+const (
+	SCOPE_NOTES_READ  = "notes:read"  // Чтение заметок
+	SCOPE_NOTES_WRITE = "notes:write" // Создание, изменение и удаление заметок
+)
+
+// Роли пользователей
+const (
+	ROLE_USER  = "user"  // Обычный пользователь
+	ROLE_ADMIN = "admin" // Администратор
+)
+
+// RequireScopes создает middleware, пропускающий запрос только если токен
+// содержит все перечисленные области доступа.
+// Должен применяться после JWTInterceptor, который сохраняет утверждения в контексте
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetCurrentClaims(c)
+		if err != nil {
+			c.JSON(401, gin.H{
+				"error": MsgTokenRequired,
+			})
+			c.Abort()
+			return
+		}
+
+		// Собираем недостающие области доступа, чтобы клиент понял, какой токен нужен
+		var missing []string
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			// RFC 6750: ответ с недостаточными правами содержит требуемые области
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+			c.JSON(403, gin.H{
+				"error":           MsgInsufficientScope,
+				"required_scopes": scopes,
+				"missing_scopes":  missing,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole создает middleware, пропускающий запрос только если токен
+// содержит хотя бы одну из перечисленных ролей.
+// Должен применяться после JWTInterceptor, который сохраняет утверждения в контексте
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetCurrentClaims(c)
+		if err != nil {
+			c.JSON(401, gin.H{
+				"error": MsgTokenRequired,
+			})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(403, gin.H{
+			"error":          MsgInsufficientRole,
+			"required_roles": roles,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,103 @@
+package jwtmanager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims - утверждения токенов, выпускаемых JWTManager
+// Помимо зарегистрированных утверждений (iss, sub, aud, exp, nbf, iat, jti)
+// содержит ID пользователя, тип токена, сессию, области доступа, роли и OAuth клиента
+type Claims struct {
+	UserID    int      `json:"id"`                  // ID пользователя (совпадает с sub)
+	Type      string   `json:"type"`                // Тип токена: access или refresh
+	SessionID string   `json:"sid,omitempty"`       // ID сессии, к которой относится токен
+	Scope     string   `json:"scope,omitempty"`     // Области доступа, разделенные пробелом
+	Roles     []string `json:"roles,omitempty"`     // Роли пользователя
+	ClientID  string   `json:"client_id,omitempty"` // OAuth клиент, которому выдан токен
+	// Время выпуска в миллисекундах. iat - целое число секунд (NumericDate), а отзыв всех токенов
+	// пользователя должен отличать токены, выпущенные до и после отзыва в ту же секунду
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Scopes возвращает области доступа токена в виде списка
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope проверяет, содержит ли токен область доступа scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// HasRole проверяет, содержит ли токен роль role
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// IssuedTime возвращает время выпуска токена
+// с точностью до миллисекунды, если токен содержит iat_ms, иначе до секунды
+func (c *Claims) IssuedTime() time.Time {
+	if c.IssuedAtMilli > 0 {
+		return time.UnixMilli(c.IssuedAtMilli)
+	}
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Time
+}
+
+// ExpiresTime возвращает время истечения токена
+func (c *Claims) ExpiresTime() time.Time {
+	if c.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return c.ExpiresAt.Time
+}
+
+// Subject - данные, на которые выпускается пара токенов
+type Subject struct {
+	UserID    int      // ID пользователя
+	SessionID string   // ID сессии (если не пустой, записывается в sid)
+	Scope     string   // Области доступа, разделенные пробелом
+	Roles     []string // Роли пользователя
+	ClientID  string   // OAuth клиент, которому выдаются токены (пусто для входа в сам сервис)
+}
+
+// ValidationOptions - параметры проверки утверждений токена
+type ValidationOptions struct {
+	Issuer    string        // Ожидаемый издатель (iss); пустая строка отключает проверку
+	Audience  string        // Аудитория, которую должен содержать aud; пустая строка отключает проверку
+	ClockSkew time.Duration // Допустимое расхождение часов при проверке exp, nbf и iat
+}
+
+// validate проверяет тип токена, сроки действия, издателя и аудиторию
+func (c *Claims) validate(tokenType string, opts ValidationOptions, now time.Time) error {
+	if tokenType != "" && c.Type != tokenType {
+		return fmt.Errorf("%s: ожидается %s, получен %s", ErrInvalidTokenType, tokenType, c.Type)
+	}
+	if c.ExpiresAt == nil || !now.Before(c.ExpiresAt.Time.Add(opts.ClockSkew)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(opts.ClockSkew).Before(c.NotBefore.Time) {
+		return fmt.Errorf("%s: токен еще не действителен", ErrInvalidToken)
+	}
+	if c.IssuedAt != nil && now.Add(opts.ClockSkew).Before(c.IssuedAt.Time) {
+		return fmt.Errorf("%s: токен выпущен в будущем", ErrInvalidToken)
+	}
+	if opts.Issuer != "" && c.Issuer != opts.Issuer {
+		return fmt.Errorf("%s: %s", ErrInvalidIssuer, c.Issuer)
+	}
+	if opts.Audience != "" && !slices.Contains(c.Audience, opts.Audience) {
+		return fmt.Errorf("%s: %v", ErrInvalidAudience, c.Audience)
+	}
+	if c.UserID == 0 && c.Subject == "" {
+		return ErrMissingUserID
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package jwtmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestClaimsValidate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	opts := ValidationOptions{Issuer: "auth", Audience: "notes", ClockSkew: 30 * time.Second}
+
+	// valid возвращает утверждения, которые проходят проверку
+	valid := func() *Claims {
+		return &Claims{
+			UserID: 1,
+			Type:   ACCESS_TOKEN,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "1",
+				Issuer:    "auth",
+				Audience:  jwt.ClaimStrings{"notes", "admin"},
+				IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+				NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Claims)
+		opts   ValidationOptions
+		target error
+	}{
+		{"действительный токен", func(c *Claims) {}, opts, nil},
+		{"другой тип", func(c *Claims) { c.Type = REFRESH_TOKEN }, opts, ErrInvalidTokenType},
+		{"истек", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }, opts, ErrTokenExpired},
+		{"истек в пределах расхождения часов", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second)) }, opts, nil},
+		{"без exp", func(c *Claims) { c.ExpiresAt = nil }, opts, ErrTokenExpired},
+		{"nbf в будущем", func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Minute)) }, opts, ErrInvalidToken},
+		{"nbf в пределах расхождения часов", func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(10 * time.Second)) }, opts, nil},
+		{"iat в будущем", func(c *Claims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Minute)) }, opts, ErrInvalidToken},
+		{"другой издатель", func(c *Claims) { c.Issuer = "other" }, opts, ErrInvalidIssuer},
+		{"издатель не проверяется", func(c *Claims) { c.Issuer = "" }, ValidationOptions{Audience: "notes"}, nil},
+		{"другая аудитория", func(c *Claims) { c.Audience = jwt.ClaimStrings{"admin"} }, opts, ErrInvalidAudience},
+		{"без аудитории", func(c *Claims) { c.Audience = nil }, opts, ErrInvalidAudience},
+		{"аудитория не проверяется", func(c *Claims) { c.Audience = nil }, ValidationOptions{Issuer: "auth"}, nil},
+		{"без пользователя", func(c *Claims) { c.UserID, c.Subject = 0, "" }, opts, ErrMissingUserID},
+		{"только sub", func(c *Claims) { c.UserID = 0 }, opts, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			err := claims.validate(ACCESS_TOKEN, tt.opts, now)
+			if tt.target == nil {
+				if err != nil {
+					t.Errorf("validate: %v", err)
+				}
+				return
+			}
+			expectErrorText(t, "validate", err, tt.target)
+		})
+	}
+
+	// Пустой ожидаемый тип не проверяется
+	claims := valid()
+	claims.Type = MFA_PENDING_TOKEN
+	if err := claims.validate("", opts, now); err != nil {
+		t.Errorf("validate без типа: %v", err)
+	}
+}
+
+func TestClaimsAccessors(t *testing.T) {
+	claims := &Claims{Scope: "notes:read  notes:write", Roles: []string{"admin"}}
+	if !claims.HasScope("notes:write") || claims.HasScope("notes") || len(claims.Scopes()) != 2 {
+		t.Errorf("Scopes = %q", claims.Scopes())
+	}
+	if !claims.HasRole("admin") || claims.HasRole("user") {
+		t.Errorf("HasRole: роли %v", claims.Roles)
+	}
+	if !claims.IssuedTime().IsZero() || !claims.ExpiresTime().IsZero() {
+		t.Error("IssuedTime и ExpiresTime без iat и exp должны быть нулевыми")
+	}
+}
+
+// TestValidateTokenTypes проверяет, что токены одного типа не принимаются вместо другого
+func TestValidateTokenTypes(t *testing.T) {
+	manager, err := NewJWTManager(JWTConfig{
+		SecretKey:              "test-secret-key-for-jwtmanager-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		Issuer:                 "auth",
+		Audience:               "notes",
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	pair, err := manager.GenerateTokenPair(Subject{UserID: 1, SessionID: "sid", Scope: "notes:read"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	mfa, err := manager.GenerateMFAPendingToken(1, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAPendingToken: %v", err)
+	}
+
+	claims, err := manager.ValidateAccessTokenClaims(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if claims.UserID != 1 || claims.SessionID != "sid" || claims.Issuer != "auth" || !claims.HasScope("notes:read") {
+		t.Errorf("ValidateAccessTokenClaims: утверждения %+v", claims)
+	}
+
+	_, err = manager.ValidateAccessTokenClaims(pair.RefreshToken)
+	expectErrorText(t, "refresh токен вместо access", err, ErrInvalidTokenType)
+	_, err = manager.ValidateRefreshTokenClaims(pair.AccessToken)
+	expectErrorText(t, "access токен вместо refresh", err, ErrInvalidTokenType)
+	_, err = manager.ValidateAccessTokenClaims(mfa)
+	expectErrorText(t, "промежуточный токен вместо access", err, ErrInvalidTokenType)
+	_, err = manager.ValidateAccessTokenClaims(pair.AccessToken + "x")
+	expectErrorText(t, "измененная подпись", err, ErrInvalidToken)
+}
+
+// TestLegacyScope проверяет, что access токен, выпущенный до появления scope,
+// получает LegacyScope, а новые токены и токены OAuth клиентов - нет
+func TestLegacyScope(t *testing.T) {
+	secret := "legacy-scope-secret-key-0123456789-0123456789"
+	manager, err := NewJWTManager(JWTConfig{
+		SecretKey:              secret,
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		LegacyScope:            SCOPE_NOTES_READ + " " + SCOPE_NOTES_WRITE,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return token
+	}
+	now := time.Now()
+
+	// Старый токен: без scope, client_id, iat_ms и kid
+	legacy := sign(jwt.MapClaims{"id": 1, "type": ACCESS_TOKEN, "exp": now.Add(time.Hour).Unix(), "iat": now.Unix()})
+	claims, err := manager.ValidateAccessTokenClaims(legacy)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if !claims.HasScope(SCOPE_NOTES_READ) || !claims.HasScope(SCOPE_NOTES_WRITE) {
+		t.Errorf("старый токен получил области %q", claims.Scope)
+	}
+
+	// Токен OAuth клиента без областей доступа их не получает
+	client := sign(jwt.MapClaims{"id": 1, "type": ACCESS_TOKEN, "client_id": "app", "exp": now.Add(time.Hour).Unix()})
+	if claims, err := manager.ValidateAccessTokenClaims(client); err != nil {
+		t.Errorf("токен OAuth клиента: %v", err)
+	} else if claims.Scope != "" {
+		t.Errorf("токен OAuth клиента получил области %q", claims.Scope)
+	}
+
+	// Новый токен с пустым scope содержит iat_ms и областей не получает
+	token, _, err := manager.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if claims, err := manager.ValidateAccessTokenClaims(token); err != nil {
+		t.Errorf("новый токен: %v", err)
+	} else if claims.Scope != "" {
+		t.Errorf("новый токен получил области %q", claims.Scope)
+	}
+
+	// Без LegacyScope старый токен остается без областей доступа
+	strict, err := NewJWTManager(JWTConfig{SecretKey: secret, AccessTokenExpiration: 1, RefreshTokenExpiration: 24})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	if claims, err := strict.ValidateAccessTokenClaims(legacy); err != nil {
+		t.Errorf("без LegacyScope: %v", err)
+	} else if claims.Scope != "" {
+		t.Errorf("без LegacyScope старый токен получил области %q", claims.Scope)
+	}
+}
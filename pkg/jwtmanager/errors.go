@@ -4,15 +4,25 @@ import "errors"
 
 // JWT-специфичные ошибки
 var (
-	ErrInvalidToken      = errors.New("неверный токен")
-	ErrTokenExpired      = errors.New("токен истек")
-	ErrInvalidTokenType  = errors.New("неверный тип токена")
-	ErrTokenGeneration   = errors.New("ошибка генерации токена")
-	ErrMissingUserID     = errors.New("ID пользователя отсутствует в токене")
-	ErrInvalidSignature  = errors.New("неверная подпись токена")
-	ErrMissingMetadata   = errors.New("метаданные отсутствуют в контексте")
-	ErrMissingAuthHeader = errors.New("отсутствует заголовок Authorization")
-	ErrInvalidAuthFormat = errors.New("неверный формат токена")
+	ErrInvalidToken         = errors.New("неверный токен")
+	ErrTokenExpired         = errors.New("токен истек")
+	ErrInvalidTokenType     = errors.New("неверный тип токена")
+	ErrTokenGeneration      = errors.New("ошибка генерации токена")
+	ErrMissingUserID        = errors.New("ID пользователя отсутствует в токене")
+	ErrInvalidSignature     = errors.New("неверная подпись токена")
+	ErrMissingMetadata      = errors.New("метаданные отсутствуют в контексте")
+	ErrMissingAuthHeader    = errors.New("отсутствует заголовок Authorization")
+	ErrInvalidAuthFormat    = errors.New("неверный формат токена")
+	ErrTokenRevoked         = errors.New("токен отозван")
+	ErrRevocationStore      = errors.New("ошибка хранилища отозванных токенов")
+	ErrInvalidKey           = errors.New("неверный ключ подписи")
+	ErrUnsupportedAlgorithm = errors.New("неподдерживаемый алгоритм подписи")
+	ErrUnknownKey           = errors.New("неизвестный ключ подписи")
+	ErrInvalidIssuer        = errors.New("неверный издатель токена")
+	ErrInvalidAudience      = errors.New("неверная аудитория токена")
+	ErrInsufficientScope    = errors.New("недостаточно областей доступа")
+	ErrInsufficientRole     = errors.New("недостаточно прав")
+	ErrPATStore             = errors.New("ошибка хранилища персональных токенов")
 )
 
 // Сообщения для JWT ошибок
@@ -27,4 +37,9 @@ const (
 	MsgMissingAuthHeader    = "отсутствует заголовок Authorization"
 	MsgInvalidAuthFormat    = "неверный формат токена"
 	MsgTokenRequired        = "токен отсутствует или неверный формат"
+	MsgTokenRevoked         = "токен отозван"
+	MsgRevocationCheck      = "не удалось проверить статус отзыва токена"
+	MsgInsufficientScope    = "у токена нет необходимых областей доступа"
+	MsgInsufficientRole     = "недостаточно прав для выполнения операции"
+	MsgPATCheck             = "не удалось проверить персональный токен доступа"
 )
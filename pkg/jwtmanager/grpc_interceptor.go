@@ -0,0 +1,160 @@
+package jwtmanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// claimsContextKey - ключ утверждений токена в контексте gRPC запроса
+type claimsContextKey struct{}
+
+// UnaryServerInterceptor создает gRPC interceptor для проверки токена в унарных вызовах
+// Токен передается в метаданных authorization в формате "Bearer <токен>", как и в HTTP.
+// Методы из publicMethods (полные имена вида "/auth.v1.AuthService/Login") вызываются без токена
+func (j *JWTManager) UnaryServerInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := methodSet(publicMethods)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := public[info.FullMethod]; ok {
+			return handler(ctx, req)
+		}
+
+		claims, err := j.authenticateMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ContextWithClaims(ctx, claims), req)
+	}
+}
+
+// StreamServerInterceptor создает gRPC interceptor для проверки токена в потоковых вызовах
+// Правила те же, что и в UnaryServerInterceptor
+func (j *JWTManager) StreamServerInterceptor(publicMethods ...string) grpc.StreamServerInterceptor {
+	public := methodSet(publicMethods)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := public[info.FullMethod]; ok {
+			return handler(srv, ss)
+		}
+
+		claims, err := j.authenticateMetadata(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{
+			ServerStream: ss,
+			ctx:          ContextWithClaims(ss.Context(), claims),
+		})
+	}
+}
+
+// authenticatedStream подменяет контекст потока контекстом с утверждениями токена
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока с утверждениями токена
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticateMetadata проверяет токен из метаданных запроса
+// Возвращает ошибку со статусом gRPC: Unauthenticated для отсутствующего или неверного токена
+// и Unavailable, если не удалось обратиться к хранилищам токенов
+func (j *JWTManager) authenticateMetadata(ctx context.Context) (*Claims, error) {
+	tokenString, err := extractTokenFromMetadata(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, MsgTokenRequired)
+	}
+
+	// Персональные токены доступа проверяются по хранилищу, а не по подписи
+	if strings.HasPrefix(tokenString, PAT_PREFIX) {
+		claims, err := j.validatePAT(ctx, tokenString)
+		if errors.Is(err, ErrPATStore) {
+			return nil, status.Error(codes.Unavailable, MsgPATCheck)
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, MsgInvalidToken)
+		}
+
+		// Время использования нужно только для отображения, ошибка не отклоняет запрос
+		_ = j.config.PATStore.TouchPAT(ctx, claims.ID, time.Now())
+		return claims, nil
+	}
+
+	claims, err := j.ValidateAccessTokenClaims(tokenString)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, MsgInvalidToken)
+	}
+
+	// Проверяем, что токен не был отозван (logout, logout-all)
+	if err := j.CheckRevoked(ctx, claims); err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			return nil, status.Error(codes.Unauthenticated, MsgTokenRevoked)
+		}
+		return nil, status.Error(codes.Unavailable, MsgRevocationCheck)
+	}
+
+	return claims, nil
+}
+
+// extractTokenFromMetadata извлекает токен из метаданных authorization входящего gRPC запроса
+func extractTokenFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ErrMissingMetadata
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return "", ErrMissingAuthHeader
+	}
+
+	// Проверяем на формат Bearer
+	const bearerPrefix = "Bearer "
+	authHeader := values[0]
+	if len(authHeader) <= len(bearerPrefix) || !strings.HasPrefix(authHeader, bearerPrefix) {
+		return "", ErrInvalidAuthFormat
+	}
+
+	return authHeader[len(bearerPrefix):], nil
+}
+
+// methodSet собирает полные имена методов в множество
+func methodSet(methods []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+	return set
+}
+
+// ContextWithClaims возвращает контекст с утверждениями токена
+// Используется interceptor'ами; в тестах позволяет вызывать обработчики без токена
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext получает утверждения текущего токена из контекста gRPC запроса
+func ClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	if !ok || claims == nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// UserIDFromContext получает ID текущего пользователя из контекста gRPC запроса
+func UserIDFromContext(ctx context.Context) (int, error) {
+	claims, err := ClaimsFromContext(ctx)
+	if err != nil {
+		return 0, ErrMissingUserID
+	}
+	return claims.UserID, nil
+}
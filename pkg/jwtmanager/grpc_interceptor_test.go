@@ -0,0 +1,237 @@
+package jwtmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubRevocations - хранилище отозванных токенов для тестов interceptor'ов
+type stubRevocations struct {
+	revoked map[string]bool // Отозванные jti
+	err     error           // Ошибка, которую возвращает IsRevoked
+}
+
+func (s *stubRevocations) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.revoked[jti] = true
+	return nil
+}
+
+func (s *stubRevocations) RevokeAllUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	return nil
+}
+
+func (s *stubRevocations) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return nil
+}
+
+func (s *stubRevocations) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.revoked[jti], nil
+}
+
+// testStream - серверный поток gRPC, у которого определен только контекст
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+// interceptorFixture - менеджер токенов с хранилищами для тестов interceptor'ов
+type interceptorFixture struct {
+	manager     *JWTManager
+	revocations *stubRevocations
+	redis       *miniredis.Miniredis
+	pats        *RedisPATStore
+}
+
+func newInterceptorFixture(t *testing.T) *interceptorFixture {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	f := &interceptorFixture{
+		revocations: &stubRevocations{revoked: map[string]bool{}},
+		redis:       server,
+		pats:        NewRedisPATStore(client),
+	}
+	manager, err := NewJWTManager(JWTConfig{
+		SecretKey:              "test-secret-key-for-jwtmanager-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+		RevocationStore:        f.revocations,
+		PATStore:               f.pats,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	f.manager = manager
+	return f
+}
+
+// incoming создает контекст входящего запроса с метаданными authorization
+func incoming(authorization string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+}
+
+// callUnary вызывает обработчик через унарный interceptor и возвращает утверждения из контекста обработчика
+func callUnary(ctx context.Context, interceptor grpc.UnaryServerInterceptor, method string) (*Claims, error) {
+	var claims *Claims
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		claims, _ = ClaimsFromContext(ctx)
+		return nil, nil
+	})
+	return claims, err
+}
+
+// callStream вызывает обработчик через потоковый interceptor и возвращает утверждения из контекста потока
+func callStream(ctx context.Context, interceptor grpc.StreamServerInterceptor, method string) (*Claims, error) {
+	var claims *Claims
+	err := interceptor(nil, &testStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(srv any, ss grpc.ServerStream) error {
+		claims, _ = ClaimsFromContext(ss.Context())
+		return nil
+	})
+	return claims, err
+}
+
+// TestServerInterceptors проверяет унарный и потоковый interceptor'ы на одинаковых сценариях
+func TestServerInterceptors(t *testing.T) {
+	const public = "/auth.v1.AuthService/Login"
+	const private = "/auth.v1.AuthService/GetProfile"
+
+	f := newInterceptorFixture(t)
+	token, _, err := f.manager.GenerateAccessToken(Subject{UserID: 7, Scope: SCOPE_NOTES_READ})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	revoked, revokedClaims, err := f.manager.GenerateAccessToken(Subject{UserID: 7})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	f.revocations.revoked[revokedClaims.ID] = true
+	other, err := NewJWTManager(JWTConfig{
+		SecretKey:              "another-secret-key-for-jwtmanager-012345",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	forged, _, err := other.GenerateAccessToken(Subject{UserID: 7})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	pat, hash, err := GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+	if err := f.pats.SavePAT(context.Background(), hash, PATRecord{ID: "pat", UserID: 9, Scope: SCOPE_NOTES_WRITE}); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	unknownPAT, _, err := GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		code    codes.Code
+		userID  int
+		noClaim bool
+	}{
+		{name: "действительный токен", ctx: incoming("Bearer " + token), method: private, code: codes.OK, userID: 7},
+		{name: "нет метаданных", ctx: context.Background(), method: private, code: codes.Unauthenticated},
+		{name: "нет authorization", ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "1")), method: private, code: codes.Unauthenticated},
+		{name: "без префикса Bearer", ctx: incoming(token), method: private, code: codes.Unauthenticated},
+		{name: "пустой токен", ctx: incoming("Bearer "), method: private, code: codes.Unauthenticated},
+		{name: "неверная подпись", ctx: incoming("Bearer " + forged), method: private, code: codes.Unauthenticated},
+		{name: "отозванный токен", ctx: incoming("Bearer " + revoked), method: private, code: codes.Unauthenticated},
+		{name: "публичный метод без токена", ctx: context.Background(), method: public, code: codes.OK, noClaim: true},
+		{name: "персональный токен", ctx: incoming("Bearer " + pat), method: private, code: codes.OK, userID: 9},
+		{name: "неизвестный персональный токен", ctx: incoming("Bearer " + unknownPAT), method: private, code: codes.Unauthenticated},
+	}
+
+	calls := map[string]func(ctx context.Context, method string) (*Claims, error){
+		"unary": func(ctx context.Context, method string) (*Claims, error) {
+			return callUnary(ctx, f.manager.UnaryServerInterceptor(public), method)
+		},
+		"stream": func(ctx context.Context, method string) (*Claims, error) {
+			return callStream(ctx, f.manager.StreamServerInterceptor(public), method)
+		},
+	}
+	for kind, call := range calls {
+		for _, tt := range tests {
+			t.Run(kind+"/"+tt.name, func(t *testing.T) {
+				claims, err := call(tt.ctx, tt.method)
+				if code := status.Code(err); code != tt.code {
+					t.Fatalf("код = %v, ожидался %v (%v)", code, tt.code, err)
+				}
+				if err != nil {
+					return
+				}
+				if tt.noClaim {
+					if claims != nil {
+						t.Errorf("в контексте публичного метода утверждения: %+v", claims)
+					}
+					return
+				}
+				if claims == nil || claims.UserID != tt.userID {
+					t.Errorf("утверждения в контексте = %+v, ожидался пользователь %d", claims, tt.userID)
+				}
+			})
+		}
+	}
+
+	// Время использования персонального токена сохраняется
+	used, err := f.pats.LastUsed(context.Background(), []string{"pat"})
+	if err != nil {
+		t.Fatalf("LastUsed: %v", err)
+	}
+	if used["pat"].IsZero() {
+		t.Error("время использования персонального токена не сохранено")
+	}
+}
+
+// TestServerInterceptorsUnavailable проверяет, что при недоступных хранилищах
+// запрос отклоняется с кодом Unavailable, а не Unauthenticated
+func TestServerInterceptorsUnavailable(t *testing.T) {
+	const method = "/auth.v1.AuthService/GetProfile"
+
+	f := newInterceptorFixture(t)
+	token, _, err := f.manager.GenerateAccessToken(Subject{UserID: 7})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	pat, _, err := GeneratePAT()
+	if err != nil {
+		t.Fatalf("GeneratePAT: %v", err)
+	}
+	f.revocations.err = errors.New("redis недоступен")
+	f.redis.Close()
+
+	for _, authorization := range []string{"Bearer " + token, "Bearer " + pat} {
+		if _, err := callUnary(incoming(authorization), f.manager.UnaryServerInterceptor(), method); status.Code(err) != codes.Unavailable {
+			t.Errorf("unary: код = %v, ожидался Unavailable", status.Code(err))
+		}
+		if _, err := callStream(incoming(authorization), f.manager.StreamServerInterceptor(), method); status.Code(err) != codes.Unavailable {
+			t.Errorf("stream: код = %v, ожидался Unavailable", status.Code(err))
+		}
+	}
+}
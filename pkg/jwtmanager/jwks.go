@@ -0,0 +1,179 @@
+package jwtmanager
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK - открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`           // Тип ключа: RSA или OKP
+	Kid string `json:"kid,omitempty"` // Идентификатор ключа
+	Alg string `json:"alg,omitempty"` // Алгоритм подписи
+	Use string `json:"use,omitempty"` // Назначение ключа (sig)
+	N   string `json:"n,omitempty"`   // Модуль RSA
+	E   string `json:"e,omitempty"`   // Экспонента RSA
+	Crv string `json:"crv,omitempty"` // Кривая OKP (Ed25519)
+	X   string `json:"x,omitempty"`   // Открытый ключ OKP
+}
+
+// JWKSet - набор открытых ключей, публикуемый по адресу jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey преобразует JWK в открытый ключ
+func (k JWK) PublicKey() (*PublicKey, error) {
+	var key crypto.PublicKey
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%s: кривая %s", ErrUnsupportedAlgorithm, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s: неверный ключ Ed25519", ErrInvalidKey)
+		}
+		key = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("%s: тип ключа %s", ErrUnsupportedAlgorithm, k.Kty)
+	}
+	return &PublicKey{ID: k.Kid, Algorithm: k.Alg, Key: key}, nil
+}
+
+// JWKSClient - источник открытых ключей, загружаемых с JWKS endpoint сервиса auth
+// Ключи кэшируются на время cacheTTL. Если токен подписан неизвестным ключом,
+// набор перезагружается, но не чаще одного раза в minRefreshInterval.
+// Загрузка выполняется без блокировки кэша: пока она идет, запросы с известным kid
+// проверяются ранее загруженными ключами и не ждут ответа auth
+type JWKSClient struct {
+	url                string        // Адрес JWKS endpoint
+	cacheTTL           time.Duration // Время жизни кэша ключей
+	minRefreshInterval time.Duration // Минимальный интервал между загрузками
+	httpClient         *http.Client  // HTTP клиент для загрузки ключей
+
+	mu         sync.Mutex
+	keys       map[string]*PublicKey // Кэш ключей по kid
+	fetchedAt  time.Time             // Время последней успешной загрузки
+	triedAt    time.Time             // Время последней попытки загрузки
+	refreshing chan struct{}         // Закрывается по завершении текущей загрузки; nil, если загрузки нет
+}
+
+// Проверка, что JWKSClient реализует интерфейс KeySource
+var _ KeySource = (*JWKSClient)(nil)
+
+// NewJWKSClient создает клиент JWKS с указанным временем жизни кэша
+func NewJWKSClient(url string, cacheTTL time.Duration) *JWKSClient {
+	return &JWKSClient{
+		url:                url,
+		cacheTTL:           cacheTTL,
+		minRefreshInterval: 10 * time.Second,
+		httpClient:         &http.Client{Timeout: 5 * time.Second},
+		keys:               map[string]*PublicKey{},
+	}
+}
+
+// VerificationKey возвращает открытый ключ по kid, при необходимости обновляя кэш
+// Одновременно выполняется не больше одной загрузки; ждут ее только запросы
+// с kid, которого нет в кэше
+func (c *JWKSClient) VerificationKey(kid string) (*PublicKey, error) {
+	c.mu.Lock()
+	key, found := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.cacheTTL
+	refreshing := c.refreshing
+	if refreshing == nil && (stale || !found) && time.Since(c.triedAt) >= c.minRefreshInterval {
+		c.triedAt = time.Now()
+		refreshing = make(chan struct{})
+		c.refreshing = refreshing
+		c.mu.Unlock()
+		c.refresh(refreshing)
+		c.mu.Lock()
+		key, found = c.keys[kid]
+	} else if refreshing != nil && !found {
+		// Ключ мог появиться в наборе, который сейчас загружается
+		c.mu.Unlock()
+		<-refreshing
+		c.mu.Lock()
+		key, found = c.keys[kid]
+	}
+	c.mu.Unlock()
+
+	if !found {
+		return nil, fmt.Errorf("%s: %s", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// refresh загружает набор ключей, заменяет им кэш и закрывает done
+// При недоступности auth продолжают использоваться ранее загруженные ключи
+func (c *JWKSClient) refresh(done chan struct{}) {
+	keys, err := c.fetch()
+
+	c.mu.Lock()
+	if err == nil {
+		c.keys = keys
+		c.fetchedAt = time.Now()
+	}
+	c.refreshing = nil
+	c.mu.Unlock()
+	close(done)
+
+	if err != nil {
+		fmt.Printf("Ошибка загрузки JWKS: %v\n", err)
+	}
+}
+
+// fetch загружает набор ключей с JWKS endpoint
+func (c *JWKSClient) fetch() (map[string]*PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("неожиданный статус ответа JWKS: %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Пропускаем ключи неподдерживаемых типов
+			continue
+		}
+		keys[key.ID] = key
+	}
+	return keys, nil
+}
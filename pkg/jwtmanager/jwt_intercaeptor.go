@@ -1,7 +1,9 @@
 package jwtmanager
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,8 +22,34 @@ func (j *JWTManager) JWTInterceptor() gin.HandlerFunc {
 			return
 		}
 
+		// Персональные токены доступа проверяются по хранилищу, а не по подписи
+		if strings.HasPrefix(tokenString, PAT_PREFIX) {
+			claims, err := j.validatePAT(c.Request.Context(), tokenString)
+			if errors.Is(err, ErrPATStore) {
+				c.JSON(503, gin.H{
+					"error": MsgPATCheck,
+				})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(401, gin.H{
+					"error": MsgInvalidToken,
+				})
+				c.Abort()
+				return
+			}
+
+			// Время использования нужно только для отображения, ошибка не отклоняет запрос
+			_ = j.config.PATStore.TouchPAT(c.Request.Context(), claims.ID, time.Now())
+
+			setClaims(c, claims)
+			c.Next()
+			return
+		}
+
 		// Валидируем токен
-		userID, err := j.ValidateAccessToken(tokenString)
+		claims, err := j.ValidateAccessTokenClaims(tokenString)
 		if err != nil {
 			c.JSON(401, gin.H{
 				"error": MsgInvalidToken,
@@ -30,12 +58,36 @@ func (j *JWTManager) JWTInterceptor() gin.HandlerFunc {
 			return
 		}
 
-		// Сохраняем ID пользователя в контексте
-		c.Set("user_id", userID)
+		// Проверяем, что токен не был отозван (logout, logout-all)
+		if err := j.CheckRevoked(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, ErrTokenRevoked) {
+				c.JSON(401, gin.H{
+					"error": MsgTokenRevoked,
+				})
+			} else {
+				c.JSON(503, gin.H{
+					"error": MsgRevocationCheck,
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
 		c.Next()
 	}
 }
 
+// setClaims сохраняет утверждения токена в контексте
+// Отдельные значения сохраняются для обратной совместимости
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("token_id", claims.ID)
+	c.Set("token_expires_at", claims.ExpiresTime())
+	c.Set("session_id", claims.SessionID)
+}
+
 // extractTokenFromHeader извлекает JWT токен из HTTP заголовка Authorization
 func (j *JWTManager) extractTokenFromHeader(c *gin.Context) (string, error) {
 	authHeader := c.GetHeader("Authorization")
@@ -68,3 +120,32 @@ func GetCurrentUserID(c *gin.Context) (int, error) {
 
 	return id, nil
 }
+
+// GetCurrentTokenID получает jti и время истечения текущего access токена из контекста Gin
+// Используется, например, для отзыва токена при выходе из системы
+func GetCurrentTokenID(c *gin.Context) (string, time.Time, error) {
+	jti := c.GetString("token_id")
+	if jti == "" {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	return jti, c.GetTime("token_expires_at"), nil
+}
+
+// GetCurrentSessionID получает ID сессии текущего access токена из контекста Gin
+// Возвращает пустую строку, если токен не привязан к сессии
+func GetCurrentSessionID(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
+// GetCurrentClaims получает утверждения текущего access токена из контекста Gin
+func GetCurrentClaims(c *gin.Context) (*Claims, error) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := value.(*Claims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
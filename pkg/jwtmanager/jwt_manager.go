@@ -2,132 +2,434 @@
 package jwtmanager
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // Константы для типов токенов
 const (
 	ACCESS_TOKEN  = "accessToken"  // Тип токена для доступа к API
 	REFRESH_TOKEN = "refreshToken" // Тип токена для обновления access токена
+	// Тип промежуточного токена, выдаваемого после проверки пароля, если включен второй фактор
+	MFA_PENDING_TOKEN = "mfaPendingToken"
+	// Тип персонального токена доступа; такие токены не являются JWT и проверяются по PATStore
+	PERSONAL_ACCESS_TOKEN = "personalAccessToken"
 )
 
 // JWTConfig представляет конфигурацию JWT
 type JWTConfig struct {
-	SecretKey              string // Секретный ключ для подписи JWT токенов
-	AccessTokenExpiration  int    // Срок действия access токена в часах
-	RefreshTokenExpiration int    // Срок действия refresh токена в часах
+	SecretKey              string          // Секретный ключ для подписи JWT токенов (HS256)
+	Algorithm              string          // Алгоритм подписи: HS256 (по умолчанию), RS256 или EdDSA
+	PrivateKeyPEM          []byte          // Закрытый ключ в формате PEM для RS256 и EdDSA
+	Keys                   []SigningKey    // Связка ключей; если задана, SecretKey, Algorithm и PrivateKeyPEM не используются
+	KeySource              KeySource       // Источник открытых ключей (JWKS); если задан, менеджер только проверяет токены
+	AccessTokenExpiration  int             // Срок действия access токена в часах
+	RefreshTokenExpiration int             // Срок действия refresh токена в часах
+	Issuer                 string          // Издатель токенов (iss); если задан, проверяется при валидации
+	Audience               string          // Аудитория токенов (aud); если задана, проверяется при валидации
+	ClockSkew              time.Duration   // Допустимое расхождение часов при проверке сроков действия
+	RevocationStore        RevocationStore // Хранилище отозванных токенов (если nil, отзыв не проверяется)
+	PATStore               PATStore        // Хранилище персональных токенов доступа (если nil, такие токены не принимаются)
+	LegacyScope            string          // Области доступа для access токенов, выпущенных до появления scope, см. applyLegacyScope
 }
 
 // JWTManager предоставляет функционал для работы с JWT токенами.
 // Структура инкапсулирует логику создания, проверки и извлечения токенов.
 type JWTManager struct {
-	config JWTConfig // Конфигурация JWT
+	config  JWTConfig // Конфигурация JWT
+	keyring *Keyring  // Связка ключей подписи (nil, если токены проверяются по KeySource)
 }
 
 // NewJWTManager создает новый сервис для работы с JWT токенами.
-func NewJWTManager(config JWTConfig) *JWTManager {
-	return &JWTManager{
+// Если связка ключей не задана, она строится из одного ключа SecretKey или PrivateKeyPEM.
+func NewJWTManager(config JWTConfig) (*JWTManager, error) {
+	manager := &JWTManager{
 		config: config,
 	}
+
+	// Менеджер, проверяющий токены по внешнему источнику ключей, не подписывает токены
+	if config.KeySource != nil {
+		return manager, nil
+	}
+
+	keys := config.Keys
+	if len(keys) == 0 {
+		key, err := singleKey(config)
+		if err != nil {
+			return nil, err
+		}
+		keys = []SigningKey{key}
+	}
+
+	keyring, err := NewKeyring(keys...)
+	if err != nil {
+		return nil, err
+	}
+	manager.keyring = keyring
+	return manager, nil
+}
+
+// singleKey строит единственный ключ из SecretKey или PrivateKeyPEM
+// Ключ HS256 не получает идентификатор, чтобы выпускаемые им токены не отличались от прежних,
+// асимметричный ключ идентифицируется отпечатком открытого ключа
+func singleKey(config JWTConfig) (SigningKey, error) {
+	method, err := signingMethod(config.Algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	if method.Alg() == ALG_HS256 {
+		return SigningKey{Algorithm: ALG_HS256, Secret: []byte(config.SecretKey)}, nil
+	}
+
+	signer, err := ParsePrivateKeyPEM(method.Alg(), config.PrivateKeyPEM)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	keyID, err := KeyThumbprint(signer.Public())
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return SigningKey{ID: keyID, Algorithm: method.Alg(), PrivateKey: signer}, nil
+}
+
+// JWKS возвращает набор открытых ключей для публикации по адресу jwks.json
+// Ключи HS256 никогда не публикуются
+func (s *JWTManager) JWKS() JWKSet {
+	if s.keyring == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return s.keyring.JWKS(time.Now())
+}
+
+// TokenPair содержит пару выпущенных токенов вместе с их идентификаторами (jti)
+// и временем истечения. Используется сервисами, которым нужно хранить
+// состояние токенов на своей стороне (например, для ротации refresh токенов).
+type TokenPair struct {
+	AccessToken      string    // Подписанный access токен
+	AccessTokenID    string    // Уникальный идентификатор (jti) access токена
+	AccessExpiresAt  time.Time // Время истечения access токена
+	RefreshToken     string    // Подписанный refresh токен
+	RefreshTokenID   string    // Уникальный идентификатор (jti) refresh токена
+	RefreshExpiresAt time.Time // Время истечения refresh токена
+	SessionID        string    // ID сессии (sid), к которой относятся токены
 }
 
 // GenerateTokens генерирует пару токенов (access и refresh) для указанного ID пользователя.
 // Возвращает строки токенов и ошибку, если генерация не удалась.
 func (s *JWTManager) GenerateTokens(id int) (access string, refresh string, err error) {
+	pair, err := s.GenerateTokenPair(Subject{UserID: id})
+	if err != nil {
+		return "", "", err
+	}
+	return pair.AccessToken, pair.RefreshToken, nil
+}
+
+// GenerateTokenPair генерирует пару токенов (access и refresh) для указанного субъекта.
+// В отличие от GenerateTokens возвращает также jti и время истечения каждого токена.
+func (s *JWTManager) GenerateTokenPair(subject Subject) (*TokenPair, error) {
 	// Генерация access token
-	accessTokenString, err := s.generateToken(id, ACCESS_TOKEN, s.config.AccessTokenExpiration)
+	accessTokenString, accessClaims, err := s.generateToken(subject, ACCESS_TOKEN, s.AccessTokenTTL())
 	if err != nil {
-		return "", "", fmt.Errorf("%s: %w", ErrTokenGeneration, err)
+		return nil, fmt.Errorf("%s: %w", ErrTokenGeneration, err)
 	}
 
 	// Генерация refresh token
-	refreshTokenString, err := s.generateToken(id, REFRESH_TOKEN, s.config.RefreshTokenExpiration)
+	refreshTokenString, refreshClaims, err := s.generateToken(subject, REFRESH_TOKEN, time.Hour*time.Duration(s.config.RefreshTokenExpiration))
 	if err != nil {
-		return "", "", fmt.Errorf("%s: %w", ErrTokenGeneration, err)
+		return nil, fmt.Errorf("%s: %w", ErrTokenGeneration, err)
 	}
 
-	return accessTokenString, refreshTokenString, nil
+	return &TokenPair{
+		AccessToken:      accessTokenString,
+		AccessTokenID:    accessClaims.ID,
+		AccessExpiresAt:  accessClaims.ExpiresTime(),
+		RefreshToken:     refreshTokenString,
+		RefreshTokenID:   refreshClaims.ID,
+		RefreshExpiresAt: refreshClaims.ExpiresTime(),
+		SessionID:        subject.SessionID,
+	}, nil
+}
+
+// GenerateAccessToken генерирует только access токен для указанного субъекта.
+// Используется, когда refresh токен не выдается (например, при OAuth client_credentials).
+// Возвращает подписанный токен и его утверждения.
+func (s *JWTManager) GenerateAccessToken(subject Subject) (string, *Claims, error) {
+	token, claims, err := s.generateToken(subject, ACCESS_TOKEN, s.AccessTokenTTL())
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", ErrTokenGeneration, err)
+	}
+	return token, claims, nil
 }
 
 // ValidateAccessToken проверяет корректность access токена.
 // Возвращает ID пользователя из токена и ошибку валидации.
 func (s *JWTManager) ValidateAccessToken(tokenString string) (int, error) {
-	return s.validateToken(tokenString, ACCESS_TOKEN)
+	claims, err := s.ValidateAccessTokenClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// ValidateAccessTokenClaims проверяет access токен с издателем, аудиторией
+// и допустимым расхождением часов из конфигурации. Возвращает утверждения токена.
+func (s *JWTManager) ValidateAccessTokenClaims(tokenString string) (*Claims, error) {
+	return s.ValidateAccessTokenWithOptions(tokenString, s.validationOptions())
+}
+
+// ValidateAccessTokenWithOptions проверяет access токен с явно заданными параметрами проверки.
+// Используется, когда сервис ожидает издателя или аудиторию, отличные от конфигурации.
+func (s *JWTManager) ValidateAccessTokenWithOptions(tokenString string, opts ValidationOptions) (*Claims, error) {
+	return s.parseClaims(tokenString, ACCESS_TOKEN, opts)
 }
 
 // ValidateRefreshToken проверяет корректность refresh токена.
 // Возвращает ID пользователя из токена и ошибку валидации.
 func (s *JWTManager) ValidateRefreshToken(tokenString string) (int, error) {
-	return s.validateToken(tokenString, REFRESH_TOKEN)
+	claims, err := s.ValidateRefreshTokenClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// ValidateRefreshTokenClaims проверяет корректность refresh токена.
+// Возвращает утверждения токена, в том числе его jti.
+func (s *JWTManager) ValidateRefreshTokenClaims(tokenString string) (*Claims, error) {
+	claims, err := s.parseClaims(tokenString, REFRESH_TOKEN, s.validationOptions())
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%s: отсутствует jti", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// GenerateMFAPendingToken генерирует короткоживущий токен, подтверждающий, что пользователь
+// ввел верный пароль, но еще не прошел проверку второго фактора.
+// Токен не дает доступа к API и обменивается на пару токенов после проверки кода
+func (s *JWTManager) GenerateMFAPendingToken(userID int, ttl time.Duration) (string, error) {
+	token, _, err := s.generateToken(Subject{UserID: userID}, MFA_PENDING_TOKEN, ttl)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ErrTokenGeneration, err)
+	}
+	return token, nil
+}
+
+// ValidateMFAPendingToken проверяет промежуточный токен второго фактора.
+// Возвращает утверждения токена.
+func (s *JWTManager) ValidateMFAPendingToken(tokenString string) (*Claims, error) {
+	return s.parseClaims(tokenString, MFA_PENDING_TOKEN, s.validationOptions())
+}
+
+// AccessTokenTTL возвращает срок жизни access токена
+func (s *JWTManager) AccessTokenTTL() time.Duration {
+	return time.Hour * time.Duration(s.config.AccessTokenExpiration)
+}
+
+// RevocationStore возвращает хранилище отозванных токенов или nil, если оно не задано
+func (s *JWTManager) RevocationStore() RevocationStore {
+	return s.config.RevocationStore
+}
+
+// GenerateTokenID генерирует случайный уникальный идентификатор,
+// пригодный для использования в качестве jti или ID семейства токенов.
+func GenerateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validationOptions возвращает параметры проверки токенов из конфигурации
+func (s *JWTManager) validationOptions() ValidationOptions {
+	return ValidationOptions{
+		Issuer:    s.config.Issuer,
+		Audience:  s.config.Audience,
+		ClockSkew: s.config.ClockSkew,
+	}
 }
 
 // generateToken создает и подписывает токен заданного типа.
 // Функция используется внутри сервиса для генерации как access, так и refresh токенов.
-func (s *JWTManager) generateToken(id int, tokenType string, expirationHours int) (string, error) {
+// Возвращает подписанный токен и его утверждения.
+func (s *JWTManager) generateToken(subject Subject, tokenType string, ttl time.Duration) (string, *Claims, error) {
 	now := time.Now()
-	expiration := now.Add(time.Hour * time.Duration(expirationHours))
+	expiration := now.Add(ttl)
+
+	// Уникальный идентификатор токена
+	jti, err := GenerateTokenID()
+	if err != nil {
+		return "", nil, err
+	}
 
 	// Создаем набор утверждений (claims) для токена
-	claims := jwt.MapClaims{
-		"id":   id,                // ID пользователя
-		"type": tokenType,         // Тип токена (access или refresh)
-		"iat":  now.Unix(),        // Время выпуска токена
-		"exp":  expiration.Unix(), // Время истечения срока действия
+	claims := &Claims{
+		UserID:    subject.UserID,    // ID пользователя
+		Type:      tokenType,         // Тип токена (access или refresh)
+		SessionID: subject.SessionID, // ID сессии, к которой относится токен
+		Scope:     subject.Scope,     // Области доступа
+		Roles:     subject.Roles,     // Роли пользователя
+		ClientID:  subject.ClientID,  // OAuth клиент
+		// Время выпуска с миллисекундами для сравнения с отметкой отзыва всех токенов пользователя
+		IssuedAtMilli: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,                            // Уникальный идентификатор токена
+			Subject:   strconv.Itoa(subject.UserID),   // Субъект токена
+			Issuer:    s.config.Issuer,                // Издатель токена
+			IssuedAt:  jwt.NewNumericDate(now),        // Время выпуска токена
+			NotBefore: jwt.NewNumericDate(now),        // Токен действителен с момента выпуска
+			ExpiresAt: jwt.NewNumericDate(expiration), // Время истечения срока действия
+		},
+	}
+	if s.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.config.Audience}
+	}
+
+	// Менеджер без связки ключей (только проверка по JWKS) не может выпускать токены
+	if s.keyring == nil {
+		return "", nil, ErrInvalidKey
+	}
+	key, err := s.keyring.signingKey(now)
+	if err != nil {
+		return "", nil, err
+	}
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", nil, err
 	}
 
 	// Создаем новый токен с выбранным алгоритмом подписи и утверждениями
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Подписываем токен секретным ключом
-	tokenString, err := token.SignedString([]byte(s.config.SecretKey))
+	token := jwt.NewWithClaims(method, claims)
+	// Идентификатор ключа позволяет проверяющей стороне выбрать ключ из связки или JWKS
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	// Подписываем токен ключом
+	tokenString, err := token.SignedString(key.signingMaterial())
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", ErrInvalidSignature, err)
+		return "", nil, fmt.Errorf("%s: %w", ErrInvalidSignature, err)
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
 }
 
-// validateToken - общая функция для валидации токенов.
-// Проверяет подпись, срок действия и тип токена.
-func (s *JWTManager) validateToken(tokenString, tokenType string) (int, error) {
-	// Функция для проверки ключа подписи
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		// Проверка алгоритма подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("%s: %v", ErrInvalidSignature, token.Header["alg"])
+// parseClaims разбирает токен, проверяет подпись, тип токена и утверждения.
+// Возвращает утверждения (claims) токена.
+func (s *JWTManager) parseClaims(tokenString, tokenType string, opts ValidationOptions) (*Claims, error) {
+	// Парсинг токена и проверка подписи
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ErrInvalidToken, err)
+	}
+
+	// Проверка утверждений с учетом допустимого расхождения часов
+	if err := claims.validate(tokenType, opts, time.Now()); err != nil {
+		if errors.Is(err, ErrTokenExpired) {
+			return nil, fmt.Errorf("%s: %w", ErrTokenExpired, err)
 		}
-		return []byte(s.config.SecretKey), nil
-	}
-	// Парсинг токена
-	token, err := jwt.Parse(tokenString, keyFunc)
-	if err != nil { // Проверяем тип ошибки, чтобы дать более точную информацию
-		if ve, ok := err.(*jwt.ValidationError); ok {
-			if ve.Errors&jwt.ValidationErrorExpired != 0 {
-				return 0, fmt.Errorf("%s: %w", ErrTokenExpired, err)
+		return nil, err
+	}
+	if tokenType == ACCESS_TOKEN {
+		s.applyLegacyScope(claims)
+	}
+
+	return claims, nil
+}
+
+// applyLegacyScope назначает LegacyScope access токену, выпущенному до появления областей доступа
+// Такие токены не содержат scope, client_id и iat_ms: сервис auth теперь записывает iat_ms
+// в каждый токен, а токены OAuth клиентов всегда содержат client_id. Поэтому переходный период
+// заканчивается сам, когда истекают последние старые токены (AccessTokenExpiration после обновления auth)
+func (s *JWTManager) applyLegacyScope(claims *Claims) {
+	if s.config.LegacyScope == "" || claims.Scope != "" || claims.ClientID != "" || claims.IssuedAtMilli != 0 {
+		return
+	}
+	claims.Scope = s.config.LegacyScope
+}
+
+// parseToken разбирает токен и проверяет только его подпись,
+// утверждения проверяются отдельно в Claims.validate
+// Токен без kid проверяется по очереди всеми ключами HS256 связки,
+// поскольку до появления связки токены подписывались одним секретом без kid
+func (s *JWTManager) parseToken(tokenString string) (*Claims, error) {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+
+	claims := &Claims{}
+	token, err := parser.ParseWithClaims(tokenString, claims, s.keyFunc)
+	if err == nil || s.keyring == nil || token == nil || token.Header["kid"] != nil {
+		return claims, err
+	}
+	ve, ok := err.(*jwt.ValidationError)
+	if !ok || ve.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+		return claims, err
+	}
+
+	legacy := s.keyring.legacyKeys(time.Now())
+	for i := 1; i < len(legacy); i++ {
+		key := legacy[i]
+		claims = &Claims{}
+		_, err = parser.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+			if t.Method.Alg() != ALG_HS256 {
+				return nil, fmt.Errorf("%s: %v", ErrInvalidSignature, t.Method.Alg())
 			}
+			return key.Secret, nil
+		})
+		if err == nil {
+			break
 		}
-		return 0, fmt.Errorf("%s: %w", ErrInvalidToken, err)
 	}
+	return claims, err
+}
 
-	// Проверка валидности токена и получение данных
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+// keyFunc выбирает ключ для проверки подписи токена по заголовку kid
+// Алгоритм токена должен совпадать с алгоритмом ключа, иначе возможна подмена алгоритма
+func (s *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	kid, _ := token.Header["kid"].(string)
 
-		// Проверка типа токена, если требуется
-		if tokenType != "" {
-			if claimType, exists := claims["type"].(string); !exists || claimType != tokenType {
-				return 0, fmt.Errorf("%s: ожидается %s, получен %s", ErrInvalidTokenType, tokenType, claims["type"])
-			}
+	// Проверка по внешнему источнику открытых ключей
+	if s.config.KeySource != nil {
+		key, err := s.config.KeySource.VerificationKey(kid)
+		if err != nil {
+			return nil, err
 		}
-		// Получение ID пользователя из токена
-		idValue, exists := claims["id"].(float64)
-		if !exists {
-			return 0, fmt.Errorf("%s", ErrMissingUserID)
+		if key.Algorithm != alg {
+			return nil, fmt.Errorf("%s: %v", ErrInvalidSignature, alg)
 		}
+		return key.Key, nil
+	}
 
-		return int(idValue), nil
+	// Проверка ключом из собственной связки
+	key, err := s.keyring.verificationKey(kid, alg, time.Now())
+	if err != nil {
+		return nil, err
 	}
+	return key.verificationMaterial(), nil
+}
 
-	return 0, fmt.Errorf("%s", ErrInvalidToken)
+// CheckRevoked проверяет, не был ли access токен отозван
+// Возвращает ErrTokenRevoked для отозванного токена или ошибку хранилища, если проверить не удалось
+// Если хранилище отозванных токенов не задано, проверка пропускается
+func (s *JWTManager) CheckRevoked(ctx context.Context, claims *Claims) error {
+	if s.config.RevocationStore == nil {
+		return nil
+	}
+	revoked, err := s.config.RevocationStore.IsRevoked(ctx, claims.ID, claims.SessionID, claims.UserID, claims.IssuedTime())
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
 }
@@ -0,0 +1,275 @@
+package jwtmanager
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// KeyState - состояние ключа в связке ключей
+type KeyState string
+
+// Состояния ключей
+const (
+	KEY_ACTIVE      KeyState = "active"      // Ключ подписывает новые токены и проверяет выпущенные
+	KEY_VERIFY_ONLY KeyState = "verify-only" // Ключ только проверяет ранее выпущенные токены
+	KEY_RETIRED     KeyState = "retired"     // Ключ выведен из оборота, токены с ним отклоняются
+)
+
+// SigningKey - ключ из связки ключей JWTManager
+// Окно действия [NotBefore, NotAfter) ограничивает подпись новых токенов,
+// а NotAfter дополнительно ограничивает и проверку. Нулевое время означает отсутствие границы
+type SigningKey struct {
+	ID         string           // Идентификатор ключа, записывается в заголовок kid токена
+	Algorithm  string           // Алгоритм подписи: HS256, RS256 или EdDSA
+	State      KeyState         // Состояние ключа
+	NotBefore  time.Time        // Начало окна, в котором ключ может подписывать токены
+	NotAfter   time.Time        // Окончание действия ключа
+	Secret     []byte           // Общий секрет для HS256
+	PrivateKey crypto.Signer    // Закрытый ключ для RS256 и EdDSA (не нужен для verify-only)
+	PublicKey  crypto.PublicKey // Открытый ключ; вычисляется из закрытого, если не задан
+}
+
+// canSign проверяет, может ли ключ подписывать токены в момент now
+func (k *SigningKey) canSign(now time.Time) bool {
+	if k.State != KEY_ACTIVE {
+		return false
+	}
+	if !k.NotBefore.IsZero() && now.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !now.Before(k.NotAfter) {
+		return false
+	}
+	if k.Algorithm == ALG_HS256 {
+		return len(k.Secret) > 0
+	}
+	return k.PrivateKey != nil
+}
+
+// canVerify проверяет, может ли ключ проверять токены в момент now
+func (k *SigningKey) canVerify(now time.Time) bool {
+	if k.State == KEY_RETIRED {
+		return false
+	}
+	return k.NotAfter.IsZero() || now.Before(k.NotAfter)
+}
+
+// signingMaterial возвращает ключ в виде, ожидаемом библиотекой jwt для подписи
+func (k *SigningKey) signingMaterial() interface{} {
+	if k.Algorithm == ALG_HS256 {
+		return k.Secret
+	}
+	return k.PrivateKey
+}
+
+// verificationMaterial возвращает ключ в виде, ожидаемом библиотекой jwt для проверки
+func (k *SigningKey) verificationMaterial() interface{} {
+	if k.Algorithm == ALG_HS256 {
+		return k.Secret
+	}
+	return k.PublicKey
+}
+
+// Keyring - связка ключей подписи
+// Позволяет выпускать токены текущим ключом и одновременно принимать токены,
+// подписанные предыдущими ключами, что делает ротацию ключей бесшовной
+type Keyring struct {
+	keys []*SigningKey          // Ключи, отсортированные по убыванию NotBefore
+	byID map[string]*SigningKey // Ключи по идентификатору
+}
+
+// NewKeyring создает связку ключей и проверяет корректность каждого ключа
+func NewKeyring(keys ...SigningKey) (*Keyring, error) {
+	ring := &Keyring{byID: make(map[string]*SigningKey, len(keys))}
+	for i := range keys {
+		key := keys[i]
+		// Без идентификатора допускается только ключ HS256, перенесенный из JWT_SECRET_KEY:
+		// токены, подписанные им, не содержат kid, как и до появления связки ключей
+		if key.ID == "" && (len(keys) > 1 || (key.Algorithm != "" && key.Algorithm != ALG_HS256)) {
+			return nil, fmt.Errorf("%s: не указан идентификатор ключа", ErrInvalidKey)
+		}
+		if _, exists := ring.byID[key.ID]; exists {
+			return nil, fmt.Errorf("%s: повторяющийся идентификатор %s", ErrInvalidKey, key.ID)
+		}
+		if key.State == "" {
+			key.State = KEY_ACTIVE
+		}
+		if _, err := signingMethod(key.Algorithm); err != nil {
+			return nil, err
+		}
+		if key.Algorithm == "" {
+			key.Algorithm = ALG_HS256
+		}
+		if key.PublicKey == nil && key.PrivateKey != nil {
+			key.PublicKey = key.PrivateKey.Public()
+		}
+		if key.Algorithm != ALG_HS256 && key.PublicKey == nil {
+			return nil, fmt.Errorf("%s: для ключа %s не задан ни закрытый, ни открытый ключ", ErrInvalidKey, key.ID)
+		}
+		ring.keys = append(ring.keys, &key)
+		ring.byID[key.ID] = &key
+	}
+
+	// Более новые ключи имеют приоритет при выборе ключа подписи
+	sort.SliceStable(ring.keys, func(i, j int) bool {
+		return ring.keys[i].NotBefore.After(ring.keys[j].NotBefore)
+	})
+	return ring, nil
+}
+
+// signingKey возвращает ключ для подписи новых токенов:
+// активный ключ в своем окне действия с самым поздним NotBefore
+func (r *Keyring) signingKey(now time.Time) (*SigningKey, error) {
+	for _, key := range r.keys {
+		if key.canSign(now) {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: нет активного ключа подписи", ErrInvalidKey)
+}
+
+// verificationKey выбирает ключ для проверки токена по kid и алгоритму
+// Токены без kid (выпущенные до появления связки) проверяются ключами HS256, см. legacyKeys
+func (r *Keyring) verificationKey(kid, alg string, now time.Time) (*SigningKey, error) {
+	if kid == "" {
+		if legacy := r.legacyKeys(now); alg == ALG_HS256 && len(legacy) > 0 {
+			return legacy[0], nil
+		}
+		return nil, fmt.Errorf("%s: токен без kid", ErrUnknownKey)
+	}
+
+	key, ok := r.byID[kid]
+	if !ok || !key.canVerify(now) {
+		return nil, fmt.Errorf("%s: %s", ErrUnknownKey, kid)
+	}
+	if key.Algorithm != alg {
+		return nil, fmt.Errorf("%s: %v", ErrInvalidSignature, alg)
+	}
+	return key, nil
+}
+
+// legacyKeys возвращает все ключи HS256, пригодные для проверки токенов без kid
+// Такие токены выпускались до появления связки ключей одним общим секретом
+func (r *Keyring) legacyKeys(now time.Time) []*SigningKey {
+	var keys []*SigningKey
+	for _, key := range r.keys {
+		if key.Algorithm == ALG_HS256 && key.canVerify(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// JWKS возвращает открытые ключи всех не выведенных из оборота асимметричных ключей
+func (r *Keyring) JWKS(now time.Time) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if key.Algorithm == ALG_HS256 || !key.canVerify(now) {
+			continue
+		}
+		jwk, err := newJWK(key.ID, key.Algorithm, key.PublicKey)
+		if err == nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+	return set
+}
+
+// keyFileEntry - описание ключа в файле связки ключей
+type keyFileEntry struct {
+	ID             string    `json:"id"`
+	Algorithm      string    `json:"algorithm"`
+	State          KeyState  `json:"state"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+	Secret         string    `json:"secret"`
+	PrivateKeyFile string    `json:"private_key_file"`
+	PublicKeyFile  string    `json:"public_key_file"`
+}
+
+// LoadKeysFile загружает описание связки ключей из JSON файла вида
+//
+//	{"keys": [{"id": "2025-07", "algorithm": "EdDSA", "state": "active",
+//	           "not_before": "2025-07-01T00:00:00Z", "private_key_file": "2025-07.pem"}]}
+//
+// Относительные пути к PEM файлам отсчитываются от каталога файла связки
+func LoadKeysFile(path string) ([]SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+	}
+
+	var file struct {
+		Keys []keyFileEntry `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+	}
+
+	dir := filepath.Dir(path)
+	readPEM := func(name string) ([]byte, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.ReadFile(name)
+	}
+
+	keys := make([]SigningKey, 0, len(file.Keys))
+	for _, entry := range file.Keys {
+		key := SigningKey{
+			ID:        entry.ID,
+			Algorithm: entry.Algorithm,
+			State:     entry.State,
+			NotBefore: entry.NotBefore,
+			NotAfter:  entry.NotAfter,
+			Secret:    []byte(entry.Secret),
+		}
+		if entry.PrivateKeyFile != "" {
+			pemData, err := readPEM(entry.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+			}
+			if key.PrivateKey, err = ParsePrivateKeyPEM(entry.Algorithm, pemData); err != nil {
+				return nil, err
+			}
+		}
+		if entry.PublicKeyFile != "" {
+			pemData, err := readPEM(entry.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+			}
+			if key.PublicKey, err = ParsePublicKeyPEM(entry.Algorithm, pemData); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParsePublicKeyPEM разбирает открытый ключ в формате PEM для указанного алгоритма
+func ParsePublicKeyPEM(algorithm string, data []byte) (crypto.PublicKey, error) {
+	switch algorithm {
+	case ALG_RS256:
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		return key, nil
+	case ALG_EDDSA:
+		key, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
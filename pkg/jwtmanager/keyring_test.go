@@ -0,0 +1,335 @@
+package jwtmanager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newEdKey создает ключ Ed25519 для связки
+func newEdKey(t *testing.T, id string, state KeyState, notBefore time.Time) SigningKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	return SigningKey{ID: id, Algorithm: ALG_EDDSA, State: state, NotBefore: notBefore, PrivateKey: private}
+}
+
+// newKeyringManager создает менеджер со связкой ключей
+func newKeyringManager(t *testing.T, keys ...SigningKey) *JWTManager {
+	t.Helper()
+	manager, err := NewJWTManager(JWTConfig{Keys: keys, AccessTokenExpiration: 1, RefreshTokenExpiration: 24})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	return manager
+}
+
+// expectErrorText проверяет, что текст ошибки содержит текст target
+// Ошибки пакета оборачиваются через %s, поэтому errors.Is для них не подходит
+func expectErrorText(t *testing.T, op string, err, target error) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), target.Error()) {
+		t.Errorf("%s: ожидалась ошибка %v, получено %v", op, target, err)
+	}
+}
+
+// tokenKeyID возвращает kid из заголовка токена
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	part, _, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatalf("DecodeString: %v", err)
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	return header.Kid
+}
+
+func TestKeyringRotation(t *testing.T) {
+	now := time.Now()
+	old := newEdKey(t, "2025-01", KEY_ACTIVE, now.Add(-48*time.Hour))
+	next := newEdKey(t, "2025-02", KEY_ACTIVE, now.Add(-time.Hour))
+	future := newEdKey(t, "2025-03", KEY_ACTIVE, now.Add(time.Hour))
+
+	// До ротации токены подписывает старый ключ
+	before := newKeyringManager(t, old)
+	oldToken, _, err := before.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	// После ротации старый ключ только проверяет, подписывает самый новый ключ в своем окне
+	old.State = KEY_VERIFY_ONLY
+	after := newKeyringManager(t, old, future, next)
+	newToken, _, err := after.GenerateAccessToken(Subject{UserID: 2})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if kid := tokenKeyID(t, newToken); kid != "2025-02" {
+		t.Errorf("новый токен подписан ключом %q, ожидался 2025-02", kid)
+	}
+	for name, token := range map[string]string{"старый": oldToken, "новый": newToken} {
+		if _, err := after.ValidateAccessTokenClaims(token); err != nil {
+			t.Errorf("%s токен не принят после ротации: %v", name, err)
+		}
+	}
+
+	// Выведенный из оборота ключ больше не принимается
+	old.State = KEY_RETIRED
+	retired := newKeyringManager(t, old, next)
+	_, err = retired.ValidateAccessTokenClaims(oldToken)
+	expectErrorText(t, "токен выведенного ключа", err, ErrUnknownKey)
+
+	// Истекший ключ тоже не принимается
+	old.State = KEY_VERIFY_ONLY
+	old.NotAfter = now.Add(-time.Minute)
+	expired := newKeyringManager(t, old, next)
+	_, err = expired.ValidateAccessTokenClaims(oldToken)
+	expectErrorText(t, "токен истекшего ключа", err, ErrUnknownKey)
+
+	// Без активного ключа токены не выпускаются
+	idle := newKeyringManager(t, future)
+	_, _, err = idle.GenerateAccessToken(Subject{UserID: 1})
+	expectErrorText(t, "связка без активного ключа", err, ErrInvalidKey)
+}
+
+func TestKeyringLegacyTokens(t *testing.T) {
+	secret := "legacy-secret-key-0123456789-0123456789"
+	legacy, err := NewJWTManager(JWTConfig{SecretKey: secret, AccessTokenExpiration: 1, RefreshTokenExpiration: 24})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	token, _, err := legacy.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if kid := tokenKeyID(t, token); kid != "" {
+		t.Fatalf("токен ключа JWT_SECRET_KEY содержит kid %q", kid)
+	}
+
+	// Токен без kid проверяется всеми ключами HS256 связки
+	ring := newKeyringManager(t,
+		SigningKey{ID: "hs-new", Algorithm: ALG_HS256, State: KEY_VERIFY_ONLY, Secret: []byte("another-secret-0123456789-0123456789")},
+		SigningKey{ID: "hs-old", Algorithm: ALG_HS256, State: KEY_VERIFY_ONLY, Secret: []byte(secret)},
+		newEdKey(t, "ed", KEY_ACTIVE, time.Time{}),
+	)
+	if _, err := ring.ValidateAccessTokenClaims(token); err != nil {
+		t.Errorf("токен без kid не принят связкой: %v", err)
+	}
+
+	other := newKeyringManager(t, newEdKey(t, "ed", KEY_ACTIVE, time.Time{}))
+	_, err = other.ValidateAccessTokenClaims(token)
+	expectErrorText(t, "токен без kid в связке без HS256", err, ErrUnknownKey)
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	ed := newEdKey(t, "ed", KEY_ACTIVE, time.Time{})
+	tests := []struct {
+		name   string
+		keys   []SigningKey
+		target error
+	}{
+		{"ключ без идентификатора в связке", []SigningKey{{Algorithm: ALG_HS256, Secret: []byte("s")}, ed}, ErrInvalidKey},
+		{"асимметричный ключ без идентификатора", []SigningKey{{Algorithm: ALG_EDDSA, PrivateKey: ed.PrivateKey}}, ErrInvalidKey},
+		{"повторяющийся идентификатор", []SigningKey{ed, ed}, ErrInvalidKey},
+		{"неподдерживаемый алгоритм", []SigningKey{{ID: "es", Algorithm: "ES256"}}, ErrUnsupportedAlgorithm},
+		{"нет ни закрытого, ни открытого ключа", []SigningKey{{ID: "rs", Algorithm: ALG_RS256}}, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		_, err := NewKeyring(tt.keys...)
+		expectErrorText(t, tt.name, err, tt.target)
+	}
+
+	// Единственный ключ HS256 без идентификатора допустим
+	if _, err := NewKeyring(SigningKey{Secret: []byte("secret")}); err != nil {
+		t.Errorf("NewKeyring с ключом JWT_SECRET_KEY: %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	ed := newEdKey(t, "ed", KEY_ACTIVE, time.Time{})
+	retired := newEdKey(t, "retired", KEY_RETIRED, time.Time{})
+	manager := newKeyringManager(t,
+		ed,
+		SigningKey{ID: "rs", Algorithm: ALG_RS256, State: KEY_VERIFY_ONLY, PrivateKey: rsaKey},
+		retired,
+		SigningKey{ID: "hs", Algorithm: ALG_HS256, State: KEY_VERIFY_ONLY, Secret: []byte("secret")},
+	)
+
+	// Публикуются только открытые ключи действующих асимметричных ключей
+	set := manager.JWKS()
+	published := map[string]JWK{}
+	for _, jwk := range set.Keys {
+		published[jwk.Kid] = jwk
+	}
+	if len(published) != 2 || published["ed"].Kty != "OKP" || published["rs"].Kty != "RSA" {
+		t.Fatalf("JWKS: опубликованы ключи %+v", set.Keys)
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "sig" || jwk.Alg == "" {
+			t.Errorf("JWKS: неполный ключ %+v", jwk)
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey(%s): %v", jwk.Kid, err)
+		}
+		want, _ := KeyThumbprint(manager.keyring.byID[jwk.Kid].PublicKey)
+		got, _ := KeyThumbprint(key.Key)
+		if got != want || key.ID != jwk.Kid || key.Algorithm != jwk.Alg {
+			t.Errorf("PublicKey(%s): ключ не совпадает с исходным", jwk.Kid)
+		}
+	}
+
+	if _, err := (JWK{Kty: "EC"}).PublicKey(); err == nil {
+		t.Error("PublicKey: принят неподдерживаемый тип ключа")
+	}
+	if _, err := (JWK{Kty: "OKP", Crv: "Ed25519", X: "short"}).PublicKey(); err == nil {
+		t.Error("PublicKey: принят неверный ключ Ed25519")
+	}
+
+	// Отпечаток зависит только от открытого ключа
+	first, _ := KeyThumbprint(ed.PrivateKey.Public())
+	second, _ := KeyThumbprint(ed.PrivateKey.Public())
+	other, _ := KeyThumbprint(retired.PrivateKey.Public())
+	if first == "" || first != second || first == other {
+		t.Errorf("KeyThumbprint: %q, %q, %q", first, second, other)
+	}
+}
+
+func TestJWKSClient(t *testing.T) {
+	current := newEdKey(t, "current", KEY_ACTIVE, time.Time{})
+	issuer := newKeyringManager(t, current)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_ = json.NewEncoder(w).Encode(issuer.JWKS())
+	}))
+	defer server.Close()
+
+	client := NewJWKSClient(server.URL, time.Hour)
+	verifier, err := NewJWTManager(JWTConfig{KeySource: client})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+
+	token, _, err := issuer.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	for range 3 {
+		if _, err := verifier.ValidateAccessTokenClaims(token); err != nil {
+			t.Fatalf("токен не принят по JWKS: %v", err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("JWKS загружен %d раз, ожидалась одна загрузка", got)
+	}
+
+	// Токен нового ключа перезагружает набор, но не чаще minRefreshInterval
+	current.State = KEY_VERIFY_ONLY
+	issuer = newKeyringManager(t, current, newEdKey(t, "next", KEY_ACTIVE, time.Now().Add(-time.Minute)))
+	rotated, _, err := issuer.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	_, err = verifier.ValidateAccessTokenClaims(rotated)
+	expectErrorText(t, "новый ключ до истечения minRefreshInterval", err, ErrUnknownKey)
+
+	client.minRefreshInterval = 0
+	if _, err := verifier.ValidateAccessTokenClaims(rotated); err != nil {
+		t.Errorf("токен нового ключа не принят после перезагрузки JWKS: %v", err)
+	}
+
+	// Менеджер, проверяющий по JWKS, не выпускает токены
+	_, _, err = verifier.GenerateAccessToken(Subject{UserID: 1})
+	expectErrorText(t, "выпуск токена без ключей подписи", err, ErrInvalidKey)
+}
+
+// TestJWKSClientRefreshWithoutLock проверяет, что медленная загрузка JWKS
+// не задерживает проверку токенов уже загруженными ключами
+func TestJWKSClientRefreshWithoutLock(t *testing.T) {
+	issuer := newKeyringManager(t, newEdKey(t, "current", KEY_ACTIVE, time.Time{}))
+	set := issuer.JWKS()
+
+	release := make(chan struct{})
+	var slow atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewJWKSClient(server.URL, time.Hour)
+	client.minRefreshInterval = 0
+	if _, err := client.VerificationKey("current"); err != nil {
+		t.Fatalf("VerificationKey: %v", err)
+	}
+
+	// Кэш устарел, а auth отвечает медленно
+	slow.Store(true)
+	client.mu.Lock()
+	client.fetchedAt = time.Time{}
+	client.mu.Unlock()
+	go client.VerificationKey("current")
+	for {
+		client.mu.Lock()
+		refreshing := client.refreshing != nil
+		client.mu.Unlock()
+		if refreshing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.VerificationKey("current")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("VerificationKey во время загрузки: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("VerificationKey ждет загрузки JWKS, хотя ключ есть в кэше")
+	}
+}
+
+// TestGeneratePrivateKeyPEM проверяет, что созданный ключ разбирается ParsePrivateKeyPEM
+func TestGeneratePrivateKeyPEM(t *testing.T) {
+	for _, algorithm := range []string{ALG_EDDSA, ALG_RS256} {
+		data, err := GeneratePrivateKeyPEM(algorithm)
+		if err != nil {
+			t.Fatalf("GeneratePrivateKeyPEM(%s): %v", algorithm, err)
+		}
+		if _, err := ParsePrivateKeyPEM(algorithm, data); err != nil {
+			t.Errorf("ParsePrivateKeyPEM(%s): %v", algorithm, err)
+		}
+	}
+	_, err := GeneratePrivateKeyPEM(ALG_HS256)
+	expectErrorText(t, "GeneratePrivateKeyPEM(HS256)", err, ErrUnsupportedAlgorithm)
+}
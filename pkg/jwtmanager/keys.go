@@ -0,0 +1,160 @@
+package jwtmanager
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Поддерживаемые алгоритмы подписи токенов
+const (
+	ALG_HS256 = "HS256" // Симметричная подпись общим секретом (по умолчанию)
+	ALG_RS256 = "RS256" // Асимметричная подпись ключом RSA
+	ALG_EDDSA = "EdDSA" // Асимметричная подпись ключом Ed25519
+)
+
+// PublicKey - открытый ключ для проверки подписи токенов
+type PublicKey struct {
+	ID        string           // Идентификатор ключа (kid)
+	Algorithm string           // Алгоритм подписи (RS256, EdDSA)
+	Key       crypto.PublicKey // *rsa.PublicKey или ed25519.PublicKey
+}
+
+// KeySource - источник открытых ключей для проверки токенов
+// Используется сервисами, которые только проверяют токены и не должны хранить ключи подписи
+type KeySource interface {
+	// VerificationKey возвращает открытый ключ по идентификатору kid
+	VerificationKey(kid string) (*PublicKey, error)
+}
+
+// ParsePrivateKeyPEM разбирает закрытый ключ в формате PEM для указанного алгоритма
+// Для RS256 ожидается ключ RSA (PKCS#1 или PKCS#8), для EdDSA - ключ Ed25519 (PKCS#8)
+func ParsePrivateKeyPEM(algorithm string, data []byte) (crypto.Signer, error) {
+	switch algorithm {
+	case ALG_RS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		return key, nil
+	case ALG_EDDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrInvalidKey, err)
+		}
+		signer, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: ожидается ключ Ed25519", ErrInvalidKey)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// GeneratePrivateKeyPEM создает новый закрытый ключ для указанного алгоритма в формате PKCS#8 PEM,
+// который принимает ParsePrivateKeyPEM
+func GeneratePrivateKeyPEM(algorithm string) ([]byte, error) {
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case ALG_RS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ALG_EDDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// signingMethod возвращает метод подписи библиотеки jwt по названию алгоритма
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case "", ALG_HS256:
+		return jwt.SigningMethodHS256, nil
+	case ALG_RS256:
+		return jwt.SigningMethodRS256, nil
+	case ALG_EDDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// KeyThumbprint вычисляет отпечаток открытого ключа по RFC 7638
+// Используется как идентификатор ключа (kid), если он не задан явно
+func KeyThumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := newJWK("", "", key)
+	if err != nil {
+		return "", err
+	}
+
+	// Члены JWK в лексикографическом порядке, как требует RFC 7638
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// newJWK преобразует открытый ключ в представление JWK
+func newJWK(kid, algorithm string, key crypto.PublicKey) (*JWK, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Alg: algorithm,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return &JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Alg: algorithm,
+			Use: "sig",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%s: %T", ErrUnsupportedAlgorithm, key)
+	}
+}
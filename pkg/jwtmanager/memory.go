@@ -0,0 +1,234 @@
+package jwtmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryPruneInterval - период очистки истекших записей хранилищ в памяти
+const memoryPruneInterval = time.Minute
+
+// memoryUserRevocation - отметка отзыва всех токенов пользователя
+type memoryUserRevocation struct {
+	revokedAt int64     // Время отзыва в миллисекундах
+	expiresAt time.Time // Время удаления отметки
+}
+
+// MemoryRevocationStore - реализация RevocationStore в памяти процесса
+// Подходит для одного экземпляра сервиса без Redis: отметки не видны другим сервисам
+// и теряются при перезапуске
+type MemoryRevocationStore struct {
+	mu       sync.Mutex
+	tokens   map[string]time.Time // Отозванные токены по jti и время удаления отметки
+	sessions map[string]time.Time // Отозванные сессии и время удаления отметки
+	users    map[int]memoryUserRevocation
+	pruned   time.Time // Время последней очистки истекших отметок
+}
+
+// Проверка, что MemoryRevocationStore реализует интерфейс RevocationStore
+var _ RevocationStore = (*MemoryRevocationStore)(nil)
+
+// NewMemoryRevocationStore создает хранилище отозванных токенов в памяти процесса
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		users:    make(map[int]memoryUserRevocation),
+	}
+}
+
+// RevokeToken добавляет jti в список отозванных до истечения срока действия токена
+func (m *MemoryRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return ErrInvalidToken
+	}
+	if !time.Now().Before(expiresAt) {
+		// Токен уже истек, отзывать нечего
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	m.tokens[jti] = expiresAt
+	return nil
+}
+
+// RevokeAllUserTokens сохраняет время отзыва всех токенов пользователя
+func (m *MemoryRevocationStore) RevokeAllUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	m.users[userID] = memoryUserRevocation{
+		revokedAt: now.UnixMilli(),
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+// RevokeSession сохраняет отметку об отзыве сессии
+func (m *MemoryRevocationStore) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if sessionID == "" {
+		return ErrInvalidToken
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	m.sessions[sessionID] = now.Add(ttl)
+	return nil
+}
+
+// IsRevoked проверяет jti токена, его сессию и отметку отзыва всех токенов пользователя
+func (m *MemoryRevocationStore) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Токен отозван по jti
+	if expiresAt, ok := m.tokens[jti]; jti != "" && ok && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	// Отозвана сессия, к которой относится токен
+	if expiresAt, ok := m.sessions[sessionID]; sessionID != "" && ok && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	// Отозваны все токены пользователя, выпущенные до отметки
+	if revocation, ok := m.users[userID]; ok && now.Before(revocation.expiresAt) {
+		return issuedAt.UnixMilli() < revocation.revokedAt, nil
+	}
+
+	return false, nil
+}
+
+// prune удаляет истекшие отметки не чаще раза в memoryPruneInterval
+func (m *MemoryRevocationStore) prune(now time.Time) {
+	if now.Sub(m.pruned) < memoryPruneInterval {
+		return
+	}
+	m.pruned = now
+	for jti, expiresAt := range m.tokens {
+		if !now.Before(expiresAt) {
+			delete(m.tokens, jti)
+		}
+	}
+	for sessionID, expiresAt := range m.sessions {
+		if !now.Before(expiresAt) {
+			delete(m.sessions, sessionID)
+		}
+	}
+	for userID, revocation := range m.users {
+		if !now.Before(revocation.expiresAt) {
+			delete(m.users, userID)
+		}
+	}
+}
+
+// MemoryPATStore - реализация PATStore в памяти процесса
+// Подходит для одного экземпляра сервиса без Redis: токены не видны другим сервисам,
+// а после перезапуска восстанавливаются сервисом auth из базы данных
+type MemoryPATStore struct {
+	mu      sync.Mutex
+	records map[string]PATRecord // Данные токенов по хешу
+	used    map[string]time.Time // Время последнего использования по ID токена
+}
+
+// Проверка, что MemoryPATStore реализует интерфейс PATStore
+var _ PATStore = (*MemoryPATStore)(nil)
+
+// NewMemoryPATStore создает хранилище персональных токенов в памяти процесса
+func NewMemoryPATStore() *MemoryPATStore {
+	return &MemoryPATStore{
+		records: make(map[string]PATRecord),
+		used:    make(map[string]time.Time),
+	}
+}
+
+// SavePAT сохраняет токен; истекший токен не сохраняется
+func (m *MemoryPATStore) SavePAT(ctx context.Context, hash string, record PATRecord) error {
+	if patExpired(record, time.Now()) {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[hash] = record
+	return nil
+}
+
+// DeletePAT удаляет токен
+func (m *MemoryPATStore) DeletePAT(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, hash)
+	return nil
+}
+
+// LookupPAT находит токен по хешу; истекший токен удаляется, как ключ Redis с истекшим сроком
+func (m *MemoryPATStore) LookupPAT(ctx context.Context, hash string) (*PATRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[hash]
+	if !ok {
+		return nil, nil
+	}
+	if patExpired(record, time.Now()) {
+		delete(m.records, hash)
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// ListPATHashes возвращает хеши всех действующих токенов
+func (m *MemoryPATStore) ListPATHashes(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	hashes := make([]string, 0, len(m.records))
+	for hash, record := range m.records {
+		if patExpired(record, now) {
+			delete(m.records, hash)
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// TouchPAT сохраняет время последнего использования токена
+// Время хранится с точностью до секунды, как в RedisPATStore
+func (m *MemoryPATStore) TouchPAT(ctx context.Context, id string, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used[id] = time.Unix(usedAt.Unix(), 0)
+	return nil
+}
+
+// LastUsed возвращает время последнего использования токенов
+// Токены, которые еще не использовались, в результат не попадают
+func (m *MemoryPATStore) LastUsed(ctx context.Context, ids []string) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]time.Time, len(ids))
+	for _, id := range ids {
+		if usedAt, ok := m.used[id]; ok {
+			result[id] = usedAt
+		}
+	}
+	return result, nil
+}
+
+// patExpired проверяет, истек ли срок действия токена; бессрочный токен не истекает
+func patExpired(record PATRecord, now time.Time) bool {
+	return !record.ExpiresAt.IsZero() && !now.Before(record.ExpiresAt)
+}
@@ -0,0 +1,59 @@
+package jwtmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryRevocationStore проверяет отзыв токена, сессии и всех токенов пользователя
+// в хранилище без Redis, включая истечение отметок
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+	issuedAt := time.Now()
+
+	if err := store.RevokeToken(ctx, "jti1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := store.RevokeSession(ctx, "sid1", 20*time.Millisecond); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		jti       string
+		sessionID string
+		want      bool
+	}{
+		{"отозванный jti", "jti1", "sid2", true},
+		{"отозванная сессия", "jti2", "sid1", true},
+		{"действующий токен", "jti2", "sid2", false},
+	}
+	for _, tt := range tests {
+		revoked, err := store.IsRevoked(ctx, tt.jti, tt.sessionID, 1, issuedAt)
+		if err != nil || revoked != tt.want {
+			t.Errorf("%s: IsRevoked = %v, %v, ожидалось %v", tt.name, revoked, err, tt.want)
+		}
+	}
+
+	// Отметка сессии хранится только ttl
+	time.Sleep(40 * time.Millisecond)
+	if revoked, err := store.IsRevoked(ctx, "jti2", "sid1", 1, issuedAt); err != nil || revoked {
+		t.Errorf("IsRevoked после истечения отметки сессии = %v, %v", revoked, err)
+	}
+
+	// Отзыв всех токенов пользователя не затрагивает токены, выпущенные после него
+	if err := store.RevokeAllUserTokens(ctx, 1, time.Hour); err != nil {
+		t.Fatalf("RevokeAllUserTokens: %v", err)
+	}
+	if revoked, err := store.IsRevoked(ctx, "jti2", "sid2", 1, issuedAt); err != nil || !revoked {
+		t.Errorf("IsRevoked для токена до отзыва пользователя = %v, %v", revoked, err)
+	}
+	if revoked, err := store.IsRevoked(ctx, "jti3", "sid3", 1, time.Now().Add(time.Millisecond)); err != nil || revoked {
+		t.Errorf("IsRevoked для токена после отзыва пользователя = %v, %v", revoked, err)
+	}
+	if revoked, err := store.IsRevoked(ctx, "jti2", "sid2", 2, issuedAt); err != nil || revoked {
+		t.Errorf("IsRevoked для другого пользователя = %v, %v", revoked, err)
+	}
+}
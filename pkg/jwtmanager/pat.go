@@ -0,0 +1,226 @@
+package jwtmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// PAT_PREFIX - префикс персональных токенов доступа
+// По префиксу JWTInterceptor отличает персональный токен от JWT
+const PAT_PREFIX = "pat_"
+
+// PATRecord - данные персонального токена доступа, необходимые сервисам для его проверки
+type PATRecord struct {
+	ID        string    `json:"id"`                   // Публичный идентификатор токена
+	UserID    int       `json:"user_id"`              // ID владельца
+	Scope     string    `json:"scope"`                // Области доступа, разделенные пробелом
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Время истечения; нулевое значение - бессрочный токен
+}
+
+// PATStore - общее для сервисов хранилище персональных токенов доступа
+// Сервис auth сохраняет в нем выпущенные токены, остальные сервисы проверяют их
+// Токены хранятся по SHA-256 хешу, сам токен не сохраняется
+type PATStore interface {
+	// SavePAT сохраняет токен с хешем hash
+	SavePAT(ctx context.Context, hash string, record PATRecord) error
+	// DeletePAT удаляет токен с хешем hash
+	DeletePAT(ctx context.Context, hash string) error
+	// LookupPAT находит токен по хешу; возвращает nil, если токена нет
+	LookupPAT(ctx context.Context, hash string) (*PATRecord, error)
+	// ListPATHashes возвращает хеши всех сохраненных токенов
+	ListPATHashes(ctx context.Context) ([]string, error)
+	// TouchPAT сохраняет время последнего использования токена id
+	TouchPAT(ctx context.Context, id string, usedAt time.Time) error
+	// LastUsed возвращает время последнего использования токенов по их ID
+	LastUsed(ctx context.Context, ids []string) (map[string]time.Time, error)
+}
+
+// GeneratePAT генерирует новый персональный токен доступа и его хеш для хранения
+func GeneratePAT() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = PAT_PREFIX + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPAT(token), nil
+}
+
+// HashPAT вычисляет хеш персонального токена доступа
+func HashPAT(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Префиксы ключей Redis для хранения персональных токенов
+const (
+	patKeyPrefix     = "jwt:pat:"      // Данные токена по хешу
+	patUsedKeyPrefix = "jwt:pat:used:" // Время последнего использования по ID токена
+)
+
+// RedisPATStore - реализация PATStore на основе Redis
+type RedisPATStore struct {
+	client *redis.Client // Клиент Redis
+}
+
+// Проверка, что RedisPATStore реализует интерфейс PATStore
+var _ PATStore = (*RedisPATStore)(nil)
+
+// NewRedisPATStore создает хранилище персональных токенов на основе клиента Redis
+func NewRedisPATStore(client *redis.Client) *RedisPATStore {
+	return &RedisPATStore{
+		client: client,
+	}
+}
+
+// SavePAT сохраняет токен; ключ бессрочного токена не истекает
+func (r *RedisPATStore) SavePAT(ctx context.Context, hash string, record PATRecord) error {
+	var ttl time.Duration
+	if !record.ExpiresAt.IsZero() {
+		ttl = time.Until(record.ExpiresAt)
+		if ttl <= 0 {
+			// Токен уже истек, сохранять нечего
+			return nil
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := r.client.WithContext(ctx).Set(patKeyPrefix+hash, data, ttl).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+	return nil
+}
+
+// DeletePAT удаляет токен
+func (r *RedisPATStore) DeletePAT(ctx context.Context, hash string) error {
+	if err := r.client.WithContext(ctx).Del(patKeyPrefix + hash).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+	return nil
+}
+
+// LookupPAT находит токен по хешу
+func (r *RedisPATStore) LookupPAT(ctx context.Context, hash string) (*PATRecord, error) {
+	data, err := r.client.WithContext(ctx).Get(patKeyPrefix + hash).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+
+	var record PATRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+	return &record, nil
+}
+
+// ListPATHashes возвращает хеши всех сохраненных токенов
+// Ключи перебираются командой SCAN, чтобы не блокировать Redis на большом наборе ключей
+func (r *RedisPATStore) ListPATHashes(ctx context.Context) ([]string, error) {
+	client := r.client.WithContext(ctx)
+
+	var hashes []string
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(cursor, patKeyPrefix+"*", 1000).Result()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPATStore, err)
+		}
+		for _, key := range keys {
+			// Ключи времени использования имеют тот же префикс
+			if strings.HasPrefix(key, patUsedKeyPrefix) {
+				continue
+			}
+			hashes = append(hashes, strings.TrimPrefix(key, patKeyPrefix))
+		}
+		if next == 0 {
+			return hashes, nil
+		}
+		cursor = next
+	}
+}
+
+// TouchPAT сохраняет время последнего использования токена
+func (r *RedisPATStore) TouchPAT(ctx context.Context, id string, usedAt time.Time) error {
+	if err := r.client.WithContext(ctx).Set(patUsedKeyPrefix+id, usedAt.Unix(), 0).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+	return nil
+}
+
+// LastUsed возвращает время последнего использования токенов за один запрос к Redis
+// Токены, которые еще не использовались, в результат не попадают
+func (r *RedisPATStore) LastUsed(ctx context.Context, ids []string) (map[string]time.Time, error) {
+	result := make(map[string]time.Time, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, patUsedKeyPrefix+id)
+	}
+	values, err := r.client.WithContext(ctx).MGet(keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPATStore, err)
+	}
+
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		result[ids[i]] = time.Unix(unix, 0)
+	}
+	return result, nil
+}
+
+// validatePAT проверяет персональный токен доступа по хранилищу
+// и возвращает утверждения, аналогичные утверждениям access токена
+func (j *JWTManager) validatePAT(ctx context.Context, token string) (*Claims, error) {
+	if j.config.PATStore == nil || !strings.HasPrefix(token, PAT_PREFIX) {
+		return nil, ErrInvalidToken
+	}
+
+	record, err := j.config.PATStore.LookupPAT(ctx, HashPAT(token))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrInvalidToken
+	}
+	if !record.ExpiresAt.IsZero() && !time.Now().Before(record.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	claims := &Claims{
+		UserID: record.UserID,
+		Type:   PERSONAL_ACCESS_TOKEN,
+		Scope:  record.Scope,
+	}
+	claims.ID = record.ID
+	claims.Subject = strconv.Itoa(record.UserID)
+	if !record.ExpiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(record.ExpiresAt)
+	}
+	return claims, nil
+}
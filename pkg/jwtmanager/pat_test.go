@@ -0,0 +1,97 @@
+package jwtmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// newTestRedis создает клиент Redis в памяти
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestRedisPATStore проверяет хранилище персональных токенов в Redis
+func TestRedisPATStore(t *testing.T) {
+	testPATStore(t, NewRedisPATStore(newTestRedis(t)))
+}
+
+// TestMemoryPATStore проверяет хранилище персональных токенов в памяти процесса
+func TestMemoryPATStore(t *testing.T) {
+	testPATStore(t, NewMemoryPATStore())
+}
+
+// testPATStore проверяет сохранение, поиск, удаление и перечисление персональных токенов
+func testPATStore(t *testing.T, store PATStore) {
+	ctx := context.Background()
+
+	record := PATRecord{ID: "pat1", UserID: 1, Scope: SCOPE_NOTES_READ}
+	if err := store.SavePAT(ctx, "hash1", record); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	if err := store.SavePAT(ctx, "hash2", PATRecord{ID: "pat2", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	// Истекший токен не сохраняется
+	if err := store.SavePAT(ctx, "expired", PATRecord{ID: "pat3", UserID: 1, ExpiresAt: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatalf("SavePAT: %v", err)
+	}
+	// Время использования хранится с тем же префиксом, но не является токеном
+	if err := store.TouchPAT(ctx, "pat1", time.Now()); err != nil {
+		t.Fatalf("TouchPAT: %v", err)
+	}
+
+	found, err := store.LookupPAT(ctx, "hash1")
+	if err != nil || found == nil || found.ID != "pat1" || found.Scope != SCOPE_NOTES_READ {
+		t.Fatalf("LookupPAT = %+v, %v", found, err)
+	}
+
+	hashes, err := store.ListPATHashes(ctx)
+	if err != nil {
+		t.Fatalf("ListPATHashes: %v", err)
+	}
+	slices.Sort(hashes)
+	if !slices.Equal(hashes, []string{"hash1", "hash2"}) {
+		t.Errorf("ListPATHashes = %v, ожидалось [hash1 hash2]", hashes)
+	}
+
+	if err := store.DeletePAT(ctx, "hash1"); err != nil {
+		t.Fatalf("DeletePAT: %v", err)
+	}
+	if found, err := store.LookupPAT(ctx, "hash1"); err != nil || found != nil {
+		t.Errorf("LookupPAT после DeletePAT = %+v, %v", found, err)
+	}
+
+	used, err := store.LastUsed(ctx, []string{"pat1", "pat2"})
+	if err != nil {
+		t.Fatalf("LastUsed: %v", err)
+	}
+	if _, ok := used["pat1"]; !ok || len(used) != 1 {
+		t.Errorf("LastUsed = %v, ожидалось время использования только pat1", used)
+	}
+}
+
+// TestRedisPATStoreUnavailable проверяет, что ошибки Redis распознаются как ErrPATStore,
+// чтобы недоступность хранилища не выдавалась за неверный токен
+func TestRedisPATStoreUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisPATStore(client)
+	server.Close()
+
+	if _, err := store.LookupPAT(context.Background(), "hash"); !errors.Is(err, ErrPATStore) {
+		t.Errorf("LookupPAT = %v, ожидалась ErrPATStore", err)
+	}
+	if err := store.DeletePAT(context.Background(), "hash"); !errors.Is(err, ErrPATStore) {
+		t.Errorf("DeletePAT = %v, ожидалась ErrPATStore", err)
+	}
+}
@@ -0,0 +1,130 @@
+package jwtmanager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RevocationStore - хранилище отозванных токенов
+// Используется JWTInterceptor для отклонения токенов, отозванных до истечения срока действия.
+// Хранилище должно быть общим для всех сервисов, проверяющих токены (например, Redis)
+type RevocationStore interface {
+	// RevokeToken добавляет jti токена в список отозванных до момента expiresAt
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeAllUserTokens отзывает все токены пользователя, выпущенные до текущего момента.
+	// ttl - время хранения отметки, должно быть не меньше срока жизни access токена
+	RevokeAllUserTokens(ctx context.Context, userID int, ttl time.Duration) error
+	// RevokeSession отзывает все токены сессии sessionID
+	// ttl - время хранения отметки, должно быть не меньше срока жизни access токена
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+	// IsRevoked проверяет, отозван ли токен с указанным jti и сессией sessionID,
+	// выпущенный для userID в момент issuedAt
+	IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// Префиксы ключей Redis для хранения отозванных токенов
+const (
+	revokedTokenKeyPrefix = "jwt:revoked:jti:"  // Отозванные токены по jti
+	revokedUserKeyPrefix  = "jwt:revoked:user:" // Время отзыва всех токенов пользователя
+	revokedSessionPrefix  = "jwt:revoked:sid:"  // Отозванные сессии
+)
+
+// RedisRevocationStore - реализация RevocationStore на основе Redis
+type RedisRevocationStore struct {
+	client *redis.Client // Клиент Redis
+}
+
+// Проверка, что RedisRevocationStore реализует интерфейс RevocationStore
+var _ RevocationStore = (*RedisRevocationStore)(nil)
+
+// NewRedisRevocationStore создает хранилище отозванных токенов на основе клиента Redis
+func NewRedisRevocationStore(client *redis.Client) *RedisRevocationStore {
+	return &RedisRevocationStore{
+		client: client,
+	}
+}
+
+// RevokeToken добавляет jti в список отозванных, ключ живет до истечения срока действия токена
+func (r *RedisRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return ErrInvalidToken
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Токен уже истек, отзывать нечего
+		return nil
+	}
+	if err := r.client.WithContext(ctx).Set(revokedTokenKeyPrefix+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrRevocationStore, err)
+	}
+	return nil
+}
+
+// RevokeAllUserTokens сохраняет время отзыва всех токенов пользователя
+func (r *RedisRevocationStore) RevokeAllUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	key := revokedUserKeyPrefix + strconv.Itoa(userID)
+	if err := r.client.WithContext(ctx).Set(key, time.Now().UnixMilli(), ttl).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrRevocationStore, err)
+	}
+	return nil
+}
+
+// RevokeSession сохраняет отметку об отзыве сессии
+func (r *RedisRevocationStore) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if sessionID == "" {
+		return ErrInvalidToken
+	}
+	if err := r.client.WithContext(ctx).Set(revokedSessionPrefix+sessionID, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrRevocationStore, err)
+	}
+	return nil
+}
+
+// IsRevoked проверяет jti токена, его сессию и отметку отзыва всех токенов пользователя
+// за один запрос к Redis
+func (r *RedisRevocationStore) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	values, err := r.client.WithContext(ctx).MGet(
+		revokedTokenKeyPrefix+jti,
+		revokedSessionPrefix+sessionID,
+		revokedUserKeyPrefix+strconv.Itoa(userID),
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrRevocationStore, err)
+	}
+
+	// Токен отозван по jti
+	if jti != "" && values[0] != nil {
+		return true, nil
+	}
+
+	// Отозвана сессия, к которой относится токен
+	if sessionID != "" && values[1] != nil {
+		return true, nil
+	}
+
+	// Отозваны все токены пользователя, выпущенные до отметки
+	if raw, ok := values[2].(string); ok {
+		revoked, err := issuedBeforeRevocation(issuedAt, raw)
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrRevocationStore, err)
+		}
+		return revoked, nil
+	}
+
+	return false, nil
+}
+
+// issuedBeforeRevocation проверяет, выпущен ли токен раньше отметки отзыва всех токенов пользователя
+// Отметка хранится в миллисекундах, как и время выпуска токена в iat_ms, поэтому токен,
+// выпущенный сразу после отзыва, остается действительным
+func issuedBeforeRevocation(issuedAt time.Time, raw string) (bool, error) {
+	revokedAt, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.UnixMilli() < revokedAt, nil
+}
@@ -0,0 +1,121 @@
+package jwtmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+func TestIssuedBeforeRevocation(t *testing.T) {
+	revokedAt := time.Date(2026, 1, 2, 3, 4, 5, 500*int(time.Millisecond), time.UTC)
+	millis := strconv.FormatInt(revokedAt.UnixMilli(), 10)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		raw      string
+		want     bool
+	}{
+		{"выпущен раньше отзыва", revokedAt.Add(-time.Millisecond), millis, true},
+		{"выпущен в ту же секунду до отзыва", revokedAt.Add(-400 * time.Millisecond), millis, true},
+		{"выпущен в ту же секунду после отзыва", revokedAt.Add(200 * time.Millisecond), millis, false},
+		{"выпущен в момент отзыва", revokedAt, millis, false},
+		{"iat без миллисекунд из той же секунды", revokedAt.Truncate(time.Second), millis, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := issuedBeforeRevocation(tt.issuedAt, tt.raw)
+			if err != nil {
+				t.Fatalf("issuedBeforeRevocation: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("issuedBeforeRevocation(%v, %s) = %v, ожидалось %v", tt.issuedAt, tt.raw, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := issuedBeforeRevocation(revokedAt, "not-a-number"); err == nil {
+		t.Error("issuedBeforeRevocation: ожидалась ошибка для неверной отметки")
+	}
+}
+
+// TestIssuedAtMilli проверяет, что iat остается целым числом секунд,
+// а время выпуска с миллисекундами передается в iat_ms
+func TestIssuedAtMilli(t *testing.T) {
+	manager, err := NewJWTManager(JWTConfig{
+		SecretKey:              "test-secret-key-for-jwtmanager-0123456789",
+		AccessTokenExpiration:  1,
+		RefreshTokenExpiration: 24,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+
+	before := time.Now().UnixMilli()
+	token, _, err := manager.GenerateAccessToken(Subject{UserID: 1})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	after := time.Now().UnixMilli()
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("DecodeString: %v", err)
+	}
+	// Числа декодируются как json.Number, чтобы отличить 1700000000 от 1700000000.123
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var claims map[string]any
+	if err := decoder.Decode(&claims); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	for _, name := range []string{"iat", "exp", "nbf"} {
+		if number, ok := claims[name].(json.Number); !ok || strings.Contains(number.String(), ".") {
+			t.Errorf("%s = %v, ожидалось целое число секунд", name, claims[name])
+		}
+	}
+
+	parsed, err := manager.ValidateAccessTokenClaims(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessTokenClaims: %v", err)
+	}
+	if ms := parsed.IssuedTime().UnixMilli(); ms < before || ms > after {
+		t.Errorf("IssuedTime = %d мс, ожидалось от %d до %d", ms, before, after)
+	}
+	if parsed.IssuedTime().Unix() != parsed.IssuedAt.Unix() {
+		t.Errorf("iat_ms %v не совпадает с iat %v", parsed.IssuedTime(), parsed.IssuedAt.Time)
+	}
+}
+
+// TestRedisRevocationStoreUnavailable проверяет, что ошибки Redis распознаются как ErrRevocationStore,
+// чтобы недоступность хранилища можно было отличить от отозванного токена
+func TestRedisRevocationStoreUnavailable(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisRevocationStore(client)
+	server.Close()
+
+	if _, err := store.IsRevoked(ctx, "jti", "sid", 1, time.Now()); !errors.Is(err, ErrRevocationStore) {
+		t.Errorf("IsRevoked = %v, ожидалась ErrRevocationStore", err)
+	}
+	if err := store.RevokeToken(ctx, "jti", time.Now().Add(time.Hour)); !errors.Is(err, ErrRevocationStore) {
+		t.Errorf("RevokeToken = %v, ожидалась ErrRevocationStore", err)
+	}
+	if err := store.RevokeSession(ctx, "sid", time.Hour); !errors.Is(err, ErrRevocationStore) {
+		t.Errorf("RevokeSession = %v, ожидалась ErrRevocationStore", err)
+	}
+	if err := store.RevokeAllUserTokens(ctx, 1, time.Hour); !errors.Is(err, ErrRevocationStore) {
+		t.Errorf("RevokeAllUserTokens = %v, ожидалась ErrRevocationStore", err)
+	}
+}
@@ -14,4 +14,8 @@ cd auth && \
  POSTGRES_PASSWORD=postgres \
  POSTGRES_DB=postgres \
  POSTGRES_USE_SSL=disable \
+ REDIS_HOST=localhost \
+ REDIS_PORT=6379 \
+ REDIS_PASSWORD=redis \
+ MAIL_DRIVER=log \
  go run main.go
\ No newline at end of file